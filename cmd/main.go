@@ -15,7 +15,14 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/yusufziyrek/bank-app/common/app"
+	"github.com/yusufziyrek/bank-app/common/jwtkeys"
+	"github.com/yusufziyrek/bank-app/common/keyring"
+	"github.com/yusufziyrek/bank-app/common/mailer"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/common/password"
 	"github.com/yusufziyrek/bank-app/common/postgresql"
+	"github.com/yusufziyrek/bank-app/internal/controller"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
 	"github.com/yusufziyrek/bank-app/internal/repository"
 	"github.com/yusufziyrek/bank-app/internal/routes"
 	"github.com/yusufziyrek/bank-app/internal/service"
@@ -46,6 +53,7 @@ func getCORSConfig(cfg *app.ConfigurationManager) middleware.CORSConfig {
 			AllowHeaders: []string{
 				echo.HeaderOrigin, echo.HeaderContentType,
 				echo.HeaderAccept, echo.HeaderAuthorization,
+				controller.HeaderIdempotencyKey,
 			},
 			MaxAge: 86400,
 		}
@@ -60,11 +68,41 @@ func getCORSConfig(cfg *app.ConfigurationManager) middleware.CORSConfig {
 		AllowHeaders: []string{
 			echo.HeaderOrigin, echo.HeaderContentType,
 			echo.HeaderAccept, echo.HeaderAuthorization,
+			controller.HeaderIdempotencyKey,
 		},
 		MaxAge: 86400,
 	}
 }
 
+// loadPasswordPolicy builds the password policy and loads the breached
+// password list when one is configured
+func loadPasswordPolicy(cfg *app.ConfigurationManager) service.PasswordPolicy {
+	required, err := password.ParseClasses(cfg.PasswordRequiredClasses)
+	if err != nil {
+		log.Fatalf("PASSWORD_REQUIRED_CLASSES hatası: %v", err)
+	}
+	policy := service.PasswordPolicy{
+		Rules: password.Policy{
+			MinLength:          cfg.PasswordMinLength,
+			MaxLength:          72, // bcrypt sınırı
+			MinClasses:         cfg.PasswordMinClasses,
+			Required:           required,
+			RejectPersonalInfo: true,
+		},
+		History: cfg.PasswordHistory,
+	}
+	if cfg.BreachedPasswordsFile == "" {
+		log.Printf("Warning: BREACHED_PASSWORDS_FILE tanımlı değil, sızdırılmış şifre kontrolü yapılmayacak")
+		return policy
+	}
+	policy.Breached, err = password.LoadBreachedListFile(cfg.BreachedPasswordsFile)
+	if err != nil {
+		log.Fatalf("BREACHED_PASSWORDS_FILE hatası: %v", err)
+	}
+	log.Printf("✓ %d sızdırılmış şifre özeti yüklendi", policy.Breached.Len())
+	return policy
+}
+
 func main() {
 	// Çalışma dizinini kontrol et
 	wd, _ := os.Getwd()
@@ -107,7 +145,12 @@ func main() {
 
 	e := echo.New()
 	e.Debug = cfg.AppEnv != "production" // Prod'da debug kapalı
-	e.Validator = &CustomValidator{validator: validator.New()}
+	e.IPExtractor = controller.ClientIPExtractor(cfg.TrustedProxies)
+	v := validator.New()
+	if err := dto.RegisterValidations(v); err != nil {
+		log.Fatalf("Validator kayıt hatası: %v", err)
+	}
+	e.Validator = &CustomValidator{validator: v}
 
 	// Middleware setup
 	e.Use(middleware.Logger())
@@ -117,11 +160,82 @@ func main() {
 	e.Use(middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(20)))
 	e.Use(middleware.CORSWithConfig(getCORSConfig(cfg)))
 
+	passwordPolicy := loadPasswordPolicy(cfg)
 	repo := repository.NewUserRepository(pool)
-	svc := service.NewUserService(repo)
+	svc := service.NewUserService(repo, passwordPolicy)
+	accountRepo := repository.NewAccountRepository(pool)
+	accountSvc := service.NewAccountService(accountRepo, cfg.CountryCode, cfg.Currency)
+	ledgerRepo := repository.NewLedgerRepository(pool)
+	ledgerSvc := service.NewLedgerService(ledgerRepo)
+	transactionRepo := repository.NewTransactionRepository(pool)
+	transactionSvc := service.NewTransactionService(accountRepo, ledgerRepo, transactionRepo)
+	idempotencySvc := service.NewIdempotencyService(repository.NewIdempotencyRepository(pool))
+	cardKeys, err := keyring.New(cfg.CardEncryptionKeyID, cfg.CardEncryptionKeys, cfg.CardHashKey)
+	if err != nil {
+		log.Fatalf("Kart şifreleme anahtarı hatası: %v", err)
+	}
+	cardSvc := service.NewCardService(repository.NewCardRepository(pool, cardKeys), accountRepo, cfg.CardBIN)
+	// TOTP secrets are sealed with the same keys as card data
+	mfaSvc := service.NewMFAService(repository.NewMFARepository(pool, cardKeys), repo, cfg.MFAIssuer)
+
+	var mail mailer.Mailer = mailer.NewLogMailer(os.Stdout, cfg.SMTP.From)
+	if cfg.SMTP.Host != "" {
+		mail = mailer.NewSMTPMailer(cfg.SMTP)
+	}
+	passwordResetSvc := service.NewPasswordResetService(repository.NewPasswordResetRepository(pool), repo, passwordPolicy, mail, cfg.PasswordResetURL)
+	emailVerificationSvc := service.NewEmailVerificationService(repository.NewEmailVerificationRepository(pool), repo, mail, cfg.EmailVerificationURL)
+
+	loginAttemptSvc := service.NewLoginAttemptService(repository.NewLoginAttemptRepository(pool), repo, service.LoginThrottlePolicy{
+		MaxAccountFailures: cfg.LoginMaxFailures,
+		MaxIPFailures:      cfg.LoginIPMaxFailures,
+		Window:             cfg.LoginFailureWindow,
+		LockoutDuration:    cfg.LoginLockoutDuration,
+	})
+
+	oauthSvc := service.NewOAuthService(repository.NewOAuthRepository(pool), svc)
+	apiKeySvc := service.NewAPIKeyService(repository.NewAPIKeyRepository(pool), svc)
+
+	transferThreshold, err := money.Parse(cfg.StepUpTransferThreshold, cfg.Currency)
+	if err != nil {
+		log.Fatalf("STEP_UP_TRANSFER_THRESHOLD hatası: %v", err)
+	}
+	stepUp := controller.StepUpPolicy{
+		TokenTTL:               cfg.StepUpTokenTTL,
+		EmailChange:            cfg.StepUpEmailMaxAge,
+		PasswordChange:         cfg.StepUpPasswordMaxAge,
+		LargeTransfer:          cfg.StepUpTransferMaxAge,
+		APIKeyCreation:         cfg.StepUpAPIKeyMaxAge,
+		MFADisable:             cfg.StepUpMFAMaxAge,
+		LargeTransferThreshold: transferThreshold,
+	}
+
+	jwtKeys, err := jwtkeys.New(cfg.JwtSigningKeys...)
+	if err != nil {
+		log.Fatalf("JWT anahtar hatası: %v", err)
+	}
+	if current, err := jwtKeys.Current(); err == nil {
+		log.Printf("✓ JWT imzalama anahtarı: %s (%s)", current.ID, current.Algorithm())
+	}
+	for _, k := range jwtKeys.Upcoming() {
+		log.Printf("⇨ JWT anahtarı %s %s tarihinde devreye girecek", k.ID, k.ActiveFrom.Format(time.RFC3339))
+	}
+
+	accessTokens := controller.AccessTokenConfig{
+		Keys:     jwtKeys,
+		Issuer:   cfg.JwtIssuer,
+		Audience: cfg.JwtAudience,
+		TTL:      time.Duration(cfg.JwtTTL) * time.Minute,
+		Leeway:   time.Duration(cfg.JwtLeeway) * time.Second,
+	}
 
 	// Setup routes
-	routes.SetupRoutes(e, svc, cfg.JwtSecret, time.Duration(cfg.JwtTTL)*time.Minute)
+	routes.SetupRoutes(e, svc, accountSvc, ledgerSvc, transactionSvc, idempotencySvc, cardSvc, mfaSvc, passwordResetSvc, emailVerificationSvc, loginAttemptSvc, oauthSvc, apiKeySvc, accessTokens, stepUp)
+
+	sweepCtx, stopSweeper := context.WithCancel(ctx)
+	defer stopSweeper()
+	go service.RunRefreshTokenSweeper(sweepCtx, svc, time.Duration(cfg.RefreshTokenSweepInterval)*time.Minute)
+	go service.RunLoginFailureSweeper(sweepCtx, loginAttemptSvc, time.Duration(cfg.LoginFailureSweepInterval)*time.Minute)
+	go service.RunIdempotencyKeySweeper(sweepCtx, idempotencySvc, time.Duration(cfg.IdempotencyKeySweepInterval)*time.Minute)
 
 	go func() {
 		addr := "127.0.0.1:" + cfg.AppPort
@@ -137,6 +251,7 @@ func main() {
 	<-quit
 
 	log.Println("Sunucu kapatılıyor…")
+	stopSweeper()
 	ctxShut, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := e.Shutdown(ctxShut); err != nil {
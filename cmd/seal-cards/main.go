@@ -0,0 +1,44 @@
+// Command seal-cards encrypts card data stored before card encryption. Run it
+// once after scripts/migrations/011_card_encryption.sql, with the same
+// CARD_ENCRYPTION_* and CARD_HASH_KEY settings as the server:
+//
+//	go run ./cmd/seal-cards
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/joho/godotenv"
+
+	"github.com/yusufziyrek/bank-app/common/app"
+	"github.com/yusufziyrek/bank-app/common/keyring"
+	"github.com/yusufziyrek/bank-app/common/postgresql"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: .env dosyası yüklenemedi: %v", err)
+	}
+
+	cfg := app.NewConfigurationManager()
+
+	ctx := context.Background()
+	pool, err := postgresql.GetConnectionPool(ctx, cfg.PostgreSqlConfig)
+	if err != nil {
+		log.Fatalf("DB bağlantı hatası: %v", err)
+	}
+	defer pool.Close()
+
+	keys, err := keyring.New(cfg.CardEncryptionKeyID, cfg.CardEncryptionKeys, cfg.CardHashKey)
+	if err != nil {
+		log.Fatalf("Kart şifreleme anahtarı hatası: %v", err)
+	}
+
+	n, err := repository.SealPlaintextCards(ctx, pool, keys)
+	if err != nil {
+		log.Fatalf("Kartlar şifrelenemedi: %v", err)
+	}
+	log.Printf("✓ %d kart şifrelendi", n)
+}
@@ -1,11 +1,18 @@
 package app
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"log"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/yusufziyrek/bank-app/common/jwtkeys"
+	"github.com/yusufziyrek/bank-app/common/keyring"
+	"github.com/yusufziyrek/bank-app/common/mailer"
 	"github.com/yusufziyrek/bank-app/common/postgresql"
 )
 
@@ -13,9 +20,64 @@ type ConfigurationManager struct {
 	PostgreSqlConfig postgresql.Config
 	AppPort          string
 	AppEnv           string
-	JwtSecret        string
 	JwtTTL           int
 	AllowedOrigins   string
+	CountryCode      string
+	Currency         string
+	CardBIN          string
+	// TrustedProxies may set X-Forwarded-For; requests from anywhere else
+	// are identified by their own address
+	TrustedProxies []*net.IPNet
+	// JwtSigningKeys sign access tokens; see common/jwtkeys
+	JwtSigningKeys []jwtkeys.Key
+	// Access tokens name JwtIssuer as "iss" and JwtAudience as "aud" and are
+	// only accepted with both. JwtLeeway is in seconds.
+	JwtIssuer   string
+	JwtAudience string
+	JwtLeeway   int
+	// MFAIssuer names the service in authenticator apps
+	MFAIssuer string
+	// RefreshTokenSweepInterval is in minutes
+	RefreshTokenSweepInterval int
+	// Step-up authentication; ages and the token TTL are set in minutes and
+	// an age of 0 turns the check off for that operation
+	StepUpTokenTTL          time.Duration
+	StepUpEmailMaxAge       time.Duration
+	StepUpPasswordMaxAge    time.Duration
+	StepUpTransferMaxAge    time.Duration
+	StepUpAPIKeyMaxAge      time.Duration
+	StepUpMFAMaxAge         time.Duration
+	StepUpTransferThreshold string
+	// Failed login limits; a limit of 0 turns locking off for that scope.
+	// The window and lockout duration are set in minutes.
+	LoginMaxFailures     int
+	LoginIPMaxFailures   int
+	LoginFailureWindow   time.Duration
+	LoginLockoutDuration time.Duration
+	// LoginFailureSweepInterval is in minutes
+	LoginFailureSweepInterval int
+	// IdempotencyKeySweepInterval is in minutes
+	IdempotencyKeySweepInterval int
+	// Password policy; see common/password. PasswordRequiredClasses is a
+	// comma separated list of lower, upper, digit and symbol. Without
+	// BreachedPasswordsFile the breach check is skipped.
+	PasswordMinLength       int
+	PasswordMinClasses      int
+	PasswordRequiredClasses string
+	PasswordHistory         int
+	BreachedPasswordsFile   string
+	// SMTP relay for outgoing mail; without SMTP.Host mail is only logged,
+	// which is refused in production
+	SMTP mailer.SMTPConfig
+	// PasswordResetURL is the page reset links point to; the token is added
+	// as the "token" query parameter
+	PasswordResetURL string
+	// EmailVerificationURL is the page verification links point to
+	EmailVerificationURL string
+	// Card data encryption; see common/keyring
+	CardEncryptionKeyID string
+	CardEncryptionKeys  map[string][]byte
+	CardHashKey         []byte
 }
 
 func NewConfigurationManager() *ConfigurationManager {
@@ -72,9 +134,16 @@ func NewConfigurationManager() *ConfigurationManager {
 		appEnv = "development"
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "change-me"
+	jwtSigningKeys := loadJWTSigningKeys(appEnv)
+
+	jwtIssuer := os.Getenv("JWT_ISSUER")
+	if jwtIssuer == "" {
+		jwtIssuer = "bank-app"
+	}
+
+	jwtAudience := os.Getenv("JWT_AUDIENCE")
+	if jwtAudience == "" {
+		jwtAudience = "bank-app-api"
 	}
 
 	jwtTTLStr := os.Getenv("JWT_TTL")
@@ -88,6 +157,65 @@ func NewConfigurationManager() *ConfigurationManager {
 		allowedOrigins = "http://localhost:3000,https://yourdomain.com"
 	}
 
+	trustedProxies := loadTrustedProxies()
+
+	countryCode := os.Getenv("ACCOUNT_COUNTRY_CODE")
+	if countryCode == "" {
+		countryCode = "TR"
+	}
+
+	currency := os.Getenv("ACCOUNT_CURRENCY")
+	if currency == "" {
+		currency = "TRY"
+	}
+
+	cardBIN := os.Getenv("CARD_BIN")
+	if cardBIN == "" {
+		cardBIN = "979200"
+	}
+
+	mfaIssuer := os.Getenv("MFA_ISSUER")
+	if mfaIssuer == "" {
+		mfaIssuer = "Bank App"
+	}
+
+	sweepStr := os.Getenv("REFRESH_TOKEN_SWEEP_INTERVAL")
+	sweepInterval, err := strconv.Atoi(sweepStr)
+	if err != nil || sweepInterval <= 0 {
+		sweepInterval = 60 // Default 60 minutes
+	}
+
+	loginSweepStr := os.Getenv("LOGIN_FAILURE_SWEEP_INTERVAL")
+	loginSweepInterval, err := strconv.Atoi(loginSweepStr)
+	if err != nil || loginSweepInterval <= 0 {
+		loginSweepInterval = 15 // Default 15 minutes
+	}
+
+	idempotencySweepStr := os.Getenv("IDEMPOTENCY_KEY_SWEEP_INTERVAL")
+	idempotencySweepInterval, err := strconv.Atoi(idempotencySweepStr)
+	if err != nil || idempotencySweepInterval <= 0 {
+		idempotencySweepInterval = 60 // Default 60 minutes
+	}
+
+	stepUpThreshold := os.Getenv("STEP_UP_TRANSFER_THRESHOLD")
+	if stepUpThreshold == "" {
+		stepUpThreshold = "10000.00"
+	}
+
+	smtpConfig := loadSMTPConfig(appEnv)
+
+	passwordResetURL := os.Getenv("PASSWORD_RESET_URL")
+	if passwordResetURL == "" {
+		passwordResetURL = "http://localhost:3000/reset-password"
+	}
+
+	emailVerificationURL := os.Getenv("EMAIL_VERIFICATION_URL")
+	if emailVerificationURL == "" {
+		emailVerificationURL = "http://localhost:3000/verify-email"
+	}
+
+	cardKeyID, cardKeys, cardHashKey := loadCardKeys(appEnv)
+
 	// Debug log'ları ekle
 	log.Printf("PostgreSQL Config - Host: %s, Port: %s, User: %s, DB: %s", host, port, user, db)
 
@@ -103,8 +231,183 @@ func NewConfigurationManager() *ConfigurationManager {
 		},
 		AppPort:        appPort,
 		AppEnv:         appEnv,
-		JwtSecret:      jwtSecret,
+		JwtSigningKeys: jwtSigningKeys,
 		JwtTTL:         jwtTTL,
 		AllowedOrigins: allowedOrigins,
+		CountryCode:    countryCode,
+		Currency:       currency,
+		CardBIN:        cardBIN,
+		TrustedProxies: trustedProxies,
+
+		JwtIssuer:   jwtIssuer,
+		JwtAudience: jwtAudience,
+		JwtLeeway:   envInt("JWT_LEEWAY", 30),
+
+		MFAIssuer:                 mfaIssuer,
+		RefreshTokenSweepInterval: sweepInterval,
+
+		StepUpTokenTTL:          envMinutes("STEP_UP_TOKEN_TTL", 10),
+		StepUpEmailMaxAge:       envMinutes("STEP_UP_EMAIL_MAX_AGE", 5),
+		StepUpPasswordMaxAge:    envMinutes("STEP_UP_PASSWORD_MAX_AGE", 5),
+		StepUpTransferMaxAge:    envMinutes("STEP_UP_TRANSFER_MAX_AGE", 5),
+		StepUpAPIKeyMaxAge:      envMinutes("STEP_UP_API_KEY_MAX_AGE", 5),
+		StepUpMFAMaxAge:         envMinutes("STEP_UP_MFA_MAX_AGE", 5),
+		StepUpTransferThreshold: stepUpThreshold,
+
+		LoginMaxFailures:     envInt("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures:   envInt("LOGIN_IP_MAX_FAILURES", 50),
+		LoginFailureWindow:   envMinutes("LOGIN_FAILURE_WINDOW", 15),
+		LoginLockoutDuration: envMinutes("LOGIN_LOCKOUT_DURATION", 15),
+
+		LoginFailureSweepInterval: loginSweepInterval,
+
+		IdempotencyKeySweepInterval: idempotencySweepInterval,
+
+		PasswordMinLength:       envInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMinClasses:      envInt("PASSWORD_MIN_CLASSES", 2),
+		PasswordRequiredClasses: os.Getenv("PASSWORD_REQUIRED_CLASSES"),
+		PasswordHistory:         envInt("PASSWORD_HISTORY", 5),
+		BreachedPasswordsFile:   os.Getenv("BREACHED_PASSWORDS_FILE"),
+
+		SMTP:                 smtpConfig,
+		PasswordResetURL:     passwordResetURL,
+		EmailVerificationURL: emailVerificationURL,
+
+		CardEncryptionKeyID: cardKeyID,
+		CardEncryptionKeys:  cardKeys,
+		CardHashKey:         cardHashKey,
+	}
+}
+
+// envMinutes reads a non-negative number of minutes, falling back to def
+// minutes when the variable is unset or malformed
+func envMinutes(name string, def int) time.Duration {
+	return time.Duration(envInt(name, def)) * time.Minute
+}
+
+// envInt reads a non-negative integer, falling back to def when the variable
+// is unset or malformed
+func envInt(name string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || v < 0 {
+		return def
+	}
+	return v
+}
+
+// loadTrustedProxies reads TRUSTED_PROXIES, a comma separated list of IPs or
+// CIDR ranges. It defaults to a proxy on the loopback interface; "none"
+// trusts no proxy.
+func loadTrustedProxies() []*net.IPNet {
+	spec := os.Getenv("TRUSTED_PROXIES")
+	if spec == "" {
+		spec = "127.0.0.1,::1"
+	}
+	if strings.TrimSpace(spec) == "none" {
+		return nil
+	}
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				log.Fatalf("Geçersiz TRUSTED_PROXIES: %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Fatalf("Geçersiz TRUSTED_PROXIES: %v", err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// loadJWTSigningKeys reads the keys listed in JWT_SIGNING_KEYS. Outside
+// production a throwaway Ed25519 key is generated when none are configured.
+func loadJWTSigningKeys(appEnv string) []jwtkeys.Key {
+	spec := os.Getenv("JWT_SIGNING_KEYS")
+	if spec == "" {
+		if appEnv == "production" {
+			log.Fatalf("JWT_SIGNING_KEYS production ortamında zorunludur")
+		}
+		log.Printf("Warning: JWT_SIGNING_KEYS tanımlı değil, geçici bir geliştirme anahtarı üretiliyor; token'lar yeniden başlatmada geçersiz olur")
+		key, err := jwtkeys.GenerateEd25519("dev")
+		if err != nil {
+			log.Fatalf("JWT geliştirme anahtarı üretilemedi: %v", err)
+		}
+		return []jwtkeys.Key{key}
+	}
+	keys, err := jwtkeys.LoadKeys(spec)
+	if err != nil {
+		log.Fatalf("Geçersiz JWT_SIGNING_KEYS: %v", err)
+	}
+	return keys
+}
+
+func loadSMTPConfig(appEnv string) mailer.SMTPConfig {
+	cfg := mailer.SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("MAIL_FROM"),
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	if cfg.From == "" {
+		cfg.From = "Bank App <no-reply@bank-app.local>"
+	}
+	if cfg.Host == "" {
+		if appEnv == "production" {
+			log.Fatalf("SMTP_HOST production ortamında zorunludur")
+		}
+		log.Printf("Warning: SMTP_HOST tanımlı değil, e-postalar sadece log'a yazılacak")
+	}
+	return cfg
+}
+
+// loadCardKeys reads the card encryption keys. CARD_ENCRYPTION_KEYS lists every
+// key that may still be needed for decryption as id:base64 pairs and
+// CARD_ENCRYPTION_KEY_ID selects the one new data is sealed with; rotating
+// means adding a key and pointing the id at it. Outside production, fixed
+// development keys are used when nothing is configured.
+func loadCardKeys(appEnv string) (string, map[string][]byte, []byte) {
+	keysSpec := os.Getenv("CARD_ENCRYPTION_KEYS")
+	keyID := os.Getenv("CARD_ENCRYPTION_KEY_ID")
+	hashKeyStr := os.Getenv("CARD_HASH_KEY")
+
+	if keysSpec == "" && hashKeyStr == "" {
+		if appEnv == "production" {
+			log.Fatalf("CARD_ENCRYPTION_KEYS ve CARD_HASH_KEY production ortamında zorunludur")
+		}
+		log.Printf("Warning: kart şifreleme anahtarları tanımlı değil, geliştirme anahtarları kullanılıyor")
+		devKey := sha256.Sum256([]byte("bank-app-dev-card-encryption-key"))
+		devHashKey := sha256.Sum256([]byte("bank-app-dev-card-hash-key"))
+		return "dev", map[string][]byte{"dev": devKey[:]}, devHashKey[:]
+	}
+
+	keys, err := keyring.ParseKeys(keysSpec)
+	if err != nil {
+		log.Fatalf("Geçersiz CARD_ENCRYPTION_KEYS: %v", err)
+	}
+	if _, ok := keys[keyID]; !ok {
+		log.Fatalf("Geçersiz CARD_ENCRYPTION_KEY_ID: %q CARD_ENCRYPTION_KEYS içinde yok", keyID)
+	}
+	hashKey, err := base64.StdEncoding.DecodeString(hashKeyStr)
+	if err != nil || len(hashKey) != keyring.KeySize {
+		log.Fatalf("Geçersiz CARD_HASH_KEY: 32 byte'lık base64 anahtar olmalı")
 	}
+	return keyID, keys, hashKey
 }
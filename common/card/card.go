@@ -0,0 +1,92 @@
+package card
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// PANLength is the length of a generated primary account number
+const PANLength = 16
+
+// CVVLength is the length of a generated card verification value
+const CVVLength = 3
+
+var ErrInvalidBIN = errors.New("card: BIN must be 6 to 8 digits")
+
+// GeneratePAN returns a random card number that starts with bin and ends with
+// a Luhn check digit
+func GeneratePAN(bin string) (string, error) {
+	if !isDigits(bin) || len(bin) < 6 || len(bin) > 8 {
+		return "", ErrInvalidBIN
+	}
+
+	body, err := randomDigits(PANLength - len(bin) - 1)
+	if err != nil {
+		return "", err
+	}
+	partial := bin + body
+	return partial + string(rune('0'+luhnCheckDigit(partial))), nil
+}
+
+// GenerateCVV returns a random card verification value
+func GenerateCVV() (string, error) {
+	return randomDigits(CVVLength)
+}
+
+// ValidLuhn reports whether s is a numeric string with a valid Luhn check digit
+func ValidLuhn(s string) bool {
+	if len(s) < 2 || !isDigits(s) {
+		return false
+	}
+	return luhnCheckDigit(s[:len(s)-1]) == int(s[len(s)-1]-'0')
+}
+
+// MaskPAN hides all but the last four digits of a card number
+func MaskPAN(pan string) string {
+	if len(pan) <= 4 {
+		return strings.Repeat("*", len(pan))
+	}
+	return strings.Repeat("*", len(pan)-4) + pan[len(pan)-4:]
+}
+
+// luhnCheckDigit computes the digit that makes partial+digit pass the Luhn check
+func luhnCheckDigit(partial string) int {
+	sum := 0
+	// Walking from the right, every digit at an even offset gets doubled once
+	// the check digit is appended
+	for i := len(partial) - 1; i >= 0; i-- {
+		d := int(partial[i] - '0')
+		if (len(partial)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+func randomDigits(n int) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("card: random: %w", err)
+		}
+		b[i] = byte('0' + d.Int64())
+	}
+	return string(b), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
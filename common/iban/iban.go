@@ -0,0 +1,68 @@
+package iban
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Length is the total length of a generated account number:
+// 2 letter country code + 2 check digits + BBAN
+const Length = 20
+
+const bbanLength = Length - 4
+
+var ErrInvalidCountryCode = errors.New("iban: country code must be two uppercase letters")
+
+// Generate returns a random account number in IBAN layout (CCkk + numeric BBAN)
+// whose check digits are computed with the ISO 7064 mod-97 algorithm
+func Generate(countryCode string) (string, error) {
+	if !isCountryCode(countryCode) {
+		return "", ErrInvalidCountryCode
+	}
+
+	bban := make([]byte, bbanLength)
+	for i := range bban {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("iban: random: %w", err)
+		}
+		bban[i] = byte('0' + n.Int64())
+	}
+
+	check := 98 - mod97(string(bban)+countryCode+"00")
+	return fmt.Sprintf("%s%02d%s", countryCode, check, bban), nil
+}
+
+// Validate reports whether s is a well-formed account number with valid check digits
+func Validate(s string) bool {
+	if len(s) != Length || !isCountryCode(s[:2]) {
+		return false
+	}
+	for _, r := range s[2:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return mod97(s[4:]+s[:4]) == 1
+}
+
+func isCountryCode(s string) bool {
+	return len(s) == 2 && s[0] >= 'A' && s[0] <= 'Z' && s[1] >= 'A' && s[1] <= 'Z'
+}
+
+// mod97 computes the remainder of the IBAN numeric representation of s
+// (letters replaced by 10..35) piecewise, so no big integer is needed
+func mod97(s string) int {
+	rem := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			rem = (rem*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			rem = (rem*100 + int(r-'A') + 10) % 97
+		}
+	}
+	return rem
+}
@@ -0,0 +1,51 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK is the public half of a key as described in RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (RFC 8037)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set, including keys scheduled to
+// become active later. The legacy HS256 secret is never published.
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	enc := base64.RawURLEncoding
+	for _, k := range s.keys {
+		jwk := JWK{Use: "sig", Kid: k.ID, Alg: k.Algorithm()}
+		switch pub := k.signer.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = enc.EncodeToString(pub.N.Bytes())
+			jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = enc.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
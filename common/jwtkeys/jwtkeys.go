@@ -0,0 +1,188 @@
+// Package jwtkeys signs access tokens with asymmetric keys identified by
+// "kid", verifies them, and publishes the public keys as a JSON Web Key Set
+// so other services can verify tokens without sharing a secret.
+//
+// Rotation is scheduled by giving a new key an ActiveFrom time in the future.
+// The key is published right away, so verifiers that cache the key set know
+// it before the first token signed with it appears. Once it is active, older
+// keys stay configured until the tokens they signed have expired.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA modulus accepted for signing
+const minRSABits = 2048
+
+var (
+	ErrInvalidKeyID         = errors.New("jwtkeys: key id must be non-empty letters, digits, '-', '_' or '.'")
+	ErrDuplicateKeyID       = errors.New("jwtkeys: duplicate key id")
+	ErrUnsupportedKey       = errors.New("jwtkeys: keys must be RSA with at least 2048 bits or Ed25519")
+	ErrNoActiveKey          = errors.New("jwtkeys: no key is active yet")
+	ErrUnknownKey           = errors.New("jwtkeys: unknown key id")
+	ErrAlgorithmMismatch    = errors.New("jwtkeys: token algorithm does not match its key")
+	ErrInvalidPrivateKeyPEM = errors.New("jwtkeys: no PEM encoded private key found")
+)
+
+// Key is one signing key
+type Key struct {
+	ID string
+	// ActiveFrom is when the key starts signing; the zero time means at once
+	ActiveFrom time.Time
+	signer     crypto.Signer
+	method     jwt.SigningMethod
+}
+
+// NewKey wraps an RSA or Ed25519 private key. RSA keys sign with RS256 and
+// Ed25519 keys with EdDSA.
+func NewKey(id string, private crypto.Signer, activeFrom time.Time) (Key, error) {
+	if !validKeyID(id) {
+		return Key{}, ErrInvalidKeyID
+	}
+	k := Key{ID: id, ActiveFrom: activeFrom, signer: private}
+	switch priv := private.(type) {
+	case *rsa.PrivateKey:
+		if priv.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("%w: key %q has %d bits", ErrUnsupportedKey, id, priv.N.BitLen())
+		}
+		k.method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return Key{}, fmt.Errorf("%w: key %q is %T", ErrUnsupportedKey, id, private)
+	}
+	return k, nil
+}
+
+// ParsePrivateKeyPEM reads a PKCS#8 or PKCS#1 ("RSA PRIVATE KEY") block
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPrivateKeyPEM
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwtkeys: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKey
+	}
+	return signer, nil
+}
+
+// Algorithm returns the JWS algorithm the key signs with
+func (k Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+// KeySet signs with the most recently activated key and verifies tokens
+// signed by any of its keys
+type KeySet struct {
+	// keys is ordered by ActiveFrom, oldest first
+	keys []Key
+	byID map[string]Key
+	now  func() time.Time
+}
+
+// New builds a key set. At least one key must already be active.
+func New(keys ...Key) (*KeySet, error) {
+	s := &KeySet{byID: make(map[string]Key, len(keys)), now: time.Now}
+	for _, k := range keys {
+		if k.signer == nil {
+			return nil, ErrUnsupportedKey
+		}
+		if _, dup := s.byID[k.ID]; dup {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateKeyID, k.ID)
+		}
+		s.byID[k.ID] = k
+		s.keys = append(s.keys, k)
+	}
+	sort.SliceStable(s.keys, func(i, j int) bool {
+		return s.keys[i].ActiveFrom.Before(s.keys[j].ActiveFrom)
+	})
+	if _, err := s.Current(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Current returns the key new tokens are signed with
+func (s *KeySet) Current() (Key, error) {
+	now := s.now()
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if !s.keys[i].ActiveFrom.After(now) {
+			return s.keys[i], nil
+		}
+	}
+	return Key{}, ErrNoActiveKey
+}
+
+// Upcoming returns keys that are published but not signing yet
+func (s *KeySet) Upcoming() []Key {
+	now := s.now()
+	var out []Key
+	for _, k := range s.keys {
+		if k.ActiveFrom.After(now) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// Sign signs claims with the current key and names it in the "kid" header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	k, err := s.Current()
+	if err != nil {
+		return "", err
+	}
+	t := jwt.NewWithClaims(k.method, claims)
+	t.Header["kid"] = k.ID
+	return t.SignedString(k.signer)
+}
+
+// Keyfunc resolves the verification key of a token for jwt.Parse. The
+// algorithm in the header must be the one of the named key, so a token
+// cannot get an RSA public key used as an HMAC secret.
+func (s *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrUnknownKey
+	}
+	k, ok := s.byID[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if t.Method.Alg() != k.method.Alg() {
+		return nil, ErrAlgorithmMismatch
+	}
+	return k.signer.Public(), nil
+}
+
+func validKeyID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
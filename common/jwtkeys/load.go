@@ -0,0 +1,69 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// LoadKeys reads the keys named by spec, a comma separated list of id:path
+// entries. An entry may end in @ and an RFC 3339 time to schedule when the
+// key starts signing, e.g.
+// "2026-01:/etc/bank/jwt-2026-01.pem,2026-04:/etc/bank/jwt-2026-04.pem@2026-04-01T00:00:00Z"
+func LoadKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, rest, ok := strings.Cut(entry, ":")
+		if !ok || !validKeyID(id) {
+			return nil, ErrInvalidKeyID
+		}
+		path, activeFrom, err := splitActiveFrom(rest)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: key %q: %w", id, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: key %q: %w", id, err)
+		}
+		signer, err := ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: key %q: %w", id, err)
+		}
+		k, err := NewKey(id, signer, activeFrom)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func splitActiveFrom(s string) (string, time.Time, error) {
+	i := strings.LastIndexByte(s, '@')
+	if i < 0 {
+		return s, time.Time{}, nil
+	}
+	at, err := time.Parse(time.RFC3339, s[i+1:])
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("activation time: %w", err)
+	}
+	return s[:i], at, nil
+}
+
+// GenerateEd25519 creates a random key that lives only as long as the
+// process. It is meant for development, where tokens may as well stop
+// working after a restart.
+func GenerateEd25519(id string) (Key, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Key{}, fmt.Errorf("jwtkeys: %w", err)
+	}
+	return NewKey(id, priv, time.Time{})
+}
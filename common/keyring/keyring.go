@@ -0,0 +1,199 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the required length of key-encryption and hash keys (AES-256)
+const KeySize = 32
+
+// version prefixes every sealed value so the format can evolve
+const version = "v1"
+
+var (
+	ErrInvalidKey   = errors.New("keyring: keys must be 32 bytes")
+	ErrInvalidKeyID = errors.New("keyring: key id must be non-empty letters, digits, '-' or '_'")
+	ErrUnknownKey   = errors.New("keyring: unknown key id")
+	ErrMalformed    = errors.New("keyring: malformed sealed value")
+)
+
+// Keyring performs envelope encryption: every value is encrypted with a fresh
+// data key, and the data key is wrapped with the current key-encryption key.
+// Sealed values name the key that wrapped them, so rotating keys only means
+// adding a new current key while keeping the old ones for decryption.
+type Keyring struct {
+	currentID string
+	keks      map[string]cipher.AEAD
+	hashKey   []byte
+}
+
+// New builds a keyring that seals with keys[currentID] and can open values
+// sealed with any of keys. hashKey is used for lookup hashes and is never
+// rotated, since that would change every stored hash.
+func New(currentID string, keys map[string][]byte, hashKey []byte) (*Keyring, error) {
+	if len(hashKey) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	if _, ok := keys[currentID]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, currentID)
+	}
+
+	keks := make(map[string]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		if !validKeyID(id) {
+			return nil, ErrInvalidKeyID
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		keks[id] = aead
+	}
+	return &Keyring{currentID: currentID, keks: keks, hashKey: hashKey}, nil
+}
+
+// ParseKeys parses a comma separated list of id:base64-key pairs,
+// e.g. "2025-01:q83v...,2024-06:Zm9v..."
+func ParseKeys(spec string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, pair := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || !validKeyID(id) {
+			return nil, ErrInvalidKeyID
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("keyring: key %q: %w", id, err)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("%w: key %q", ErrInvalidKey, id)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// CurrentKeyID returns the id of the key new values are sealed with
+func (k *Keyring) CurrentKeyID() string {
+	return k.currentID
+}
+
+// Seal encrypts plaintext. aad is authenticated but not stored, and the same
+// aad must be passed to Open; it binds the ciphertext to where it is stored.
+func (k *Keyring) Seal(plaintext, aad string) (string, error) {
+	dek := make([]byte, KeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", fmt.Errorf("keyring: data key: %w", err)
+	}
+	data, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(data, []byte(plaintext), []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keks[k.currentID], dek, []byte(k.currentID))
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawStdEncoding
+	return strings.Join([]string{version, k.currentID, enc.EncodeToString(wrapped), enc.EncodeToString(ciphertext)}, ":"), nil
+}
+
+// Open decrypts a value produced by Seal with the same aad
+func (k *Keyring) Open(sealed, aad string) (string, error) {
+	parts := strings.Split(sealed, ":")
+	if len(parts) != 4 || parts[0] != version {
+		return "", ErrMalformed
+	}
+	kek, ok := k.keks[parts[1]]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, parts[1])
+	}
+
+	enc := base64.RawStdEncoding
+	wrapped, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	ciphertext, err := enc.DecodeString(parts[3])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	dek, err := open(kek, wrapped, []byte(parts[1]))
+	if err != nil {
+		return "", err
+	}
+	data, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(data, ciphertext, []byte(aad))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Hash returns a keyed HMAC-SHA256 of value for equality lookups on sealed data
+func (k *Keyring) Hash(value string) string {
+	mac := hmac.New(sha256.New, k.hashKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce || ciphertext
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("keyring: nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func validKeyID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogMailer writes every message to w instead of delivering it. Point it at
+// os.Stdout or a file during development.
+type LogMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewLogMailer(w io.Writer, from string) *LogMailer {
+	return &LogMailer{w: w, from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	raw, err := format(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := fmt.Fprintf(m.w, "----- mail -----\r\n%s----- end mail -----\r\n", raw); err != nil {
+		return fmt.Errorf("mailer:log: %w", err)
+	}
+	return nil
+}
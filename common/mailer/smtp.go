@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// implicitTLSPort is the SMTP submission port that expects TLS from the
+// first byte (RFC 8314); other ports upgrade with STARTTLS when offered
+const implicitTLSPort = "465"
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer delivers messages through an SMTP relay, authenticating with
+// PLAIN when a username is configured
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	raw, err := format(m.cfg.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	// The envelope takes bare addresses, while headers may carry a display name
+	from, err := mail.ParseAddress(m.cfg.From)
+	if err != nil {
+		return fmt.Errorf("mailer:smtp:from: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("mailer:smtp:to: %w", err)
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	tlsConfig := &tls.Config{ServerName: m.cfg.Host}
+	var conn net.Conn
+	if m.cfg.Port == implicitTLSPort {
+		d := &tls.Dialer{Config: tlsConfig}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("mailer:smtp:dial: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("mailer:smtp:hello: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && m.cfg.Port != implicitTLSPort {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("mailer:smtp:starttls: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		auth := smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("mailer:smtp:auth: %w", err)
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("mailer:smtp:mail: %w", err)
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("mailer:smtp:rcpt: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("mailer:smtp:data: %w", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return fmt.Errorf("mailer:smtp:write: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("mailer:smtp:data: %w", err)
+	}
+	return c.Quit()
+}
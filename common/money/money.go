@@ -0,0 +1,267 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scale is the number of minor-unit digits; every supported currency uses cents
+const Scale = 2
+
+const minorPerMajor = 100
+
+var (
+	ErrInvalidAmount    = errors.New("money: invalid amount")
+	ErrPrecision        = errors.New("money: amount has more than two decimal places")
+	ErrOverflow         = errors.New("money: amount out of range")
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+)
+
+// RoundingMode selects how ParseRounded treats digits beyond Scale
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest cent, ties to the even cent (banker's rounding)
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest cent, ties away from zero
+	RoundHalfUp
+	// RoundDown truncates towards zero
+	RoundDown
+)
+
+// Money is an exact amount in minor units (cents) of a currency.
+// The zero value is zero in an unspecified currency.
+type Money struct {
+	minor    int64
+	currency string
+}
+
+// New returns an amount of minor units in the given ISO 4217 currency
+func New(minor int64, currency string) Money {
+	return Money{minor: minor, currency: currency}
+}
+
+// Parse parses a decimal string such as "12.34" or "-0.5". Amounts with more
+// than two decimal places are rejected rather than silently rounded.
+func Parse(s, currency string) (Money, error) {
+	intPart, frac, neg, err := split(s)
+	if err != nil {
+		return Money{}, err
+	}
+	if len(frac) > Scale {
+		return Money{}, ErrPrecision
+	}
+	return build(intPart, frac+strings.Repeat("0", Scale-len(frac)), neg, 0, currency)
+}
+
+// ParseRounded parses like Parse but rounds digits beyond two decimal places using mode
+func ParseRounded(s, currency string, mode RoundingMode) (Money, error) {
+	intPart, frac, neg, err := split(s)
+	if err != nil {
+		return Money{}, err
+	}
+	if len(frac) <= Scale {
+		return build(intPart, frac+strings.Repeat("0", Scale-len(frac)), neg, 0, currency)
+	}
+
+	kept, rest := frac[:Scale], frac[Scale:]
+	var carry int64
+	switch mode {
+	case RoundHalfUp:
+		if rest[0] >= '5' {
+			carry = 1
+		}
+	case RoundHalfEven:
+		tail := strings.TrimRight(rest[1:], "0")
+		switch {
+		case rest[0] > '5', rest[0] == '5' && tail != "":
+			carry = 1
+		case rest[0] == '5':
+			// Exact tie: round to the even cent
+			if (kept[Scale-1]-'0')%2 == 1 {
+				carry = 1
+			}
+		}
+	case RoundDown:
+	default:
+		return Money{}, fmt.Errorf("money: unknown rounding mode %d", mode)
+	}
+	return build(intPart, kept, neg, carry, currency)
+}
+
+// MustParse is like Parse but panics on error; intended for constants and tests
+func MustParse(s, currency string) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func split(s string) (intPart, frac string, neg bool, err error) {
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	}
+	intPart, frac, hasDot := strings.Cut(s, ".")
+	if intPart == "" || (hasDot && frac == "") || !isDigits(intPart) || !isDigits(frac) {
+		return "", "", false, ErrInvalidAmount
+	}
+	return intPart, frac, neg, nil
+}
+
+func build(intPart, frac string, neg bool, carry int64, currency string) (Money, error) {
+	major, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || major > (math.MaxInt64-minorPerMajor)/minorPerMajor {
+		return Money{}, ErrOverflow
+	}
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+	minor := major*minorPerMajor + cents + carry
+	if neg {
+		minor = -minor
+	}
+	return Money{minor: minor, currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MinorUnits returns the amount in cents
+func (m Money) MinorUnits() int64 { return m.minor }
+
+// Currency returns the ISO 4217 currency code, empty if unspecified
+func (m Money) Currency() string { return m.currency }
+
+// WithCurrency returns the same amount in the given currency; used after
+// scanning, since NUMERIC columns carry no currency
+func (m Money) WithCurrency(currency string) Money {
+	return Money{minor: m.minor, currency: currency}
+}
+
+func (m Money) IsZero() bool     { return m.minor == 0 }
+func (m Money) IsPositive() bool { return m.minor > 0 }
+func (m Money) IsNegative() bool { return m.minor < 0 }
+
+// Neg returns the amount with its sign flipped
+func (m Money) Neg() Money { return Money{minor: -m.minor, currency: m.currency} }
+
+// Add returns m+o; both amounts must be in the same currency
+func (m Money) Add(o Money) (Money, error) {
+	if m.currency != o.currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	sum := m.minor + o.minor
+	if (o.minor > 0 && sum < m.minor) || (o.minor < 0 && sum > m.minor) {
+		return Money{}, ErrOverflow
+	}
+	return Money{minor: sum, currency: m.currency}, nil
+}
+
+// Sub returns m-o; both amounts must be in the same currency
+func (m Money) Sub(o Money) (Money, error) {
+	if o.minor == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	return m.Add(o.Neg())
+}
+
+// Cmp compares the amounts, returning -1, 0 or +1; both must be in the same currency
+func (m Money) Cmp(o Money) (int, error) {
+	if m.currency != o.currency {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case m.minor < o.minor:
+		return -1, nil
+	case m.minor > o.minor:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// String formats the amount with exactly two decimal places, e.g. "-12.30"
+func (m Money) String() string {
+	sign := ""
+	abs := uint64(m.minor)
+	if m.minor < 0 {
+		sign = "-"
+		abs = uint64(-(m.minor + 1)) + 1
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, abs/minorPerMajor, abs%minorPerMajor)
+}
+
+// MarshalJSON encodes the amount as a JSON string so clients never see a float
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts a decimal string ("12.34") or a JSON number literal;
+// both are parsed from their text so no float conversion takes place
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if bytes.HasPrefix(b, []byte(`"`)) {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return ErrInvalidAmount
+		}
+	}
+	parsed, err := Parse(s, m.currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ScanNumeric implements pgtype.NumericScanner so NUMERIC columns scan exactly
+func (m *Money) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return fmt.Errorf("%w: NULL", ErrInvalidAmount)
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return fmt.Errorf("%w: not a finite number", ErrInvalidAmount)
+	}
+
+	minor := new(big.Int).Set(v.Int)
+	shift := int64(v.Exp) + Scale
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(absInt64(shift)), nil)
+	if shift >= 0 {
+		minor.Mul(minor, pow)
+	} else {
+		var rem big.Int
+		minor.QuoRem(minor, pow, &rem)
+		if rem.Sign() != 0 {
+			return ErrPrecision
+		}
+	}
+	if !minor.IsInt64() {
+		return ErrOverflow
+	}
+	m.minor = minor.Int64()
+	return nil
+}
+
+// NumericValue implements pgtype.NumericValuer so amounts are written as exact NUMERIC values
+func (m Money) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(m.minor), Exp: -Scale, Valid: true}, nil
+}
+
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
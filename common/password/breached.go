@@ -0,0 +1,104 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// prefixLength is the size of the hash prefix ranges are looked up by, as in
+// the Have I Been Pwned range API
+const prefixLength = 5
+
+// BreachedList holds SHA-1 hashes of passwords known from breaches, grouped
+// by hash prefix. Lookups go through Range the way a k-anonymity client asks
+// a remote service, so the list can be replaced by one without changing
+// callers. A nil list contains nothing.
+type BreachedList struct {
+	ranges map[string][]string
+	size   int
+}
+
+// LoadBreachedList reads one uppercase or lowercase SHA-1 hex digest per
+// line. Anything after a colon, such as the counts in Have I Been Pwned
+// dumps, is ignored, as are blank lines and lines starting with #.
+func LoadBreachedList(r io.Reader) (*BreachedList, error) {
+	l := &BreachedList{ranges: make(map[string][]string)}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		hash := strings.ToUpper(line)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("password: breached list line %d: not a SHA-1 digest", n)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("password: breached list line %d: not a SHA-1 digest", n)
+		}
+		prefix := hash[:prefixLength]
+		l.ranges[prefix] = append(l.ranges[prefix], hash[prefixLength:])
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("password: reading breached list: %w", err)
+	}
+
+	for prefix, suffixes := range l.ranges {
+		sort.Strings(suffixes)
+		// Drop duplicates in place
+		uniq := suffixes[:0]
+		for i, s := range suffixes {
+			if i == 0 || s != suffixes[i-1] {
+				uniq = append(uniq, s)
+			}
+		}
+		l.ranges[prefix] = uniq
+		l.size += len(uniq)
+	}
+	return l, nil
+}
+
+// LoadBreachedListFile loads the list from path
+func LoadBreachedListFile(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("password: %w", err)
+	}
+	defer f.Close()
+	return LoadBreachedList(f)
+}
+
+// Len returns the number of distinct hashes in the list
+func (l *BreachedList) Len() int {
+	if l == nil {
+		return 0
+	}
+	return l.size
+}
+
+// Range returns the sorted hash suffixes that start with the given
+// five-character prefix
+func (l *BreachedList) Range(prefix string) []string {
+	if l == nil {
+		return nil
+	}
+	return l.ranges[strings.ToUpper(prefix)]
+}
+
+// Contains reports whether pwd appears in the list
+func (l *BreachedList) Contains(pwd string) bool {
+	sum := sha1.Sum([]byte(pwd))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes := l.Range(hash[:prefixLength])
+	i := sort.SearchStrings(suffixes, hash[prefixLength:])
+	return i < len(suffixes) && suffixes[i] == hash[prefixLength:]
+}
@@ -0,0 +1,123 @@
+// Package password checks new passwords against a configurable policy and
+// against a locally loaded list of breached password hashes.
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Class is a kind of character a password can contain
+type Class string
+
+const (
+	ClassLower  Class = "lower"
+	ClassUpper  Class = "upper"
+	ClassDigit  Class = "digit"
+	ClassSymbol Class = "symbol"
+)
+
+var allClasses = []Class{ClassLower, ClassUpper, ClassDigit, ClassSymbol}
+
+// minPersonalWordLength keeps short fragments such as initials from
+// rejecting unrelated passwords
+const minPersonalWordLength = 3
+
+// Policy describes an acceptable password. Zero values turn a rule off.
+type Policy struct {
+	// MinLength is counted in characters
+	MinLength int
+	// MaxLength is counted in bytes; bcrypt only uses the first 72
+	MaxLength int
+	// MinClasses is how many different classes must appear
+	MinClasses int
+	// Required classes must each appear at least once
+	Required []Class
+	// RejectPersonalInfo refuses passwords containing a word of the user's
+	// name or email
+	RejectPersonalInfo bool
+}
+
+// ParseClasses reads a comma separated list such as "upper,digit"
+func ParseClasses(spec string) ([]Class, error) {
+	var out []Class
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		c := Class(part)
+		if classOf(c) == nil {
+			return nil, fmt.Errorf("password: unknown character class %q", part)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// Check returns the rules pwd breaks, or nil when it is acceptable. personal
+// holds the user's name, email and similar values it must not contain.
+func (p Policy) Check(pwd string, personal ...string) []string {
+	var violations []string
+	if p.MinLength > 0 && utf8.RuneCountInString(pwd) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(pwd) > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+
+	present := make(map[Class]bool)
+	for _, r := range pwd {
+		for _, c := range allClasses {
+			if classOf(c)(r) {
+				present[c] = true
+			}
+		}
+	}
+	if p.MinClasses > 0 && len(present) < p.MinClasses {
+		violations = append(violations, fmt.Sprintf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses))
+	}
+	for _, c := range p.Required {
+		if !present[c] {
+			violations = append(violations, fmt.Sprintf("must contain at least one %s character", c))
+		}
+	}
+
+	if p.RejectPersonalInfo && containsPersonalWord(pwd, personal) {
+		violations = append(violations, "must not contain your name or email")
+	}
+	return violations
+}
+
+func classOf(c Class) func(rune) bool {
+	switch c {
+	case ClassLower:
+		return unicode.IsLower
+	case ClassUpper:
+		return unicode.IsUpper
+	case ClassDigit:
+		return unicode.IsDigit
+	case ClassSymbol:
+		return func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+		}
+	}
+	return nil
+}
+
+func containsPersonalWord(pwd string, personal []string) bool {
+	lower := strings.ToLower(pwd)
+	for _, value := range personal {
+		words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, w := range words {
+			if utf8.RuneCountInString(w) >= minPersonalWordLength && strings.Contains(lower, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+// Package totp implements RFC 6238 time-based one-time passwords with the
+// parameters every authenticator app supports: HMAC-SHA1, 6 digits and a
+// 30 second step.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the length of a generated code
+	Digits = 6
+	// Period is the time step in seconds
+	Period = 30
+	// SecretSize is the number of random bytes in a generated secret (RFC 4226 recommends 160 bits)
+	SecretSize = 20
+)
+
+var ErrInvalidSecret = errors.New("totp: secret is not valid base32")
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random secret, base32 encoded without padding
+func GenerateSecret() (string, error) {
+	b := make([]byte, SecretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// Step returns the time step t falls into
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// CodeAt returns the code for the given time step
+func CodeAt(secret string, step int64) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, bin%1_000_000), nil
+}
+
+// Code returns the code valid at t
+func Code(secret string, t time.Time) (string, error) {
+	return CodeAt(secret, Step(t))
+}
+
+// Validate checks code against the steps within skew of t and returns the
+// matching step, so callers can refuse to accept the same step twice. Every
+// candidate is compared in constant time.
+func Validate(secret, code string, t time.Time, skew int) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+	now := Step(t)
+	var matched int64
+	ok := false
+	for i := -skew; i <= skew; i++ {
+		candidate, err := CodeAt(secret, now+int64(i))
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(code)) == 1 && !ok {
+			matched, ok = now+int64(i), true
+		}
+	}
+	return matched, ok
+}
+
+// URI returns the otpauth:// URI authenticator apps read from a QR code
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(Period))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := b32.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
@@ -0,0 +1,62 @@
+// Package useragent turns a User-Agent header into a short, human readable
+// device label such as "Chrome on Windows". It recognises the common browsers
+// and platforms only; it is meant for display, not for making decisions.
+package useragent
+
+import "strings"
+
+// Unknown is returned when neither browser nor platform can be recognised
+const Unknown = "Unknown device"
+
+// Order matters: Edge and Opera also claim to be Chrome, Chrome claims to be
+// Safari, and every Android UA mentions Linux.
+var browsers = []struct{ token, name string }{
+	{"edg/", "Edge"},
+	{"opr/", "Opera"},
+	{"firefox/", "Firefox"},
+	{"chrome/", "Chrome"},
+	{"crios/", "Chrome"},
+	{"safari/", "Safari"},
+	{"curl/", "curl"},
+	{"postmanruntime/", "Postman"},
+	{"okhttp/", "Android app"},
+	{"cfnetwork/", "iOS app"},
+}
+
+var platforms = []struct{ token, name string }{
+	{"iphone", "iOS"},
+	{"ipad", "iPadOS"},
+	{"android", "Android"},
+	{"windows", "Windows"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"cros", "ChromeOS"},
+	{"linux", "Linux"},
+}
+
+// Label returns "<browser> on <platform>", or whichever half is known
+func Label(ua string) string {
+	lower := strings.ToLower(ua)
+	browser := match(lower, browsers)
+	platform := match(lower, platforms)
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	default:
+		return Unknown
+	}
+}
+
+func match(ua string, table []struct{ token, name string }) string {
+	for _, entry := range table {
+		if strings.Contains(ua, entry.token) {
+			return entry.name
+		}
+	}
+	return ""
+}
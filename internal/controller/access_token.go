@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/common/jwtkeys"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const tokenIDLength = 16
+
+// AccessClaims are the claims of an access token. Sub is the user ID in
+// decimal; the token is only accepted with our issuer and audience.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+	// Scopes limit what the token may be used for; a token without scopes
+	// acts with the full rights of its user
+	Scopes []string `json:"scopes,omitempty"`
+	// ClientID is the third-party app an OAuth token was issued to
+	ClientID string `json:"client_id,omitempty"`
+	// TokenVersion is the user's token version at issue time; see
+	// RequireLiveToken
+	TokenVersion int64 `json:"ver"`
+	// AuthTime and AMR are only set on tokens issued by POST /reauth
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR      []string         `json:"amr,omitempty"`
+}
+
+// AccessTokenConfig says how access tokens are signed and which ones are
+// accepted
+type AccessTokenConfig struct {
+	Keys     *jwtkeys.KeySet
+	Issuer   string
+	Audience string
+	TTL      time.Duration
+	// Leeway tolerates clock skew when checking exp, nbf and iat
+	Leeway time.Duration
+}
+
+// newClaims returns the claims of an access token for u that is valid from
+// now until exp
+func (t AccessTokenConfig) newClaims(u model.User, now, exp time.Time) (AccessClaims, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return AccessClaims{}, err
+	}
+	return AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.Issuer,
+			Subject:   strconv.FormatInt(u.ID, 10),
+			Audience:  jwt.ClaimStrings{t.Audience},
+			ExpiresAt: jwt.NewNumericDate(exp),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+		Role:         u.Role,
+		TokenVersion: u.TokenVersion,
+	}, nil
+}
+
+func (t AccessTokenConfig) sign(claims AccessClaims) (string, error) {
+	return t.Keys.Sign(claims)
+}
+
+// Parse verifies an access token's signature, issuer, audience and times
+func (t AccessTokenConfig) Parse(raw string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(raw, &AccessClaims{}, t.Keys.Keyfunc,
+		jwt.WithIssuer(t.Issuer),
+		jwt.WithAudience(t.Audience),
+		jwt.WithLeeway(t.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+}
+
+// ParseTokenFunc adapts Parse to the JWT middleware
+func (t AccessTokenConfig) ParseTokenFunc(c echo.Context, auth string) (interface{}, error) {
+	return t.Parse(auth)
+}
+
+// newTokenID returns a random value for the "jti" claim
+func newTokenID() (string, error) {
+	b := make([]byte, tokenIDLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
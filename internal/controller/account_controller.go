@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+type AccountController struct {
+	svc service.AccountService
+}
+
+func NewAccountController(svc service.AccountService) *AccountController {
+	return &AccountController{svc: svc}
+}
+
+func (a *AccountController) Open(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	account, err := a.svc.OpenAccount(ctx, userID)
+	if err != nil {
+		return handleServiceError(c, err, "open account")
+	}
+
+	return c.JSON(http.StatusCreated, dto.AccountResponseFromModel(account))
+}
+
+func (a *AccountController) GetMine(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	accounts, err := a.svc.GetUserAccounts(ctx, userID)
+	if err != nil {
+		return handleServiceError(c, err, "fetch accounts")
+	}
+
+	return c.JSON(http.StatusOK, dto.AccountsResponseFromModels(accounts))
+}
+
+func (a *AccountController) GetByID(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	id, herr := parseResourceID(c, "account")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	account, err := a.svc.GetAccountByID(ctx, userID, id)
+	if err != nil {
+		return handleServiceError(c, err, "fetch account")
+	}
+
+	return c.JSON(http.StatusOK, dto.AccountResponseFromModel(account))
+}
+
+func (a *AccountController) Close(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	id, herr := parseResourceID(c, "account")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := a.svc.CloseAccount(ctx, userID, id); err != nil {
+		return handleServiceError(c, err, "close account")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// APIKeyController lets users manage the API keys their scripts call the API
+// with
+type APIKeyController struct {
+	svc service.APIKeyService
+}
+
+func NewAPIKeyController(svc service.APIKeyService) *APIKeyController {
+	return &APIKeyController{svc: svc}
+}
+
+// Create issues a key for the caller. The key is only shown in this response.
+func (ac *APIKeyController) Create(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	var req dto.CreateAPIKeyRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	key, raw, err := ac.svc.CreateKey(ctx, userID, req.Name, req.Scopes, ttl)
+	if err != nil {
+		return handleServiceError(c, err, "create api key")
+	}
+	resp := dto.APIKeyResponseFromModel(key)
+	resp.Key = raw
+	setNoStore(c)
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// GetMine lists the caller's unexpired keys
+func (ac *APIKeyController) GetMine(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	keys, err := ac.svc.GetUserKeys(ctx, userID)
+	if err != nil {
+		return handleServiceError(c, err, "fetch api keys")
+	}
+	return c.JSON(http.StatusOK, dto.APIKeysResponseFromModels(keys))
+}
+
+// Revoke deletes one of the caller's keys; it stops working immediately
+func (ac *APIKeyController) Revoke(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	keyID, herr := parseResourceID(c, "key")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := ac.svc.RevokeKey(ctx, userID, keyID); err != nil {
+		return handleServiceError(c, err, "revoke api key")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AuthenticateAPIKey accepts an API key sent as the Bearer token in place of
+// an access token. It must run before the JWT middleware, which skips the
+// requests it authenticated (see AuthenticatedByAPIKey). The key acts as its
+// user, limited to the key's scopes like an OAuth token.
+func AuthenticateAPIKey(keys service.APIKeyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			raw, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+			if !ok || !strings.HasPrefix(raw, model.APIKeyPrefix) {
+				return next(c)
+			}
+
+			ctx, cancel := withTimeout(c.Request().Context())
+			defer cancel()
+
+			key, u, err := keys.Authenticate(ctx, raw)
+			if err != nil {
+				return handleServiceError(c, err, "check api key")
+			}
+			c.Set("user", &jwt.Token{Claims: apiKeyClaims(key, u), Valid: true})
+			return next(c)
+		}
+	}
+}
+
+// AuthenticatedByAPIKey is the JWT middleware's skipper for requests
+// AuthenticateAPIKey let through
+func AuthenticatedByAPIKey(c echo.Context) bool {
+	_, ok := c.Get("user").(*jwt.Token)
+	return ok
+}
+
+// apiKeyClaims describes an API key request the way an access token would,
+// so the rest of the chain treats both alike. The key has no jti: revoking a
+// key deletes it instead.
+func apiKeyClaims(key model.APIKey, u model.User) *AccessClaims {
+	return &AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(u.ID, 10),
+			ExpiresAt: jwt.NewNumericDate(key.ExpiresAt),
+		},
+		Role:         u.Role,
+		Scopes:       key.Scopes,
+		TokenVersion: key.TokenVersion,
+	}
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -12,16 +13,23 @@ import (
 )
 
 type AuthController struct {
-	svc       service.UserService
-	jwtSecret string
-	jwtTTL    time.Duration
+	svc          service.UserService
+	mfa          service.MFAService
+	verification service.EmailVerificationService
+	attempts     service.LoginAttemptService
+	tokens       AccessTokenConfig
+	// stepUpTTL caps the lifetime of tokens issued by Reauthenticate
+	stepUpTTL time.Duration
 }
 
-func NewAuthController(svc service.UserService, jwtSecret string, jwtTTL time.Duration) *AuthController {
+func NewAuthController(svc service.UserService, mfa service.MFAService, verification service.EmailVerificationService, attempts service.LoginAttemptService, tokens AccessTokenConfig, stepUpTTL time.Duration) *AuthController {
 	return &AuthController{
-		svc:       svc,
-		jwtSecret: jwtSecret,
-		jwtTTL:    jwtTTL,
+		svc:          svc,
+		mfa:          mfa,
+		verification: verification,
+		attempts:     attempts,
+		tokens:       tokens,
+		stepUpTTL:    stepUpTTL,
 	}
 }
 
@@ -35,11 +43,15 @@ func (a *AuthController) Register(c echo.Context) error {
 	if err := a.svc.CreateUser(c.Request().Context(), &user); err != nil {
 		return handleServiceError(c, err, "register")
 	}
+	// The account exists either way; a failed link can be sent again
+	if err := a.verification.SendVerification(c.Request().Context(), user.ID); err != nil {
+		c.Logger().Errorf("register: could not send verification email: %v", err)
+	}
 	token, exp, err := a.issueToken(user)
 	if err != nil {
 		return sendError(c, http.StatusInternalServerError, "TOKEN_ERROR", "Token creation failed", err.Error())
 	}
-	refreshToken, refreshExp, err := a.svc.GenerateRefreshToken(c.Request().Context(), user.ID)
+	refreshToken, refreshExp, err := a.svc.GenerateRefreshToken(c.Request().Context(), user.ID, clientInfo(c, ""))
 	if err != nil {
 		return sendError(c, http.StatusInternalServerError, "REFRESH_TOKEN_ERROR", "Refresh token creation failed", err.Error())
 	}
@@ -52,21 +64,93 @@ func (a *AuthController) Register(c echo.Context) error {
 	})
 }
 
+// Login verifies the password. Users with two-factor authentication get an
+// MFA challenge to complete through LoginMFA instead of tokens. Wrong
+// passwords are counted per account and per client IP; too many of them
+// delay and then lock further attempts.
 func (a *AuthController) Login(c echo.Context) error {
 	var req dto.LoginRequest
 	if err := bindAndValidate(c, &req); err != nil {
 		return err
 	}
 
+	if err := a.attempts.Check(c.Request().Context(), req.Email, c.RealIP()); err != nil {
+		return handleServiceError(c, err, "login")
+	}
 	user, err := a.svc.AuthenticateUser(c.Request().Context(), req.Email, req.Password)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			a.recordLoginFailure(c, req.Email)
+		}
+		return handleServiceError(c, err, "login")
+	}
+	mfaEnabled, err := a.mfa.IsEnabled(c.Request().Context(), user.ID)
+	if err != nil {
+		return handleServiceError(c, err, "login")
+	}
+	if mfaEnabled {
+		token, exp, err := a.mfa.CreateChallenge(c.Request().Context(), user.ID)
+		if err != nil {
+			return handleServiceError(c, err, "login")
+		}
+		return c.JSON(http.StatusOK, dto.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    token,
+			ExpiresAt:   exp,
+		})
+	}
+	return a.completeLogin(c, user, req.DeviceLabel)
+}
+
+// LoginMFA is the second step of a login for users with two-factor
+// authentication
+func (a *AuthController) LoginMFA(c echo.Context) error {
+	var req dto.MFALoginRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	userID, err := a.mfa.VerifyChallenge(c.Request().Context(), req.MFAToken, req.Code)
+	if err != nil {
+		// Wrong codes count like wrong passwords, otherwise a known password
+		// would give fresh challenges to guess codes with
+		if errors.Is(err, service.ErrInvalidMFACode) && userID != 0 {
+			if user, uerr := a.svc.GetUserByID(c.Request().Context(), userID); uerr == nil {
+				a.recordLoginFailure(c, user.Email)
+			}
+		}
 		return handleServiceError(c, err, "login")
 	}
+	user, err := a.svc.GetUserByID(c.Request().Context(), userID)
+	if err != nil {
+		return handleServiceError(c, err, "login")
+	}
+	if !user.IsActive {
+		return handleServiceError(c, service.ErrInactiveAccount, "login")
+	}
+	if err := a.attempts.Check(c.Request().Context(), user.Email, c.RealIP()); err != nil {
+		return handleServiceError(c, err, "login")
+	}
+	return a.completeLogin(c, user, req.DeviceLabel)
+}
+
+// recordLoginFailure counts a failed login. The caller still gets the
+// original error, so a failure to record is only logged.
+func (a *AuthController) recordLoginFailure(c echo.Context, email string) {
+	if err := a.attempts.RecordFailure(c.Request().Context(), email, c.RealIP()); err != nil {
+		c.Logger().Errorf("login: could not record failed attempt: %v", err)
+	}
+}
+
+func (a *AuthController) completeLogin(c echo.Context, user model.User, deviceLabel string) error {
+	if err := a.attempts.RecordSuccess(c.Request().Context(), user.Email); err != nil {
+		c.Logger().Errorf("login: could not reset failed attempts: %v", err)
+	}
 	token, exp, err := a.issueToken(user)
 	if err != nil {
 		return sendError(c, http.StatusInternalServerError, "TOKEN_ERROR", "Token creation failed", err.Error())
 	}
-	refreshToken, refreshExp, err := a.svc.GenerateRefreshToken(c.Request().Context(), user.ID)
+	refreshToken, refreshExp, err := a.svc.GenerateRefreshToken(c.Request().Context(), user.ID, clientInfo(c, deviceLabel))
 	if err != nil {
 		return sendError(c, http.StatusInternalServerError, "REFRESH_TOKEN_ERROR", "Refresh token creation failed", err.Error())
 	}
@@ -84,11 +168,17 @@ func (a *AuthController) Refresh(c echo.Context) error {
 	if err := bindAndValidate(c, &req); err != nil {
 		return err
 	}
-	userID, err := a.svc.ValidateRefreshToken(c.Request().Context(), req.RefreshToken)
+	rt, err := a.svc.RotateRefreshToken(c.Request().Context(), req.RefreshToken, clientInfo(c, ""))
 	if err != nil {
-		return sendError(c, http.StatusUnauthorized, "INVALID_REFRESH_TOKEN", "Refresh token invalid or expired", "")
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			return sendError(c, http.StatusUnauthorized, "REFRESH_TOKEN_REUSED", "Refresh token was already used; session revoked", "")
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			return sendError(c, http.StatusUnauthorized, "INVALID_REFRESH_TOKEN", "Refresh token invalid or expired", "")
+		}
+		return handleServiceError(c, err, "refresh token")
 	}
-	user, err := a.svc.GetUserByID(c.Request().Context(), userID)
+	user, err := a.svc.GetUserByID(c.Request().Context(), rt.UserID)
 	if err != nil {
 		return handleServiceError(c, err, "refresh token user")
 	}
@@ -97,15 +187,132 @@ func (a *AuthController) Refresh(c echo.Context) error {
 		return sendError(c, http.StatusInternalServerError, "TOKEN_ERROR", "Token creation failed", err.Error())
 	}
 	return c.JSON(http.StatusOK, dto.RefreshResponse{
-		Token:     token,
-		ExpiresAt: exp,
+		Token:        token,
+		ExpiresAt:    exp,
+		RefreshToken: rt.Token,
+		RefreshExp:   rt.ExpiresAt,
 	})
 }
 
+// Logout revokes the presented refresh token. Unknown tokens are ignored so
+// that logging out twice is not an error. An access token sent along in the
+// Authorization header is revoked as well.
+func (a *AuthController) Logout(c echo.Context) error {
+	var req dto.LogoutRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+	if err := a.svc.RevokeRefreshToken(c.Request().Context(), req.RefreshToken); err != nil {
+		return handleServiceError(c, err, "logout")
+	}
+	if err := revokeBearerToken(c, a.svc, a.tokens); err != nil {
+		return handleServiceError(c, err, "logout")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// LogoutAll revokes every refresh token and access token of the
+// authenticated user
+func (a *AuthController) LogoutAll(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	if err := a.svc.RevokeAllUserRefreshTokens(c.Request().Context(), userID); err != nil {
+		return handleServiceError(c, err, "logout")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Reauthenticate checks the caller's credentials again and issues a
+// short-lived access token stamped with auth_time, which step-up protected
+// endpoints require. Users with two-factor authentication must use a TOTP or
+// recovery code; everyone else uses their password. Wrong answers count
+// towards the same lockout as failed logins, so a stolen access token gives
+// no unlimited guesses.
+func (a *AuthController) Reauthenticate(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	var req dto.ReauthRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	user, err := a.svc.GetUserByID(ctx, userID)
+	if err != nil {
+		return handleServiceError(c, err, "reauthenticate")
+	}
+	if !user.IsActive {
+		return handleServiceError(c, service.ErrInactiveAccount, "reauthenticate")
+	}
+	if err := a.attempts.Check(ctx, user.Email, c.RealIP()); err != nil {
+		return handleServiceError(c, err, "reauthenticate")
+	}
+	mfaEnabled, err := a.mfa.IsEnabled(ctx, userID)
+	if err != nil {
+		return handleServiceError(c, err, "reauthenticate")
+	}
+	var method string
+	if mfaEnabled {
+		if req.Code == "" {
+			return sendError(c, http.StatusUnauthorized, "MFA_CODE_REQUIRED", "Two-factor code required", "")
+		}
+		if err := a.mfa.VerifyCode(ctx, userID, req.Code); err != nil {
+			if errors.Is(err, service.ErrInvalidMFACode) {
+				a.recordLoginFailure(c, user.Email)
+			}
+			return handleServiceError(c, err, "reauthenticate")
+		}
+		method = amrOTP
+	} else {
+		if req.Password == "" {
+			return sendError(c, http.StatusUnauthorized, "PASSWORD_REQUIRED", "Password required", "")
+		}
+		if _, err := a.svc.VerifyPassword(ctx, userID, req.Password); err != nil {
+			if errors.Is(err, service.ErrInvalidCredentials) {
+				a.recordLoginFailure(c, user.Email)
+			}
+			return handleServiceError(c, err, "reauthenticate")
+		}
+		method = amrPassword
+	}
+	if err := a.attempts.RecordSuccess(ctx, user.Email); err != nil {
+		c.Logger().Errorf("reauthenticate: could not reset failed attempts: %v", err)
+	}
+
+	ttl := a.tokens.TTL
+	if a.stepUpTTL > 0 && a.stepUpTTL < ttl {
+		ttl = a.stepUpTTL
+	}
+	now := time.Now()
+	claims, err := a.tokens.newClaims(user, now, now.Add(ttl))
+	if err != nil {
+		return sendError(c, http.StatusInternalServerError, "TOKEN_ERROR", "Token creation failed", err.Error())
+	}
+	claims.AuthTime = jwt.NewNumericDate(now)
+	claims.AMR = []string{method}
+	token, exp, err := a.signToken(claims)
+	if err != nil {
+		return sendError(c, http.StatusInternalServerError, "TOKEN_ERROR", "Token creation failed", err.Error())
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, dto.ReauthResponse{Token: token, ExpiresAt: exp})
+}
+
 func (a *AuthController) issueToken(u model.User) (string, time.Time, error) {
-	exp := time.Now().Add(a.jwtTTL)
-	claims := jwt.MapClaims{"sub": u.ID, "exp": exp.Unix(), "role": u.Role}
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	s, err := t.SignedString([]byte(a.jwtSecret))
-	return s, exp, err
+	now := time.Now()
+	claims, err := a.tokens.newClaims(u, now, now.Add(a.tokens.TTL))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return a.signToken(claims)
+}
+
+// signToken signs an access token and returns it with its expiry
+func (a *AuthController) signToken(claims AccessClaims) (string, time.Time, error) {
+	s, err := a.tokens.sign(claims)
+	return s, claims.ExpiresAt.Time, err
 }
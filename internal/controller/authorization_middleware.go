@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// RequireRole allows the request only if the JWT "role" claim is one of roles.
+// Like every authorization middleware it must run after the JWT middleware.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, herr := currentPrincipal(c)
+			if herr != nil {
+				return c.JSON(herr.Code, herr.Message)
+			}
+			if !p.HasRole(roles...) {
+				return sendForbidden(c)
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireSelfOrRole allows the request if the :id path parameter is the
+// authenticated user's own ID, or if the user has one of roles
+func RequireSelfOrRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, herr := currentPrincipal(c)
+			if herr != nil {
+				return c.JSON(herr.Code, herr.Message)
+			}
+			if id, err := strconv.ParseInt(c.Param("id"), 10, 64); err == nil && id == p.UserID {
+				return next(c)
+			}
+			if !p.HasRole(roles...) {
+				return sendForbidden(c)
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireVerifiedEmail allows the request only once the authenticated user has
+// confirmed their email address. The user is looked up on every request so a
+// confirmation takes effect without a new token.
+func RequireVerifiedEmail(users service.UserService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID, herr := currentUserID(c)
+			if herr != nil {
+				return c.JSON(herr.Code, herr.Message)
+			}
+			ctx, cancel := withTimeout(c.Request().Context())
+			defer cancel()
+			user, err := users.GetUserByID(ctx, userID)
+			if err != nil {
+				return handleServiceError(c, err, "check email verification")
+			}
+			if !user.EmailVerified() {
+				return handleServiceError(c, service.ErrEmailNotVerified, "check email verification")
+			}
+			return next(c)
+		}
+	}
+}
+
+func sendForbidden(c echo.Context) error {
+	return sendError(c, http.StatusForbidden, "FORBIDDEN", "You are not allowed to perform this action", "")
+}
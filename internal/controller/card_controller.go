@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+type CardController struct {
+	svc service.CardService
+}
+
+func NewCardController(svc service.CardService) *CardController {
+	return &CardController{svc: svc}
+}
+
+type cardActionFunc func(ctx context.Context, userID, cardID int64) (model.Card, error)
+
+func (cc *CardController) Issue(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	accountID, herr := parseResourceID(c, "account")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	issued, err := cc.svc.IssueCard(ctx, userID, accountID)
+	if err != nil {
+		return handleServiceError(c, err, "issue card")
+	}
+
+	return c.JSON(http.StatusCreated, dto.CardResponseFromModel(issued))
+}
+
+func (cc *CardController) GetByAccount(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	accountID, herr := parseResourceID(c, "account")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	cards, err := cc.svc.GetAccountCards(ctx, userID, accountID)
+	if err != nil {
+		return handleServiceError(c, err, "fetch cards")
+	}
+
+	return c.JSON(http.StatusOK, dto.CardsResponseFromModels(cards))
+}
+
+func (cc *CardController) GetByID(c echo.Context) error {
+	return cc.cardAction(c, cc.svc.GetCard, "fetch card", http.StatusOK)
+}
+
+func (cc *CardController) Freeze(c echo.Context) error {
+	return cc.cardAction(c, cc.svc.FreezeCard, "freeze card", http.StatusOK)
+}
+
+func (cc *CardController) Unfreeze(c echo.Context) error {
+	return cc.cardAction(c, cc.svc.UnfreezeCard, "unfreeze card", http.StatusOK)
+}
+
+func (cc *CardController) Replace(c echo.Context) error {
+	return cc.cardAction(c, cc.svc.ReplaceCard, "replace card", http.StatusCreated)
+}
+
+// Reveal is the only endpoint that returns the full card number and CVV
+func (cc *CardController) Reveal(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	cardID, herr := parseResourceID(c, "card")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	revealed, err := cc.svc.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return handleServiceError(c, err, "reveal card")
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.JSON(http.StatusOK, dto.CardRevealResponseFromModel(revealed))
+}
+
+func (cc *CardController) cardAction(c echo.Context, action cardActionFunc, operation string, status int) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	cardID, herr := parseResourceID(c, "card")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	result, err := action(ctx, userID, cardID)
+	if err != nil {
+		return handleServiceError(c, err, operation)
+	}
+
+	return c.JSON(status, dto.CardResponseFromModel(result))
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+type AccountResponse struct {
+	ID            int64       `json:"id"`
+	UserID        int64       `json:"user_id"`
+	AccountNumber string      `json:"account_number"`
+	Balance       money.Money `json:"balance"`
+	Currency      string      `json:"currency"`
+	IsActive      bool        `json:"is_active"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+type AccountsResponse struct {
+	Accounts []AccountResponse `json:"accounts"`
+	Count    int               `json:"count"`
+}
+
+func AccountResponseFromModel(a model.Account) AccountResponse {
+	return AccountResponse{
+		ID:            a.ID,
+		UserID:        a.UserID,
+		AccountNumber: a.AccountNumber,
+		Balance:       a.Balance,
+		Currency:      a.Currency,
+		IsActive:      a.IsActive,
+		CreatedAt:     a.CreatedAt,
+		UpdatedAt:     a.UpdatedAt,
+	}
+}
+
+func AccountsResponseFromModels(accounts []model.Account) AccountsResponse {
+	resp := make([]AccountResponse, len(accounts))
+	for i, a := range accounts {
+		resp[i] = AccountResponseFromModel(a)
+	}
+	return AccountsResponse{
+		Accounts: resp,
+		Count:    len(resp),
+	}
+}
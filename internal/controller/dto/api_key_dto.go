@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"required,min=1,max=365"`
+}
+
+type APIKeyResponse struct {
+	ID     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	// Key is only returned when the key is created
+	Key        string     `json:"key,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type APIKeysResponse struct {
+	Keys  []APIKeyResponse `json:"keys"`
+	Count int              `json:"count"`
+}
+
+func APIKeyResponseFromModel(k model.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		ExpiresAt:  k.ExpiresAt,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+	}
+}
+
+func APIKeysResponseFromModels(keys []model.APIKey) APIKeysResponse {
+	resp := make([]APIKeyResponse, len(keys))
+	for i, k := range keys {
+		resp[i] = APIKeyResponseFromModel(k)
+	}
+	return APIKeysResponse{
+		Keys:  resp,
+		Count: len(resp),
+	}
+}
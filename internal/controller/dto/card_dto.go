@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yusufziyrek/bank-app/common/card"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// cardExpiryLayout is the MM/YY format printed on cards
+const cardExpiryLayout = "01/06"
+
+// CardResponse never carries the full card number; use CardRevealResponse for that
+type CardResponse struct {
+	ID           int64     `json:"id"`
+	AccountID    int64     `json:"account_id"`
+	CardNumber   string    `json:"card_number"`
+	Expiry       string    `json:"expiry"`
+	IsActive     bool      `json:"is_active"`
+	ReplacedByID *int64    `json:"replaced_by_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type CardsResponse struct {
+	Cards []CardResponse `json:"cards"`
+	Count int            `json:"count"`
+}
+
+type CardRevealResponse struct {
+	ID         int64  `json:"id"`
+	CardNumber string `json:"card_number"`
+	Expiry     string `json:"expiry"`
+	CVV        string `json:"cvv"`
+}
+
+func CardResponseFromModel(c model.Card) CardResponse {
+	return CardResponse{
+		ID:           c.ID,
+		AccountID:    c.AccountID,
+		CardNumber:   card.MaskPAN(c.CardNumber),
+		Expiry:       c.ExpiryDate.Format(cardExpiryLayout),
+		IsActive:     c.IsActive,
+		ReplacedByID: c.ReplacedByID,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+}
+
+func CardsResponseFromModels(cards []model.Card) CardsResponse {
+	resp := make([]CardResponse, len(cards))
+	for i, c := range cards {
+		resp[i] = CardResponseFromModel(c)
+	}
+	return CardsResponse{
+		Cards: resp,
+		Count: len(resp),
+	}
+}
+
+func CardRevealResponseFromModel(c model.Card) CardRevealResponse {
+	return CardRevealResponse{
+		ID:         c.ID,
+		CardNumber: c.CardNumber,
+		Expiry:     c.ExpiryDate.Format(cardExpiryLayout),
+		CVV:        c.CVV,
+	}
+}
@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+type BalanceMismatchResponse struct {
+	AccountID      int64       `json:"account_id"`
+	Currency       string      `json:"currency"`
+	Balance        money.Money `json:"balance"`
+	DerivedBalance money.Money `json:"derived_balance"`
+}
+
+type LedgerReportResponse struct {
+	Balanced          bool                      `json:"balanced"`
+	UnbalancedEntries []int64                   `json:"unbalanced_entries"`
+	BalanceMismatches []BalanceMismatchResponse `json:"balance_mismatches"`
+	CheckedAt         time.Time                 `json:"checked_at"`
+}
+
+func LedgerReportResponseFromModel(r model.LedgerReport) LedgerReportResponse {
+	mismatches := make([]BalanceMismatchResponse, len(r.BalanceMismatches))
+	for i, m := range r.BalanceMismatches {
+		mismatches[i] = BalanceMismatchResponse{
+			AccountID:      m.AccountID,
+			Currency:       m.Currency,
+			Balance:        m.Balance,
+			DerivedBalance: m.DerivedBalance,
+		}
+	}
+	unbalanced := r.UnbalancedEntries
+	if unbalanced == nil {
+		unbalanced = []int64{}
+	}
+	return LedgerReportResponse{
+		Balanced:          r.Balanced(),
+		UnbalancedEntries: unbalanced,
+		BalanceMismatches: mismatches,
+		CheckedAt:         r.CheckedAt,
+	}
+}
@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+// MFAChallengeResponse is returned by login instead of AuthResponse when the
+// user has two-factor authentication enabled
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// MFALoginRequest completes a login with a TOTP code or a recovery code
+type MFALoginRequest struct {
+	MFAToken    string `json:"mfa_token" validate:"required"`
+	Code        string `json:"code" validate:"required,max=32"`
+	DeviceLabel string `json:"device_label,omitempty" validate:"omitempty,max=100"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// RecoveryCodesResponse is the only time recovery codes are shown
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
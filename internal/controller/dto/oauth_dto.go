@@ -0,0 +1,119 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// OAuthClientRequest registers a third-party app
+type OAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1,max=10,dive,required,max=2000"`
+	Scopes       []string `json:"scopes" validate:"required,min=1,dive,required"`
+	// Confidential clients run on a server and get a secret; apps on the
+	// user's device are public and rely on PKCE alone
+	Confidential bool `json:"confidential"`
+}
+
+type OAuthClientResponse struct {
+	ClientID     string   `json:"client_id"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"`
+	// ClientSecret is only returned when the client is registered
+	ClientSecret string    `json:"client_secret,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type OAuthClientsResponse struct {
+	Clients []OAuthClientResponse `json:"clients"`
+	Count   int                   `json:"count"`
+}
+
+func OAuthClientResponseFromModel(c model.OAuthClient) OAuthClientResponse {
+	return OAuthClientResponse{
+		ClientID:     c.ClientID,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectURIs,
+		Scopes:       c.Scopes,
+		Confidential: c.Confidential(),
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+func OAuthClientsResponseFromModels(clients []model.OAuthClient) OAuthClientsResponse {
+	resp := make([]OAuthClientResponse, len(clients))
+	for i, c := range clients {
+		resp[i] = OAuthClientResponseFromModel(c)
+	}
+	return OAuthClientsResponse{
+		Clients: resp,
+		Count:   len(resp),
+	}
+}
+
+// OAuthAuthorizeRequest carries the parameters of an RFC 6749 authorization
+// request. The consent screen gets them as query parameters from the app's
+// link and posts them back with the user's decision.
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `query:"response_type" json:"response_type" validate:"required,eq=code"`
+	ClientID            string `query:"client_id" json:"client_id" validate:"required,max=64"`
+	RedirectURI         string `query:"redirect_uri" json:"redirect_uri" validate:"omitempty,max=2000"`
+	Scope               string `query:"scope" json:"scope" validate:"required,max=200"`
+	State               string `query:"state" json:"state" validate:"omitempty,max=500"`
+	CodeChallenge       string `query:"code_challenge" json:"code_challenge" validate:"required,max=128"`
+	CodeChallengeMethod string `query:"code_challenge_method" json:"code_challenge_method" validate:"required"`
+}
+
+// OAuthConsentResponse is what the consent screen shows the user
+type OAuthConsentResponse struct {
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	RedirectURI string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+	State       string   `json:"state,omitempty"`
+}
+
+// OAuthConsentRequest is the user's answer to an authorization request
+type OAuthConsentRequest struct {
+	OAuthAuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// OAuthConsentResult tells the consent screen where to send the browser:
+// the app's redirect URI with either a code or error=access_denied
+type OAuthConsentResult struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// OAuthTokenResponse is the RFC 6749 token response
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// OAuthErrorResponse is the RFC 6749 error format used by the token,
+// introspection and revocation endpoints
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthIntrospectionResponse is the RFC 7662 introspection response. Only
+// Active is set for tokens that are not active.
+type OAuthIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required,max=128"`
+	NewPassword string `json:"new_password" validate:"required,min=8,max=100"`
+}
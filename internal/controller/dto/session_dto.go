@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+type SessionResponse struct {
+	ID          int64     `json:"id"`
+	ClientID    string    `json:"client_id,omitempty"`
+	Scopes      []string  `json:"scopes,omitempty"`
+	DeviceLabel string    `json:"device_label"`
+	UserAgent   string    `json:"user_agent"`
+	IPAddress   string    `json:"ip_address"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+}
+
+type SessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+	Count    int               `json:"count"`
+}
+
+func SessionResponseFromModel(s model.Session) SessionResponse {
+	return SessionResponse{
+		ID:          s.ID,
+		ClientID:    s.ClientID,
+		Scopes:      s.Scopes,
+		DeviceLabel: s.DeviceLabel,
+		UserAgent:   s.UserAgent,
+		IPAddress:   s.IPAddress,
+		CreatedAt:   s.CreatedAt,
+		LastUsedAt:  s.LastUsedAt,
+	}
+}
+
+func SessionsResponseFromModels(sessions []model.Session) SessionsResponse {
+	resp := make([]SessionResponse, len(sessions))
+	for i, s := range sessions {
+		resp[i] = SessionResponseFromModel(s)
+	}
+	return SessionsResponse{
+		Sessions: resp,
+		Count:    len(resp),
+	}
+}
@@ -0,0 +1,90 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+type MoneyMovementRequest struct {
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency" validate:"omitempty,len=3,uppercase"`
+	Description string      `json:"description" validate:"max=255"`
+}
+
+type TransferRequest struct {
+	FromAccountID   int64       `json:"from_account_id" validate:"required,gt=0"`
+	ToAccountNumber string      `json:"to_account_number" validate:"required,account_number"`
+	Amount          money.Money `json:"amount"`
+	Currency        string      `json:"currency" validate:"omitempty,len=3,uppercase"`
+	Description     string      `json:"description" validate:"max=255"`
+}
+
+type TransactionResponse struct {
+	ID          int64       `json:"id"`
+	AccountID   int64       `json:"account_id"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"`
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	TransferID  *int64      `json:"transfer_id,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+type MoneyMovementResponse struct {
+	Transaction TransactionResponse `json:"transaction"`
+	Account     AccountResponse     `json:"account"`
+}
+
+func TransactionResponseFromModel(t model.Transaction) TransactionResponse {
+	return TransactionResponse{
+		ID:          t.ID,
+		AccountID:   t.AccountID,
+		Amount:      t.Amount,
+		Currency:    t.Amount.Currency(),
+		Type:        t.Type,
+		Description: t.Description,
+		TransferID:  t.TransferID,
+		CreatedAt:   t.CreatedAt,
+	}
+}
+
+// TransferResponse is the sender's view of a transfer. The recipient is named
+// by the account number the sender entered, and only the sender's own history
+// row is included, so the recipient's account ID and credit row stay private.
+type TransferResponse struct {
+	ID              int64               `json:"id"`
+	FromAccountID   int64               `json:"from_account_id"`
+	ToAccountNumber string              `json:"to_account_number"`
+	Amount          money.Money         `json:"amount"`
+	Currency        string              `json:"currency"`
+	Description     string              `json:"description,omitempty"`
+	Transaction     TransactionResponse `json:"transaction"`
+	CreatedAt       time.Time           `json:"created_at"`
+}
+
+type TransferResultResponse struct {
+	Transfer TransferResponse `json:"transfer"`
+	Account  AccountResponse  `json:"account"`
+}
+
+func TransferResponseFromModel(t model.Transfer, toAccountNumber string) TransferResponse {
+	var debit TransactionResponse
+	for _, tx := range t.Transactions {
+		if tx.AccountID == t.FromAccountID {
+			debit = TransactionResponseFromModel(tx)
+			break
+		}
+	}
+	return TransferResponse{
+		ID:              t.ID,
+		FromAccountID:   t.FromAccountID,
+		ToAccountNumber: toAccountNumber,
+		Amount:          t.Amount,
+		Currency:        t.Amount.Currency(),
+		Description:     t.Description,
+		Transaction:     debit,
+		CreatedAt:       t.CreatedAt,
+	}
+}
@@ -15,6 +15,9 @@ type CreateUserRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// DeviceLabel names the session, e.g. "Work laptop"; derived from the
+	// User-Agent when empty
+	DeviceLabel string `json:"device_label,omitempty" validate:"omitempty,max=100"`
 }
 
 type UpdateUserEmailRequest struct {
@@ -26,17 +29,21 @@ type UpdateUserPasswordRequest struct {
 }
 
 type UpdateUserStatusRequest struct {
-	IsActive bool `json:"is_active" validate:"required"`
+	// A pointer so that "required" accepts false
+	IsActive *bool `json:"is_active" validate:"required"`
 }
 
 type UserResponse struct {
-	ID        int64     `json:"id"`
-	FullName  string    `json:"full_name"`
-	Email     string    `json:"email"`
-	Role      string    `json:"role"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            int64     `json:"id"`
+	FullName      string    `json:"full_name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	IsActive      bool      `json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// ServiceAccount is set for accounts scripts act as through API keys
+	ServiceAccount bool `json:"service_account,omitempty"`
 }
 
 type UsersResponse struct {
@@ -56,20 +63,43 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
 type RefreshResponse struct {
+	Token        string    `json:"token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+	RefreshExp   time.Time `json:"refresh_expires_at"`
+}
+
+// ReauthRequest proves the caller's identity again before a sensitive
+// operation. Users with two-factor authentication send a code, others their
+// password.
+type ReauthRequest struct {
+	Password string `json:"password,omitempty" validate:"required_without=Code,max=72"`
+	Code     string `json:"code,omitempty" validate:"required_without=Password,max=32"`
+}
+
+// ReauthResponse carries an access token with a fresh auth_time claim
+type ReauthResponse struct {
 	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
 func UserResponseFromModel(u model.User) UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		FullName:  u.FullName,
-		Email:     u.Email,
-		Role:      u.Role,
-		IsActive:  u.IsActive,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:            u.ID,
+		FullName:      u.FullName,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified(),
+		Role:          u.Role,
+		IsActive:      u.IsActive,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+
+		ServiceAccount: u.ServiceAccount,
 	}
 }
 
@@ -83,3 +113,10 @@ func UsersResponseFromModels(users []model.User) UsersResponse {
 		Count: len(resp),
 	}
 }
+
+// CreateServiceAccountRequest names a new service account; Role defaults to
+// user
+type CreateServiceAccountRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=100"`
+	Role string `json:"role,omitempty" validate:"omitempty,oneof=user admin"`
+}
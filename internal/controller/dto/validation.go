@@ -0,0 +1,15 @@
+package dto
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/yusufziyrek/bank-app/common/iban"
+)
+
+// RegisterValidations registers the custom validate tags used by the DTOs
+//
+//	account_number: IBAN-style account number with valid mod-97 check digits
+func RegisterValidations(v *validator.Validate) error {
+	return v.RegisterValidation("account_number", func(fl validator.FieldLevel) bool {
+		return iban.Validate(fl.Field().String())
+	})
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+type EmailVerificationController struct {
+	svc service.EmailVerificationService
+}
+
+func NewEmailVerificationController(svc service.EmailVerificationService) *EmailVerificationController {
+	return &EmailVerificationController{svc: svc}
+}
+
+// Verify confirms the address a verification link was sent to. The token is
+// the proof, so no access token is needed.
+func (e *EmailVerificationController) Verify(c echo.Context) error {
+	var req dto.VerifyEmailRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := e.svc.ConfirmEmail(ctx, req.Token); err != nil {
+		return handleServiceError(c, err, "verify email")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Resend sends a new verification link for the caller's current address
+func (e *EmailVerificationController) Resend(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := e.svc.SendVerification(ctx, userID); err != nil {
+		return handleServiceError(c, err, "resend verification")
+	}
+	return c.NoContent(http.StatusAccepted)
+}
@@ -3,14 +3,17 @@ package controller
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
 	"github.com/yusufziyrek/bank-app/internal/service"
 )
 
@@ -24,24 +27,73 @@ func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
 
 // parseID parses and validates user ID from URL parameter
 func parseID(c echo.Context) (int64, *echo.HTTPError) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	return parseResourceID(c, "user")
+}
+
+// parseResourceID parses and validates the :id URL parameter of the given resource
+func parseResourceID(c echo.Context, resource string) (int64, *echo.HTTPError) {
+	return parseParamID(c, "id", resource)
+}
+
+// parseParamID is parseResourceID for an ID in the path parameter param
+func parseParamID(c echo.Context, param, resource string) (int64, *echo.HTTPError) {
+	msg := "Invalid " + resource + " ID"
+	code := "INVALID_" + strings.ToUpper(resource) + "_ID"
+	id, err := strconv.ParseInt(c.Param(param), 10, 64)
 	if err != nil {
 		return 0, echo.NewHTTPError(http.StatusBadRequest, dto.ErrorResponse{
-			Message: "Invalid user ID",
-			Code:    "INVALID_USER_ID",
+			Message: msg,
+			Code:    code,
 			Details: "ID must be a valid number",
 		})
 	}
 	if id <= 0 {
 		return 0, echo.NewHTTPError(http.StatusBadRequest, dto.ErrorResponse{
-			Message: "Invalid user ID",
-			Code:    "INVALID_USER_ID",
+			Message: msg,
+			Code:    code,
 			Details: "ID must be greater than 0",
 		})
 	}
 	return id, nil
 }
 
+// currentUserID returns the authenticated user's ID; see currentPrincipal
+func currentUserID(c echo.Context) (int64, *echo.HTTPError) {
+	p, herr := currentPrincipal(c)
+	if herr != nil {
+		return 0, herr
+	}
+	return p.UserID, nil
+}
+
+// ClientIPExtractor decides what RealIP reports. X-Forwarded-For is only
+// read from requests that come from one of trustedProxies; any other
+// request is identified by its own address, so clients cannot choose the IP
+// that login throttling and sessions record.
+func ClientIPExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, n := range trustedProxies {
+		opts = append(opts, echo.TrustIPRange(n))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...)
+}
+
+// clientInfo describes the caller for session bookkeeping
+func clientInfo(c echo.Context, deviceLabel string) model.ClientInfo {
+	return model.ClientInfo{
+		DeviceLabel: deviceLabel,
+		UserAgent:   c.Request().UserAgent(),
+		IPAddress:   c.RealIP(),
+	}
+}
+
 // sendError sends a standardized error response
 func sendError(c echo.Context, status int, code, msg, details string) error {
 	// In production, don't expose internal error details
@@ -56,24 +108,99 @@ func sendError(c echo.Context, status int, code, msg, details string) error {
 	})
 }
 
-// sendValidationError sends validation error response
-func sendValidationError(c echo.Context, errors []dto.ValidationError) error {
-	return c.JSON(http.StatusBadRequest, dto.ErrorResponse{
-		Message:          "Validation failed",
-		Code:             "VALIDATION_ERROR",
-		ValidationErrors: errors,
-	})
-}
-
 // handleServiceError handles service layer errors and returns appropriate HTTP responses
 func handleServiceError(c echo.Context, err error, operation string) error {
 	switch {
 	case errors.Is(err, service.ErrUserNotFound):
 		return sendError(c, http.StatusNotFound, "USER_NOT_FOUND", err.Error(), "")
+	case errors.Is(err, service.ErrServiceAccountNotFound):
+		return sendError(c, http.StatusNotFound, "SERVICE_ACCOUNT_NOT_FOUND", err.Error(), "")
+	case errors.Is(err, service.ErrServiceAccountPassword):
+		return sendError(c, http.StatusBadRequest, "SERVICE_ACCOUNT_PASSWORD", err.Error(), "Service accounts authenticate with API keys only")
 	case errors.Is(err, service.ErrEmailAlreadyRegistered):
 		return sendError(c, http.StatusConflict, "EMAIL_EXISTS", err.Error(), "")
+	case errors.Is(err, service.ErrUserHasLedgerHistory):
+		return sendError(c, http.StatusConflict, "USER_HAS_LEDGER_HISTORY", err.Error(), "")
 	case errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrInactiveAccount):
 		return sendError(c, http.StatusUnauthorized, "AUTH_FAILED", err.Error(), "")
+	case errors.Is(err, service.ErrAccountLocked):
+		setRetryAfter(c, err)
+		return sendError(c, http.StatusLocked, "ACCOUNT_LOCKED", err.Error(), "")
+	case errors.Is(err, service.ErrLoginThrottled):
+		setRetryAfter(c, err)
+		return sendError(c, http.StatusTooManyRequests, "TOO_MANY_LOGIN_ATTEMPTS", err.Error(), "")
+	case errors.Is(err, service.ErrInvalidMFACode):
+		return sendError(c, http.StatusUnauthorized, "INVALID_MFA_CODE", err.Error(), "")
+	case errors.Is(err, service.ErrMFALocked):
+		return sendError(c, http.StatusLocked, "MFA_LOCKED", err.Error(), "Try again later")
+	case errors.Is(err, service.ErrInvalidMFAChallenge):
+		return sendError(c, http.StatusUnauthorized, "INVALID_MFA_TOKEN", err.Error(), "")
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		return sendError(c, http.StatusConflict, "MFA_ALREADY_ENABLED", err.Error(), "")
+	case errors.Is(err, service.ErrMFANotEnrolled):
+		return sendError(c, http.StatusConflict, "MFA_NOT_ENROLLED", err.Error(), "")
+	case errors.Is(err, service.ErrMFANotEnabled):
+		return sendError(c, http.StatusConflict, "MFA_NOT_ENABLED", err.Error(), "")
+	case errors.Is(err, service.ErrEmailNotVerified):
+		return sendError(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", err.Error(), "Confirm your email address first")
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		return sendError(c, http.StatusConflict, "EMAIL_ALREADY_VERIFIED", err.Error(), "")
+	case errors.Is(err, service.ErrInvalidVerificationToken):
+		return sendError(c, http.StatusBadRequest, "INVALID_VERIFICATION_TOKEN", err.Error(), "")
+	case errors.Is(err, service.ErrWeakPassword):
+		return sendError(c, http.StatusBadRequest, "WEAK_PASSWORD", err.Error(), "")
+	case errors.Is(err, service.ErrBreachedPassword):
+		return sendError(c, http.StatusBadRequest, "BREACHED_PASSWORD", err.Error(), "Choose a password that has not appeared in a data breach")
+	case errors.Is(err, service.ErrPasswordReused):
+		return sendError(c, http.StatusBadRequest, "PASSWORD_REUSED", err.Error(), "")
+	case errors.Is(err, service.ErrInvalidResetToken):
+		return sendError(c, http.StatusBadRequest, "INVALID_RESET_TOKEN", err.Error(), "")
+	case errors.Is(err, service.ErrAccessTokenRevoked):
+		return sendError(c, http.StatusUnauthorized, "TOKEN_REVOKED", err.Error(), "Log in again")
+	case errors.Is(err, service.ErrSessionNotFound):
+		return sendError(c, http.StatusNotFound, "SESSION_NOT_FOUND", err.Error(), "")
+	case errors.Is(err, service.ErrOAuthClientNotFound):
+		return sendError(c, http.StatusNotFound, "OAUTH_CLIENT_NOT_FOUND", err.Error(), "")
+	case errors.Is(err, service.ErrInvalidRedirectURI):
+		return sendError(c, http.StatusBadRequest, "INVALID_REDIRECT_URI", err.Error(), "")
+	case errors.Is(err, service.ErrInvalidScope):
+		return sendError(c, http.StatusBadRequest, "INVALID_SCOPE", err.Error(), "")
+	case errors.Is(err, service.ErrPKCERequired):
+		return sendError(c, http.StatusBadRequest, "PKCE_REQUIRED", err.Error(), "")
+	case errors.Is(err, service.ErrInvalidAPIKey):
+		return sendError(c, http.StatusUnauthorized, "INVALID_API_KEY", err.Error(), "")
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		return sendError(c, http.StatusNotFound, "API_KEY_NOT_FOUND", err.Error(), "")
+	case errors.Is(err, service.ErrAPIKeyLimitReached):
+		return sendError(c, http.StatusConflict, "API_KEY_LIMIT_REACHED", err.Error(), "Revoke an unused key first")
+	case errors.Is(err, service.ErrInvalidAPIKeyExpiry):
+		return sendError(c, http.StatusBadRequest, "INVALID_API_KEY_EXPIRY", err.Error(), "")
+	case errors.Is(err, service.ErrAccountNotFound):
+		return sendError(c, http.StatusNotFound, "ACCOUNT_NOT_FOUND", err.Error(), "")
+	case errors.Is(err, service.ErrAccountClosed):
+		return sendError(c, http.StatusConflict, "ACCOUNT_CLOSED", err.Error(), "")
+	case errors.Is(err, service.ErrAccountHasBalance):
+		return sendError(c, http.StatusConflict, "ACCOUNT_HAS_BALANCE", err.Error(), "")
+	case errors.Is(err, service.ErrCardNotFound):
+		return sendError(c, http.StatusNotFound, "CARD_NOT_FOUND", err.Error(), "")
+	case errors.Is(err, service.ErrCardReplaced):
+		return sendError(c, http.StatusConflict, "CARD_REPLACED", err.Error(), "")
+	case errors.Is(err, service.ErrInvalidAmount):
+		return sendError(c, http.StatusBadRequest, "INVALID_AMOUNT", err.Error(), "")
+	case errors.Is(err, service.ErrCurrencyMismatch):
+		return sendError(c, http.StatusBadRequest, "CURRENCY_MISMATCH", err.Error(), "")
+	case errors.Is(err, service.ErrRecipientNotFound):
+		return sendError(c, http.StatusNotFound, "RECIPIENT_NOT_FOUND", err.Error(), "")
+	case errors.Is(err, service.ErrSameAccount):
+		return sendError(c, http.StatusBadRequest, "SAME_ACCOUNT", err.Error(), "")
+	case errors.Is(err, service.ErrInsufficientFunds):
+		return sendError(c, http.StatusUnprocessableEntity, "INSUFFICIENT_FUNDS", err.Error(), "")
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return sendError(c, http.StatusConflict, "IDEMPOTENCY_KEY_REUSED", err.Error(), "")
+	case errors.Is(err, service.ErrIdempotencyInProgress):
+		return sendError(c, http.StatusConflict, "IDEMPOTENCY_IN_PROGRESS", err.Error(), "")
+	case errors.Is(err, service.ErrUnbalancedEntry), errors.Is(err, service.ErrInvalidPosting):
+		return sendError(c, http.StatusUnprocessableEntity, "INVALID_LEDGER_ENTRY", err.Error(), "")
 	default:
 		// In production, use generic error message
 		errorMsg := "Could not " + operation
@@ -84,10 +211,27 @@ func handleServiceError(c echo.Context, err error, operation string) error {
 	}
 }
 
-// bindAndValidate binds request body and validates it
+// setRetryAfter sets the Retry-After header, in whole seconds, when err
+// carries a wait time
+func setRetryAfter(c echo.Context, err error) {
+	var blocked *service.LoginBlockedError
+	if !errors.As(err, &blocked) || blocked.RetryAfter <= 0 {
+		return
+	}
+	secs := int64((blocked.RetryAfter + time.Second - 1) / time.Second)
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+}
+
+// bindAndValidate binds request body and validates it. The returned error is an
+// *echo.HTTPError carrying a dto.ErrorResponse, so handlers must return it as-is
+// and stop processing the request.
 func bindAndValidate(c echo.Context, req interface{}) error {
 	if err := c.Bind(req); err != nil {
-		return sendError(c, http.StatusBadRequest, "INVALID_BODY", "Invalid JSON", err.Error())
+		return echo.NewHTTPError(http.StatusBadRequest, dto.ErrorResponse{
+			Message: "Invalid JSON",
+			Code:    "INVALID_BODY",
+			Details: err.Error(),
+		})
 	}
 	if err := c.Validate(req); err != nil {
 		// Convert validation errors to structured format
@@ -100,9 +244,17 @@ func bindAndValidate(c echo.Context, req interface{}) error {
 					Value: fieldError.Param(),
 				})
 			}
-			return sendValidationError(c, errors)
+			return echo.NewHTTPError(http.StatusBadRequest, dto.ErrorResponse{
+				Message:          "Validation failed",
+				Code:             "VALIDATION_ERROR",
+				ValidationErrors: errors,
+			})
 		}
-		return sendError(c, http.StatusBadRequest, "VALIDATION_ERROR", "Validation failed", err.Error())
+		return echo.NewHTTPError(http.StatusBadRequest, dto.ErrorResponse{
+			Message: "Validation failed",
+			Code:    "VALIDATION_ERROR",
+			Details: err.Error(),
+		})
 	}
 	return nil
 }
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// Idempotency deduplicates retried requests that carry an Idempotency-Key
+// header. The first request with a key is processed and its response stored;
+// retries with the same body get the stored response back without reaching
+// the handler, while reusing the key for a different request is a conflict.
+// Requests without the header are passed through unchanged. It must run
+// after the JWT middleware because keys are scoped to the authenticated user.
+func Idempotency(svc service.IdempotencyService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return sendError(c, http.StatusBadRequest, "INVALID_IDEMPOTENCY_KEY", "Invalid idempotency key", "Key must be at most 255 characters")
+			}
+			userID, herr := currentUserID(c)
+			if herr != nil {
+				return c.JSON(herr.Code, herr.Message)
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return sendError(c, http.StatusBadRequest, "INVALID_BODY", "Could not read request body", err.Error())
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+			hash := requestHash(c.Request(), body)
+
+			ctx, cancel := withTimeout(c.Request().Context())
+			rec, replay, err := svc.Begin(ctx, userID, key, hash)
+			cancel()
+			if err != nil {
+				return handleServiceError(c, err, "process request")
+			}
+			if replay {
+				c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+				return c.JSONBlob(rec.ResponseStatus, rec.ResponseBody)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			// Recover runs outside this middleware, so a panicking handler
+			// would otherwise leave the key reserved
+			defer func() {
+				if p := recover(); p != nil {
+					c.Response().Writer = recorder.ResponseWriter
+					ctx, cancel := withTimeout(context.WithoutCancel(c.Request().Context()))
+					defer cancel()
+					if aerr := svc.Abandon(ctx, userID, key); aerr != nil {
+						c.Logger().Errorf("idempotency: could not release key: %v", aerr)
+					}
+					panic(p)
+				}
+			}()
+			err = next(c)
+			c.Response().Writer = recorder.ResponseWriter
+
+			// The outcome must be stored even if the client has gone away meanwhile
+			ctx, cancel = withTimeout(context.WithoutCancel(c.Request().Context()))
+			defer cancel()
+
+			// Errors returned to Echo, server errors and authorization failures
+			// (e.g. a missing step-up) are not final: release the key so that the
+			// client can retry
+			status := c.Response().Status
+			if err != nil || !c.Response().Committed || status >= http.StatusInternalServerError ||
+				status == http.StatusUnauthorized || status == http.StatusForbidden {
+				if aerr := svc.Abandon(ctx, userID, key); aerr != nil {
+					c.Logger().Errorf("idempotency: could not release key: %v", aerr)
+				}
+				return err
+			}
+			if cerr := svc.Complete(ctx, userID, key, c.Response().Status, recorder.body.Bytes()); cerr != nil {
+				c.Logger().Errorf("idempotency: could not store response: %v", cerr)
+			}
+			return nil
+		}
+	}
+}
+
+// requestHash fingerprints the method, path and body so that a key cannot be
+// replayed against a different endpoint or payload
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies the response body while passing it through
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/common/jwtkeys"
+)
+
+// jwksMaxAge lets verifiers cache the key set briefly. Keys are published
+// before they start signing, so a stale copy still knows the current key.
+const jwksMaxAge = "public, max-age=300"
+
+type JWKSController struct {
+	keys *jwtkeys.KeySet
+}
+
+func NewJWKSController(keys *jwtkeys.KeySet) *JWKSController {
+	return &JWKSController{keys: keys}
+}
+
+// Get serves the public signing keys as a JSON Web Key Set
+func (j *JWKSController) Get(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", jwksMaxAge)
+	return c.JSON(http.StatusOK, j.keys.JWKS())
+}
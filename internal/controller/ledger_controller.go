@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+type LedgerController struct {
+	svc service.LedgerService
+}
+
+func NewLedgerController(svc service.LedgerService) *LedgerController {
+	return &LedgerController{svc: svc}
+}
+
+func (l *LedgerController) Reconcile(c echo.Context) error {
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	report, err := l.svc.Reconcile(ctx)
+	if err != nil {
+		return handleServiceError(c, err, "reconcile ledger")
+	}
+
+	return c.JSON(http.StatusOK, dto.LedgerReportResponseFromModel(report))
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+type MFAController struct {
+	svc service.MFAService
+}
+
+func NewMFAController(svc service.MFAService) *MFAController {
+	return &MFAController{svc: svc}
+}
+
+// EnrollTOTP starts TOTP enrollment; the secret is shown once and must be
+// confirmed with a code before it protects the account
+func (m *MFAController) EnrollTOTP(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	secret, uri, err := m.svc.BeginTOTPEnrollment(ctx, userID)
+	if err != nil {
+		return handleServiceError(c, err, "start two-factor enrollment")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, dto.TOTPEnrollmentResponse{Secret: secret, OTPAuthURI: uri})
+}
+
+func (m *MFAController) ConfirmTOTP(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	var req dto.TOTPCodeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	codes, err := m.svc.ConfirmTOTPEnrollment(ctx, userID, req.Code)
+	if err != nil {
+		return handleServiceError(c, err, "confirm two-factor enrollment")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (m *MFAController) DisableTOTP(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	var req dto.TOTPCodeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := m.svc.DisableTOTP(ctx, userID, req.Code); err != nil {
+		return handleServiceError(c, err, "disable two-factor authentication")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,363 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// OAuth grant types accepted by the token endpoint
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+)
+
+var errMultipleClientAuth = errors.New("client credentials sent more than once")
+
+// OAuthController serves the OAuth 2.0 authorization server for third-party
+// apps: client registration for admins, the consent API behind our login,
+// and the token, introspection and revocation endpoints the apps call. The
+// latter three speak the RFC formats instead of dto.ErrorResponse.
+type OAuthController struct {
+	svc    service.OAuthService
+	users  service.UserService
+	tokens AccessTokenConfig
+}
+
+func NewOAuthController(svc service.OAuthService, users service.UserService, tokens AccessTokenConfig) *OAuthController {
+	return &OAuthController{svc: svc, users: users, tokens: tokens}
+}
+
+// RegisterClient adds a third-party app. The secret of a confidential client
+// is only shown in this response.
+func (o *OAuthController) RegisterClient(c echo.Context) error {
+	var req dto.OAuthClientRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	client, secret, err := o.svc.RegisterClient(ctx, req.Name, req.RedirectURIs, req.Scopes, req.Confidential)
+	if err != nil {
+		return handleServiceError(c, err, "register client")
+	}
+	resp := dto.OAuthClientResponseFromModel(client)
+	resp.ClientSecret = secret
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (o *OAuthController) GetClients(c echo.Context) error {
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	clients, err := o.svc.GetAllClients(ctx)
+	if err != nil {
+		return handleServiceError(c, err, "fetch clients")
+	}
+	return c.JSON(http.StatusOK, dto.OAuthClientsResponseFromModels(clients))
+}
+
+// DeleteClient removes an app and ends every grant users gave it
+func (o *OAuthController) DeleteClient(c echo.Context) error {
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := o.svc.DeleteClient(ctx, c.Param("client_id")); err != nil {
+		return handleServiceError(c, err, "delete client")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Authorize validates an authorization request for the logged-in user and
+// returns what the consent screen should show
+func (o *OAuthController) Authorize(c echo.Context) error {
+	var req dto.OAuthAuthorizeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	grant, err := o.svc.ValidateAuthorization(ctx, authorizationRequest(req))
+	if err != nil {
+		return handleServiceError(c, err, "authorize")
+	}
+	return c.JSON(http.StatusOK, dto.OAuthConsentResponse{
+		ClientID:    grant.Client.ClientID,
+		ClientName:  grant.Client.Name,
+		RedirectURI: grant.RedirectURI,
+		Scopes:      grant.Scopes,
+		State:       req.State,
+	})
+}
+
+// Consent records the user's decision and returns where to send the browser.
+// Invalid requests are answered here rather than redirected, so an
+// unregistered redirect URI never receives anything.
+func (o *OAuthController) Consent(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	var req dto.OAuthConsentRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	params := url.Values{}
+	var grant service.AuthorizationGrant
+	var err error
+	if req.Approve {
+		var code string
+		code, grant, err = o.svc.Approve(ctx, userID, authorizationRequest(req.OAuthAuthorizeRequest))
+		params.Set("code", code)
+	} else {
+		grant, err = o.svc.ValidateAuthorization(ctx, authorizationRequest(req.OAuthAuthorizeRequest))
+		params.Set("error", "access_denied")
+	}
+	if err != nil {
+		return handleServiceError(c, err, "authorize")
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(http.StatusOK, dto.OAuthConsentResult{RedirectTo: withQuery(grant.RedirectURI, params)})
+}
+
+// Token is the RFC 6749 token endpoint. It redeems authorization codes and
+// rotates refresh tokens; every response carries a fresh refresh token.
+func (o *OAuthController) Token(c echo.Context) error {
+	client, err := o.authenticateClient(c)
+	if err != nil {
+		return o.handleOAuthError(c, err)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	var rt model.RefreshToken
+	switch c.FormValue("grant_type") {
+	case grantTypeAuthorizationCode:
+		code, verifier := c.FormValue("code"), c.FormValue("code_verifier")
+		if code == "" || verifier == "" {
+			return sendOAuthError(c, http.StatusBadRequest, "invalid_request", "code and code_verifier are required")
+		}
+		rt, err = o.svc.ExchangeCode(ctx, client, code, c.FormValue("redirect_uri"), verifier, clientInfo(c, ""))
+	case grantTypeRefreshToken:
+		token := c.FormValue("refresh_token")
+		if token == "" {
+			return sendOAuthError(c, http.StatusBadRequest, "invalid_request", "refresh_token is required")
+		}
+		rt, err = o.svc.RefreshGrant(ctx, client, token, clientInfo(c, ""))
+	case "":
+		return sendOAuthError(c, http.StatusBadRequest, "invalid_request", "grant_type is required")
+	default:
+		return sendOAuthError(c, http.StatusBadRequest, "unsupported_grant_type", "")
+	}
+	if err != nil {
+		return o.handleOAuthError(c, err)
+	}
+
+	user, err := o.users.GetUserByID(ctx, rt.UserID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			return o.handleOAuthError(c, service.ErrInvalidGrant)
+		}
+		return o.handleOAuthError(c, err)
+	}
+	if !user.IsActive {
+		return o.handleOAuthError(c, service.ErrInvalidGrant)
+	}
+	now := time.Now()
+	claims, err := o.tokens.newClaims(user, now, now.Add(o.tokens.TTL))
+	if err != nil {
+		return o.handleOAuthError(c, err)
+	}
+	claims.Scopes = rt.Scopes
+	claims.ClientID = client.ClientID
+	token, err := o.tokens.sign(claims)
+	if err != nil {
+		return o.handleOAuthError(c, err)
+	}
+
+	setNoStore(c)
+	return c.JSON(http.StatusOK, dto.OAuthTokenResponse{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(o.tokens.TTL / time.Second),
+		RefreshToken: rt.Token,
+		Scope:        strings.Join(rt.Scopes, " "),
+	})
+}
+
+// Introspect is the RFC 7662 introspection endpoint for confidential
+// clients. It describes access tokens issued to the calling client; any
+// other token is reported as inactive.
+func (o *OAuthController) Introspect(c echo.Context) error {
+	client, err := o.authenticateClient(c)
+	if err == nil && !client.Confidential() {
+		err = service.ErrInvalidClient
+	}
+	if err != nil {
+		return o.handleOAuthError(c, err)
+	}
+	raw := c.FormValue("token")
+	if raw == "" {
+		return sendOAuthError(c, http.StatusBadRequest, "invalid_request", "token is required")
+	}
+
+	setNoStore(c)
+	claims, p, ok := o.clientAccessToken(raw, client)
+	if !ok {
+		return c.JSON(http.StatusOK, dto.OAuthIntrospectionResponse{Active: false})
+	}
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+	if err := o.users.CheckAccessToken(ctx, p.UserID, p.TokenVersion, p.TokenID); err != nil {
+		if errors.Is(err, service.ErrAccessTokenRevoked) {
+			return c.JSON(http.StatusOK, dto.OAuthIntrospectionResponse{Active: false})
+		}
+		return o.handleOAuthError(c, err)
+	}
+	return c.JSON(http.StatusOK, dto.OAuthIntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(p.Scopes, " "),
+		ClientID:  p.ClientID,
+		Subject:   claims.Subject,
+		TokenType: "Bearer",
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		IssuedAt:  claims.IssuedAt.Unix(),
+		Issuer:    claims.Issuer,
+		TokenID:   claims.ID,
+	})
+}
+
+// Revoke is the RFC 7009 revocation endpoint. Access tokens go on the
+// denylist; refresh tokens end their grant. Tokens the client does not own
+// are ignored, and the answer is 200 either way.
+func (o *OAuthController) Revoke(c echo.Context) error {
+	client, err := o.authenticateClient(c)
+	if err != nil {
+		return o.handleOAuthError(c, err)
+	}
+	raw := c.FormValue("token")
+	if raw == "" {
+		return sendOAuthError(c, http.StatusBadRequest, "invalid_request", "token is required")
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if _, p, ok := o.clientAccessToken(raw, client); ok {
+		err = o.users.RevokeAccessToken(ctx, p.UserID, p.TokenID, p.ExpiresAt)
+	} else {
+		err = o.svc.RevokeRefreshToken(ctx, client, raw)
+	}
+	if err != nil {
+		return o.handleOAuthError(c, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// clientAccessToken parses raw as an access token issued to client
+func (o *OAuthController) clientAccessToken(raw string, client model.OAuthClient) (*AccessClaims, Principal, bool) {
+	token, err := o.tokens.Parse(raw)
+	if err != nil {
+		return nil, Principal{}, false
+	}
+	p, ok := principalFromToken(token)
+	if !ok || p.ClientID != client.ClientID {
+		return nil, Principal{}, false
+	}
+	return token.Claims.(*AccessClaims), p, true
+}
+
+// authenticateClient reads client credentials from HTTP Basic auth
+// (client_secret_basic) or the form (client_secret_post). Public clients
+// send only client_id in the form.
+func (o *OAuthController) authenticateClient(c echo.Context) (model.OAuthClient, error) {
+	clientID, secret := c.FormValue("client_id"), c.FormValue("client_secret")
+	if id, pw, ok := c.Request().BasicAuth(); ok {
+		if secret != "" {
+			return model.OAuthClient{}, errMultipleClientAuth
+		}
+		// RFC 6749 section 2.3.1 form-encodes both values
+		var err1, err2 error
+		clientID, err1 = url.QueryUnescape(id)
+		secret, err2 = url.QueryUnescape(pw)
+		if err1 != nil || err2 != nil {
+			return model.OAuthClient{}, service.ErrInvalidClient
+		}
+	}
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+	return o.svc.AuthenticateClient(ctx, clientID, secret)
+}
+
+// handleOAuthError answers the token, introspection and revocation endpoints
+func (o *OAuthController) handleOAuthError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidClient):
+		if _, _, ok := c.Request().BasicAuth(); ok {
+			c.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		return sendOAuthError(c, http.StatusUnauthorized, "invalid_client", err.Error())
+	case errors.Is(err, errMultipleClientAuth):
+		return sendOAuthError(c, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, service.ErrInvalidGrant):
+		return sendOAuthError(c, http.StatusBadRequest, "invalid_grant", err.Error())
+	default:
+		c.Logger().Errorf("oauth: %v", err)
+		return sendOAuthError(c, http.StatusInternalServerError, "server_error", "")
+	}
+}
+
+func sendOAuthError(c echo.Context, status int, code, description string) error {
+	setNoStore(c)
+	return c.JSON(status, dto.OAuthErrorResponse{Error: code, ErrorDescription: description})
+}
+
+// setNoStore keeps tokens out of caches, as RFC 6749 section 5.1 requires
+func setNoStore(c echo.Context) {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+}
+
+func authorizationRequest(req dto.OAuthAuthorizeRequest) service.AuthorizationRequest {
+	return service.AuthorizationRequest{
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+}
+
+// withQuery adds params to the query of a registered redirect URI
+func withQuery(base string, params url.Values) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+type PasswordResetController struct {
+	svc service.PasswordResetService
+}
+
+func NewPasswordResetController(svc service.PasswordResetService) *PasswordResetController {
+	return &PasswordResetController{svc: svc}
+}
+
+// Forgot always answers 202 so the response does not reveal whether the
+// email is registered
+func (p *PasswordResetController) Forgot(c echo.Context) error {
+	var req dto.ForgotPasswordRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := p.svc.RequestReset(ctx, req.Email); err != nil {
+		return handleServiceError(c, err, "forgot password")
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (p *PasswordResetController) Reset(c echo.Context) error {
+	var req dto.ResetPasswordRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := p.svc.ResetPassword(ctx, req.Token, req.NewPassword); err != nil {
+		return handleServiceError(c, err, "reset password")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
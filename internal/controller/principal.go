@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// Principal is the authenticated caller of a protected endpoint, taken from
+// the verified access token. Handlers decide whose data they act on from the
+// principal, never from the path alone.
+type Principal struct {
+	UserID       int64
+	Role         string
+	Scopes       []string
+	ClientID     string
+	TokenID      string
+	TokenVersion int64
+	ExpiresAt    time.Time
+	// AuthTime is zero unless the token came from a re-authentication
+	AuthTime time.Time
+}
+
+// HasRole reports whether the principal has one of roles
+func (p Principal) HasRole(roles ...string) bool {
+	return p.Role != "" && slices.Contains(roles, p.Role)
+}
+
+// HasScope reports whether the token may be used for scope. Tokens without
+// scopes are not restricted.
+func (p Principal) HasScope(scope string) bool {
+	return len(p.Scopes) == 0 || slices.Contains(p.Scopes, scope)
+}
+
+// CanManageUser reports whether the principal may act on the user with id:
+// their own record, or anyone's as an admin
+func (p Principal) CanManageUser(id int64) bool {
+	return p.UserID == id || p.HasRole(model.RoleAdmin)
+}
+
+// currentPrincipal returns the caller of a request that passed the JWT
+// middleware
+func currentPrincipal(c echo.Context) (Principal, *echo.HTTPError) {
+	token, _ := c.Get("user").(*jwt.Token)
+	p, ok := principalFromToken(token)
+	if !ok {
+		return Principal{}, echo.NewHTTPError(http.StatusUnauthorized, dto.ErrorResponse{
+			Message: "Invalid or missing token",
+			Code:    "UNAUTHORIZED",
+		})
+	}
+	return p, nil
+}
+
+// principalFromToken reads the principal from a verified access token
+func principalFromToken(token *jwt.Token) (Principal, bool) {
+	if token == nil {
+		return Principal{}, false
+	}
+	claims, ok := token.Claims.(*AccessClaims)
+	if !ok {
+		return Principal{}, false
+	}
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || userID <= 0 {
+		return Principal{}, false
+	}
+	p := Principal{
+		UserID:       userID,
+		Role:         claims.Role,
+		Scopes:       claims.Scopes,
+		ClientID:     claims.ClientID,
+		TokenID:      claims.ID,
+		TokenVersion: claims.TokenVersion,
+	}
+	if claims.ExpiresAt != nil {
+		p.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if claims.AuthTime != nil {
+		p.AuthTime = claims.AuthTime.Time
+	}
+	return p, true
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// ServiceAccountController lets admins create service accounts and manage
+// their API keys. A service account cannot log in, so its keys are the only
+// way to act as it and they survive any person's password change or logout.
+type ServiceAccountController struct {
+	users service.UserService
+	keys  service.APIKeyService
+}
+
+func NewServiceAccountController(users service.UserService, keys service.APIKeyService) *ServiceAccountController {
+	return &ServiceAccountController{users: users, keys: keys}
+}
+
+func (sc *ServiceAccountController) Create(c echo.Context) error {
+	var req dto.CreateServiceAccountRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	account, err := sc.users.CreateServiceAccount(ctx, req.Name, req.Role)
+	if err != nil {
+		return handleServiceError(c, err, "create service account")
+	}
+	return c.JSON(http.StatusCreated, dto.UserResponseFromModel(account))
+}
+
+func (sc *ServiceAccountController) GetAll(c echo.Context) error {
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	accounts, err := sc.users.GetServiceAccounts(ctx)
+	if err != nil {
+		return handleServiceError(c, err, "fetch service accounts")
+	}
+	return c.JSON(http.StatusOK, dto.UsersResponseFromModels(accounts))
+}
+
+// CreateKey issues a key for the service account. The key is only shown in
+// this response.
+func (sc *ServiceAccountController) CreateKey(c echo.Context) error {
+	id, herr := parseID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	var req dto.CreateAPIKeyRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if _, err := sc.users.GetServiceAccount(ctx, id); err != nil {
+		return handleServiceError(c, err, "create api key")
+	}
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	key, raw, err := sc.keys.CreateKey(ctx, id, req.Name, req.Scopes, ttl)
+	if err != nil {
+		return handleServiceError(c, err, "create api key")
+	}
+	resp := dto.APIKeyResponseFromModel(key)
+	resp.Key = raw
+	setNoStore(c)
+	return c.JSON(http.StatusCreated, resp)
+}
+
+func (sc *ServiceAccountController) GetKeys(c echo.Context) error {
+	id, herr := parseID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if _, err := sc.users.GetServiceAccount(ctx, id); err != nil {
+		return handleServiceError(c, err, "fetch api keys")
+	}
+	keys, err := sc.keys.GetUserKeys(ctx, id)
+	if err != nil {
+		return handleServiceError(c, err, "fetch api keys")
+	}
+	return c.JSON(http.StatusOK, dto.APIKeysResponseFromModels(keys))
+}
+
+func (sc *ServiceAccountController) RevokeKey(c echo.Context) error {
+	id, herr := parseID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	keyID, herr := parseParamID(c, "key_id", "key")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if _, err := sc.users.GetServiceAccount(ctx, id); err != nil {
+		return handleServiceError(c, err, "revoke api key")
+	}
+	if err := sc.keys.RevokeKey(ctx, id, keyID); err != nil {
+		return handleServiceError(c, err, "revoke api key")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
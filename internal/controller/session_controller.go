@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+type SessionController struct {
+	svc service.UserService
+}
+
+func NewSessionController(svc service.UserService) *SessionController {
+	return &SessionController{svc: svc}
+}
+
+// GetMine lists the caller's active sessions
+func (sc *SessionController) GetMine(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	sessions, err := sc.svc.ListSessions(ctx, userID)
+	if err != nil {
+		return handleServiceError(c, err, "fetch sessions")
+	}
+
+	return c.JSON(http.StatusOK, dto.SessionsResponseFromModels(sessions))
+}
+
+// Revoke ends one of the caller's sessions; its refresh tokens stop working
+// immediately
+func (sc *SessionController) Revoke(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	sessionID, herr := parseResourceID(c, "session")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := sc.svc.RevokeSession(ctx, userID, sessionID); err != nil {
+		return handleServiceError(c, err, "revoke session")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// RequireLiveToken rejects access tokens that were revoked after they were
+// issued: tokens of deleted or deactivated users, tokens older than the user's
+// last logout-all or password change, and tokens on the denylist. It must run
+// right after the JWT middleware.
+func RequireLiveToken(users service.UserService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, herr := currentPrincipal(c)
+			if herr != nil {
+				return c.JSON(herr.Code, herr.Message)
+			}
+			ctx, cancel := withTimeout(c.Request().Context())
+			defer cancel()
+			if err := users.CheckAccessToken(ctx, p.UserID, p.TokenVersion, p.TokenID); err != nil {
+				return handleServiceError(c, err, "check access token")
+			}
+			return next(c)
+		}
+	}
+}
+
+// revokeBearerToken puts the access token the request was made with on the
+// denylist. Requests without a valid token are left alone.
+func revokeBearerToken(c echo.Context, users service.UserService, tokens AccessTokenConfig) error {
+	raw, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok || raw == "" {
+		return nil
+	}
+	token, err := tokens.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	p, ok := principalFromToken(token)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+	return users.RevokeAccessToken(ctx, p.UserID, p.TokenID, p.ExpiresAt)
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+type TransactionController struct {
+	svc    service.TransactionService
+	stepUp StepUpPolicy
+}
+
+func NewTransactionController(svc service.TransactionService, stepUp StepUpPolicy) *TransactionController {
+	return &TransactionController{svc: svc, stepUp: stepUp}
+}
+
+type moneyMovementFunc func(ctx context.Context, userID, accountID int64, amount money.Money, description string) (model.Transaction, model.Account, error)
+
+func (t *TransactionController) Deposit(c echo.Context) error {
+	return t.moveMoney(c, t.svc.Deposit, "deposit")
+}
+
+func (t *TransactionController) Withdraw(c echo.Context) error {
+	return t.moveMoney(c, t.svc.Withdraw, "withdraw")
+}
+
+func (t *TransactionController) moveMoney(c echo.Context, move moneyMovementFunc, operation string) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+	accountID, herr := parseResourceID(c, "account")
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	var req dto.MoneyMovementRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	tx, account, err := move(ctx, userID, accountID, req.Amount.WithCurrency(req.Currency), req.Description)
+	if err != nil {
+		return handleServiceError(c, err, operation)
+	}
+
+	return c.JSON(http.StatusCreated, dto.MoneyMovementResponse{
+		Transaction: dto.TransactionResponseFromModel(tx),
+		Account:     dto.AccountResponseFromModel(account),
+	})
+}
+
+func (t *TransactionController) Transfer(c echo.Context) error {
+	userID, herr := currentUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	var req dto.TransferRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+	amount := req.Amount.WithCurrency(req.Currency)
+	if t.stepUp.requiresStepUp(amount) && !recentlyAuthenticated(c, t.stepUp.LargeTransfer) {
+		return sendStepUpRequired(c, t.stepUp.LargeTransfer)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	transfer, account, err := t.svc.Transfer(ctx, userID, req.FromAccountID, req.ToAccountNumber, amount, req.Description)
+	if err != nil {
+		return handleServiceError(c, err, "transfer")
+	}
+
+	return c.JSON(http.StatusCreated, dto.TransferResultResponse{
+		Transfer: dto.TransferResponseFromModel(transfer, req.ToAccountNumber),
+		Account:  dto.AccountResponseFromModel(account),
+	})
+}
@@ -9,11 +9,13 @@ import (
 )
 
 type UserController struct {
-	svc service.UserService
+	svc          service.UserService
+	verification service.EmailVerificationService
+	attempts     service.LoginAttemptService
 }
 
-func NewUserController(svc service.UserService) *UserController {
-	return &UserController{svc: svc}
+func NewUserController(svc service.UserService, verification service.EmailVerificationService, attempts service.LoginAttemptService) *UserController {
+	return &UserController{svc: svc, verification: verification, attempts: attempts}
 }
 
 func (u *UserController) GetAll(c echo.Context) error {
@@ -36,7 +38,7 @@ func (u *UserController) GetAll(c echo.Context) error {
 }
 
 func (u *UserController) GetByID(c echo.Context) error {
-	id, herr := parseID(c)
+	id, herr := targetUserID(c)
 	if herr != nil {
 		return c.JSON(herr.Code, herr.Message)
 	}
@@ -53,7 +55,7 @@ func (u *UserController) GetByID(c echo.Context) error {
 }
 
 func (u *UserController) UpdateEmail(c echo.Context) error {
-	id, herr := parseID(c)
+	id, herr := targetUserID(c)
 	if herr != nil {
 		return c.JSON(herr.Code, herr.Message)
 	}
@@ -66,15 +68,16 @@ func (u *UserController) UpdateEmail(c echo.Context) error {
 	ctx, cancel := withTimeout(c.Request().Context())
 	defer cancel()
 
-	if err := u.svc.UpdateUserEmail(ctx, id, req.NewEmail); err != nil {
+	// The new address only replaces the current one after it is confirmed
+	if err := u.verification.RequestEmailChange(ctx, id, req.NewEmail); err != nil {
 		return handleServiceError(c, err, "update email")
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.NoContent(http.StatusAccepted)
 }
 
 func (u *UserController) UpdatePassword(c echo.Context) error {
-	id, herr := parseID(c)
+	id, herr := targetUserID(c)
 	if herr != nil {
 		return c.JSON(herr.Code, herr.Message)
 	}
@@ -95,7 +98,7 @@ func (u *UserController) UpdatePassword(c echo.Context) error {
 }
 
 func (u *UserController) UpdateStatus(c echo.Context) error {
-	id, herr := parseID(c)
+	id, herr := targetUserID(c)
 	if herr != nil {
 		return c.JSON(herr.Code, herr.Message)
 	}
@@ -108,15 +111,32 @@ func (u *UserController) UpdateStatus(c echo.Context) error {
 	ctx, cancel := withTimeout(c.Request().Context())
 	defer cancel()
 
-	if err := u.svc.UpdateUserActiveStatus(ctx, id, req.IsActive); err != nil {
+	if err := u.svc.UpdateUserActiveStatus(ctx, id, *req.IsActive); err != nil {
 		return handleServiceError(c, err, "update status")
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
+// Unlock lifts a login lockout of the user's account
+func (u *UserController) Unlock(c echo.Context) error {
+	id, herr := targetUserID(c)
+	if herr != nil {
+		return c.JSON(herr.Code, herr.Message)
+	}
+
+	ctx, cancel := withTimeout(c.Request().Context())
+	defer cancel()
+
+	if err := u.attempts.Unlock(ctx, id); err != nil {
+		return handleServiceError(c, err, "unlock user")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 func (u *UserController) DeleteByID(c echo.Context) error {
-	id, herr := parseID(c)
+	id, herr := targetUserID(c)
 	if herr != nil {
 		return c.JSON(herr.Code, herr.Message)
 	}
@@ -130,3 +150,24 @@ func (u *UserController) DeleteByID(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// targetUserID returns the :id of the user the request acts on, provided the
+// principal may act on that user. Route middleware narrows this further, e.g.
+// to admins only.
+func targetUserID(c echo.Context) (int64, *echo.HTTPError) {
+	p, herr := currentPrincipal(c)
+	if herr != nil {
+		return 0, herr
+	}
+	id, herr := parseID(c)
+	if herr != nil {
+		return 0, herr
+	}
+	if !p.CanManageUser(id) {
+		return 0, echo.NewHTTPError(http.StatusForbidden, dto.ErrorResponse{
+			Message: "You are not allowed to perform this action",
+			Code:    "FORBIDDEN",
+		})
+	}
+	return id, nil
+}
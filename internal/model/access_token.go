@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// RevokedAccessToken denies one access token, identified by its "jti" claim,
+// until it would have expired anyway
+type RevokedAccessToken struct {
+	JTI       string    `db:"jti" json:"jti"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+}
+
+// AccessTokenState is what an access token is checked against on every
+// request. Revoked says whether the token's jti is on the denylist.
+type AccessTokenState struct {
+	TokenVersion int64 `db:"token_version" json:"token_version"`
+	IsActive     bool  `db:"is_active" json:"is_active"`
+	Revoked      bool  `db:"revoked" json:"revoked"`
+}
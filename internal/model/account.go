@@ -1,12 +1,18 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/yusufziyrek/bank-app/common/money"
+)
 
 type Account struct {
-	ID            int64     `db:"id" json:"id"`
-	UserID        int64     `db:"user_id" json:"user_id"`
-	AccountNumber string    `db:"account_number" json:"account_number"`
-	Balance       float64   `db:"balance" json:"balance"`
-	CreatedAt     time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+	ID            int64       `db:"id" json:"id"`
+	UserID        int64       `db:"user_id" json:"user_id"`
+	AccountNumber string      `db:"account_number" json:"account_number"`
+	Balance       money.Money `db:"balance" json:"balance"`
+	Currency      string      `db:"currency" json:"currency"`
+	IsActive      bool        `db:"is_active" json:"is_active"`
+	CreatedAt     time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time   `db:"updated_at" json:"updated_at"`
 }
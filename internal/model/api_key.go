@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// APIKeyPrefix starts every API key so secret scanners can recognize leaked
+// keys
+const APIKeyPrefix = "bak_"
+
+// APIKey lets scripts call the API as its user without a password. Only the
+// SHA-256 digest of the key is stored; Prefix keeps its first characters so
+// users can tell their keys apart. A key is limited to its Scopes, and it
+// stops working once its user's token version moves past TokenVersion.
+type APIKey struct {
+	ID           int64      `db:"id" json:"id"`
+	UserID       int64      `db:"user_id" json:"user_id"`
+	Name         string     `db:"name" json:"name"`
+	Prefix       string     `db:"prefix" json:"prefix"`
+	KeyHash      string     `db:"key_hash" json:"-"`
+	Scopes       []string   `db:"scopes" json:"scopes"`
+	TokenVersion int64      `db:"token_version" json:"-"`
+	ExpiresAt    time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt   *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+}
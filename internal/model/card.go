@@ -2,13 +2,21 @@ package model
 
 import "time"
 
+// Card holds plain card data; CardRepository encrypts CardNumber and CVV at rest
 type Card struct {
-	ID         int64     `db:"id" json:"id"`
-	AccountID  int64     `db:"account_id" json:"account_id"`
-	CardNumber string    `db:"card_number" json:"card_number"`
-	CVV        string    `db:"cvv" json:"-"`
-	IsActive   bool      `db:"is_active" json:"is_active"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
-	ExpiryDate time.Time `db:"expiry_date" json:"expiry_date"`
+	ID           int64     `db:"id" json:"id"`
+	AccountID    int64     `db:"account_id" json:"account_id"`
+	CardNumber   string    `db:"card_number" json:"card_number"`
+	CVV          string    `db:"cvv" json:"-"`
+	IsActive     bool      `db:"is_active" json:"is_active"`
+	ReplacedByID *int64    `db:"replaced_by_id" json:"replaced_by_id,omitempty"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+	ExpiryDate   time.Time `db:"expiry_date" json:"expiry_date"`
+}
+
+// Replaced reports whether the card was permanently retired in favour of a
+// replacement card. Unlike a frozen card it can never be reactivated.
+func (c Card) Replaced() bool {
+	return c.ReplacedByID != nil
 }
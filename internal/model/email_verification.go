@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// What an email verification confirms
+const (
+	// EmailVerificationVerify confirms the user's current address
+	EmailVerificationVerify = "verify"
+	// EmailVerificationChange confirms a new address the user asked to move to
+	EmailVerificationChange = "change"
+)
+
+// EmailVerification is an outstanding request to confirm an address. Email is
+// the user's current address after registration, or the pending new address
+// after an email change; the change takes effect only once it is confirmed.
+type EmailVerification struct {
+	ID        int64     `db:"id" json:"id"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	Email     string    `db:"email" json:"email"`
+	Purpose   string    `db:"purpose" json:"purpose"`
+	TokenHash string    `db:"token_hash" json:"-"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// IdempotencyRecord remembers the outcome of a request sent with an
+// Idempotency-Key header. ResponseStatus is zero while the original request
+// is still being processed.
+type IdempotencyRecord struct {
+	ID             int64     `db:"id" json:"id"`
+	UserID         int64     `db:"user_id" json:"user_id"`
+	Key            string    `db:"key" json:"key"`
+	RequestHash    string    `db:"request_hash" json:"request_hash"`
+	ResponseStatus int       `db:"response_status" json:"response_status"`
+	ResponseBody   []byte    `db:"response_body" json:"-"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	ExpiresAt      time.Time `db:"expires_at" json:"expires_at"`
+}
+
+// Completed reports whether a response has been stored for the key
+func (r IdempotencyRecord) Completed() bool {
+	return r.ResponseStatus != 0
+}
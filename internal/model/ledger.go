@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yusufziyrek/bank-app/common/money"
+)
+
+const (
+	PostingDebit  = "debit"
+	PostingCredit = "credit"
+)
+
+// JournalEntry groups the postings of a single business event; the sum of its
+// debit postings always equals the sum of its credit postings
+type JournalEntry struct {
+	ID          int64     `db:"id" json:"id"`
+	Description string    `db:"description" json:"description,omitempty"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	Postings    []Posting `db:"-" json:"postings"`
+}
+
+// Posting is one side of a journal entry against a single account.
+// Credits increase an account's balance, debits decrease it.
+type Posting struct {
+	ID             int64       `db:"id" json:"id"`
+	JournalEntryID int64       `db:"journal_entry_id" json:"journal_entry_id"`
+	AccountID      int64       `db:"account_id" json:"account_id"`
+	Direction      string      `db:"direction" json:"direction"`
+	Amount         money.Money `db:"amount" json:"amount"`
+	Currency       string      `db:"currency" json:"currency"`
+	CreatedAt      time.Time   `db:"created_at" json:"created_at"`
+}
+
+// BalanceMismatch reports an account whose materialized balance differs from
+// the balance derived from its postings
+type BalanceMismatch struct {
+	AccountID      int64       `db:"account_id" json:"account_id"`
+	Currency       string      `db:"currency" json:"currency"`
+	Balance        money.Money `db:"balance" json:"balance"`
+	DerivedBalance money.Money `db:"derived_balance" json:"derived_balance"`
+}
+
+// LedgerReport is the result of a ledger reconciliation run
+type LedgerReport struct {
+	UnbalancedEntries []int64           `json:"unbalanced_entries"`
+	BalanceMismatches []BalanceMismatch `json:"balance_mismatches"`
+	CheckedAt         time.Time         `json:"checked_at"`
+}
+
+// Balanced reports whether the books balance
+func (r LedgerReport) Balanced() bool {
+	return len(r.UnbalancedEntries) == 0 && len(r.BalanceMismatches) == 0
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Scopes failed logins are counted under
+const (
+	LoginScopeAccount = "account"
+	LoginScopeIP      = "ip"
+)
+
+// LoginFailure counts recent failed logins for one account or client IP.
+// Accounts are keyed by normalized email so unknown addresses are throttled
+// the same way as registered ones.
+type LoginFailure struct {
+	Scope         string     `db:"scope" json:"scope"`
+	Key           string     `db:"key" json:"key"`
+	Failures      int        `db:"failures" json:"failures"`
+	LastFailureAt time.Time  `db:"last_failure_at" json:"last_failure_at"`
+	LockedUntil   *time.Time `db:"locked_until" json:"locked_until,omitempty"`
+}
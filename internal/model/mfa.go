@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// UserTOTP is a user's authenticator enrollment. It stays pending until the
+// user proves possession of the secret by confirming a code.
+type UserTOTP struct {
+	UserID int64 `db:"user_id" json:"user_id"`
+	// Secret is the base32 TOTP secret; it is encrypted at rest
+	Secret       string `db:"secret" json:"-"`
+	Enabled      bool   `db:"enabled" json:"enabled"`
+	LastUsedStep int64  `db:"last_used_step" json:"-"`
+	// FailedAttempts counts code attempts in a row that did not succeed since
+	// LastFailureAt's window started; see MFAService.VerifyCode
+	FailedAttempts int        `db:"failed_attempts" json:"-"`
+	LastFailureAt  *time.Time `db:"last_failure_at" json:"-"`
+	ConfirmedAt    *time.Time `db:"confirmed_at" json:"confirmed_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// MFAChallenge is the server side half of a login that passed the password
+// check and still awaits a second factor
+type MFAChallenge struct {
+	ID        int64     `db:"id" json:"id"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	TokenHash string    `db:"token_hash" json:"-"`
+	Attempts  int       `db:"attempts" json:"attempts"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
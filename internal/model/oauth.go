@@ -0,0 +1,63 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// Scopes limit what third-party apps, through OAuth, and API keys may do
+const (
+	ScopeAccountsRead  = "accounts:read"
+	ScopePaymentsWrite = "payments:write"
+)
+
+// KnownScopes lists every scope a client or API key can be given
+var KnownScopes = []string{ScopeAccountsRead, ScopePaymentsWrite}
+
+// OAuthClient is a third-party app that may act for customers who consent.
+// Confidential clients authenticate with a secret, stored as its SHA-256
+// digest; public clients have none and rely on PKCE alone.
+type OAuthClient struct {
+	ID           int64     `db:"id" json:"id"`
+	ClientID     string    `db:"client_id" json:"client_id"`
+	SecretHash   string    `db:"secret_hash" json:"-"`
+	Name         string    `db:"name" json:"name"`
+	RedirectURIs []string  `db:"redirect_uris" json:"redirect_uris"`
+	Scopes       []string  `db:"scopes" json:"scopes"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// Confidential reports whether the client must authenticate with a secret
+func (c OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// AllowsRedirectURI reports whether uri is registered for the client. URIs
+// are compared exactly.
+func (c OAuthClient) AllowsRedirectURI(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// AllowsScope reports whether the client may ask for scope
+func (c OAuthClient) AllowsScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// OAuthAuthorizationCode is the single-use code a client receives on its
+// redirect URI once the user consents. Only the SHA-256 digest of the code is
+// stored; CodeChallenge is the S256 PKCE challenge the client sent.
+// RedirectURIProvided records whether the authorization request named the
+// redirect URI, in which case the token request has to repeat it.
+type OAuthAuthorizationCode struct {
+	ID                  int64      `db:"id" json:"id"`
+	CodeHash            string     `db:"code_hash" json:"-"`
+	ClientID            string     `db:"client_id" json:"client_id"`
+	UserID              int64      `db:"user_id" json:"user_id"`
+	RedirectURI         string     `db:"redirect_uri" json:"redirect_uri"`
+	RedirectURIProvided bool       `db:"redirect_uri_provided" json:"redirect_uri_provided"`
+	Scopes              []string   `db:"scopes" json:"scopes"`
+	CodeChallenge       string     `db:"code_challenge" json:"-"`
+	ExpiresAt           time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt           time.Time  `db:"created_at" json:"created_at"`
+	UsedAt              *time.Time `db:"used_at" json:"used_at,omitempty"`
+}
@@ -3,9 +3,23 @@ package model
 import "time"
 
 type RefreshToken struct {
-	ID        int64     `db:"id" json:"id"`
-	UserID    int64     `db:"user_id" json:"user_id"`
-	Token     string    `db:"token" json:"token"`
+	ID     int64 `db:"id" json:"id"`
+	UserID int64 `db:"user_id" json:"user_id"`
+	// Token is the plaintext value handed to the client. It is only set on
+	// freshly issued tokens; the database keeps TokenHash alone.
+	Token     string    `db:"-" json:"-"`
+	TokenHash string    `db:"token_hash" json:"-"`
+	FamilyID  string    `db:"family_id" json:"family_id"`
 	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// UsedAt is set once the token has been exchanged for its successor
+	UsedAt *time.Time `db:"used_at" json:"used_at,omitempty"`
+	// ClientID and Scopes come from the token's session
+	ClientID string   `db:"-" json:"client_id,omitempty"`
+	Scopes   []string `db:"-" json:"scopes,omitempty"`
+}
+
+// Used reports whether the token has already been rotated
+func (rt RefreshToken) Used() bool {
+	return rt.UsedAt != nil
 }
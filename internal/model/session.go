@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Session is one login of a user on one device. Its refresh tokens share the
+// session's FamilyID, so a session lives exactly as long as its token family.
+// Sessions with a ClientID are OAuth grants to a third-party app, limited to
+// Scopes.
+type Session struct {
+	ID          int64     `db:"id" json:"id"`
+	UserID      int64     `db:"user_id" json:"user_id"`
+	FamilyID    string    `db:"family_id" json:"-"`
+	ClientID    string    `db:"client_id" json:"client_id,omitempty"`
+	Scopes      []string  `db:"scopes" json:"scopes,omitempty"`
+	DeviceLabel string    `db:"device_label" json:"device_label"`
+	UserAgent   string    `db:"user_agent" json:"user_agent"`
+	IPAddress   string    `db:"ip_address" json:"ip_address"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	LastUsedAt  time.Time `db:"last_used_at" json:"last_used_at"`
+}
+
+// ClientInfo describes the client a session is opened or refreshed from.
+// OAuthClientID is set only when a third-party app acts through an OAuth
+// grant; refresh tokens work only for the client their session belongs to.
+type ClientInfo struct {
+	DeviceLabel   string
+	UserAgent     string
+	IPAddress     string
+	OAuthClientID string
+}
@@ -1,12 +1,40 @@
 package model
 
-import "time"
+import (
+	"time"
 
+	"github.com/yusufziyrek/bank-app/common/money"
+)
+
+const (
+	TransactionDeposit  = "deposit"
+	TransactionWithdraw = "withdraw"
+	TransactionTransfer = "transfer"
+)
+
+// Transaction is a customer-facing history row of an account. Amount is signed:
+// money entering the account is positive, money leaving it is negative.
 type Transaction struct {
-	ID          int64     `db:"id" json:"id"`
-	AccountID   int64     `db:"account_id" json:"account_id"`
-	Amount      float64   `db:"amount" json:"amount"`
-	Type        string    `db:"type" json:"type"`
-	Description string    `db:"description" json:"description,omitempty"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	ID             int64       `db:"id" json:"id"`
+	AccountID      int64       `db:"account_id" json:"account_id"`
+	Amount         money.Money `db:"amount" json:"amount"`
+	Type           string      `db:"type" json:"type"`
+	Description    string      `db:"description" json:"description,omitempty"`
+	JournalEntryID int64       `db:"journal_entry_id" json:"journal_entry_id"`
+	TransferID     *int64      `db:"transfer_id" json:"transfer_id,omitempty"`
+	CreatedAt      time.Time   `db:"created_at" json:"created_at"`
+}
+
+// Transfer moves money between two customer accounts. It is booked as a single
+// journal entry and shows up in each account's history as a linked transaction
+// carrying the transfer's ID.
+type Transfer struct {
+	ID             int64         `db:"id" json:"id"`
+	FromAccountID  int64         `db:"from_account_id" json:"from_account_id"`
+	ToAccountID    int64         `db:"to_account_id" json:"to_account_id"`
+	Amount         money.Money   `db:"amount" json:"amount"`
+	Description    string        `db:"description" json:"description,omitempty"`
+	JournalEntryID int64         `db:"journal_entry_id" json:"journal_entry_id"`
+	CreatedAt      time.Time     `db:"created_at" json:"created_at"`
+	Transactions   []Transaction `db:"-" json:"transactions"`
 }
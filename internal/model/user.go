@@ -2,13 +2,29 @@ package model
 
 import "time"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID           int64     `db:"id"            json:"id"`
-	FullName     string    `db:"full_name"     json:"full_name"`
-	Email        string    `db:"email"         json:"email"`
-	PasswordHash string    `db:"password_hash" json:"-"`
-	Role         string    `db:"role"          json:"role"`
-	IsActive     bool      `db:"is_active"     json:"is_active"`
-	CreatedAt    time.Time `db:"created_at"    json:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at"    json:"updated_at"`
+	ID              int64      `db:"id"                json:"id"`
+	FullName        string     `db:"full_name"         json:"full_name"`
+	Email           string     `db:"email"             json:"email"`
+	PasswordHash    string     `db:"password_hash"     json:"-"`
+	Role            string     `db:"role"              json:"role"`
+	IsActive        bool       `db:"is_active"         json:"is_active"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"`
+	TokenVersion    int64      `db:"token_version"     json:"-"`
+	CreatedAt       time.Time  `db:"created_at"        json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"        json:"updated_at"`
+	// ServiceAccount marks a user that only scripts act as, through API keys
+	// an admin creates for it. It has no password and cannot log in, so its
+	// keys do not depend on any person's credentials.
+	ServiceAccount bool `db:"is_service_account" json:"service_account"`
+}
+
+// EmailVerified reports whether the user has confirmed they own Email
+func (u User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
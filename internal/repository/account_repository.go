@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const (
+	queryGetAccountsByUserID = `
+        SELECT id, user_id, account_number, balance, currency, is_active, created_at, updated_at
+        FROM accounts WHERE user_id=$1 ORDER BY id
+    `
+	queryGetAccountByID = `
+        SELECT id, COALESCE(user_id, 0), account_number, balance, currency, is_active, created_at, updated_at
+        FROM accounts WHERE id=$1
+    `
+	queryGetAccountByIDForUpdate = `
+        SELECT id, COALESCE(user_id, 0), account_number, balance, currency, is_active, created_at, updated_at
+        FROM accounts WHERE id=$1
+        FOR UPDATE
+    `
+	queryEnsureSystemAccount = `
+        INSERT INTO accounts
+            (user_id, account_number, balance, currency, is_active, created_at, updated_at)
+        VALUES (NULL, $1, 0, $2, true, $3, $3)
+        ON CONFLICT (account_number) DO NOTHING
+    `
+	queryGetAccountByNumber = `
+        SELECT id, COALESCE(user_id, 0), account_number, balance, currency, is_active, created_at, updated_at
+        FROM accounts WHERE account_number=$1
+    `
+	queryAddAccount = `
+        INSERT INTO accounts
+            (user_id, account_number, balance, currency, is_active, created_at, updated_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7)
+        RETURNING id
+    `
+	queryCloseAccount = `
+        UPDATE accounts SET is_active=false, updated_at=$1
+        WHERE id=$2 AND is_active AND balance=0
+    `
+)
+
+type AccountRepository interface {
+	GetAccountsByUserID(ctx context.Context, userID int64) ([]model.Account, error)
+	GetAccountByID(ctx context.Context, id int64) (model.Account, error)
+	GetAccountByNumber(ctx context.Context, number string) (model.Account, error)
+	AddAccount(ctx context.Context, a *model.Account) error
+	CloseAccount(ctx context.Context, id int64) error
+
+	WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error
+	// GetAccountForUpdate reads the account and locks its row until tx ends
+	GetAccountForUpdate(ctx context.Context, tx pgx.Tx, id int64) (model.Account, error)
+	// EnsureSystemAccount returns the bank-owned account with the given number,
+	// creating it on first use. System accounts have no owning user.
+	EnsureSystemAccount(ctx context.Context, tx pgx.Tx, number, currency string) (model.Account, error)
+}
+
+type accountRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAccountRepository(pool *pgxpool.Pool) AccountRepository {
+	return &accountRepo{pool: pool}
+}
+
+func (r *accountRepo) WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error {
+	return withTransaction(ctx, r.pool, fn)
+}
+
+func (r *accountRepo) GetAccountsByUserID(ctx context.Context, userID int64) ([]model.Account, error) {
+	rows, err := r.pool.Query(ctx, queryGetAccountsByUserID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetAccountsByUserID:query: %w", err)
+	}
+	defer rows.Close()
+	accounts, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.Account])
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetAccountsByUserID:scan: %w", err)
+	}
+	for i := range accounts {
+		accounts[i].Balance = accounts[i].Balance.WithCurrency(accounts[i].Currency)
+	}
+	return accounts, nil
+}
+
+func (r *accountRepo) GetAccountByID(ctx context.Context, id int64) (model.Account, error) {
+	a, err := scanAccount(r.pool.QueryRow(ctx, queryGetAccountByID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return a, pgx.ErrNoRows
+	} else if err != nil {
+		return a, fmt.Errorf("repo:GetAccountByID: %w", err)
+	}
+	return a, nil
+}
+
+func (r *accountRepo) GetAccountByNumber(ctx context.Context, number string) (model.Account, error) {
+	a, err := scanAccount(r.pool.QueryRow(ctx, queryGetAccountByNumber, number))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return a, pgx.ErrNoRows
+	} else if err != nil {
+		return a, fmt.Errorf("repo:GetAccountByNumber: %w", err)
+	}
+	return a, nil
+}
+
+func (r *accountRepo) GetAccountForUpdate(ctx context.Context, tx pgx.Tx, id int64) (model.Account, error) {
+	a, err := scanAccount(tx.QueryRow(ctx, queryGetAccountByIDForUpdate, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return a, pgx.ErrNoRows
+	} else if err != nil {
+		return a, fmt.Errorf("repo:GetAccountForUpdate: %w", err)
+	}
+	return a, nil
+}
+
+func (r *accountRepo) EnsureSystemAccount(ctx context.Context, tx pgx.Tx, number, currency string) (model.Account, error) {
+	if _, err := tx.Exec(ctx, queryEnsureSystemAccount, number, currency, time.Now()); err != nil {
+		return model.Account{}, fmt.Errorf("repo:EnsureSystemAccount:insert: %w", err)
+	}
+	a, err := scanAccount(tx.QueryRow(ctx, queryGetAccountByNumber, number))
+	if err != nil {
+		return a, fmt.Errorf("repo:EnsureSystemAccount:select: %w", err)
+	}
+	return a, nil
+}
+
+func scanAccount(row pgx.Row) (model.Account, error) {
+	var a model.Account
+	err := row.Scan(
+		&a.ID,
+		&a.UserID,
+		&a.AccountNumber,
+		&a.Balance,
+		&a.Currency,
+		&a.IsActive,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	a.Balance = a.Balance.WithCurrency(a.Currency)
+	return a, err
+}
+
+func (r *accountRepo) AddAccount(ctx context.Context, a *model.Account) error {
+	now := time.Now()
+	a.CreatedAt = now
+	a.UpdatedAt = now
+
+	err := r.pool.QueryRow(ctx, queryAddAccount, a.UserID, a.AccountNumber, a.Balance, a.Currency, a.IsActive, a.CreatedAt, a.UpdatedAt).
+		Scan(&a.ID)
+	if err != nil {
+		return fmt.Errorf("repo:AddAccount: %w", err)
+	}
+	return nil
+}
+
+// CloseAccount deactivates an account; it only succeeds for an active account with a zero balance
+func (r *accountRepo) CloseAccount(ctx context.Context, id int64) error {
+	cmd, err := r.pool.Exec(ctx, queryCloseAccount, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("repo:CloseAccount: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const (
+	// Locking the user serializes key creation so the limit holds, and
+	// keeps the token version from moving until the key is stored
+	queryLockAPIKeyOwner = `
+        SELECT token_version FROM users WHERE id=$1 FOR UPDATE
+    `
+	queryDeleteStaleUserAPIKeys = `
+        DELETE FROM api_keys WHERE user_id=$1 AND (expires_at <= $2 OR token_version <> $3)
+    `
+	queryCountUserAPIKeys = `
+        SELECT COUNT(*) FROM api_keys WHERE user_id=$1
+    `
+	queryInsertAPIKey = `
+        INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, token_version, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id
+    `
+	queryGetAPIKeyByHash = `
+        SELECT id, user_id, name, prefix, key_hash, scopes, token_version, expires_at, created_at, last_used_at
+        FROM api_keys WHERE key_hash=$1 AND expires_at > $2
+    `
+	queryGetUserAPIKeys = `
+        SELECT k.id, k.user_id, k.name, k.prefix, k.key_hash, k.scopes, k.token_version, k.expires_at, k.created_at, k.last_used_at
+        FROM api_keys k JOIN users u ON u.id = k.user_id
+        WHERE k.user_id=$1 AND k.expires_at > $2 AND k.token_version = u.token_version
+        ORDER BY k.id
+    `
+	queryTouchAPIKey = `
+        UPDATE api_keys SET last_used_at=$2 WHERE id=$1
+    `
+	queryDeleteUserAPIKey = `
+        DELETE FROM api_keys WHERE id=$1 AND user_id=$2
+    `
+)
+
+type APIKeyRepository interface {
+	// InsertAPIKey stores a new key with the user's current token version
+	// unless the user already holds limit usable keys, in which case it
+	// returns false. Expired keys and keys of an older token version are
+	// dropped first.
+	InsertAPIKey(ctx context.Context, k *model.APIKey, limit int) (bool, error)
+	// GetAPIKeyByHash returns the unexpired key with the digest, or
+	// pgx.ErrNoRows. Its token version is not checked.
+	GetAPIKeyByHash(ctx context.Context, keyHash string, now time.Time) (model.APIKey, error)
+	// GetUserAPIKeys returns the user's unexpired keys of the current token
+	// version
+	GetUserAPIKeys(ctx context.Context, userID int64, now time.Time) ([]model.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int64, at time.Time) error
+	// DeleteUserAPIKey removes a key of the user; pgx.ErrNoRows means the
+	// user has no such key
+	DeleteUserAPIKey(ctx context.Context, userID, id int64) error
+}
+
+type apiKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(pool *pgxpool.Pool) APIKeyRepository {
+	return &apiKeyRepo{pool: pool}
+}
+
+func (r *apiKeyRepo) InsertAPIKey(ctx context.Context, k *model.APIKey, limit int) (bool, error) {
+	inserted := false
+	err := withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, queryLockAPIKeyOwner, k.UserID).Scan(&k.TokenVersion); err != nil {
+			return fmt.Errorf("repo:InsertAPIKey:lock: %w", err)
+		}
+		if _, err := tx.Exec(ctx, queryDeleteStaleUserAPIKeys, k.UserID, k.CreatedAt, k.TokenVersion); err != nil {
+			return fmt.Errorf("repo:InsertAPIKey:purge: %w", err)
+		}
+		var count int
+		if err := tx.QueryRow(ctx, queryCountUserAPIKeys, k.UserID).Scan(&count); err != nil {
+			return fmt.Errorf("repo:InsertAPIKey:count: %w", err)
+		}
+		if count >= limit {
+			return nil
+		}
+		err := tx.QueryRow(ctx, queryInsertAPIKey,
+			k.UserID, k.Name, k.Prefix, k.KeyHash, k.Scopes, k.TokenVersion, k.ExpiresAt, k.CreatedAt,
+		).Scan(&k.ID)
+		if err != nil {
+			return fmt.Errorf("repo:InsertAPIKey: %w", err)
+		}
+		inserted = true
+		return nil
+	})
+	return inserted, err
+}
+
+func (r *apiKeyRepo) GetAPIKeyByHash(ctx context.Context, keyHash string, now time.Time) (model.APIKey, error) {
+	rows, err := r.pool.Query(ctx, queryGetAPIKeyByHash, keyHash, now)
+	if err != nil {
+		return model.APIKey{}, fmt.Errorf("repo:GetAPIKeyByHash: %w", err)
+	}
+	k, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[model.APIKey])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return k, pgx.ErrNoRows
+	} else if err != nil {
+		return k, fmt.Errorf("repo:GetAPIKeyByHash: %w", err)
+	}
+	return k, nil
+}
+
+func (r *apiKeyRepo) GetUserAPIKeys(ctx context.Context, userID int64, now time.Time) ([]model.APIKey, error) {
+	rows, err := r.pool.Query(ctx, queryGetUserAPIKeys, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetUserAPIKeys: %w", err)
+	}
+	keys, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.APIKey])
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetUserAPIKeys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepo) TouchAPIKey(ctx context.Context, id int64, at time.Time) error {
+	if _, err := r.pool.Exec(ctx, queryTouchAPIKey, id, at); err != nil {
+		return fmt.Errorf("repo:TouchAPIKey: %w", err)
+	}
+	return nil
+}
+
+func (r *apiKeyRepo) DeleteUserAPIKey(ctx context.Context, userID, id int64) error {
+	cmd, err := r.pool.Exec(ctx, queryDeleteUserAPIKey, id, userID)
+	if err != nil {
+		return fmt.Errorf("repo:DeleteUserAPIKey: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
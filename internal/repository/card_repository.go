@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/common/keyring"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// card_number and cvv hold values sealed by the keyring; card_number_hash is
+// a keyed hash of the plain card number used for uniqueness and lookups
+const (
+	queryAddCard = `
+        INSERT INTO cards
+            (account_id, card_number, card_number_hash, expiry_date, cvv, is_active, created_at, updated_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+        RETURNING id
+    `
+	queryGetCardByID = `
+        SELECT id, account_id, card_number, cvv, is_active, replaced_by_id, created_at, updated_at, expiry_date
+        FROM cards WHERE id=$1
+    `
+	queryGetCardsByAccountID = `
+        SELECT id, account_id, card_number, cvv, is_active, replaced_by_id, created_at, updated_at, expiry_date
+        FROM cards WHERE account_id=$1 ORDER BY id
+    `
+	queryGetCardByNumberHash = `
+        SELECT id, account_id, card_number, cvv, is_active, replaced_by_id, created_at, updated_at, expiry_date
+        FROM cards WHERE card_number_hash=$1
+    `
+	querySetCardActive = `
+        UPDATE cards SET is_active=$1, updated_at=$2
+        WHERE id=$3 AND replaced_by_id IS NULL
+    `
+	queryRetireCard = `
+        UPDATE cards SET is_active=false, replaced_by_id=$1, updated_at=$2
+        WHERE id=$3 AND replaced_by_id IS NULL
+    `
+	// Rows written before encryption have no card_number_hash yet
+	queryGetPlaintextCards = `
+        SELECT id, account_id, card_number, cvv FROM cards
+        WHERE card_number_hash IS NULL
+        ORDER BY id
+        FOR UPDATE
+    `
+	querySealCard = `
+        UPDATE cards SET card_number=$1, card_number_hash=$2, cvv=$3
+        WHERE id=$4
+    `
+	queryRequireCardNumberHash = `
+        ALTER TABLE cards ALTER COLUMN card_number_hash SET NOT NULL
+    `
+)
+
+type CardRepository interface {
+	AddCard(ctx context.Context, c *model.Card) error
+	GetCardByID(ctx context.Context, id int64) (model.Card, error)
+	GetCardsByAccountID(ctx context.Context, accountID int64) ([]model.Card, error)
+	GetCardByNumber(ctx context.Context, cardNumber string) (model.Card, error)
+	// SetCardActive freezes or unfreezes a card; replaced cards are left untouched
+	// and reported as pgx.ErrNoRows
+	SetCardActive(ctx context.Context, id int64, active bool) error
+	// ReplaceCard inserts the replacement and retires the old card in one
+	// transaction; it returns pgx.ErrNoRows if the old card was already replaced
+	ReplaceCard(ctx context.Context, oldID int64, replacement *model.Card) error
+}
+
+// cardRepo encrypts card numbers and CVVs before they reach the database and
+// decrypts them on the way out, so callers only ever see plain values
+type cardRepo struct {
+	pool *pgxpool.Pool
+	keys *keyring.Keyring
+}
+
+func NewCardRepository(pool *pgxpool.Pool, keys *keyring.Keyring) CardRepository {
+	return &cardRepo{pool: pool, keys: keys}
+}
+
+func (r *cardRepo) AddCard(ctx context.Context, c *model.Card) error {
+	return r.addCard(ctx, r.pool, c)
+}
+
+// cardInserter is satisfied by both the pool and a transaction
+type cardInserter interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (r *cardRepo) addCard(ctx context.Context, q cardInserter, c *model.Card) error {
+	sealedNumber, err := r.keys.Seal(c.CardNumber, cardNumberAAD(c.AccountID))
+	if err != nil {
+		return fmt.Errorf("repo:AddCard:seal: %w", err)
+	}
+	sealedCVV, err := r.keys.Seal(c.CVV, cvvAAD(c.AccountID))
+	if err != nil {
+		return fmt.Errorf("repo:AddCard:seal: %w", err)
+	}
+
+	now := time.Now()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+
+	err = q.QueryRow(ctx, queryAddCard, c.AccountID, sealedNumber, r.keys.Hash(c.CardNumber), c.ExpiryDate, sealedCVV, c.IsActive, c.CreatedAt, c.UpdatedAt).
+		Scan(&c.ID)
+	if err != nil {
+		return fmt.Errorf("repo:AddCard: %w", err)
+	}
+	return nil
+}
+
+func (r *cardRepo) GetCardByID(ctx context.Context, id int64) (model.Card, error) {
+	rows, err := r.pool.Query(ctx, queryGetCardByID, id)
+	if err != nil {
+		return model.Card{}, fmt.Errorf("repo:GetCardByID:query: %w", err)
+	}
+	defer rows.Close()
+	c, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[model.Card])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, pgx.ErrNoRows
+	} else if err != nil {
+		return c, fmt.Errorf("repo:GetCardByID:scan: %w", err)
+	}
+	if err := r.openCard(&c); err != nil {
+		return model.Card{}, fmt.Errorf("repo:GetCardByID: %w", err)
+	}
+	return c, nil
+}
+
+func (r *cardRepo) GetCardByNumber(ctx context.Context, cardNumber string) (model.Card, error) {
+	rows, err := r.pool.Query(ctx, queryGetCardByNumberHash, r.keys.Hash(cardNumber))
+	if err != nil {
+		return model.Card{}, fmt.Errorf("repo:GetCardByNumber:query: %w", err)
+	}
+	defer rows.Close()
+	c, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[model.Card])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, pgx.ErrNoRows
+	} else if err != nil {
+		return c, fmt.Errorf("repo:GetCardByNumber:scan: %w", err)
+	}
+	if err := r.openCard(&c); err != nil {
+		return model.Card{}, fmt.Errorf("repo:GetCardByNumber: %w", err)
+	}
+	return c, nil
+}
+
+func (r *cardRepo) GetCardsByAccountID(ctx context.Context, accountID int64) ([]model.Card, error) {
+	rows, err := r.pool.Query(ctx, queryGetCardsByAccountID, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetCardsByAccountID:query: %w", err)
+	}
+	defer rows.Close()
+	cards, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.Card])
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetCardsByAccountID:scan: %w", err)
+	}
+	for i := range cards {
+		if err := r.openCard(&cards[i]); err != nil {
+			return nil, fmt.Errorf("repo:GetCardsByAccountID: %w", err)
+		}
+	}
+	return cards, nil
+}
+
+func (r *cardRepo) SetCardActive(ctx context.Context, id int64, active bool) error {
+	cmd, err := r.pool.Exec(ctx, querySetCardActive, active, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("repo:SetCardActive: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *cardRepo) ReplaceCard(ctx context.Context, oldID int64, replacement *model.Card) error {
+	return withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := r.addCard(ctx, tx, replacement); err != nil {
+			return err
+		}
+		cmd, err := tx.Exec(ctx, queryRetireCard, replacement.ID, replacement.CreatedAt, oldID)
+		if err != nil {
+			return fmt.Errorf("repo:ReplaceCard: %w", err)
+		}
+		if cmd.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// SealPlaintextCards encrypts the card numbers and CVVs of cards stored
+// before encryption, fills in their lookup hash and then makes the hash
+// column required, all in one transaction. It returns how many cards were
+// sealed; running it again after it succeeded seals nothing.
+func SealPlaintextCards(ctx context.Context, pool *pgxpool.Pool, keys *keyring.Keyring) (int64, error) {
+	var sealed int64
+	err := withTransaction(ctx, pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, queryGetPlaintextCards)
+		if err != nil {
+			return fmt.Errorf("repo:SealPlaintextCards:query: %w", err)
+		}
+		type plainCard struct {
+			id, accountID   int64
+			cardNumber, cvv string
+		}
+		var cards []plainCard
+		for rows.Next() {
+			var c plainCard
+			if err := rows.Scan(&c.id, &c.accountID, &c.cardNumber, &c.cvv); err != nil {
+				rows.Close()
+				return fmt.Errorf("repo:SealPlaintextCards:scan: %w", err)
+			}
+			cards = append(cards, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("repo:SealPlaintextCards:rows: %w", err)
+		}
+
+		for _, c := range cards {
+			number, err := keys.Seal(c.cardNumber, cardNumberAAD(c.accountID))
+			if err != nil {
+				return fmt.Errorf("repo:SealPlaintextCards:seal card %d: %w", c.id, err)
+			}
+			cvv, err := keys.Seal(c.cvv, cvvAAD(c.accountID))
+			if err != nil {
+				return fmt.Errorf("repo:SealPlaintextCards:seal card %d: %w", c.id, err)
+			}
+			if _, err := tx.Exec(ctx, querySealCard, number, keys.Hash(c.cardNumber), cvv, c.id); err != nil {
+				return fmt.Errorf("repo:SealPlaintextCards:update card %d: %w", c.id, err)
+			}
+			sealed++
+		}
+		if _, err := tx.Exec(ctx, queryRequireCardNumberHash); err != nil {
+			return fmt.Errorf("repo:SealPlaintextCards:require hash: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return sealed, nil
+}
+
+// openCard replaces the sealed card number and CVV read from the database with their plain values
+func (r *cardRepo) openCard(c *model.Card) error {
+	number, err := r.keys.Open(c.CardNumber, cardNumberAAD(c.AccountID))
+	if err != nil {
+		return fmt.Errorf("open card number: %w", err)
+	}
+	cvv, err := r.keys.Open(c.CVV, cvvAAD(c.AccountID))
+	if err != nil {
+		return fmt.Errorf("open cvv: %w", err)
+	}
+	c.CardNumber = number
+	c.CVV = cvv
+	return nil
+}
+
+// The additional data binds each ciphertext to its column and account, so a
+// sealed value copied into another row fails to decrypt
+func cardNumberAAD(accountID int64) string {
+	return fmt.Sprintf("cards.card_number:%d", accountID)
+}
+
+func cvvAAD(accountID int64) string {
+	return fmt.Sprintf("cards.cvv:%d", accountID)
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const (
+	queryDeleteUserEmailVerifications = `
+        DELETE FROM email_verifications WHERE user_id=$1 AND purpose=$2
+    `
+	queryInsertEmailVerification = `
+        INSERT INTO email_verifications (user_id, email, purpose, token_hash, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `
+	queryClaimEmailVerification = `
+        DELETE FROM email_verifications
+        WHERE token_hash=$1 AND expires_at > $2
+        RETURNING user_id, email, purpose
+    `
+	queryConfirmUserEmail = `
+        UPDATE users SET email=$2, email_verified_at=$3, updated_at=$3 WHERE id=$1
+    `
+	// A link for the current address is void once the address was changed
+	queryVerifyUserEmail = `
+        UPDATE users SET email_verified_at=$3, updated_at=$3 WHERE id=$1 AND email=$2
+    `
+)
+
+type EmailVerificationRepository interface {
+	// InsertEmailVerification replaces any earlier verification of the user
+	// with the same purpose, so only the most recent link of each kind works
+	InsertEmailVerification(ctx context.Context, v *model.EmailVerification) error
+	// ConfirmEmail consumes an unexpired token and makes its address the
+	// user's verified email in one transaction. It returns the user's ID, or
+	// pgx.ErrNoRows when the token cannot be used. A unique violation means the
+	// address was taken by another user in the meantime.
+	ConfirmEmail(ctx context.Context, tokenHash string, now time.Time) (int64, error)
+}
+
+type emailVerificationRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewEmailVerificationRepository(pool *pgxpool.Pool) EmailVerificationRepository {
+	return &emailVerificationRepo{pool: pool}
+}
+
+func (r *emailVerificationRepo) InsertEmailVerification(ctx context.Context, v *model.EmailVerification) error {
+	return withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queryDeleteUserEmailVerifications, v.UserID, v.Purpose); err != nil {
+			return fmt.Errorf("repo:InsertEmailVerification:purge: %w", err)
+		}
+		err := tx.QueryRow(ctx, queryInsertEmailVerification, v.UserID, v.Email, v.Purpose, v.TokenHash, v.ExpiresAt, v.CreatedAt).Scan(&v.ID)
+		if err != nil {
+			return fmt.Errorf("repo:InsertEmailVerification: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *emailVerificationRepo) ConfirmEmail(ctx context.Context, tokenHash string, now time.Time) (int64, error) {
+	var (
+		userID  int64
+		email   string
+		purpose string
+	)
+	err := withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, queryClaimEmailVerification, tokenHash, now).Scan(&userID, &email, &purpose); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return pgx.ErrNoRows
+			}
+			return fmt.Errorf("repo:ConfirmEmail:claim: %w", err)
+		}
+		query := queryConfirmUserEmail
+		if purpose == model.EmailVerificationVerify {
+			query = queryVerifyUserEmail
+		}
+		cmd, err := tx.Exec(ctx, query, userID, email, now)
+		if err != nil {
+			return fmt.Errorf("repo:ConfirmEmail:update: %w", err)
+		}
+		if cmd.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
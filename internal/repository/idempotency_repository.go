@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const (
+	// An expired key, or a reservation whose request never finished, is taken
+	// over by the new request instead of conflicting with it
+	queryReserveIdempotencyKey = `
+        INSERT INTO idempotency_keys (user_id, key, request_hash, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (user_id, key) DO UPDATE
+            SET request_hash = EXCLUDED.request_hash,
+                response_status = NULL,
+                response_body = NULL,
+                created_at = EXCLUDED.created_at,
+                expires_at = EXCLUDED.expires_at
+            WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+               OR (idempotency_keys.response_status IS NULL AND idempotency_keys.created_at <= $6)
+        RETURNING id
+    `
+	queryGetIdempotencyRecord = `
+        SELECT id, user_id, key, request_hash, COALESCE(response_status, 0), response_body, created_at, expires_at
+        FROM idempotency_keys WHERE user_id=$1 AND key=$2
+    `
+	querySaveIdempotencyResponse = `
+        UPDATE idempotency_keys SET response_status=$1, response_body=$2
+        WHERE user_id=$3 AND key=$4
+    `
+	queryDeleteIdempotencyRecord = `
+        DELETE FROM idempotency_keys WHERE user_id=$1 AND key=$2
+    `
+	queryDeleteExpiredIdempotencyKeys = `
+        DELETE FROM idempotency_keys WHERE expires_at <= $1
+    `
+)
+
+type IdempotencyRepository interface {
+	// ReserveKey inserts the record unless an unexpired record with the same
+	// user and key exists; it reports whether the key was reserved. A record
+	// without a response created before staleBefore counts as abandoned.
+	ReserveKey(ctx context.Context, r *model.IdempotencyRecord, staleBefore time.Time) (bool, error)
+	GetRecord(ctx context.Context, userID int64, key string) (model.IdempotencyRecord, error)
+	SaveResponse(ctx context.Context, userID int64, key string, status int, body []byte) error
+	DeleteRecord(ctx context.Context, userID int64, key string) error
+	// DeleteExpiredIdempotencyKeys removes records expired at now and returns
+	// how many were deleted
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+type idempotencyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(pool *pgxpool.Pool) IdempotencyRepository {
+	return &idempotencyRepo{pool: pool}
+}
+
+func (r *idempotencyRepo) ReserveKey(ctx context.Context, rec *model.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	err := r.pool.QueryRow(ctx, queryReserveIdempotencyKey, rec.UserID, rec.Key, rec.RequestHash, rec.CreatedAt, rec.ExpiresAt, staleBefore).
+		Scan(&rec.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("repo:ReserveKey: %w", err)
+	}
+	return true, nil
+}
+
+func (r *idempotencyRepo) GetRecord(ctx context.Context, userID int64, key string) (model.IdempotencyRecord, error) {
+	var rec model.IdempotencyRecord
+	err := r.pool.QueryRow(ctx, queryGetIdempotencyRecord, userID, key).Scan(
+		&rec.ID,
+		&rec.UserID,
+		&rec.Key,
+		&rec.RequestHash,
+		&rec.ResponseStatus,
+		&rec.ResponseBody,
+		&rec.CreatedAt,
+		&rec.ExpiresAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return rec, pgx.ErrNoRows
+	} else if err != nil {
+		return rec, fmt.Errorf("repo:GetRecord: %w", err)
+	}
+	return rec, nil
+}
+
+func (r *idempotencyRepo) SaveResponse(ctx context.Context, userID int64, key string, status int, body []byte) error {
+	cmd, err := r.pool.Exec(ctx, querySaveIdempotencyResponse, status, body, userID, key)
+	if err != nil {
+		return fmt.Errorf("repo:SaveResponse: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *idempotencyRepo) DeleteRecord(ctx context.Context, userID int64, key string) error {
+	if _, err := r.pool.Exec(ctx, queryDeleteIdempotencyRecord, userID, key); err != nil {
+		return fmt.Errorf("repo:DeleteRecord: %w", err)
+	}
+	return nil
+}
+
+func (r *idempotencyRepo) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	cmd, err := r.pool.Exec(ctx, queryDeleteExpiredIdempotencyKeys, now)
+	if err != nil {
+		return 0, fmt.Errorf("repo:DeleteExpiredIdempotencyKeys: %w", err)
+	}
+	return cmd.RowsAffected(), nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const (
+	queryInsertJournalEntry = `
+        INSERT INTO journal_entries (description, created_at)
+        VALUES ($1, $2)
+        RETURNING id
+    `
+	queryInsertPosting = `
+        INSERT INTO postings (journal_entry_id, account_id, direction, amount, currency, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `
+	queryApplyPostingToBalance = `
+        UPDATE accounts SET balance = balance + $1, updated_at=$2 WHERE id=$3 AND currency=$4
+    `
+	queryGetPostingsByAccountID = `
+        SELECT id, journal_entry_id, account_id, direction, amount, currency, created_at
+        FROM postings WHERE account_id=$1 ORDER BY id
+    `
+	queryGetUnbalancedEntries = `
+        SELECT e.id
+        FROM journal_entries e
+        LEFT JOIN postings p ON p.journal_entry_id = e.id
+        GROUP BY e.id
+        HAVING COALESCE(SUM(CASE p.direction WHEN 'debit' THEN p.amount ELSE -p.amount END), 0) <> 0
+            OR COUNT(p.id) < 2
+        ORDER BY e.id
+    `
+	queryGetBalanceMismatches = `
+        SELECT a.id AS account_id, a.currency, a.balance,
+               COALESCE(SUM(CASE p.direction WHEN 'credit' THEN p.amount ELSE -p.amount END), 0) AS derived_balance
+        FROM accounts a
+        LEFT JOIN postings p ON p.account_id = a.id
+        GROUP BY a.id, a.currency, a.balance
+        HAVING a.balance <> COALESCE(SUM(CASE p.direction WHEN 'credit' THEN p.amount ELSE -p.amount END), 0)
+        ORDER BY a.id
+    `
+)
+
+type LedgerRepository interface {
+	WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error
+	// InsertJournalEntry stores the entry with its postings and applies every
+	// posting to the materialized account balance using the given transaction
+	InsertJournalEntry(ctx context.Context, tx pgx.Tx, e *model.JournalEntry) error
+	GetPostingsByAccountID(ctx context.Context, accountID int64) ([]model.Posting, error)
+	GetUnbalancedEntries(ctx context.Context) ([]int64, error)
+	GetBalanceMismatches(ctx context.Context) ([]model.BalanceMismatch, error)
+}
+
+type ledgerRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewLedgerRepository(pool *pgxpool.Pool) LedgerRepository {
+	return &ledgerRepo{pool: pool}
+}
+
+func (r *ledgerRepo) WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error {
+	return withTransaction(ctx, r.pool, fn)
+}
+
+func (r *ledgerRepo) InsertJournalEntry(ctx context.Context, tx pgx.Tx, e *model.JournalEntry) error {
+	now := time.Now()
+	e.CreatedAt = now
+
+	if err := tx.QueryRow(ctx, queryInsertJournalEntry, e.Description, e.CreatedAt).Scan(&e.ID); err != nil {
+		return fmt.Errorf("repo:InsertJournalEntry: %w", err)
+	}
+
+	for i := range e.Postings {
+		p := &e.Postings[i]
+		p.JournalEntryID = e.ID
+		p.CreatedAt = now
+		err := tx.QueryRow(ctx, queryInsertPosting, p.JournalEntryID, p.AccountID, p.Direction, p.Amount, p.Currency, p.CreatedAt).
+			Scan(&p.ID)
+		if err != nil {
+			return fmt.Errorf("repo:InsertPosting: %w", err)
+		}
+
+		delta := p.Amount
+		if p.Direction == model.PostingDebit {
+			delta = delta.Neg()
+		}
+		// A posting in a different currency than the account matches no row
+		cmd, err := tx.Exec(ctx, queryApplyPostingToBalance, delta, now, p.AccountID, p.Currency)
+		if err != nil {
+			return fmt.Errorf("repo:ApplyPosting: %w", err)
+		}
+		if cmd.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+	}
+	return nil
+}
+
+func (r *ledgerRepo) GetPostingsByAccountID(ctx context.Context, accountID int64) ([]model.Posting, error) {
+	rows, err := r.pool.Query(ctx, queryGetPostingsByAccountID, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetPostingsByAccountID:query: %w", err)
+	}
+	defer rows.Close()
+	postings, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.Posting])
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetPostingsByAccountID:scan: %w", err)
+	}
+	for i := range postings {
+		postings[i].Amount = postings[i].Amount.WithCurrency(postings[i].Currency)
+	}
+	return postings, nil
+}
+
+func (r *ledgerRepo) GetUnbalancedEntries(ctx context.Context) ([]int64, error) {
+	rows, err := r.pool.Query(ctx, queryGetUnbalancedEntries)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetUnbalancedEntries:query: %w", err)
+	}
+	defer rows.Close()
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetUnbalancedEntries:scan: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *ledgerRepo) GetBalanceMismatches(ctx context.Context) ([]model.BalanceMismatch, error) {
+	rows, err := r.pool.Query(ctx, queryGetBalanceMismatches)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetBalanceMismatches:query: %w", err)
+	}
+	defer rows.Close()
+	mismatches, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.BalanceMismatch])
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetBalanceMismatches:scan: %w", err)
+	}
+	for i := range mismatches {
+		m := &mismatches[i]
+		m.Balance = m.Balance.WithCurrency(m.Currency)
+		m.DerivedBalance = m.DerivedBalance.WithCurrency(m.Currency)
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const (
+	queryGetLoginFailures = `
+        SELECT scope, key, failures, last_failure_at, locked_until
+        FROM login_failures
+        WHERE (scope=$1 AND key=$2) OR (scope=$3 AND key=$4)
+    `
+	// A failure after the window has passed starts a new count
+	queryRecordLoginFailure = `
+        INSERT INTO login_failures (scope, key, failures, last_failure_at)
+        VALUES ($1, $2, 1, $3)
+        ON CONFLICT (scope, key) DO UPDATE SET
+            failures = CASE WHEN login_failures.last_failure_at <= $4 THEN 1 ELSE login_failures.failures + 1 END,
+            last_failure_at = EXCLUDED.last_failure_at
+        RETURNING scope, key, failures, last_failure_at, locked_until
+    `
+	queryLockLogin = `
+        UPDATE login_failures SET locked_until=$3, failures=0 WHERE scope=$1 AND key=$2
+    `
+	queryDeleteLoginFailures = `
+        DELETE FROM login_failures WHERE scope=$1 AND key=$2
+    `
+	queryDeleteStaleLoginFailures = `
+        DELETE FROM login_failures
+        WHERE last_failure_at <= $1 AND (locked_until IS NULL OR locked_until <= $2)
+    `
+)
+
+type LoginAttemptRepository interface {
+	// GetLoginFailures returns the counters of the account and the IP; either
+	// may be missing from the result
+	GetLoginFailures(ctx context.Context, accountKey, ip string) ([]model.LoginFailure, error)
+	// RecordLoginFailure adds a failure to the counter and returns it. A
+	// counter whose last failure is at or before windowStart restarts at 1.
+	RecordLoginFailure(ctx context.Context, scope, key string, now, windowStart time.Time) (model.LoginFailure, error)
+	// LockLogin blocks the key until the given time and clears its count
+	LockLogin(ctx context.Context, scope, key string, until time.Time) error
+	DeleteLoginFailures(ctx context.Context, scope, key string) (int64, error)
+	// DeleteStaleLoginFailures removes counters without a failure since
+	// before and no lock in force at now
+	DeleteStaleLoginFailures(ctx context.Context, before, now time.Time) (int64, error)
+}
+
+type loginAttemptRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewLoginAttemptRepository(pool *pgxpool.Pool) LoginAttemptRepository {
+	return &loginAttemptRepo{pool: pool}
+}
+
+func (r *loginAttemptRepo) GetLoginFailures(ctx context.Context, accountKey, ip string) ([]model.LoginFailure, error) {
+	rows, err := r.pool.Query(ctx, queryGetLoginFailures, model.LoginScopeAccount, accountKey, model.LoginScopeIP, ip)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetLoginFailures: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.LoginFailure
+	for rows.Next() {
+		var f model.LoginFailure
+		if err := rows.Scan(&f.Scope, &f.Key, &f.Failures, &f.LastFailureAt, &f.LockedUntil); err != nil {
+			return nil, fmt.Errorf("repo:GetLoginFailures:scan: %w", err)
+		}
+		out = append(out, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo:GetLoginFailures:rows: %w", err)
+	}
+	return out, nil
+}
+
+func (r *loginAttemptRepo) RecordLoginFailure(ctx context.Context, scope, key string, now, windowStart time.Time) (model.LoginFailure, error) {
+	var f model.LoginFailure
+	err := r.pool.QueryRow(ctx, queryRecordLoginFailure, scope, key, now, windowStart).
+		Scan(&f.Scope, &f.Key, &f.Failures, &f.LastFailureAt, &f.LockedUntil)
+	if err != nil {
+		return model.LoginFailure{}, fmt.Errorf("repo:RecordLoginFailure: %w", err)
+	}
+	return f, nil
+}
+
+func (r *loginAttemptRepo) LockLogin(ctx context.Context, scope, key string, until time.Time) error {
+	cmd, err := r.pool.Exec(ctx, queryLockLogin, scope, key, until)
+	if err != nil {
+		return fmt.Errorf("repo:LockLogin: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *loginAttemptRepo) DeleteLoginFailures(ctx context.Context, scope, key string) (int64, error) {
+	cmd, err := r.pool.Exec(ctx, queryDeleteLoginFailures, scope, key)
+	if err != nil {
+		return 0, fmt.Errorf("repo:DeleteLoginFailures: %w", err)
+	}
+	return cmd.RowsAffected(), nil
+}
+
+func (r *loginAttemptRepo) DeleteStaleLoginFailures(ctx context.Context, before, now time.Time) (int64, error) {
+	cmd, err := r.pool.Exec(ctx, queryDeleteStaleLoginFailures, before, now)
+	if err != nil {
+		return 0, fmt.Errorf("repo:DeleteStaleLoginFailures: %w", err)
+	}
+	return cmd.RowsAffected(), nil
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/common/keyring"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// user_totp.secret holds a value sealed by the keyring; recovery codes and
+// challenge tokens are stored as SHA-256 digests only
+const (
+	// An enrollment can be restarted until it is confirmed
+	queryUpsertPendingTOTP = `
+        INSERT INTO user_totp (user_id, secret, enabled, last_used_step, created_at, updated_at)
+        VALUES ($1, $2, false, 0, $3, $3)
+        ON CONFLICT (user_id) DO UPDATE
+            SET secret = EXCLUDED.secret, last_used_step = 0, updated_at = EXCLUDED.updated_at
+            WHERE user_totp.enabled = false
+    `
+	queryGetTOTP = `
+        SELECT user_id, secret, enabled, last_used_step, failed_attempts, last_failure_at, confirmed_at, created_at, updated_at
+        FROM user_totp WHERE user_id=$1
+    `
+	queryEnableTOTP = `
+        UPDATE user_totp SET enabled=true, last_used_step=$2, confirmed_at=$3, updated_at=$3
+        WHERE user_id=$1 AND enabled=false
+    `
+	// Accepting each time step at most once stops a code from being replayed
+	queryAdvanceTOTPStep = `
+        UPDATE user_totp SET last_used_step=$2, updated_at=$3
+        WHERE user_id=$1 AND last_used_step < $2
+    `
+	// Failures older than the window start ($3) no longer count. The row is
+	// only updated while fewer than $4 recent failures are recorded, so
+	// concurrent guesses cannot go past the limit.
+	queryReserveTOTPAttempt = `
+        UPDATE user_totp
+        SET failed_attempts = CASE WHEN last_failure_at IS NULL OR last_failure_at <= $3 THEN 1 ELSE failed_attempts + 1 END,
+            last_failure_at = $2
+        WHERE user_id=$1 AND (last_failure_at IS NULL OR last_failure_at <= $3 OR failed_attempts < $4)
+    `
+	queryResetTOTPFailures = `
+        UPDATE user_totp SET failed_attempts=0, last_failure_at=NULL WHERE user_id=$1
+    `
+	queryDeleteTOTP = `
+        DELETE FROM user_totp WHERE user_id=$1
+    `
+	queryDeleteRecoveryCodes = `
+        DELETE FROM mfa_recovery_codes WHERE user_id=$1
+    `
+	queryInsertRecoveryCode = `
+        INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)
+    `
+	queryUseRecoveryCode = `
+        UPDATE mfa_recovery_codes SET used_at=$3
+        WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL
+    `
+	queryDeleteExpiredMFAChallenges = `
+        DELETE FROM mfa_challenges WHERE user_id=$1 AND expires_at <= $2
+    `
+	queryInsertMFAChallenge = `
+        INSERT INTO mfa_challenges (user_id, token_hash, attempts, expires_at, created_at)
+        VALUES ($1, $2, 0, $3, $4)
+        RETURNING id
+    `
+	queryGetMFAChallenge = `
+        SELECT id, user_id, token_hash, attempts, expires_at, created_at
+        FROM mfa_challenges WHERE token_hash=$1
+    `
+	queryIncrementMFAChallengeAttempts = `
+        UPDATE mfa_challenges SET attempts = attempts + 1 WHERE token_hash=$1
+        RETURNING attempts
+    `
+	queryDeleteMFAChallenge = `
+        DELETE FROM mfa_challenges WHERE token_hash=$1
+    `
+)
+
+type MFARepository interface {
+	// UpsertPendingTOTP stores a new, not yet confirmed secret; it returns
+	// pgx.ErrNoRows if the user already has TOTP enabled
+	UpsertPendingTOTP(ctx context.Context, userID int64, secret string) error
+	GetTOTP(ctx context.Context, userID int64) (model.UserTOTP, error)
+	// EnableTOTP confirms the enrollment and replaces the user's recovery codes
+	// in one transaction
+	EnableTOTP(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error
+	// AdvanceTOTPStep records step as used; pgx.ErrNoRows means it was not newer
+	// than the last accepted step
+	AdvanceTOTPStep(ctx context.Context, userID, step int64) error
+	// ReserveTOTPAttempt counts a code attempt at time at as a failure until
+	// ResetTOTPFailures clears it, starting over when the last failure is not
+	// after windowStart. pgx.ErrNoRows means max failures are already
+	// recorded in the window.
+	ReserveTOTPAttempt(ctx context.Context, userID int64, at, windowStart time.Time, max int) error
+	ResetTOTPFailures(ctx context.Context, userID int64) error
+	DeleteTOTP(ctx context.Context, userID int64) error
+	// UseRecoveryCode marks an unused code as used; pgx.ErrNoRows means there
+	// is no such unused code
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+
+	// InsertMFAChallenge also drops the user's expired challenges, so abandoned
+	// logins do not pile up
+	InsertMFAChallenge(ctx context.Context, ch *model.MFAChallenge) error
+	GetMFAChallenge(ctx context.Context, tokenHash string) (model.MFAChallenge, error)
+	IncrementMFAChallengeAttempts(ctx context.Context, tokenHash string) (int, error)
+	DeleteMFAChallenge(ctx context.Context, tokenHash string) error
+}
+
+type mfaRepo struct {
+	pool *pgxpool.Pool
+	keys *keyring.Keyring
+}
+
+func NewMFARepository(pool *pgxpool.Pool, keys *keyring.Keyring) MFARepository {
+	return &mfaRepo{pool: pool, keys: keys}
+}
+
+func (r *mfaRepo) UpsertPendingTOTP(ctx context.Context, userID int64, secret string) error {
+	sealed, err := r.keys.Seal(secret, totpSecretAAD(userID))
+	if err != nil {
+		return fmt.Errorf("repo:UpsertPendingTOTP:seal: %w", err)
+	}
+	cmd, err := r.pool.Exec(ctx, queryUpsertPendingTOTP, userID, sealed, time.Now())
+	if err != nil {
+		return fmt.Errorf("repo:UpsertPendingTOTP: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *mfaRepo) GetTOTP(ctx context.Context, userID int64) (model.UserTOTP, error) {
+	rows, err := r.pool.Query(ctx, queryGetTOTP, userID)
+	if err != nil {
+		return model.UserTOTP{}, fmt.Errorf("repo:GetTOTP:query: %w", err)
+	}
+	defer rows.Close()
+	t, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[model.UserTOTP])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, pgx.ErrNoRows
+	} else if err != nil {
+		return t, fmt.Errorf("repo:GetTOTP:scan: %w", err)
+	}
+	secret, err := r.keys.Open(t.Secret, totpSecretAAD(userID))
+	if err != nil {
+		return model.UserTOTP{}, fmt.Errorf("repo:GetTOTP:open: %w", err)
+	}
+	t.Secret = secret
+	return t, nil
+}
+
+func (r *mfaRepo) EnableTOTP(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error {
+	now := time.Now()
+	return withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		cmd, err := tx.Exec(ctx, queryEnableTOTP, userID, step, now)
+		if err != nil {
+			return fmt.Errorf("repo:EnableTOTP: %w", err)
+		}
+		if cmd.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if _, err := tx.Exec(ctx, queryDeleteRecoveryCodes, userID); err != nil {
+			return fmt.Errorf("repo:EnableTOTP:deleteCodes: %w", err)
+		}
+		for _, h := range recoveryCodeHashes {
+			if _, err := tx.Exec(ctx, queryInsertRecoveryCode, userID, h, now); err != nil {
+				return fmt.Errorf("repo:EnableTOTP:insertCode: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *mfaRepo) AdvanceTOTPStep(ctx context.Context, userID, step int64) error {
+	cmd, err := r.pool.Exec(ctx, queryAdvanceTOTPStep, userID, step, time.Now())
+	if err != nil {
+		return fmt.Errorf("repo:AdvanceTOTPStep: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *mfaRepo) ReserveTOTPAttempt(ctx context.Context, userID int64, at, windowStart time.Time, max int) error {
+	cmd, err := r.pool.Exec(ctx, queryReserveTOTPAttempt, userID, at, windowStart, max)
+	if err != nil {
+		return fmt.Errorf("repo:ReserveTOTPAttempt: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *mfaRepo) ResetTOTPFailures(ctx context.Context, userID int64) error {
+	if _, err := r.pool.Exec(ctx, queryResetTOTPFailures, userID); err != nil {
+		return fmt.Errorf("repo:ResetTOTPFailures: %w", err)
+	}
+	return nil
+}
+
+func (r *mfaRepo) DeleteTOTP(ctx context.Context, userID int64) error {
+	return withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queryDeleteRecoveryCodes, userID); err != nil {
+			return fmt.Errorf("repo:DeleteTOTP:deleteCodes: %w", err)
+		}
+		if _, err := tx.Exec(ctx, queryDeleteTOTP, userID); err != nil {
+			return fmt.Errorf("repo:DeleteTOTP: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *mfaRepo) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	cmd, err := r.pool.Exec(ctx, queryUseRecoveryCode, userID, codeHash, time.Now())
+	if err != nil {
+		return fmt.Errorf("repo:UseRecoveryCode: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *mfaRepo) InsertMFAChallenge(ctx context.Context, ch *model.MFAChallenge) error {
+	if _, err := r.pool.Exec(ctx, queryDeleteExpiredMFAChallenges, ch.UserID, ch.CreatedAt); err != nil {
+		return fmt.Errorf("repo:InsertMFAChallenge:purge: %w", err)
+	}
+	err := r.pool.QueryRow(ctx, queryInsertMFAChallenge, ch.UserID, ch.TokenHash, ch.ExpiresAt, ch.CreatedAt).Scan(&ch.ID)
+	if err != nil {
+		return fmt.Errorf("repo:InsertMFAChallenge: %w", err)
+	}
+	return nil
+}
+
+func (r *mfaRepo) GetMFAChallenge(ctx context.Context, tokenHash string) (model.MFAChallenge, error) {
+	var ch model.MFAChallenge
+	err := r.pool.QueryRow(ctx, queryGetMFAChallenge, tokenHash).Scan(
+		&ch.ID, &ch.UserID, &ch.TokenHash, &ch.Attempts, &ch.ExpiresAt, &ch.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ch, pgx.ErrNoRows
+	} else if err != nil {
+		return ch, fmt.Errorf("repo:GetMFAChallenge: %w", err)
+	}
+	return ch, nil
+}
+
+func (r *mfaRepo) IncrementMFAChallengeAttempts(ctx context.Context, tokenHash string) (int, error) {
+	var attempts int
+	err := r.pool.QueryRow(ctx, queryIncrementMFAChallengeAttempts, tokenHash).Scan(&attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, pgx.ErrNoRows
+	} else if err != nil {
+		return 0, fmt.Errorf("repo:IncrementMFAChallengeAttempts: %w", err)
+	}
+	return attempts, nil
+}
+
+func (r *mfaRepo) DeleteMFAChallenge(ctx context.Context, tokenHash string) error {
+	_, err := r.pool.Exec(ctx, queryDeleteMFAChallenge, tokenHash)
+	if err != nil {
+		return fmt.Errorf("repo:DeleteMFAChallenge: %w", err)
+	}
+	return nil
+}
+
+// totpSecretAAD binds a sealed secret to its user, so it cannot be copied
+// onto another account
+func totpSecretAAD(userID int64) string {
+	return fmt.Sprintf("user_totp.secret:%d", userID)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const (
+	queryInsertOAuthClient = `
+        INSERT INTO oauth_clients (client_id, secret_hash, name, redirect_uris, scopes, created_at)
+        VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)
+        RETURNING id
+    `
+	queryGetOAuthClient = `
+        SELECT id, client_id, COALESCE(secret_hash, '') AS secret_hash, name, redirect_uris, scopes, created_at
+        FROM oauth_clients WHERE client_id=$1
+    `
+	queryGetAllOAuthClients = `
+        SELECT id, client_id, COALESCE(secret_hash, '') AS secret_hash, name, redirect_uris, scopes, created_at
+        FROM oauth_clients ORDER BY id
+    `
+	// Sessions and codes of the client go with it (ON DELETE CASCADE)
+	queryDeleteOAuthClient = `
+        DELETE FROM oauth_clients WHERE client_id=$1
+    `
+	queryDeleteExpiredAuthorizationCodes = `
+        DELETE FROM oauth_authorization_codes WHERE expires_at <= $1
+    `
+	queryInsertAuthorizationCode = `
+        INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, redirect_uri_provided, scopes, code_challenge, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id
+    `
+	queryClaimAuthorizationCode = `
+        UPDATE oauth_authorization_codes SET used_at=$2
+        WHERE code_hash=$1 AND used_at IS NULL AND expires_at > $2
+        RETURNING id, code_hash, client_id, user_id, redirect_uri, redirect_uri_provided, scopes, code_challenge, expires_at, created_at, used_at
+    `
+)
+
+type OAuthRepository interface {
+	InsertClient(ctx context.Context, c *model.OAuthClient) error
+	// GetClient returns the client with the public client ID, or
+	// pgx.ErrNoRows
+	GetClient(ctx context.Context, clientID string) (model.OAuthClient, error)
+	GetAllClients(ctx context.Context) ([]model.OAuthClient, error)
+	// DeleteClient removes the client with every grant and code it holds;
+	// pgx.ErrNoRows means there is no such client
+	DeleteClient(ctx context.Context, clientID string) error
+
+	// InsertAuthorizationCode stores a new code and drops expired ones
+	InsertAuthorizationCode(ctx context.Context, code *model.OAuthAuthorizationCode) error
+	// ClaimAuthorizationCode atomically marks an unused, unexpired code as
+	// used and returns it. pgx.ErrNoRows means the code is unknown, expired
+	// or was already redeemed.
+	ClaimAuthorizationCode(ctx context.Context, codeHash string, now time.Time) (model.OAuthAuthorizationCode, error)
+}
+
+type oauthRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewOAuthRepository(pool *pgxpool.Pool) OAuthRepository {
+	return &oauthRepo{pool: pool}
+}
+
+func (r *oauthRepo) InsertClient(ctx context.Context, c *model.OAuthClient) error {
+	err := r.pool.QueryRow(ctx, queryInsertOAuthClient,
+		c.ClientID, c.SecretHash, c.Name, c.RedirectURIs, c.Scopes, c.CreatedAt,
+	).Scan(&c.ID)
+	if err != nil {
+		return fmt.Errorf("repo:InsertClient: %w", err)
+	}
+	return nil
+}
+
+func (r *oauthRepo) GetClient(ctx context.Context, clientID string) (model.OAuthClient, error) {
+	rows, err := r.pool.Query(ctx, queryGetOAuthClient, clientID)
+	if err != nil {
+		return model.OAuthClient{}, fmt.Errorf("repo:GetClient: %w", err)
+	}
+	c, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[model.OAuthClient])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, pgx.ErrNoRows
+	} else if err != nil {
+		return c, fmt.Errorf("repo:GetClient: %w", err)
+	}
+	return c, nil
+}
+
+func (r *oauthRepo) GetAllClients(ctx context.Context) ([]model.OAuthClient, error) {
+	rows, err := r.pool.Query(ctx, queryGetAllOAuthClients)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetAllClients: %w", err)
+	}
+	clients, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.OAuthClient])
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetAllClients: %w", err)
+	}
+	return clients, nil
+}
+
+func (r *oauthRepo) DeleteClient(ctx context.Context, clientID string) error {
+	cmd, err := r.pool.Exec(ctx, queryDeleteOAuthClient, clientID)
+	if err != nil {
+		return fmt.Errorf("repo:DeleteClient: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *oauthRepo) InsertAuthorizationCode(ctx context.Context, code *model.OAuthAuthorizationCode) error {
+	return withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queryDeleteExpiredAuthorizationCodes, code.CreatedAt); err != nil {
+			return fmt.Errorf("repo:InsertAuthorizationCode:purge: %w", err)
+		}
+		err := tx.QueryRow(ctx, queryInsertAuthorizationCode,
+			code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.RedirectURIProvided, code.Scopes, code.CodeChallenge, code.ExpiresAt, code.CreatedAt,
+		).Scan(&code.ID)
+		if err != nil {
+			return fmt.Errorf("repo:InsertAuthorizationCode: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *oauthRepo) ClaimAuthorizationCode(ctx context.Context, codeHash string, now time.Time) (model.OAuthAuthorizationCode, error) {
+	rows, err := r.pool.Query(ctx, queryClaimAuthorizationCode, codeHash, now)
+	if err != nil {
+		return model.OAuthAuthorizationCode{}, fmt.Errorf("repo:ClaimAuthorizationCode: %w", err)
+	}
+	code, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[model.OAuthAuthorizationCode])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return code, pgx.ErrNoRows
+	} else if err != nil {
+		return code, fmt.Errorf("repo:ClaimAuthorizationCode: %w", err)
+	}
+	return code, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const (
+	queryDeleteUserPasswordResetTokens = `
+        DELETE FROM password_reset_tokens WHERE user_id=$1
+    `
+	queryInsertPasswordResetToken = `
+        INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `
+	queryGetPasswordResetUser = `
+        SELECT user_id FROM password_reset_tokens
+        WHERE token_hash=$1 AND used_at IS NULL AND expires_at > $2
+    `
+	queryClaimPasswordResetToken = `
+        UPDATE password_reset_tokens SET used_at=$2
+        WHERE token_hash=$1 AND used_at IS NULL AND expires_at > $2
+        RETURNING user_id
+    `
+)
+
+type PasswordResetRepository interface {
+	// InsertPasswordResetToken replaces any earlier tokens of the user, so only
+	// the most recent email works
+	InsertPasswordResetToken(ctx context.Context, t *model.PasswordResetToken) error
+	// GetPasswordResetUser returns the user an unused, unexpired token belongs
+	// to without claiming it, or pgx.ErrNoRows
+	GetPasswordResetUser(ctx context.Context, tokenHash string, now time.Time) (int64, error)
+	// ResetPassword claims an unused, unexpired token, stores the new password
+	// hash and revokes every session of the user in one transaction. It returns
+	// the user's ID, or pgx.ErrNoRows when the token cannot be claimed.
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (int64, error)
+}
+
+type passwordResetRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPasswordResetRepository(pool *pgxpool.Pool) PasswordResetRepository {
+	return &passwordResetRepo{pool: pool}
+}
+
+func (r *passwordResetRepo) InsertPasswordResetToken(ctx context.Context, t *model.PasswordResetToken) error {
+	return withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queryDeleteUserPasswordResetTokens, t.UserID); err != nil {
+			return fmt.Errorf("repo:InsertPasswordResetToken:purge: %w", err)
+		}
+		err := tx.QueryRow(ctx, queryInsertPasswordResetToken, t.UserID, t.TokenHash, t.ExpiresAt, t.CreatedAt).Scan(&t.ID)
+		if err != nil {
+			return fmt.Errorf("repo:InsertPasswordResetToken: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *passwordResetRepo) GetPasswordResetUser(ctx context.Context, tokenHash string, now time.Time) (int64, error) {
+	var userID int64
+	if err := r.pool.QueryRow(ctx, queryGetPasswordResetUser, tokenHash, now).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, pgx.ErrNoRows
+		}
+		return 0, fmt.Errorf("repo:GetPasswordResetUser: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *passwordResetRepo) ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (int64, error) {
+	var userID int64
+	err := withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, queryClaimPasswordResetToken, tokenHash, now).Scan(&userID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return pgx.ErrNoRows
+			}
+			return fmt.Errorf("repo:ResetPassword:claim: %w", err)
+		}
+		cmd, err := tx.Exec(ctx, queryUpdateUserPassword, passwordHash, now, userID)
+		if err != nil {
+			return fmt.Errorf("repo:ResetPassword:update: %w", err)
+		}
+		if cmd.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if _, err := tx.Exec(ctx, queryDeleteUserRefreshTokens, userID); err != nil {
+			return fmt.Errorf("repo:ResetPassword:revokeSessions: %w", err)
+		}
+		// Any other outstanding reset link dies with the one just used
+		if _, err := tx.Exec(ctx, queryDeleteUserPasswordResetTokens, userID); err != nil {
+			return fmt.Errorf("repo:ResetPassword:purge: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+const (
+	queryInsertTransaction = `
+        INSERT INTO transactions (account_id, amount, type, description, journal_entry_id, transfer_id, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id
+    `
+	queryInsertTransfer = `
+        INSERT INTO transfers (from_account_id, to_account_id, amount, currency, description, journal_entry_id, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id
+    `
+)
+
+type TransactionRepository interface {
+	// InsertTransaction appends a row to the account's transaction history using the given transaction
+	InsertTransaction(ctx context.Context, tx pgx.Tx, t *model.Transaction) error
+	// InsertTransfer records a transfer using the given transaction; its linked
+	// history rows are inserted separately with InsertTransaction
+	InsertTransfer(ctx context.Context, tx pgx.Tx, t *model.Transfer) error
+}
+
+type transactionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewTransactionRepository(pool *pgxpool.Pool) TransactionRepository {
+	return &transactionRepo{pool: pool}
+}
+
+func (r *transactionRepo) InsertTransaction(ctx context.Context, tx pgx.Tx, t *model.Transaction) error {
+	t.CreatedAt = time.Now()
+
+	err := tx.QueryRow(ctx, queryInsertTransaction, t.AccountID, t.Amount, t.Type, t.Description, t.JournalEntryID, t.TransferID, t.CreatedAt).
+		Scan(&t.ID)
+	if err != nil {
+		return fmt.Errorf("repo:InsertTransaction: %w", err)
+	}
+	return nil
+}
+
+func (r *transactionRepo) InsertTransfer(ctx context.Context, tx pgx.Tx, t *model.Transfer) error {
+	t.CreatedAt = time.Now()
+
+	err := tx.QueryRow(ctx, queryInsertTransfer, t.FromAccountID, t.ToAccountID, t.Amount, t.Amount.Currency(), t.Description, t.JournalEntryID, t.CreatedAt).
+		Scan(&t.ID)
+	if err != nil {
+		return fmt.Errorf("repo:InsertTransfer: %w", err)
+	}
+	return nil
+}
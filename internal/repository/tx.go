@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// withTransaction runs fn inside a database transaction, committing if fn
+// succeeds and rolling back if it returns an error or panics
+func withTransaction(ctx context.Context, pool *pgxpool.Pool, fn func(pgx.Tx) error) (err error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("repo:begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			// A panic occurred, rollback and re-panic
+			tx.Rollback(ctx)
+			panic(p)
+		} else if err != nil {
+			// Something went wrong, rollback
+			tx.Rollback(ctx)
+		} else {
+			// All good, commit
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	err = fn(tx)
+	return err
+}
@@ -13,28 +13,49 @@ import (
 
 const (
 	queryGetAllUsers = `
-        SELECT id, full_name, email, password_hash, role, is_active, created_at, updated_at 
+        SELECT id, full_name, email, password_hash, role, is_active, email_verified_at, token_version, is_service_account, created_at, updated_at 
         FROM users
     `
 	queryGetUserByID = `
-        SELECT id, full_name, email, password_hash, role, is_active, created_at, updated_at
+        SELECT id, full_name, email, password_hash, role, is_active, email_verified_at, token_version, is_service_account, created_at, updated_at
         FROM users WHERE id=$1
     `
 	queryGetUserByEmail = `
-        SELECT id, full_name, email, password_hash, role, is_active, created_at, updated_at
+        SELECT id, full_name, email, password_hash, role, is_active, email_verified_at, token_version, is_service_account, created_at, updated_at
         FROM users WHERE email=$1
     `
 	queryAddUser = `
         INSERT INTO users
-            (full_name, email, password_hash, role, is_active, created_at, updated_at)
-        VALUES ($1,$2,$3,$4,$5,$6,$7)
+            (full_name, email, password_hash, role, is_active, email_verified_at, is_service_account, created_at, updated_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
         RETURNING id
     `
 	queryUpdateUserEmail = `
-        UPDATE users SET email=$1, updated_at=$2 WHERE id=$3
+        UPDATE users SET email=$1, email_verified_at=NULL, updated_at=$2 WHERE id=$3
     `
+	// The hash being replaced moves to password_history; both statements see
+	// the row as it was before the update
 	queryUpdateUserPassword = `
+        WITH previous AS (
+            INSERT INTO password_history (user_id, password_hash, created_at)
+            SELECT id, password_hash, $2 FROM users WHERE id=$3
+        )
         UPDATE users SET password_hash=$1, updated_at=$2 WHERE id=$3
+    `
+	queryGetPasswordHistory = `
+        SELECT password_hash FROM password_history
+        WHERE user_id=$1
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2
+    `
+	queryTrimPasswordHistory = `
+        DELETE FROM password_history
+        WHERE user_id=$1 AND id NOT IN (
+            SELECT id FROM password_history
+            WHERE user_id=$1
+            ORDER BY created_at DESC, id DESC
+            LIMIT $2
+        )
     `
 	queryUpdateUserActiveStatus = `
         UPDATE users SET is_active=$1, updated_at=$2 WHERE id=$3
@@ -43,18 +64,84 @@ const (
         DELETE FROM users WHERE id=$1
     `
 	queryInsertRefreshToken = `
-		INSERT INTO refresh_tokens (user_id, token, expires_at, created_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id
 	`
 	queryGetRefreshToken = `
-		SELECT id, user_id, token, expires_at, created_at FROM refresh_tokens WHERE token=$1
+		SELECT rt.id, rt.user_id, rt.token_hash, rt.family_id, rt.expires_at, rt.created_at, rt.used_at,
+			COALESCE(s.client_id, ''), s.scopes
+		FROM refresh_tokens rt JOIN sessions s ON s.family_id = rt.family_id
+		WHERE rt.token_hash=$1
+	`
+	// Only the client the session belongs to can claim its tokens; our own
+	// clients pass an empty client ID
+	queryClaimRefreshToken = `
+		UPDATE refresh_tokens rt SET used_at=$2
+		FROM sessions s
+		WHERE rt.token_hash=$1 AND rt.used_at IS NULL AND rt.expires_at > $2
+			AND s.family_id = rt.family_id AND COALESCE(s.client_id, '') = $3
+		RETURNING rt.id, rt.user_id, rt.token_hash, rt.family_id, rt.expires_at, rt.created_at, rt.used_at,
+			COALESCE(s.client_id, ''), s.scopes
+	`
+	// Refresh tokens reference their session with ON DELETE CASCADE, so
+	// deleting the session revokes the whole family
+	queryDeleteRefreshTokenFamily = `
+		DELETE FROM sessions WHERE family_id=$1
 	`
 	queryDeleteRefreshToken = `
-		DELETE FROM refresh_tokens WHERE token=$1
+		DELETE FROM refresh_tokens WHERE token_hash=$1
+	`
+	queryDeleteExpiredRefreshTokens = `
+		DELETE FROM refresh_tokens WHERE expires_at <= $1
+	`
+	queryDeleteEmptySessions = `
+		DELETE FROM sessions s
+		WHERE NOT EXISTS (SELECT 1 FROM refresh_tokens rt WHERE rt.family_id = s.family_id)
 	`
+	queryInsertSession = `
+		INSERT INTO sessions (user_id, family_id, client_id, scopes, device_label, user_agent, ip_address, created_at, last_used_at)
+		VALUES ($1, $2, NULLIF($3, ''), COALESCE($4::TEXT[], '{}'), $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+	queryTouchSession = `
+		UPDATE sessions SET last_used_at=$2, ip_address=COALESCE(NULLIF($3, ''), ip_address)
+		WHERE family_id=$1
+	`
+	// A session is active while it still holds an unused, unexpired token
+	queryGetUserSessions = `
+		SELECT id, user_id, family_id, COALESCE(client_id, '') AS client_id, scopes,
+			device_label, user_agent, ip_address, created_at, last_used_at
+		FROM sessions s
+		WHERE s.user_id=$1 AND EXISTS (
+			SELECT 1 FROM refresh_tokens rt
+			WHERE rt.family_id = s.family_id AND rt.used_at IS NULL AND rt.expires_at > $2
+		)
+		ORDER BY last_used_at DESC, id DESC
+	`
+	queryDeleteUserSession = `
+		DELETE FROM sessions WHERE id=$1 AND user_id=$2
+	`
+	// Raising the token version in the same statement invalidates the
+	// access tokens of the sessions being ended
 	queryDeleteUserRefreshTokens = `
-		DELETE FROM refresh_tokens WHERE user_id=$1
+		WITH bumped AS (
+			UPDATE users SET token_version = token_version + 1 WHERE id=$1
+		)
+		DELETE FROM sessions WHERE user_id=$1
+	`
+	queryGetAccessTokenState = `
+		SELECT u.token_version, u.is_active,
+			EXISTS (SELECT 1 FROM revoked_access_tokens r WHERE r.jti=$2) AS revoked
+		FROM users u WHERE u.id=$1
+	`
+	queryInsertRevokedAccessToken = `
+		INSERT INTO revoked_access_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	queryDeleteExpiredRevokedAccessTokens = `
+		DELETE FROM revoked_access_tokens WHERE expires_at <= $1
 	`
 )
 
@@ -64,16 +151,42 @@ type UserRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (model.User, error)
 	AddUser(ctx context.Context, u *model.User) error
 	UpdateUserEmail(ctx context.Context, id int64, email string) error
+	// UpdateUserPassword keeps the replaced hash in the password history
 	UpdateUserPassword(ctx context.Context, id int64, hash string) error
+	// ChangePassword sets a new password hash, trims the history to the keep
+	// newest earlier hashes and ends every session of the user in a single
+	// transaction, so the old password's sessions cannot outlive the change
+	ChangePassword(ctx context.Context, id int64, hash string, keep int) error
+	// GetPasswordHistory returns up to limit earlier password hashes, newest
+	// first
+	GetPasswordHistory(ctx context.Context, id int64, limit int) ([]string, error)
+	// TrimPasswordHistory deletes all but the keep newest earlier hashes
+	TrimPasswordHistory(ctx context.Context, id int64, keep int) error
 	UpdateUserActiveStatus(ctx context.Context, id int64, isActive bool) error
 	DeleteUserByID(ctx context.Context, id int64) error
 
 	// Transaction support for future complex operations
 	WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error
 	InsertRefreshToken(ctx context.Context, rt *model.RefreshToken) error
-	GetRefreshToken(ctx context.Context, token string) (model.RefreshToken, error)
-	DeleteRefreshToken(ctx context.Context, token string) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (model.RefreshToken, error)
+	ClaimRefreshToken(ctx context.Context, tokenHash, clientID string, now time.Time) (model.RefreshToken, error)
+	DeleteRefreshToken(ctx context.Context, tokenHash string) error
+	DeleteRefreshTokenFamily(ctx context.Context, familyID string) error
+	DeleteExpiredRefreshTokens(ctx context.Context, now time.Time) (int64, error)
+	// DeleteUserRefreshTokens ends every session of the user and raises their
+	// token version, so access tokens issued before stop working as well
 	DeleteUserRefreshTokens(ctx context.Context, userID int64) error
+
+	// GetAccessTokenState returns the user's token version and status and
+	// whether jti is revoked, or pgx.ErrNoRows if the user does not exist
+	GetAccessTokenState(ctx context.Context, userID int64, jti string) (model.AccessTokenState, error)
+	InsertRevokedAccessToken(ctx context.Context, t *model.RevokedAccessToken) error
+	DeleteExpiredRevokedAccessTokens(ctx context.Context, now time.Time) (int64, error)
+
+	InsertSession(ctx context.Context, s *model.Session) error
+	TouchSession(ctx context.Context, familyID string, at time.Time, ip string) error
+	GetUserSessions(ctx context.Context, userID int64, now time.Time) ([]model.Session, error)
+	DeleteUserSession(ctx context.Context, userID, sessionID int64) error
 }
 
 type userRepo struct {
@@ -86,27 +199,7 @@ func NewUserRepository(pool *pgxpool.Pool) UserRepository {
 
 // WithTransaction executes a function within a database transaction
 func (r *userRepo) WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error {
-	tx, err := r.pool.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("repo:begin transaction: %w", err)
-	}
-
-	defer func() {
-		if p := recover(); p != nil {
-			// A panic occurred, rollback and re-panic
-			tx.Rollback(ctx)
-			panic(p)
-		} else if err != nil {
-			// Something went wrong, rollback
-			tx.Rollback(ctx)
-		} else {
-			// All good, commit
-			err = tx.Commit(ctx)
-		}
-	}()
-
-	err = fn(tx)
-	return err
+	return withTransaction(ctx, r.pool, fn)
 }
 
 func (r *userRepo) GetAllUsers(ctx context.Context) ([]model.User, error) {
@@ -131,6 +224,9 @@ func (r *userRepo) GetUserByID(ctx context.Context, id int64) (model.User, error
 		&user.PasswordHash,
 		&user.Role,
 		&user.IsActive,
+		&user.EmailVerifiedAt,
+		&user.TokenVersion,
+		&user.ServiceAccount,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -152,6 +248,9 @@ func (r *userRepo) GetUserByEmail(ctx context.Context, email string) (model.User
 		&user.PasswordHash,
 		&user.Role,
 		&user.IsActive,
+		&user.EmailVerifiedAt,
+		&user.TokenVersion,
+		&user.ServiceAccount,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -170,7 +269,7 @@ func (r *userRepo) AddUser(ctx context.Context, u *model.User) error {
 	u.CreatedAt = now
 	u.UpdatedAt = now
 
-	err := r.pool.QueryRow(ctx, queryAddUser, u.FullName, u.Email, u.PasswordHash, u.Role, u.IsActive, u.CreatedAt, u.UpdatedAt).
+	err := r.pool.QueryRow(ctx, queryAddUser, u.FullName, u.Email, u.PasswordHash, u.Role, u.IsActive, u.EmailVerifiedAt, u.ServiceAccount, u.CreatedAt, u.UpdatedAt).
 		Scan(&u.ID)
 	if err != nil {
 		return fmt.Errorf("repo:AddUser: %w", err)
@@ -200,6 +299,25 @@ func (r *userRepo) UpdateUserPassword(ctx context.Context, id int64, hash string
 	return nil
 }
 
+func (r *userRepo) ChangePassword(ctx context.Context, id int64, hash string, keep int) error {
+	return withTransaction(ctx, r.pool, func(tx pgx.Tx) error {
+		cmd, err := tx.Exec(ctx, queryUpdateUserPassword, hash, time.Now(), id)
+		if err != nil {
+			return fmt.Errorf("repo:ChangePassword:update: %w", err)
+		}
+		if cmd.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if _, err := tx.Exec(ctx, queryTrimPasswordHistory, id, keep); err != nil {
+			return fmt.Errorf("repo:ChangePassword:trimHistory: %w", err)
+		}
+		if _, err := tx.Exec(ctx, queryDeleteUserRefreshTokens, id); err != nil {
+			return fmt.Errorf("repo:ChangePassword:revokeSessions: %w", err)
+		}
+		return nil
+	})
+}
+
 func (r *userRepo) UpdateUserActiveStatus(ctx context.Context, id int64, isActive bool) error {
 	cmd, err := r.pool.Exec(ctx, queryUpdateUserActiveStatus, isActive, time.Now(), id)
 	if err != nil {
@@ -211,6 +329,34 @@ func (r *userRepo) UpdateUserActiveStatus(ctx context.Context, id int64, isActiv
 	return nil
 }
 
+func (r *userRepo) GetPasswordHistory(ctx context.Context, id int64, limit int) ([]string, error) {
+	rows, err := r.pool.Query(ctx, queryGetPasswordHistory, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetPasswordHistory: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, fmt.Errorf("repo:GetPasswordHistory:scan: %w", err)
+		}
+		hashes = append(hashes, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo:GetPasswordHistory:rows: %w", err)
+	}
+	return hashes, nil
+}
+
+func (r *userRepo) TrimPasswordHistory(ctx context.Context, id int64, keep int) error {
+	if _, err := r.pool.Exec(ctx, queryTrimPasswordHistory, id, keep); err != nil {
+		return fmt.Errorf("repo:TrimPasswordHistory: %w", err)
+	}
+	return nil
+}
+
 func (r *userRepo) DeleteUserByID(ctx context.Context, id int64) error {
 	cmd, err := r.pool.Exec(ctx, queryDeleteUserByID, id)
 	if err != nil {
@@ -223,17 +369,18 @@ func (r *userRepo) DeleteUserByID(ctx context.Context, id int64) error {
 }
 
 func (r *userRepo) InsertRefreshToken(ctx context.Context, rt *model.RefreshToken) error {
-	err := r.pool.QueryRow(ctx, queryInsertRefreshToken, rt.UserID, rt.Token, rt.ExpiresAt, rt.CreatedAt).Scan(&rt.ID)
+	err := r.pool.QueryRow(ctx, queryInsertRefreshToken, rt.UserID, rt.TokenHash, rt.FamilyID, rt.ExpiresAt, rt.CreatedAt).Scan(&rt.ID)
 	if err != nil {
 		return fmt.Errorf("repo:InsertRefreshToken: %w", err)
 	}
 	return nil
 }
 
-func (r *userRepo) GetRefreshToken(ctx context.Context, token string) (model.RefreshToken, error) {
+func (r *userRepo) GetRefreshToken(ctx context.Context, tokenHash string) (model.RefreshToken, error) {
 	var rt model.RefreshToken
-	err := r.pool.QueryRow(ctx, queryGetRefreshToken, token).Scan(
-		&rt.ID, &rt.UserID, &rt.Token, &rt.ExpiresAt, &rt.CreatedAt,
+	err := r.pool.QueryRow(ctx, queryGetRefreshToken, tokenHash).Scan(
+		&rt.ID, &rt.UserID, &rt.TokenHash, &rt.FamilyID, &rt.ExpiresAt, &rt.CreatedAt, &rt.UsedAt,
+		&rt.ClientID, &rt.Scopes,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return rt, pgx.ErrNoRows
@@ -243,14 +390,97 @@ func (r *userRepo) GetRefreshToken(ctx context.Context, token string) (model.Ref
 	return rt, nil
 }
 
-func (r *userRepo) DeleteRefreshToken(ctx context.Context, token string) error {
-	_, err := r.pool.Exec(ctx, queryDeleteRefreshToken, token)
+// ClaimRefreshToken atomically marks an unused, unexpired token of clientID's
+// session as used and returns it. pgx.ErrNoRows means the token is unknown,
+// expired, belongs to another client or was already claimed by an earlier
+// request.
+func (r *userRepo) ClaimRefreshToken(ctx context.Context, tokenHash, clientID string, now time.Time) (model.RefreshToken, error) {
+	var rt model.RefreshToken
+	err := r.pool.QueryRow(ctx, queryClaimRefreshToken, tokenHash, now, clientID).Scan(
+		&rt.ID, &rt.UserID, &rt.TokenHash, &rt.FamilyID, &rt.ExpiresAt, &rt.CreatedAt, &rt.UsedAt,
+		&rt.ClientID, &rt.Scopes,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return rt, pgx.ErrNoRows
+	} else if err != nil {
+		return rt, fmt.Errorf("repo:ClaimRefreshToken: %w", err)
+	}
+	return rt, nil
+}
+
+func (r *userRepo) DeleteRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := r.pool.Exec(ctx, queryDeleteRefreshToken, tokenHash)
 	if err != nil {
 		return fmt.Errorf("repo:DeleteRefreshToken: %w", err)
 	}
 	return nil
 }
 
+func (r *userRepo) DeleteRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.pool.Exec(ctx, queryDeleteRefreshTokenFamily, familyID)
+	if err != nil {
+		return fmt.Errorf("repo:DeleteRefreshTokenFamily: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredRefreshTokens removes every token that expired at or before
+// now, then the sessions left without tokens, and returns how many tokens
+// were deleted
+func (r *userRepo) DeleteExpiredRefreshTokens(ctx context.Context, now time.Time) (int64, error) {
+	cmd, err := r.pool.Exec(ctx, queryDeleteExpiredRefreshTokens, now)
+	if err != nil {
+		return 0, fmt.Errorf("repo:DeleteExpiredRefreshTokens: %w", err)
+	}
+	if _, err := r.pool.Exec(ctx, queryDeleteEmptySessions); err != nil {
+		return 0, fmt.Errorf("repo:DeleteExpiredRefreshTokens:sessions: %w", err)
+	}
+	return cmd.RowsAffected(), nil
+}
+
+func (r *userRepo) InsertSession(ctx context.Context, s *model.Session) error {
+	err := r.pool.QueryRow(ctx, queryInsertSession,
+		s.UserID, s.FamilyID, s.ClientID, s.Scopes, s.DeviceLabel, s.UserAgent, s.IPAddress, s.CreatedAt, s.LastUsedAt,
+	).Scan(&s.ID)
+	if err != nil {
+		return fmt.Errorf("repo:InsertSession: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepo) TouchSession(ctx context.Context, familyID string, at time.Time, ip string) error {
+	_, err := r.pool.Exec(ctx, queryTouchSession, familyID, at, ip)
+	if err != nil {
+		return fmt.Errorf("repo:TouchSession: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepo) GetUserSessions(ctx context.Context, userID int64, now time.Time) ([]model.Session, error) {
+	rows, err := r.pool.Query(ctx, queryGetUserSessions, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetUserSessions: %w", err)
+	}
+	sessions, err := pgx.CollectRows(rows, pgx.RowToStructByName[model.Session])
+	if err != nil {
+		return nil, fmt.Errorf("repo:GetUserSessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeleteUserSession ends one of the user's sessions; pgx.ErrNoRows means the
+// session does not exist or belongs to someone else
+func (r *userRepo) DeleteUserSession(ctx context.Context, userID, sessionID int64) error {
+	cmd, err := r.pool.Exec(ctx, queryDeleteUserSession, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("repo:DeleteUserSession: %w", err)
+	}
+	if cmd.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
 func (r *userRepo) DeleteUserRefreshTokens(ctx context.Context, userID int64) error {
 	_, err := r.pool.Exec(ctx, queryDeleteUserRefreshTokens, userID)
 	if err != nil {
@@ -258,3 +488,29 @@ func (r *userRepo) DeleteUserRefreshTokens(ctx context.Context, userID int64) er
 	}
 	return nil
 }
+
+func (r *userRepo) GetAccessTokenState(ctx context.Context, userID int64, jti string) (model.AccessTokenState, error) {
+	var st model.AccessTokenState
+	err := r.pool.QueryRow(ctx, queryGetAccessTokenState, userID, jti).Scan(&st.TokenVersion, &st.IsActive, &st.Revoked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return st, pgx.ErrNoRows
+	} else if err != nil {
+		return st, fmt.Errorf("repo:GetAccessTokenState: %w", err)
+	}
+	return st, nil
+}
+
+func (r *userRepo) InsertRevokedAccessToken(ctx context.Context, t *model.RevokedAccessToken) error {
+	if _, err := r.pool.Exec(ctx, queryInsertRevokedAccessToken, t.JTI, t.UserID, t.ExpiresAt); err != nil {
+		return fmt.Errorf("repo:InsertRevokedAccessToken: %w", err)
+	}
+	return nil
+}
+
+func (r *userRepo) DeleteExpiredRevokedAccessTokens(ctx context.Context, now time.Time) (int64, error) {
+	cmd, err := r.pool.Exec(ctx, queryDeleteExpiredRevokedAccessTokens, now)
+	if err != nil {
+		return 0, fmt.Errorf("repo:DeleteExpiredRevokedAccessTokens: %w", err)
+	}
+	return cmd.RowsAffected(), nil
+}
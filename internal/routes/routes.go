@@ -1,32 +1,126 @@
 package routes
 
 import (
-	"time"
-
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/yusufziyrek/bank-app/internal/controller"
+	"github.com/yusufziyrek/bank-app/internal/model"
 	"github.com/yusufziyrek/bank-app/internal/service"
 )
 
-func SetupRoutes(e *echo.Echo, userService service.UserService, jwtSecret string, jwtTTL time.Duration) {
+func SetupRoutes(e *echo.Echo, userService service.UserService, accountService service.AccountService, ledgerService service.LedgerService, transactionService service.TransactionService, idempotencyService service.IdempotencyService, cardService service.CardService, mfaService service.MFAService, passwordResetService service.PasswordResetService, emailVerificationService service.EmailVerificationService, loginAttemptService service.LoginAttemptService, oauthService service.OAuthService, apiKeyService service.APIKeyService, tokens controller.AccessTokenConfig, stepUp controller.StepUpPolicy) {
 	// Auth routes (public)
-	authCtrl := controller.NewAuthController(userService, jwtSecret, jwtTTL)
+	authCtrl := controller.NewAuthController(userService, mfaService, emailVerificationService, loginAttemptService, tokens, stepUp.TokenTTL)
 	e.POST("/api/v1/register", authCtrl.Register)
 	e.POST("/api/v1/login", authCtrl.Login)
+	e.POST("/api/v1/login/mfa", authCtrl.LoginMFA)
 	e.POST("/api/v1/refresh", authCtrl.Refresh)
+	e.POST("/api/v1/logout", authCtrl.Logout)
+
+	// Public keys for services that verify our access tokens
+	jwksCtrl := controller.NewJWKSController(tokens.Keys)
+	e.GET("/.well-known/jwks.json", jwksCtrl.Get)
+
+	passwordResetCtrl := controller.NewPasswordResetController(passwordResetService)
+	e.POST("/api/v1/password/forgot", passwordResetCtrl.Forgot)
+	e.POST("/api/v1/password/reset", passwordResetCtrl.Reset)
+
+	emailVerificationCtrl := controller.NewEmailVerificationController(emailVerificationService)
+	e.POST("/api/v1/email/verify", emailVerificationCtrl.Verify)
+
+	// OAuth endpoints third-party apps call with their client credentials
+	oauthCtrl := controller.NewOAuthController(oauthService, userService, tokens)
+	e.POST("/api/v1/oauth/token", oauthCtrl.Token)
+	e.POST("/api/v1/oauth/introspect", oauthCtrl.Introspect)
+	e.POST("/api/v1/oauth/revoke", oauthCtrl.Revoke)
 
 	// Protected routes
 	jwtGroup := e.Group("/api/v1")
+	// Scripts may send an API key instead of an access token
+	jwtGroup.Use(controller.AuthenticateAPIKey(apiKeyService))
 	jwtGroup.Use(echojwt.WithConfig(echojwt.Config{
-		SigningKey: []byte(jwtSecret),
+		Skipper:        controller.AuthenticatedByAPIKey,
+		ParseTokenFunc: tokens.ParseTokenFunc,
 	}))
+	// Signature and expiry alone would keep revoked tokens working until exp
+	jwtGroup.Use(controller.RequireLiveToken(userService))
+	// Tokens issued to third-party apps and API keys only reach routes
+	// opened with scopes.Allow
+	scopes := controller.NewScopeGuard()
+	jwtGroup.Use(scopes.Middleware)
+
+	adminOnly := controller.RequireRole(model.RoleAdmin)
+	selfOrAdmin := controller.RequireSelfOrRole(model.RoleAdmin)
+	emailVerified := controller.RequireVerifiedEmail(userService)
+
+	jwtGroup.POST("/logout-all", authCtrl.LogoutAll)
+	jwtGroup.POST("/reauth", authCtrl.Reauthenticate)
+	jwtGroup.POST("/email/verify/resend", emailVerificationCtrl.Resend)
+
+	sessionCtrl := controller.NewSessionController(userService)
+	jwtGroup.GET("/sessions", sessionCtrl.GetMine)
+	jwtGroup.DELETE("/sessions/:id", sessionCtrl.Revoke)
+
+	// Consent screen API; users see and revoke granted apps under /sessions
+	jwtGroup.GET("/oauth/authorize", oauthCtrl.Authorize)
+	jwtGroup.POST("/oauth/authorize", oauthCtrl.Consent)
+	jwtGroup.POST("/oauth/clients", oauthCtrl.RegisterClient, adminOnly)
+	jwtGroup.GET("/oauth/clients", oauthCtrl.GetClients, adminOnly)
+	jwtGroup.DELETE("/oauth/clients/:client_id", oauthCtrl.DeleteClient, adminOnly)
+
+	// Creating a key needs a recent re-authentication, so a stolen access
+	// token cannot be turned into a long-lived credential
+	apiKeyCtrl := controller.NewAPIKeyController(apiKeyService)
+	jwtGroup.POST("/api-keys", apiKeyCtrl.Create, controller.RequireRecentAuth(stepUp.APIKeyCreation))
+	jwtGroup.GET("/api-keys", apiKeyCtrl.GetMine)
+	jwtGroup.DELETE("/api-keys/:id", apiKeyCtrl.Revoke)
+
+	// Service accounts own keys that no person's credentials revoke
+	serviceAccountCtrl := controller.NewServiceAccountController(userService, apiKeyService)
+	jwtGroup.POST("/service-accounts", serviceAccountCtrl.Create, adminOnly)
+	jwtGroup.GET("/service-accounts", serviceAccountCtrl.GetAll, adminOnly)
+	jwtGroup.POST("/service-accounts/:id/api-keys", serviceAccountCtrl.CreateKey, adminOnly, controller.RequireRecentAuth(stepUp.APIKeyCreation))
+	jwtGroup.GET("/service-accounts/:id/api-keys", serviceAccountCtrl.GetKeys, adminOnly)
+	jwtGroup.DELETE("/service-accounts/:id/api-keys/:key_id", serviceAccountCtrl.RevokeKey, adminOnly)
+
+	mfaCtrl := controller.NewMFAController(mfaService)
+	jwtGroup.POST("/mfa/totp/enroll", mfaCtrl.EnrollTOTP)
+	jwtGroup.POST("/mfa/totp/confirm", mfaCtrl.ConfirmTOTP)
+	jwtGroup.POST("/mfa/totp/disable", mfaCtrl.DisableTOTP, controller.RequireRecentAuth(stepUp.MFADisable))
+
+	userCtrl := controller.NewUserController(userService, emailVerificationService, loginAttemptService)
+	jwtGroup.GET("/users", userCtrl.GetAll, adminOnly)
+	jwtGroup.GET("/users/:id", userCtrl.GetByID, selfOrAdmin)
+	// Credential changes need a recent re-authentication (POST /reauth)
+	jwtGroup.PUT("/users/:id/email", userCtrl.UpdateEmail, selfOrAdmin, controller.RequireRecentAuth(stepUp.EmailChange))
+	jwtGroup.PUT("/users/:id/password", userCtrl.UpdatePassword, selfOrAdmin, controller.RequireRecentAuth(stepUp.PasswordChange))
+	jwtGroup.PUT("/users/:id/status", userCtrl.UpdateStatus, adminOnly)
+	jwtGroup.POST("/users/:id/unlock", userCtrl.Unlock, adminOnly)
+	jwtGroup.DELETE("/users/:id", userCtrl.DeleteByID, adminOnly)
+
+	accountCtrl := controller.NewAccountController(accountService)
+	jwtGroup.POST("/accounts", accountCtrl.Open)
+	scopes.Allow(jwtGroup.GET("/accounts", accountCtrl.GetMine), model.ScopeAccountsRead)
+	scopes.Allow(jwtGroup.GET("/accounts/:id", accountCtrl.GetByID), model.ScopeAccountsRead)
+	jwtGroup.DELETE("/accounts/:id", accountCtrl.Close)
+
+	// Money-moving endpoints need a verified email and deduplicate retries
+	// sent with an Idempotency-Key header
+	idempotent := controller.Idempotency(idempotencyService)
+	transactionCtrl := controller.NewTransactionController(transactionService, stepUp)
+	jwtGroup.POST("/accounts/:id/deposits", transactionCtrl.Deposit, emailVerified, idempotent)
+	jwtGroup.POST("/accounts/:id/withdrawals", transactionCtrl.Withdraw, emailVerified, idempotent)
+	scopes.Allow(jwtGroup.POST("/transfers", transactionCtrl.Transfer, emailVerified, idempotent), model.ScopePaymentsWrite)
+
+	cardCtrl := controller.NewCardController(cardService)
+	jwtGroup.POST("/accounts/:id/cards", cardCtrl.Issue)
+	jwtGroup.GET("/accounts/:id/cards", cardCtrl.GetByAccount)
+	jwtGroup.GET("/cards/:id", cardCtrl.GetByID)
+	jwtGroup.POST("/cards/:id/reveal", cardCtrl.Reveal)
+	jwtGroup.POST("/cards/:id/freeze", cardCtrl.Freeze)
+	jwtGroup.POST("/cards/:id/unfreeze", cardCtrl.Unfreeze)
+	jwtGroup.POST("/cards/:id/replace", cardCtrl.Replace)
 
-	userCtrl := controller.NewUserController(userService)
-	jwtGroup.GET("/users", userCtrl.GetAll)
-	jwtGroup.GET("/users/:id", userCtrl.GetByID)
-	jwtGroup.PUT("/users/:id/email", userCtrl.UpdateEmail)
-	jwtGroup.PUT("/users/:id/password", userCtrl.UpdatePassword)
-	jwtGroup.PUT("/users/:id/status", userCtrl.UpdateStatus)
-	jwtGroup.DELETE("/users/:id", userCtrl.DeleteByID)
+	ledgerCtrl := controller.NewLedgerController(ledgerService)
+	jwtGroup.GET("/ledger/reconciliation", ledgerCtrl.Reconcile, adminOnly)
 }
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/common/iban"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrAccountClosed     = errors.New("account is closed")
+	ErrAccountHasBalance = errors.New("account balance must be zero to close")
+)
+
+// maxAccountNumberAttempts bounds the retries when a generated account number collides
+const maxAccountNumberAttempts = 5
+
+type AccountService interface {
+	OpenAccount(ctx context.Context, userID int64) (model.Account, error)
+	GetUserAccounts(ctx context.Context, userID int64) ([]model.Account, error)
+	GetAccountByID(ctx context.Context, userID, id int64) (model.Account, error)
+	CloseAccount(ctx context.Context, userID, id int64) error
+}
+
+type accountService struct {
+	repo        repository.AccountRepository
+	countryCode string
+	currency    string
+}
+
+func NewAccountService(r repository.AccountRepository, countryCode, currency string) AccountService {
+	return &accountService{repo: r, countryCode: countryCode, currency: currency}
+}
+
+func (s *accountService) OpenAccount(ctx context.Context, userID int64) (model.Account, error) {
+	for attempt := 0; attempt < maxAccountNumberAttempts; attempt++ {
+		number, err := iban.Generate(s.countryCode)
+		if err != nil {
+			return model.Account{}, fmt.Errorf("service:generateAccountNumber: %w", err)
+		}
+		a := model.Account{
+			UserID:        userID,
+			AccountNumber: number,
+			Balance:       money.New(0, s.currency),
+			Currency:      s.currency,
+			IsActive:      true,
+		}
+		err = s.repo.AddAccount(ctx, &a)
+		switch {
+		case err == nil:
+			return a, nil
+		case isPgError(err, pgUniqueViolation):
+			// Account number collision, try again with a fresh number
+			continue
+		case isPgError(err, pgForeignKeyViolation):
+			return model.Account{}, ErrUserNotFound
+		default:
+			return model.Account{}, fmt.Errorf("service:AddAccount: %w", err)
+		}
+	}
+	return model.Account{}, fmt.Errorf("service:OpenAccount: no unique account number after %d attempts", maxAccountNumberAttempts)
+}
+
+func (s *accountService) GetUserAccounts(ctx context.Context, userID int64) ([]model.Account, error) {
+	accounts, err := s.repo.GetAccountsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service:GetUserAccounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// GetAccountByID returns the account only if it belongs to userID; accounts of
+// other users are reported as not found so their existence is not leaked
+func (s *accountService) GetAccountByID(ctx context.Context, userID, id int64) (model.Account, error) {
+	a, err := s.repo.GetAccountByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Account{}, ErrAccountNotFound
+		}
+		return model.Account{}, fmt.Errorf("service:GetAccountByID: %w", err)
+	}
+	if a.UserID != userID {
+		return model.Account{}, ErrAccountNotFound
+	}
+	return a, nil
+}
+
+func (s *accountService) CloseAccount(ctx context.Context, userID, id int64) error {
+	a, err := s.GetAccountByID(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !a.IsActive {
+		return ErrAccountClosed
+	}
+	if !a.Balance.IsZero() {
+		return ErrAccountHasBalance
+	}
+	if err := s.repo.CloseAccount(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Balance changed or account was closed between the check and the update
+			return ErrAccountHasBalance
+		}
+		return fmt.Errorf("service:CloseAccount: %w", err)
+	}
+	return nil
+}
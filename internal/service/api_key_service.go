@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("api key invalid or expired")
+	ErrAPIKeyNotFound      = errors.New("api key not found")
+	ErrAPIKeyLimitReached  = errors.New("api key limit reached")
+	ErrInvalidAPIKeyExpiry = errors.New("api key expiry out of range")
+)
+
+const (
+	apiKeyLength = 32
+	// apiKeyDisplayLength is how much of the key, prefix included, is kept
+	// to tell keys apart
+	apiKeyDisplayLength = len(model.APIKeyPrefix) + 8
+	apiKeyMaxTTL        = 365 * 24 * time.Hour
+	apiKeyLimit         = 10
+	// apiKeyTouchInterval bounds how often a busy key writes its last use
+	apiKeyTouchInterval = time.Minute
+)
+
+type APIKeyService interface {
+	// CreateKey issues a key for the user that expires after ttl. The key is
+	// returned here once; only its digest is stored.
+	CreateKey(ctx context.Context, userID int64, name string, scopes []string, ttl time.Duration) (model.APIKey, string, error)
+	GetUserKeys(ctx context.Context, userID int64) ([]model.APIKey, error)
+	RevokeKey(ctx context.Context, userID, keyID int64) error
+	// Authenticate returns the key and its user for a raw key sent with a
+	// request. Expired keys, keys of inactive users and keys created before
+	// the user's tokens were last revoked are rejected with ErrInvalidAPIKey.
+	Authenticate(ctx context.Context, raw string) (model.APIKey, model.User, error)
+}
+
+type apiKeyService struct {
+	repo  repository.APIKeyRepository
+	users UserService
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, users UserService) APIKeyService {
+	return &apiKeyService{repo: repo, users: users}
+}
+
+func (s *apiKeyService) CreateKey(ctx context.Context, userID int64, name string, scopes []string, ttl time.Duration) (model.APIKey, string, error) {
+	if ttl <= 0 || ttl > apiKeyMaxTTL {
+		return model.APIKey{}, "", ErrInvalidAPIKeyExpiry
+	}
+	if len(scopes) == 0 {
+		return model.APIKey{}, "", ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(model.KnownScopes, scope) {
+			return model.APIKey{}, "", ErrInvalidScope
+		}
+	}
+
+	secret, err := randomString(apiKeyLength, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	raw := model.APIKeyPrefix + secret
+	now := time.Now()
+	k := model.APIKey{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    raw[:apiKeyDisplayLength],
+		KeyHash:   digest(raw),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	inserted, err := s.repo.InsertAPIKey(ctx, &k, apiKeyLimit)
+	if err != nil {
+		return model.APIKey{}, "", fmt.Errorf("service:CreateKey: %w", err)
+	}
+	if !inserted {
+		return model.APIKey{}, "", ErrAPIKeyLimitReached
+	}
+	return k, raw, nil
+}
+
+func (s *apiKeyService) GetUserKeys(ctx context.Context, userID int64) ([]model.APIKey, error) {
+	keys, err := s.repo.GetUserAPIKeys(ctx, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("service:GetUserKeys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) RevokeKey(ctx context.Context, userID, keyID int64) error {
+	if err := s.repo.DeleteUserAPIKey(ctx, userID, keyID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAPIKeyNotFound
+		}
+		return fmt.Errorf("service:RevokeKey: %w", err)
+	}
+	return nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, raw string) (model.APIKey, model.User, error) {
+	if !strings.HasPrefix(raw, model.APIKeyPrefix) {
+		return model.APIKey{}, model.User{}, ErrInvalidAPIKey
+	}
+	now := time.Now()
+	k, err := s.repo.GetAPIKeyByHash(ctx, digest(raw), now)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.APIKey{}, model.User{}, ErrInvalidAPIKey
+		}
+		return model.APIKey{}, model.User{}, fmt.Errorf("service:Authenticate: %w", err)
+	}
+	u, err := s.users.GetUserByID(ctx, k.UserID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return model.APIKey{}, model.User{}, ErrInvalidAPIKey
+		}
+		return model.APIKey{}, model.User{}, fmt.Errorf("service:Authenticate: %w", err)
+	}
+	if !u.IsActive || k.TokenVersion != u.TokenVersion {
+		return model.APIKey{}, model.User{}, ErrInvalidAPIKey
+	}
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.repo.TouchAPIKey(ctx, k.ID, now); err != nil {
+			return model.APIKey{}, model.User{}, fmt.Errorf("service:Authenticate: %w", err)
+		}
+		k.LastUsedAt = &now
+	}
+	return k, u, nil
+}
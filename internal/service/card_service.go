@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/common/card"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrCardNotFound = errors.New("card not found")
+	ErrCardReplaced = errors.New("card has been replaced")
+)
+
+const (
+	// cardValidityYears is how long an issued card stays valid
+	cardValidityYears = 3
+	// maxCardNumberAttempts bounds the retries when a generated card number collides
+	maxCardNumberAttempts = 5
+)
+
+type CardService interface {
+	IssueCard(ctx context.Context, userID, accountID int64) (model.Card, error)
+	GetAccountCards(ctx context.Context, userID, accountID int64) ([]model.Card, error)
+	GetCard(ctx context.Context, userID, cardID int64) (model.Card, error)
+	FreezeCard(ctx context.Context, userID, cardID int64) (model.Card, error)
+	UnfreezeCard(ctx context.Context, userID, cardID int64) (model.Card, error)
+	// ReplaceCard retires a lost or damaged card for good and issues a new
+	// card for the same account
+	ReplaceCard(ctx context.Context, userID, cardID int64) (model.Card, error)
+}
+
+type cardService struct {
+	cards    repository.CardRepository
+	accounts repository.AccountRepository
+	bin      string
+}
+
+func NewCardService(c repository.CardRepository, a repository.AccountRepository, bin string) CardService {
+	return &cardService{cards: c, accounts: a, bin: bin}
+}
+
+func (s *cardService) IssueCard(ctx context.Context, userID, accountID int64) (model.Card, error) {
+	a, err := s.ownedAccount(ctx, userID, accountID)
+	if err != nil {
+		return model.Card{}, err
+	}
+	if !a.IsActive {
+		return model.Card{}, ErrAccountClosed
+	}
+
+	return s.issue(ctx, a.ID, func(c *model.Card) error {
+		return s.cards.AddCard(ctx, c)
+	})
+}
+
+func (s *cardService) GetAccountCards(ctx context.Context, userID, accountID int64) ([]model.Card, error) {
+	if _, err := s.ownedAccount(ctx, userID, accountID); err != nil {
+		return nil, err
+	}
+	cards, err := s.cards.GetCardsByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("service:GetAccountCards: %w", err)
+	}
+	return cards, nil
+}
+
+// GetCard returns the card only if its account belongs to userID; cards of
+// other users are reported as not found
+func (s *cardService) GetCard(ctx context.Context, userID, cardID int64) (model.Card, error) {
+	c, err := s.cards.GetCardByID(ctx, cardID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Card{}, ErrCardNotFound
+		}
+		return model.Card{}, fmt.Errorf("service:GetCard: %w", err)
+	}
+	if _, err := s.ownedAccount(ctx, userID, c.AccountID); err != nil {
+		if errors.Is(err, ErrAccountNotFound) {
+			return model.Card{}, ErrCardNotFound
+		}
+		return model.Card{}, err
+	}
+	return c, nil
+}
+
+func (s *cardService) FreezeCard(ctx context.Context, userID, cardID int64) (model.Card, error) {
+	return s.setActive(ctx, userID, cardID, false)
+}
+
+func (s *cardService) UnfreezeCard(ctx context.Context, userID, cardID int64) (model.Card, error) {
+	return s.setActive(ctx, userID, cardID, true)
+}
+
+func (s *cardService) setActive(ctx context.Context, userID, cardID int64, active bool) (model.Card, error) {
+	c, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return model.Card{}, err
+	}
+	if c.Replaced() {
+		return model.Card{}, ErrCardReplaced
+	}
+	if err := s.cards.SetCardActive(ctx, c.ID, active); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Replaced between the check and the update
+			return model.Card{}, ErrCardReplaced
+		}
+		return model.Card{}, fmt.Errorf("service:SetCardActive: %w", err)
+	}
+	c.IsActive = active
+	c.UpdatedAt = time.Now()
+	return c, nil
+}
+
+func (s *cardService) ReplaceCard(ctx context.Context, userID, cardID int64) (model.Card, error) {
+	old, err := s.GetCard(ctx, userID, cardID)
+	if err != nil {
+		return model.Card{}, err
+	}
+	if old.Replaced() {
+		return model.Card{}, ErrCardReplaced
+	}
+	a, err := s.ownedAccount(ctx, userID, old.AccountID)
+	if err != nil {
+		return model.Card{}, err
+	}
+	if !a.IsActive {
+		return model.Card{}, ErrAccountClosed
+	}
+
+	replacement, err := s.issue(ctx, old.AccountID, func(c *model.Card) error {
+		return s.cards.ReplaceCard(ctx, old.ID, c)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Card{}, ErrCardReplaced
+	}
+	return replacement, err
+}
+
+// issue generates card details for accountID and stores them with save,
+// retrying with a fresh number on a card number collision
+func (s *cardService) issue(ctx context.Context, accountID int64, save func(*model.Card) error) (model.Card, error) {
+	for attempt := 0; attempt < maxCardNumberAttempts; attempt++ {
+		pan, err := card.GeneratePAN(s.bin)
+		if err != nil {
+			return model.Card{}, fmt.Errorf("service:generateCardNumber: %w", err)
+		}
+		cvv, err := card.GenerateCVV()
+		if err != nil {
+			return model.Card{}, fmt.Errorf("service:generateCVV: %w", err)
+		}
+		c := model.Card{
+			AccountID:  accountID,
+			CardNumber: pan,
+			CVV:        cvv,
+			IsActive:   true,
+			ExpiryDate: expiryDate(time.Now()),
+		}
+		err = save(&c)
+		switch {
+		case err == nil:
+			return c, nil
+		case isPgError(err, pgUniqueViolation):
+			// Card number collision, try again with a fresh number
+			continue
+		case errors.Is(err, pgx.ErrNoRows):
+			return model.Card{}, err
+		default:
+			return model.Card{}, fmt.Errorf("service:AddCard: %w", err)
+		}
+	}
+	return model.Card{}, fmt.Errorf("service:issueCard: no unique card number after %d attempts", maxCardNumberAttempts)
+}
+
+// ownedAccount returns the account if it belongs to userID
+func (s *cardService) ownedAccount(ctx context.Context, userID, accountID int64) (model.Account, error) {
+	a, err := s.accounts.GetAccountByID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Account{}, ErrAccountNotFound
+		}
+		return model.Account{}, fmt.Errorf("service:GetAccount: %w", err)
+	}
+	if a.UserID != userID {
+		return model.Account{}, ErrAccountNotFound
+	}
+	return a, nil
+}
+
+// expiryDate returns the last day of the month cardValidityYears after now,
+// matching the MM/YY printed on the card
+func expiryDate(now time.Time) time.Time {
+	return time.Date(now.Year()+cardValidityYears, now.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+}
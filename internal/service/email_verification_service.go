@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/common/mailer"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrEmailNotVerified         = errors.New("email address not verified")
+	ErrEmailAlreadyVerified     = errors.New("email address already verified")
+	ErrInvalidVerificationToken = errors.New("verification token invalid or expired")
+)
+
+const (
+	emailVerificationTTL         = 24 * time.Hour
+	emailVerificationTokenLength = 32
+)
+
+type EmailVerificationService interface {
+	// SendVerification emails a confirmation link for the user's current
+	// address
+	SendVerification(ctx context.Context, userID int64) error
+	// RequestEmailChange records newEmail as pending and sends the
+	// confirmation link there; the user's email changes only once it is
+	// confirmed. The current address is told about the request.
+	RequestEmailChange(ctx context.Context, userID int64, newEmail string) error
+	// ConfirmEmail marks the address behind token as the user's verified email
+	ConfirmEmail(ctx context.Context, token string) error
+}
+
+type emailVerificationService struct {
+	repo      repository.EmailVerificationRepository
+	users     repository.UserRepository
+	mail      mailer.Mailer
+	verifyURL string
+}
+
+// NewEmailVerificationService sends links of the form verifyURL?token=...;
+// the page behind it is expected to post the token to the verify endpoint
+func NewEmailVerificationService(repo repository.EmailVerificationRepository, users repository.UserRepository, mail mailer.Mailer, verifyURL string) EmailVerificationService {
+	return &emailVerificationService{repo: repo, users: users, mail: mail, verifyURL: verifyURL}
+}
+
+func (s *emailVerificationService) SendVerification(ctx context.Context, userID int64) error {
+	u, err := s.user(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+	token, err := s.createToken(ctx, u.ID, u.Email, model.EmailVerificationVerify)
+	if err != nil {
+		return err
+	}
+	s.send(ctx, u.ID, mailer.Message{
+		To:      u.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Please confirm your email address with the link below. It expires in %d hours.\n\n"+
+			"%s\n\n"+
+			"Deposits, withdrawals and transfers are available once your address is confirmed.\n",
+			u.FullName, int(emailVerificationTTL.Hours()), s.link(token)),
+	})
+	return nil
+}
+
+func (s *emailVerificationService) RequestEmailChange(ctx context.Context, userID int64, newEmail string) error {
+	u, err := s.user(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(u.Email, newEmail) {
+		if u.EmailVerified() {
+			return ErrEmailAlreadyVerified
+		}
+		return s.SendVerification(ctx, userID)
+	}
+	if _, err := s.users.GetUserByEmail(ctx, newEmail); err == nil {
+		return ErrEmailAlreadyRegistered
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("service:RequestEmailChange: %w", err)
+	}
+
+	token, err := s.createToken(ctx, u.ID, newEmail, model.EmailVerificationChange)
+	if err != nil {
+		return err
+	}
+	s.send(ctx, u.ID, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Confirm this address with the link below to make it the email of your account. It expires in %d hours.\n\n"+
+			"%s\n\n"+
+			"Until then your account keeps using %s.\n",
+			u.FullName, int(emailVerificationTTL.Hours()), s.link(token), u.Email),
+	})
+	s.send(ctx, u.ID, mailer.Message{
+		To:      u.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"A change of your account email to %s was requested. It takes effect once the new address is confirmed.\n\n"+
+			"If this was not you, reset your password right away.\n",
+			u.FullName, newEmail),
+	})
+	return nil
+}
+
+func (s *emailVerificationService) ConfirmEmail(ctx context.Context, token string) error {
+	if _, err := s.repo.ConfirmEmail(ctx, digest(token), time.Now()); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidVerificationToken
+		}
+		if isPgError(err, pgUniqueViolation) {
+			return ErrEmailAlreadyRegistered
+		}
+		return fmt.Errorf("service:ConfirmEmail: %w", err)
+	}
+	return nil
+}
+
+func (s *emailVerificationService) user(ctx context.Context, userID int64) (model.User, error) {
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, fmt.Errorf("service:emailVerification:user: %w", err)
+	}
+	return u, nil
+}
+
+func (s *emailVerificationService) createToken(ctx context.Context, userID int64, email, purpose string) (string, error) {
+	token, err := randomString(emailVerificationTokenLength, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	v := model.EmailVerification{
+		UserID:    userID,
+		Email:     email,
+		Purpose:   purpose,
+		TokenHash: digest(token),
+		ExpiresAt: now.Add(emailVerificationTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.InsertEmailVerification(ctx, &v); err != nil {
+		return "", fmt.Errorf("service:emailVerification:insert: %w", err)
+	}
+	return token, nil
+}
+
+// send does not fail the request; the user can ask for another link
+func (s *emailVerificationService) send(ctx context.Context, userID int64, msg mailer.Message) {
+	if err := s.mail.Send(ctx, msg); err != nil {
+		log.Printf("email verification: sending mail to user %d failed: %v", userID, err)
+	}
+}
+
+func (s *emailVerificationService) link(token string) string {
+	return linkWithToken(s.verifyURL, token)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+const (
+	idempotencyKeyTTL = 24 * time.Hour // 24 saat
+	// A reservation without a response after this long belongs to a request
+	// that died before it could release the key
+	idempotencyReservationTimeout = time.Minute
+)
+
+type IdempotencyService interface {
+	// Begin reserves key for a request with the given hash. When the key was
+	// already completed by an identical request, the stored record is returned
+	// with replay set and the request must not be processed again.
+	Begin(ctx context.Context, userID int64, key, requestHash string) (rec model.IdempotencyRecord, replay bool, err error)
+	// Complete stores the response of a request reserved with Begin
+	Complete(ctx context.Context, userID int64, key string, status int, body []byte) error
+	// Abandon releases a reserved key so that the request can be retried
+	Abandon(ctx context.Context, userID int64, key string) error
+	// PurgeExpired deletes expired keys and returns how many were removed
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type idempotencyService struct {
+	repo repository.IdempotencyRepository
+}
+
+func NewIdempotencyService(r repository.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{repo: r}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, userID int64, key, requestHash string) (model.IdempotencyRecord, bool, error) {
+	now := time.Now()
+	rec := model.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(idempotencyKeyTTL),
+	}
+	reserved, err := s.repo.ReserveKey(ctx, &rec, now.Add(-idempotencyReservationTimeout))
+	if err != nil {
+		return model.IdempotencyRecord{}, false, fmt.Errorf("service:ReserveKey: %w", err)
+	}
+	if reserved {
+		return rec, false, nil
+	}
+
+	existing, err := s.repo.GetRecord(ctx, userID, key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The other request was abandoned between the two queries
+			return model.IdempotencyRecord{}, false, ErrIdempotencyInProgress
+		}
+		return model.IdempotencyRecord{}, false, fmt.Errorf("service:GetRecord: %w", err)
+	}
+	if existing.RequestHash != requestHash {
+		return model.IdempotencyRecord{}, false, ErrIdempotencyKeyReused
+	}
+	if !existing.Completed() {
+		return model.IdempotencyRecord{}, false, ErrIdempotencyInProgress
+	}
+	return existing, true, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, userID int64, key string, status int, body []byte) error {
+	if err := s.repo.SaveResponse(ctx, userID, key, status, body); err != nil {
+		return fmt.Errorf("service:SaveResponse: %w", err)
+	}
+	return nil
+}
+
+func (s *idempotencyService) Abandon(ctx context.Context, userID int64, key string) error {
+	if err := s.repo.DeleteRecord(ctx, userID, key); err != nil {
+		return fmt.Errorf("service:DeleteRecord: %w", err)
+	}
+	return nil
+}
+
+func (s *idempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	n, err := s.repo.DeleteExpiredIdempotencyKeys(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("service:PurgeExpired: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunIdempotencyKeySweeper deletes expired idempotency keys every interval
+// until ctx is cancelled. Expired keys are taken over on reuse anyway;
+// sweeping keeps keys that are never reused from piling up.
+func RunIdempotencyKeySweeper(ctx context.Context, svc IdempotencyService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := svc.PurgeExpired(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("idempotency key sweeper: %v", err)
+				}
+				continue
+			}
+			if n > 0 {
+				log.Printf("idempotency key sweeper: purged %d expired keys", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrUnbalancedEntry = errors.New("journal entry debits and credits do not balance")
+	ErrInvalidPosting  = errors.New("invalid journal entry posting")
+)
+
+type LedgerService interface {
+	// RecordEntry validates the entry and stores it in its own transaction
+	RecordEntry(ctx context.Context, e *model.JournalEntry) error
+	GetAccountPostings(ctx context.Context, accountID int64) ([]model.Posting, error)
+	// Reconcile checks that every journal entry balances and that every
+	// materialized account balance equals the sum of its postings
+	Reconcile(ctx context.Context) (model.LedgerReport, error)
+}
+
+type ledgerService struct {
+	repo repository.LedgerRepository
+}
+
+func NewLedgerService(r repository.LedgerRepository) LedgerService {
+	return &ledgerService{repo: r}
+}
+
+func (s *ledgerService) RecordEntry(ctx context.Context, e *model.JournalEntry) error {
+	return s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		return recordJournalEntry(ctx, s.repo, tx, e)
+	})
+}
+
+func (s *ledgerService) GetAccountPostings(ctx context.Context, accountID int64) ([]model.Posting, error) {
+	postings, err := s.repo.GetPostingsByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("service:GetAccountPostings: %w", err)
+	}
+	return postings, nil
+}
+
+func (s *ledgerService) Reconcile(ctx context.Context) (model.LedgerReport, error) {
+	unbalanced, err := s.repo.GetUnbalancedEntries(ctx)
+	if err != nil {
+		return model.LedgerReport{}, fmt.Errorf("service:Reconcile: %w", err)
+	}
+	mismatches, err := s.repo.GetBalanceMismatches(ctx)
+	if err != nil {
+		return model.LedgerReport{}, fmt.Errorf("service:Reconcile: %w", err)
+	}
+	return model.LedgerReport{
+		UnbalancedEntries: unbalanced,
+		BalanceMismatches: mismatches,
+		CheckedAt:         time.Now(),
+	}, nil
+}
+
+// recordJournalEntry validates and stores e inside tx. Services that move money
+// call it from their own WithTransaction callback so the postings and balance
+// updates commit or roll back together with the rest of the operation.
+func recordJournalEntry(ctx context.Context, repo repository.LedgerRepository, tx pgx.Tx, e *model.JournalEntry) error {
+	if err := validateJournalEntry(e); err != nil {
+		return err
+	}
+	if err := repo.InsertJournalEntry(ctx, tx, e); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || isPgError(err, pgForeignKeyViolation) {
+			return ErrAccountNotFound
+		}
+		return fmt.Errorf("service:recordJournalEntry: %w", err)
+	}
+	return nil
+}
+
+func validateJournalEntry(e *model.JournalEntry) error {
+	if len(e.Postings) < 2 {
+		return fmt.Errorf("%w: at least two postings required", ErrInvalidPosting)
+	}
+
+	currency := e.Postings[0].Currency
+	debits, credits := money.New(0, currency), money.New(0, currency)
+	for _, p := range e.Postings {
+		if p.AccountID <= 0 {
+			return fmt.Errorf("%w: missing account", ErrInvalidPosting)
+		}
+		if p.Currency != currency || p.Amount.Currency() != currency {
+			return fmt.Errorf("%w: postings must share one currency", ErrInvalidPosting)
+		}
+		if !p.Amount.IsPositive() {
+			return fmt.Errorf("%w: amount must be positive", ErrInvalidPosting)
+		}
+		var err error
+		switch p.Direction {
+		case model.PostingDebit:
+			debits, err = debits.Add(p.Amount)
+		case model.PostingCredit:
+			credits, err = credits.Add(p.Amount)
+		default:
+			return fmt.Errorf("%w: unknown direction %q", ErrInvalidPosting, p.Direction)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPosting, err)
+		}
+	}
+	if debits != credits {
+		return ErrUnbalancedEntry
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrAccountLocked  = errors.New("account temporarily locked after too many failed logins")
+	ErrLoginThrottled = errors.New("too many failed logins; try again later")
+)
+
+const (
+	// loginFreeFailures failed logins are allowed back to back; each one
+	// after that doubles the wait before the next attempt
+	loginFreeFailures = 2
+	loginBaseDelay    = time.Second
+	loginMaxDelay     = 30 * time.Second
+)
+
+// LoginBlockedError is returned while login attempts are refused. It wraps
+// ErrAccountLocked or ErrLoginThrottled and tells how long to wait.
+type LoginBlockedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string { return e.Err.Error() }
+
+func (e *LoginBlockedError) Unwrap() error { return e.Err }
+
+// LoginThrottlePolicy sets when failed logins lock an account or client IP.
+// A limit of 0 turns locking off for that scope.
+type LoginThrottlePolicy struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	// Window is how long a failure counts towards the limits
+	Window          time.Duration
+	LockoutDuration time.Duration
+}
+
+type LoginAttemptService interface {
+	// Check returns a *LoginBlockedError while the account or the IP is
+	// locked, or while the account waits out its progressive delay
+	Check(ctx context.Context, email, ip string) error
+	// RecordFailure counts a failed login against the account and the IP,
+	// locking either once it reaches its limit
+	RecordFailure(ctx context.Context, email, ip string) error
+	// RecordSuccess clears the account's failures. The IP keeps its count so
+	// one valid account cannot be used to reset guessing at others.
+	RecordSuccess(ctx context.Context, email string) error
+	// Unlock lifts a lockout of the user's account and clears its failures
+	Unlock(ctx context.Context, userID int64) error
+	// PurgeStaleFailures deletes counters that no longer affect logins and
+	// returns how many were removed
+	PurgeStaleFailures(ctx context.Context) (int64, error)
+}
+
+type loginAttemptService struct {
+	repo   repository.LoginAttemptRepository
+	users  repository.UserRepository
+	policy LoginThrottlePolicy
+}
+
+func NewLoginAttemptService(repo repository.LoginAttemptRepository, users repository.UserRepository, policy LoginThrottlePolicy) LoginAttemptService {
+	return &loginAttemptService{repo: repo, users: users, policy: policy}
+}
+
+func (s *loginAttemptService) Check(ctx context.Context, email, ip string) error {
+	failures, err := s.repo.GetLoginFailures(ctx, loginAccountKey(email), loginIPKey(ip))
+	if err != nil {
+		return fmt.Errorf("service:CheckLogin: %w", err)
+	}
+	now := time.Now()
+	var blocked *LoginBlockedError
+	for _, f := range failures {
+		var b *LoginBlockedError
+		switch {
+		case f.LockedUntil != nil && now.Before(*f.LockedUntil):
+			b = &LoginBlockedError{Err: ErrLoginThrottled, RetryAfter: f.LockedUntil.Sub(now)}
+			if f.Scope == model.LoginScopeAccount {
+				b.Err = ErrAccountLocked
+			}
+		case f.Scope == model.LoginScopeAccount && f.LastFailureAt.After(now.Add(-s.policy.Window)):
+			if wait := f.LastFailureAt.Add(loginDelay(f.Failures)).Sub(now); wait > 0 {
+				b = &LoginBlockedError{Err: ErrLoginThrottled, RetryAfter: wait}
+			}
+		}
+		// A locked account is reported over a throttled IP
+		if b != nil && (blocked == nil || b.Err == ErrAccountLocked) {
+			blocked = b
+		}
+	}
+	if blocked != nil {
+		return blocked
+	}
+	return nil
+}
+
+func (s *loginAttemptService) RecordFailure(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	if err := s.recordFailure(ctx, model.LoginScopeAccount, loginAccountKey(email), s.policy.MaxAccountFailures, now); err != nil {
+		return err
+	}
+	key := loginIPKey(ip)
+	if key == "" {
+		return nil
+	}
+	return s.recordFailure(ctx, model.LoginScopeIP, key, s.policy.MaxIPFailures, now)
+}
+
+func (s *loginAttemptService) recordFailure(ctx context.Context, scope, key string, limit int, now time.Time) error {
+	f, err := s.repo.RecordLoginFailure(ctx, scope, key, now, now.Add(-s.policy.Window))
+	if err != nil {
+		return fmt.Errorf("service:RecordLoginFailure: %w", err)
+	}
+	if limit <= 0 || f.Failures < limit {
+		return nil
+	}
+	until := now.Add(s.policy.LockoutDuration)
+	if err := s.repo.LockLogin(ctx, scope, key, until); err != nil {
+		return fmt.Errorf("service:LockLogin: %w", err)
+	}
+	log.Printf("login throttle: %s %q locked until %s after %d failed logins", scope, key, until.Format(time.RFC3339), f.Failures)
+	return nil
+}
+
+func (s *loginAttemptService) RecordSuccess(ctx context.Context, email string) error {
+	if _, err := s.repo.DeleteLoginFailures(ctx, model.LoginScopeAccount, loginAccountKey(email)); err != nil {
+		return fmt.Errorf("service:RecordLoginSuccess: %w", err)
+	}
+	return nil
+}
+
+func (s *loginAttemptService) Unlock(ctx context.Context, userID int64) error {
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("service:UnlockLogin: %w", err)
+	}
+	if _, err := s.repo.DeleteLoginFailures(ctx, model.LoginScopeAccount, loginAccountKey(u.Email)); err != nil {
+		return fmt.Errorf("service:UnlockLogin: %w", err)
+	}
+	return nil
+}
+
+func (s *loginAttemptService) PurgeStaleFailures(ctx context.Context) (int64, error) {
+	now := time.Now()
+	n, err := s.repo.DeleteStaleLoginFailures(ctx, now.Add(-s.policy.Window), now)
+	if err != nil {
+		return 0, fmt.Errorf("service:PurgeStaleFailures: %w", err)
+	}
+	return n, nil
+}
+
+// loginAccountKey normalizes the email so that case variations share a
+// counter
+func loginAccountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginIPKey returns the IP in canonical form, or "" when it is not an IP
+// address and so cannot be counted
+func loginIPKey(ip string) string {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return ""
+	}
+	return addr.Unmap().WithZone("").String()
+}
+
+// loginDelay is how long to wait after the given number of failures
+func loginDelay(failures int) time.Duration {
+	n := failures - loginFreeFailures
+	if n <= 0 {
+		return 0
+	}
+	d := loginBaseDelay
+	for i := 1; i < n && d < loginMaxDelay; i++ {
+		d *= 2
+	}
+	if d > loginMaxDelay {
+		d = loginMaxDelay
+	}
+	return d
+}
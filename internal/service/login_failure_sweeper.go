@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunLoginFailureSweeper deletes stale failed login counters every interval
+// until ctx is cancelled. Counters outside the window are ignored anyway;
+// sweeping keeps one row per guessed email from piling up.
+func RunLoginFailureSweeper(ctx context.Context, svc LoginAttemptService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := svc.PurgeStaleFailures(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("login failure sweeper: %v", err)
+				}
+				continue
+			}
+			if n > 0 {
+				log.Printf("login failure sweeper: purged %d stale counters", n)
+			}
+		}
+	}
+}
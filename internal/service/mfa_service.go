@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/common/totp"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnrolled      = errors.New("two-factor enrollment not started")
+	ErrMFANotEnabled       = errors.New("two-factor authentication not enabled")
+	ErrInvalidMFACode      = errors.New("invalid two-factor code")
+	ErrInvalidMFAChallenge = errors.New("MFA token invalid or expired")
+	ErrMFALocked           = errors.New("too many invalid two-factor codes")
+)
+
+const (
+	mfaChallengeTTL         = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+	mfaChallengeTokenLength = 32
+	// mfaCodeMaxFailures wrong codes in a row outside a login challenge lock
+	// the user's codes for mfaCodeLockout after the last one
+	mfaCodeMaxFailures = 5
+	mfaCodeLockout     = 15 * time.Minute
+	// totpSkew accepts codes from one step either side to absorb clock drift
+	totpSkew          = 1
+	recoveryCodeCount = 10
+	// recoveryCodeLength counts base32 characters, i.e. 50 bits per code
+	recoveryCodeLength = 10
+)
+
+type MFAService interface {
+	// BeginTOTPEnrollment creates a pending secret and returns it with its
+	// otpauth:// URI. Calling it again before confirming replaces the secret.
+	BeginTOTPEnrollment(ctx context.Context, userID int64) (secret, uri string, err error)
+	// ConfirmTOTPEnrollment enables TOTP once the user proves they can
+	// generate codes, and returns one-time recovery codes
+	ConfirmTOTPEnrollment(ctx context.Context, userID int64, code string) ([]string, error)
+	// DisableTOTP turns TOTP off; it requires a current code or a recovery code
+	// and is subject to the same lockout as VerifyCode
+	DisableTOTP(ctx context.Context, userID int64, code string) error
+	IsEnabled(ctx context.Context, userID int64) (bool, error)
+	// VerifyCode checks a TOTP or recovery code of a user with TOTP enabled.
+	// After a few wrong codes in a row it returns ErrMFALocked for a while.
+	VerifyCode(ctx context.Context, userID int64, code string) error
+	// CreateChallenge opens the second step of a login whose password was
+	// already verified
+	CreateChallenge(ctx context.Context, userID int64) (string, time.Time, error)
+	// VerifyChallenge completes a login challenge with a TOTP or recovery code
+	// and returns the user it belongs to. On ErrInvalidMFACode the user is
+	// returned as well so the failure can be counted against the account.
+	VerifyChallenge(ctx context.Context, token, code string) (int64, error)
+}
+
+type mfaService struct {
+	repo   repository.MFARepository
+	users  repository.UserRepository
+	issuer string
+}
+
+func NewMFAService(repo repository.MFARepository, users repository.UserRepository, issuer string) MFAService {
+	return &mfaService{repo: repo, users: users, issuer: issuer}
+}
+
+func (s *mfaService) BeginTOTPEnrollment(ctx context.Context, userID int64) (string, string, error) {
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", ErrUserNotFound
+		}
+		return "", "", fmt.Errorf("service:BeginTOTPEnrollment: %w", err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("service:BeginTOTPEnrollment: %w", err)
+	}
+	if err := s.repo.UpsertPendingTOTP(ctx, userID, secret); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", ErrMFAAlreadyEnabled
+		}
+		return "", "", fmt.Errorf("service:BeginTOTPEnrollment: %w", err)
+	}
+	return secret, totp.URI(s.issuer, u.Email, secret), nil
+}
+
+func (s *mfaService) ConfirmTOTPEnrollment(ctx context.Context, userID int64, code string) ([]string, error) {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, fmt.Errorf("service:ConfirmTOTPEnrollment: %w", err)
+	}
+	if t.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		c, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("service:ConfirmTOTPEnrollment: %w", err)
+		}
+		codes[i] = c
+		hashes[i] = digest(normalizeRecoveryCode(c))
+	}
+	if err := s.repo.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, fmt.Errorf("service:ConfirmTOTPEnrollment: %w", err)
+	}
+	return codes, nil
+}
+
+func (s *mfaService) DisableTOTP(ctx context.Context, userID int64, code string) error {
+	t, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCode(ctx, t, code); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("service:DisableTOTP: %w", err)
+	}
+	return nil
+}
+
+func (s *mfaService) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("service:IsEnabled: %w", err)
+	}
+	return t.Enabled, nil
+}
+
+func (s *mfaService) VerifyCode(ctx context.Context, userID int64, code string) error {
+	t, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.checkCode(ctx, t, code)
+}
+
+func (s *mfaService) CreateChallenge(ctx context.Context, userID int64) (string, time.Time, error) {
+	token, err := randomString(mfaChallengeTokenLength, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	ch := model.MFAChallenge{
+		UserID:    userID,
+		TokenHash: digest(token),
+		ExpiresAt: now.Add(mfaChallengeTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.InsertMFAChallenge(ctx, &ch); err != nil {
+		return "", time.Time{}, fmt.Errorf("service:CreateChallenge: %w", err)
+	}
+	return token, ch.ExpiresAt, nil
+}
+
+// VerifyChallenge allows a handful of attempts per challenge; after that the
+// user has to start over with their password
+func (s *mfaService) VerifyChallenge(ctx context.Context, token, code string) (int64, error) {
+	hash := digest(token)
+	ch, err := s.repo.GetMFAChallenge(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrInvalidMFAChallenge
+		}
+		return 0, fmt.Errorf("service:VerifyChallenge: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(ch.TokenHash), []byte(hash)) != 1 {
+		return 0, ErrInvalidMFAChallenge
+	}
+	if !time.Now().Before(ch.ExpiresAt) {
+		_ = s.repo.DeleteMFAChallenge(ctx, hash)
+		return 0, ErrInvalidMFAChallenge
+	}
+
+	attempts, err := s.repo.IncrementMFAChallengeAttempts(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrInvalidMFAChallenge
+		}
+		return 0, fmt.Errorf("service:VerifyChallenge: %w", err)
+	}
+	if attempts > mfaChallengeMaxAttempts {
+		_ = s.repo.DeleteMFAChallenge(ctx, hash)
+		return 0, ErrInvalidMFAChallenge
+	}
+
+	t, err := s.enabledTOTP(ctx, ch.UserID)
+	if err != nil {
+		if errors.Is(err, ErrMFANotEnabled) {
+			// MFA was switched off after the password step; start over
+			_ = s.repo.DeleteMFAChallenge(ctx, hash)
+			return 0, ErrInvalidMFAChallenge
+		}
+		return 0, err
+	}
+	if err := s.verifyCode(ctx, t, code); err != nil {
+		if attempts == mfaChallengeMaxAttempts {
+			_ = s.repo.DeleteMFAChallenge(ctx, hash)
+		}
+		if errors.Is(err, ErrInvalidMFACode) {
+			return ch.UserID, err
+		}
+		return 0, err
+	}
+
+	if err := s.repo.DeleteMFAChallenge(ctx, hash); err != nil {
+		return 0, fmt.Errorf("service:VerifyChallenge: %w", err)
+	}
+	return ch.UserID, nil
+}
+
+func (s *mfaService) enabledTOTP(ctx context.Context, userID int64) (model.UserTOTP, error) {
+	t, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserTOTP{}, ErrMFANotEnabled
+		}
+		return model.UserTOTP{}, fmt.Errorf("service:enabledTOTP: %w", err)
+	}
+	if !t.Enabled {
+		return model.UserTOTP{}, ErrMFANotEnabled
+	}
+	return t, nil
+}
+
+// checkCode is verifyCode with a per-user limit on wrong codes. Login
+// challenges have their own limit and are counted against the account.
+// The attempt is counted before the code is checked, like challenge
+// attempts, so parallel guesses cannot slip past the limit.
+func (s *mfaService) checkCode(ctx context.Context, t model.UserTOTP, code string) error {
+	now := time.Now()
+	if err := s.repo.ReserveTOTPAttempt(ctx, t.UserID, now, now.Add(-mfaCodeLockout), mfaCodeMaxFailures); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMFALocked
+		}
+		return fmt.Errorf("service:checkCode: %w", err)
+	}
+	if err := s.verifyCode(ctx, t, code); err != nil {
+		return err
+	}
+	if err := s.repo.ResetTOTPFailures(ctx, t.UserID); err != nil {
+		return fmt.Errorf("service:checkCode: %w", err)
+	}
+	return nil
+}
+
+// verifyCode accepts either a TOTP code, which is then burned for its time
+// step, or an unused recovery code, which is burned for good
+func (s *mfaService) verifyCode(ctx context.Context, t model.UserTOTP, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		if err := s.repo.AdvanceTOTPStep(ctx, t.UserID, step); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrInvalidMFACode
+			}
+			return fmt.Errorf("service:verifyCode: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.repo.UseRecoveryCode(ctx, t.UserID, digest(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidMFACode
+		}
+		return fmt.Errorf("service:verifyCode: %w", err)
+	}
+	return nil
+}
+
+// generateRecoveryCode returns a code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	s, err := randomString(8, enc.EncodeToString)
+	if err != nil {
+		return "", err
+	}
+	s = strings.ToLower(s[:recoveryCodeLength])
+	return s[:recoveryCodeLength/2] + "-" + s[recoveryCodeLength/2:], nil
+}
+
+// normalizeRecoveryCode makes codes case and separator insensitive
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrOAuthClientNotFound = errors.New("oauth client not found")
+	ErrInvalidClient       = errors.New("client authentication failed")
+	ErrInvalidRedirectURI  = errors.New("redirect uri not registered for client")
+	ErrInvalidScope        = errors.New("scope unknown or not allowed for client")
+	ErrPKCERequired        = errors.New("code_challenge with code_challenge_method S256 required")
+	ErrInvalidGrant        = errors.New("authorization code or refresh token invalid, expired or already used")
+)
+
+const (
+	oauthCodeTTL          = 10 * time.Minute
+	oauthCodeLength       = 32
+	oauthClientIDLength   = 16
+	oauthSecretLength     = 32
+	pkceMethodS256        = "S256"
+	pkceVerifierMinLength = 43
+	pkceVerifierMaxLength = 128
+)
+
+// AuthorizationRequest is what a client asks the user to consent to. Scope is
+// space separated as in RFC 6749; RedirectURI may be left out when the client
+// has exactly one registered.
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// AuthorizationGrant is a validated AuthorizationRequest
+type AuthorizationGrant struct {
+	Client      model.OAuthClient
+	RedirectURI string
+	Scopes      []string
+}
+
+type OAuthService interface {
+	// RegisterClient adds a third-party app. Confidential clients get a
+	// secret, returned here once; public clients get none.
+	RegisterClient(ctx context.Context, name string, redirectURIs, scopes []string, confidential bool) (model.OAuthClient, string, error)
+	GetAllClients(ctx context.Context) ([]model.OAuthClient, error)
+	// DeleteClient removes the client and revokes every grant users gave it
+	DeleteClient(ctx context.Context, clientID string) error
+
+	// ValidateAuthorization checks a request before the user is asked for
+	// consent
+	ValidateAuthorization(ctx context.Context, req AuthorizationRequest) (AuthorizationGrant, error)
+	// Approve records the user's consent and returns the authorization code
+	// for the client's redirect URI
+	Approve(ctx context.Context, userID int64, req AuthorizationRequest) (string, AuthorizationGrant, error)
+
+	// AuthenticateClient checks the credentials the token, introspection and
+	// revocation endpoints are called with. Public clients send no secret.
+	AuthenticateClient(ctx context.Context, clientID, secret string) (model.OAuthClient, error)
+	// ExchangeCode redeems an authorization code with its PKCE verifier and
+	// opens a grant session; the returned refresh token carries the user and
+	// scopes the access token is issued for
+	ExchangeCode(ctx context.Context, client model.OAuthClient, code, redirectURI, verifier string, info model.ClientInfo) (model.RefreshToken, error)
+	// RefreshGrant rotates a refresh token of one of the client's grants
+	RefreshGrant(ctx context.Context, client model.OAuthClient, token string, info model.ClientInfo) (model.RefreshToken, error)
+	// RevokeRefreshToken ends the grant a refresh token of the client belongs
+	// to. Unknown tokens and tokens of other clients are ignored.
+	RevokeRefreshToken(ctx context.Context, client model.OAuthClient, token string) error
+}
+
+type oauthService struct {
+	repo  repository.OAuthRepository
+	users UserService
+}
+
+// NewOAuthService keeps grants as sessions of users, so their refresh tokens
+// rotate, expire and get revoked like those of our own clients
+func NewOAuthService(repo repository.OAuthRepository, users UserService) OAuthService {
+	return &oauthService{repo: repo, users: users}
+}
+
+func (s *oauthService) RegisterClient(ctx context.Context, name string, redirectURIs, scopes []string, confidential bool) (model.OAuthClient, string, error) {
+	for _, uri := range redirectURIs {
+		if !validRedirectURI(uri) {
+			return model.OAuthClient{}, "", ErrInvalidRedirectURI
+		}
+	}
+	if len(redirectURIs) == 0 {
+		return model.OAuthClient{}, "", ErrInvalidRedirectURI
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(model.KnownScopes, scope) {
+			return model.OAuthClient{}, "", ErrInvalidScope
+		}
+	}
+	if len(scopes) == 0 {
+		return model.OAuthClient{}, "", ErrInvalidScope
+	}
+
+	clientID, err := randomString(oauthClientIDLength, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return model.OAuthClient{}, "", err
+	}
+	var secret string
+	if confidential {
+		if secret, err = randomString(oauthSecretLength, base64.RawURLEncoding.EncodeToString); err != nil {
+			return model.OAuthClient{}, "", err
+		}
+	}
+	c := model.OAuthClient{
+		ClientID:     clientID,
+		Name:         strings.TrimSpace(name),
+		RedirectURIs: slices.Compact(slices.Sorted(slices.Values(redirectURIs))),
+		Scopes:       slices.Compact(slices.Sorted(slices.Values(scopes))),
+		CreatedAt:    time.Now(),
+	}
+	if secret != "" {
+		c.SecretHash = digest(secret)
+	}
+	if err := s.repo.InsertClient(ctx, &c); err != nil {
+		return model.OAuthClient{}, "", fmt.Errorf("service:RegisterClient: %w", err)
+	}
+	return c, secret, nil
+}
+
+func (s *oauthService) GetAllClients(ctx context.Context) ([]model.OAuthClient, error) {
+	clients, err := s.repo.GetAllClients(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service:GetAllClients: %w", err)
+	}
+	return clients, nil
+}
+
+func (s *oauthService) DeleteClient(ctx context.Context, clientID string) error {
+	if err := s.repo.DeleteClient(ctx, clientID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrOAuthClientNotFound
+		}
+		return fmt.Errorf("service:DeleteClient: %w", err)
+	}
+	return nil
+}
+
+func (s *oauthService) ValidateAuthorization(ctx context.Context, req AuthorizationRequest) (AuthorizationGrant, error) {
+	c, err := s.client(ctx, req.ClientID)
+	if err != nil {
+		return AuthorizationGrant{}, err
+	}
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(c.RedirectURIs) == 1 {
+		redirectURI = c.RedirectURIs[0]
+	}
+	if !c.AllowsRedirectURI(redirectURI) {
+		return AuthorizationGrant{}, ErrInvalidRedirectURI
+	}
+	if req.CodeChallengeMethod != pkceMethodS256 || !validPKCEValue(req.CodeChallenge) {
+		return AuthorizationGrant{}, ErrPKCERequired
+	}
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		return AuthorizationGrant{}, ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !c.AllowsScope(scope) {
+			return AuthorizationGrant{}, ErrInvalidScope
+		}
+	}
+	return AuthorizationGrant{
+		Client:      c,
+		RedirectURI: redirectURI,
+		Scopes:      slices.Compact(slices.Sorted(slices.Values(scopes))),
+	}, nil
+}
+
+func (s *oauthService) Approve(ctx context.Context, userID int64, req AuthorizationRequest) (string, AuthorizationGrant, error) {
+	grant, err := s.ValidateAuthorization(ctx, req)
+	if err != nil {
+		return "", AuthorizationGrant{}, err
+	}
+	code, err := randomString(oauthCodeLength, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", AuthorizationGrant{}, err
+	}
+	now := time.Now()
+	ac := model.OAuthAuthorizationCode{
+		CodeHash:            digest(code),
+		ClientID:            grant.Client.ClientID,
+		UserID:              userID,
+		RedirectURI:         grant.RedirectURI,
+		RedirectURIProvided: req.RedirectURI != "",
+		Scopes:              grant.Scopes,
+		CodeChallenge:       req.CodeChallenge,
+		ExpiresAt:           now.Add(oauthCodeTTL),
+		CreatedAt:           now,
+	}
+	if err := s.repo.InsertAuthorizationCode(ctx, &ac); err != nil {
+		return "", AuthorizationGrant{}, fmt.Errorf("service:Approve: %w", err)
+	}
+	return code, grant, nil
+}
+
+func (s *oauthService) AuthenticateClient(ctx context.Context, clientID, secret string) (model.OAuthClient, error) {
+	c, err := s.client(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, ErrOAuthClientNotFound) {
+			return model.OAuthClient{}, ErrInvalidClient
+		}
+		return model.OAuthClient{}, err
+	}
+	if !c.Confidential() {
+		if secret != "" {
+			return model.OAuthClient{}, ErrInvalidClient
+		}
+		return c, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(digest(secret)), []byte(c.SecretHash)) != 1 {
+		return model.OAuthClient{}, ErrInvalidClient
+	}
+	return c, nil
+}
+
+// ExchangeCode burns the code on first use, so a code presented with a
+// wrong verifier or redirect URI cannot be retried. As RFC 6749 section 4.1.3
+// requires, the redirect URI must be sent again if the authorization request
+// included it.
+func (s *oauthService) ExchangeCode(ctx context.Context, client model.OAuthClient, code, redirectURI, verifier string, info model.ClientInfo) (model.RefreshToken, error) {
+	ac, err := s.repo.ClaimAuthorizationCode(ctx, digest(code), time.Now())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.RefreshToken{}, ErrInvalidGrant
+		}
+		return model.RefreshToken{}, fmt.Errorf("service:ExchangeCode: %w", err)
+	}
+	if ac.ClientID != client.ClientID {
+		return model.RefreshToken{}, ErrInvalidGrant
+	}
+	if (ac.RedirectURIProvided || redirectURI != "") && redirectURI != ac.RedirectURI {
+		return model.RefreshToken{}, ErrInvalidGrant
+	}
+	if !validPKCEValue(verifier) || subtle.ConstantTimeCompare([]byte(pkceChallenge(verifier)), []byte(ac.CodeChallenge)) != 1 {
+		return model.RefreshToken{}, ErrInvalidGrant
+	}
+
+	rt, err := s.users.GrantRefreshToken(ctx, ac.UserID, ac.Scopes, grantClientInfo(client, info))
+	if err != nil {
+		return model.RefreshToken{}, fmt.Errorf("service:ExchangeCode: %w", err)
+	}
+	return rt, nil
+}
+
+func (s *oauthService) RefreshGrant(ctx context.Context, client model.OAuthClient, token string, info model.ClientInfo) (model.RefreshToken, error) {
+	rt, err := s.users.RotateRefreshToken(ctx, token, grantClientInfo(client, info))
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrRefreshTokenReused) {
+			return model.RefreshToken{}, ErrInvalidGrant
+		}
+		return model.RefreshToken{}, fmt.Errorf("service:RefreshGrant: %w", err)
+	}
+	return rt, nil
+}
+
+func (s *oauthService) RevokeRefreshToken(ctx context.Context, client model.OAuthClient, token string) error {
+	if err := s.users.RevokeGrantRefreshToken(ctx, client.ClientID, token); err != nil {
+		return fmt.Errorf("service:oauth:RevokeRefreshToken: %w", err)
+	}
+	return nil
+}
+
+func (s *oauthService) client(ctx context.Context, clientID string) (model.OAuthClient, error) {
+	if clientID == "" {
+		return model.OAuthClient{}, ErrOAuthClientNotFound
+	}
+	c, err := s.repo.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.OAuthClient{}, ErrOAuthClientNotFound
+		}
+		return model.OAuthClient{}, fmt.Errorf("service:oauth:client: %w", err)
+	}
+	return c, nil
+}
+
+// grantClientInfo names the grant session after the app, which is what the
+// user sees in their session list
+func grantClientInfo(client model.OAuthClient, info model.ClientInfo) model.ClientInfo {
+	info.OAuthClientID = client.ClientID
+	info.DeviceLabel = client.Name
+	return info
+}
+
+// pkceChallenge derives the S256 code challenge of a verifier (RFC 7636)
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// validPKCEValue accepts code verifiers and S256 challenges: 43 to 128
+// unreserved URI characters
+func validPKCEValue(v string) bool {
+	if len(v) < pkceVerifierMinLength || len(v) > pkceVerifierMaxLength {
+		return false
+	}
+	for _, r := range v {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case r == '-', r == '.', r == '_', r == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validRedirectURI accepts absolute https URIs without a fragment, and plain
+// http only on the loopback interface for apps running on the user's machine
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Fragment != "" || u.User != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/yusufziyrek/bank-app/common/password"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrWeakPassword     = errors.New("password does not meet the password policy")
+	ErrBreachedPassword = errors.New("password appears in a known data breach")
+	ErrPasswordReused   = errors.New("password was used recently")
+)
+
+// PasswordPolicy is applied whenever a password is set: on registration,
+// on a password change and on a reset
+type PasswordPolicy struct {
+	Rules password.Policy
+	// History rejects the current password and the History-1 before it; 0
+	// turns the check off
+	History int
+	// Breached may be nil
+	Breached *password.BreachedList
+}
+
+// check validates pwd as the new password of u. u.ID is 0 while the user is
+// being created, which skips the history.
+func (p PasswordPolicy) check(ctx context.Context, users repository.UserRepository, u model.User, pwd string) error {
+	if v := p.Rules.Check(pwd, u.FullName, emailLocalPart(u.Email)); len(v) > 0 {
+		return fmt.Errorf("%w: %s", ErrWeakPassword, strings.Join(v, "; "))
+	}
+	if p.Breached.Contains(pwd) {
+		return ErrBreachedPassword
+	}
+	if p.History <= 0 || u.ID == 0 {
+		return nil
+	}
+
+	hashes := []string{u.PasswordHash}
+	if p.History > 1 {
+		older, err := users.GetPasswordHistory(ctx, u.ID, p.History-1)
+		if err != nil {
+			return fmt.Errorf("service:checkPassword: %w", err)
+		}
+		hashes = append(hashes, older...)
+	}
+	for _, h := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(h), []byte(pwd)) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// trimHistory drops earlier hashes the policy no longer compares against.
+// The password has already changed, so a failure is only logged.
+func (p PasswordPolicy) trimHistory(ctx context.Context, users repository.UserRepository, userID int64) {
+	if err := users.TrimPasswordHistory(ctx, userID, p.historyKeep()); err != nil {
+		log.Printf("password history: trimming for user %d failed: %v", userID, err)
+	}
+}
+
+// historyKeep is how many earlier hashes must be kept; together with the
+// current one they make up the History passwords that may not be reused
+func (p PasswordPolicy) historyKeep() int {
+	if p.History < 1 {
+		return 0
+	}
+	return p.History - 1
+}
+
+func emailLocalPart(email string) string {
+	if i := strings.LastIndexByte(email, '@'); i >= 0 {
+		return email[:i]
+	}
+	return email
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/common/mailer"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidResetToken = errors.New("password reset token invalid or expired")
+
+const (
+	passwordResetTTL         = 30 * time.Minute
+	passwordResetTokenLength = 32
+)
+
+type PasswordResetService interface {
+	// RequestReset emails a reset link to the address if it belongs to an
+	// active user. It reports success either way so callers cannot probe
+	// which addresses are registered.
+	RequestReset(ctx context.Context, email string) error
+	// ResetPassword sets a new password with a token from RequestReset and
+	// ends every session of the user
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type passwordResetService struct {
+	repo      repository.PasswordResetRepository
+	users     repository.UserRepository
+	passwords PasswordPolicy
+	mail      mailer.Mailer
+	resetURL  string
+}
+
+// NewPasswordResetService sends links of the form resetURL?token=...; the
+// page behind it is expected to post the token to the reset endpoint
+func NewPasswordResetService(repo repository.PasswordResetRepository, users repository.UserRepository, passwords PasswordPolicy, mail mailer.Mailer, resetURL string) PasswordResetService {
+	return &passwordResetService{repo: repo, users: users, passwords: passwords, mail: mail, resetURL: resetURL}
+}
+
+func (s *passwordResetService) RequestReset(ctx context.Context, email string) error {
+	u, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("service:RequestReset: %w", err)
+	}
+	// Service accounts have no password to reset
+	if !u.IsActive || u.ServiceAccount {
+		return nil
+	}
+
+	token, err := randomString(passwordResetTokenLength, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	rt := model.PasswordResetToken{
+		UserID:    u.ID,
+		TokenHash: digest(token),
+		ExpiresAt: now.Add(passwordResetTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.InsertPasswordResetToken(ctx, &rt); err != nil {
+		return fmt.Errorf("service:RequestReset: %w", err)
+	}
+
+	msg := mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Use the link below to choose a new password. It works once and expires in %d minutes.\n\n"+
+			"%s\n\n"+
+			"If you did not ask for this, you can ignore this email; your password stays the same.\n",
+			u.FullName, int(passwordResetTTL.Minutes()), s.resetLink(token)),
+	}
+	if err := s.mail.Send(ctx, msg); err != nil {
+		// Failing the request would tell the caller the address exists
+		log.Printf("password reset: sending mail to user %d failed: %v", u.ID, err)
+	}
+	return nil
+}
+
+func (s *passwordResetService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	tokenHash := digest(token)
+	// Look the user up first so the policy can be checked without using up
+	// the token on a rejected password
+	userID, err := s.repo.GetPasswordResetUser(ctx, tokenHash, time.Now())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("service:ResetPassword: %w", err)
+	}
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("service:ResetPassword: %w", err)
+	}
+	if err := s.passwords.check(ctx, s.users, u, newPassword); err != nil {
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("service:hashPwd: %w", err)
+	}
+	if _, err := s.repo.ResetPassword(ctx, tokenHash, string(hashed), time.Now()); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("service:ResetPassword: %w", err)
+	}
+	s.passwords.trimHistory(ctx, s.users, userID)
+	return nil
+}
+
+func (s *passwordResetService) resetLink(token string) string {
+	return linkWithToken(s.resetURL, token)
+}
+
+// linkWithToken adds token to base as the "token" query parameter
+func linkWithToken(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL SQLSTATE codes the service layer maps to domain errors
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// isPgError reports whether err wraps a PostgreSQL error with the given SQLSTATE code
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunRefreshTokenSweeper deletes expired refresh tokens and denylist entries of
+// expired access tokens every interval until ctx is cancelled. Expired tokens
+// are already rejected on use; the sweeper only keeps the tables from growing.
+func RunRefreshTokenSweeper(ctx context.Context, svc UserService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := svc.PurgeExpiredRefreshTokens(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("refresh token sweeper: %v", err)
+				}
+				continue
+			}
+			if n > 0 {
+				log.Printf("refresh token sweeper: purged %d expired tokens", n)
+			}
+			n, err = svc.PurgeExpiredRevokedAccessTokens(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("refresh token sweeper: %v", err)
+				}
+				continue
+			}
+			if n > 0 {
+				log.Printf("refresh token sweeper: purged %d revoked access tokens", n)
+			}
+		}
+	}
+}
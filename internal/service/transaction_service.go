@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/repository"
+)
+
+var (
+	ErrInvalidAmount     = errors.New("amount must be greater than zero")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrCurrencyMismatch  = errors.New("currency does not match account currency")
+	ErrSameAccount       = errors.New("source and destination accounts are the same")
+	ErrRecipientNotFound = errors.New("recipient account not found")
+)
+
+// cashAccountPrefix names the bank-owned settlement account that is the
+// counterparty of every deposit and withdrawal in a currency, e.g. "SYS-CASH-TRY"
+const cashAccountPrefix = "SYS-CASH-"
+
+type TransactionService interface {
+	// Deposit credits amount to the user's account. An amount without a
+	// currency is taken to be in the account's currency.
+	Deposit(ctx context.Context, userID, accountID int64, amount money.Money, description string) (model.Transaction, model.Account, error)
+	// Withdraw debits amount from the user's account, rejecting overdrafts
+	Withdraw(ctx context.Context, userID, accountID int64, amount money.Money, description string) (model.Transaction, model.Account, error)
+	// Transfer moves amount from the user's account to the account with the
+	// given number and returns the transfer with the updated source account
+	Transfer(ctx context.Context, userID, fromAccountID int64, toAccountNumber string, amount money.Money, description string) (model.Transfer, model.Account, error)
+}
+
+type transactionService struct {
+	accounts     repository.AccountRepository
+	ledger       repository.LedgerRepository
+	transactions repository.TransactionRepository
+}
+
+func NewTransactionService(a repository.AccountRepository, l repository.LedgerRepository, t repository.TransactionRepository) TransactionService {
+	return &transactionService{accounts: a, ledger: l, transactions: t}
+}
+
+func (s *transactionService) Deposit(ctx context.Context, userID, accountID int64, amount money.Money, description string) (model.Transaction, model.Account, error) {
+	return s.moveCash(ctx, userID, accountID, amount, description, model.TransactionDeposit)
+}
+
+func (s *transactionService) Withdraw(ctx context.Context, userID, accountID int64, amount money.Money, description string) (model.Transaction, model.Account, error) {
+	return s.moveCash(ctx, userID, accountID, amount, description, model.TransactionWithdraw)
+}
+
+// moveCash books a deposit or withdrawal against the cash account. The customer
+// account row stays locked from the balance check until the ledger entry and
+// the history row are committed, so concurrent withdrawals cannot overdraw it.
+func (s *transactionService) moveCash(ctx context.Context, userID, accountID int64, amount money.Money, description, txType string) (model.Transaction, model.Account, error) {
+	if !amount.IsPositive() {
+		return model.Transaction{}, model.Account{}, ErrInvalidAmount
+	}
+
+	var t model.Transaction
+	var account model.Account
+	err := s.accounts.WithTransaction(ctx, func(tx pgx.Tx) error {
+		a, err := s.lockOwnedAccount(ctx, tx, userID, accountID)
+		if err != nil {
+			return err
+		}
+		amt, err := inAccountCurrency(amount, a)
+		if err != nil {
+			return err
+		}
+
+		if txType == model.TransactionWithdraw {
+			if cmp, _ := a.Balance.Cmp(amt); cmp < 0 {
+				return ErrInsufficientFunds
+			}
+		}
+
+		cash, err := s.accounts.EnsureSystemAccount(ctx, tx, cashAccountPrefix+a.Currency, a.Currency)
+		if err != nil {
+			return fmt.Errorf("service:cashAccount: %w", err)
+		}
+
+		// Deposits move money from the cash account into the customer account, withdrawals the other way
+		from, to, signed := cash.ID, a.ID, amt
+		if txType == model.TransactionWithdraw {
+			from, to, signed = a.ID, cash.ID, amt.Neg()
+		}
+		entry := model.JournalEntry{
+			Description: txType,
+			Postings: []model.Posting{
+				{AccountID: from, Direction: model.PostingDebit, Amount: amt, Currency: a.Currency},
+				{AccountID: to, Direction: model.PostingCredit, Amount: amt, Currency: a.Currency},
+			},
+		}
+		if err := recordJournalEntry(ctx, s.ledger, tx, &entry); err != nil {
+			return err
+		}
+
+		t = model.Transaction{
+			AccountID:      a.ID,
+			Amount:         signed,
+			Type:           txType,
+			Description:    description,
+			JournalEntryID: entry.ID,
+		}
+		if err := s.transactions.InsertTransaction(ctx, tx, &t); err != nil {
+			return fmt.Errorf("service:InsertTransaction: %w", err)
+		}
+
+		a.Balance, err = a.Balance.Add(signed)
+		if err != nil {
+			return fmt.Errorf("service:balance: %w", err)
+		}
+		account = a
+		return nil
+	})
+	if err != nil {
+		return model.Transaction{}, model.Account{}, err
+	}
+	return t, account, nil
+}
+
+func (s *transactionService) Transfer(ctx context.Context, userID, fromAccountID int64, toAccountNumber string, amount money.Money, description string) (model.Transfer, model.Account, error) {
+	if !amount.IsPositive() {
+		return model.Transfer{}, model.Account{}, ErrInvalidAmount
+	}
+
+	// Resolve the recipient before opening the transaction so that both rows
+	// can be locked by ID below
+	recipient, err := s.accounts.GetAccountByNumber(ctx, toAccountNumber)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Transfer{}, model.Account{}, ErrRecipientNotFound
+		}
+		return model.Transfer{}, model.Account{}, fmt.Errorf("service:GetRecipient: %w", err)
+	}
+	if recipient.UserID == 0 {
+		// System accounts are never valid transfer targets
+		return model.Transfer{}, model.Account{}, ErrRecipientNotFound
+	}
+	if recipient.ID == fromAccountID {
+		return model.Transfer{}, model.Account{}, ErrSameAccount
+	}
+
+	var t model.Transfer
+	var source model.Account
+	err = s.accounts.WithTransaction(ctx, func(tx pgx.Tx) error {
+		from, to, err := s.lockTransferAccounts(ctx, tx, fromAccountID, recipient.ID)
+		if err != nil {
+			return err
+		}
+		if from.UserID != userID {
+			return ErrAccountNotFound
+		}
+		if !from.IsActive {
+			return ErrAccountClosed
+		}
+		if !to.IsActive {
+			return ErrAccountClosed
+		}
+		if from.Currency != to.Currency {
+			return ErrCurrencyMismatch
+		}
+		amt, err := inAccountCurrency(amount, from)
+		if err != nil {
+			return err
+		}
+		if cmp, _ := from.Balance.Cmp(amt); cmp < 0 {
+			return ErrInsufficientFunds
+		}
+
+		entry := model.JournalEntry{
+			Description: model.TransactionTransfer,
+			Postings: []model.Posting{
+				{AccountID: from.ID, Direction: model.PostingDebit, Amount: amt, Currency: from.Currency},
+				{AccountID: to.ID, Direction: model.PostingCredit, Amount: amt, Currency: from.Currency},
+			},
+		}
+		if err := recordJournalEntry(ctx, s.ledger, tx, &entry); err != nil {
+			return err
+		}
+
+		t = model.Transfer{
+			FromAccountID:  from.ID,
+			ToAccountID:    to.ID,
+			Amount:         amt,
+			Description:    description,
+			JournalEntryID: entry.ID,
+		}
+		if err := s.transactions.InsertTransfer(ctx, tx, &t); err != nil {
+			return fmt.Errorf("service:InsertTransfer: %w", err)
+		}
+
+		// One history row per side, linked through the transfer ID
+		t.Transactions = []model.Transaction{
+			{AccountID: from.ID, Amount: amt.Neg()},
+			{AccountID: to.ID, Amount: amt},
+		}
+		for i := range t.Transactions {
+			row := &t.Transactions[i]
+			row.Type = model.TransactionTransfer
+			row.Description = description
+			row.JournalEntryID = entry.ID
+			row.TransferID = &t.ID
+			if err := s.transactions.InsertTransaction(ctx, tx, row); err != nil {
+				return fmt.Errorf("service:InsertTransaction: %w", err)
+			}
+		}
+
+		from.Balance, err = from.Balance.Sub(amt)
+		if err != nil {
+			return fmt.Errorf("service:balance: %w", err)
+		}
+		source = from
+		return nil
+	})
+	if err != nil {
+		return model.Transfer{}, model.Account{}, err
+	}
+	return t, source, nil
+}
+
+// lockTransferAccounts locks both accounts of a transfer in ascending ID order.
+// Two concurrent transfers in opposite directions therefore wait on the same
+// row first instead of each holding the lock the other one needs.
+func (s *transactionService) lockTransferAccounts(ctx context.Context, tx pgx.Tx, fromID, toID int64) (model.Account, model.Account, error) {
+	firstID, secondID := fromID, toID
+	if secondID < firstID {
+		firstID, secondID = secondID, firstID
+	}
+
+	locked := make(map[int64]model.Account, 2)
+	for _, id := range []int64{firstID, secondID} {
+		a, err := s.accounts.GetAccountForUpdate(ctx, tx, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				if id == toID {
+					return model.Account{}, model.Account{}, ErrRecipientNotFound
+				}
+				return model.Account{}, model.Account{}, ErrAccountNotFound
+			}
+			return model.Account{}, model.Account{}, fmt.Errorf("service:lockAccount: %w", err)
+		}
+		locked[id] = a
+	}
+	return locked[fromID], locked[toID], nil
+}
+
+// lockOwnedAccount locks the account row and checks that it belongs to userID
+// and is still open; other users' accounts are reported as not found
+func (s *transactionService) lockOwnedAccount(ctx context.Context, tx pgx.Tx, userID, accountID int64) (model.Account, error) {
+	a, err := s.accounts.GetAccountForUpdate(ctx, tx, accountID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Account{}, ErrAccountNotFound
+		}
+		return model.Account{}, fmt.Errorf("service:lockAccount: %w", err)
+	}
+	if a.UserID != userID {
+		return model.Account{}, ErrAccountNotFound
+	}
+	if !a.IsActive {
+		return model.Account{}, ErrAccountClosed
+	}
+	return a, nil
+}
+
+// inAccountCurrency tags amount with the account's currency, rejecting amounts
+// explicitly given in another currency
+func inAccountCurrency(amount money.Money, a model.Account) (money.Money, error) {
+	if amount.Currency() != "" && amount.Currency() != a.Currency {
+		return money.Money{}, ErrCurrencyMismatch
+	}
+	return amount.WithCurrency(a.Currency), nil
+}
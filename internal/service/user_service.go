@@ -3,13 +3,18 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
-	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/common/useragent"
 	"github.com/yusufziyrek/bank-app/internal/model"
 	"github.com/yusufziyrek/bank-app/internal/repository"
 	"golang.org/x/crypto/bcrypt"
@@ -20,32 +25,79 @@ var (
 	ErrEmailAlreadyRegistered = errors.New("email already registered")
 	ErrInvalidCredentials     = errors.New("invalid credentials")
 	ErrInactiveAccount        = errors.New("inactive account")
+	ErrUserHasLedgerHistory   = errors.New("user has accounts with ledger history")
+	ErrRefreshTokenReused     = errors.New("refresh token reused")
+	ErrSessionNotFound        = errors.New("session not found")
+	ErrAccessTokenRevoked     = errors.New("access token revoked")
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	ErrServiceAccountPassword = errors.New("service accounts have no password")
 )
 
 const refreshTokenLength = 64
+const refreshTokenFamilyIDLength = 16
+
+// Limits on client supplied session metadata
+const (
+	maxUserAgentLength   = 512
+	maxDeviceLabelLength = 100
+)
 const refreshTokenTTL = 7 * 24 * time.Hour // 7 gün
 
+const (
+	// Service accounts get an address that can never receive mail; the
+	// reserved .invalid domain keeps it from clashing with a real user
+	serviceAccountEmailDomain = "service-accounts.invalid"
+	// noPasswordHash is not a bcrypt hash, so no password matches it
+	noPasswordHash = "!"
+)
+
 type UserService interface {
 	GetAllUsers(ctx context.Context) ([]model.User, error)
 	GetUserByID(ctx context.Context, id int64) (model.User, error)
 	CreateUser(ctx context.Context, u *model.User) error
-	UpdateUserEmail(ctx context.Context, id int64, email string) error
 	UpdateUserPassword(ctx context.Context, id int64, pwd string) error
 	UpdateUserActiveStatus(ctx context.Context, id int64, isActive bool) error
 	DeleteUserByID(ctx context.Context, id int64) error
+	// CreateServiceAccount adds a service account named name with role. It
+	// gets a placeholder email and no password, and counts as verified since
+	// an admin vouches for it.
+	CreateServiceAccount(ctx context.Context, name, role string) (model.User, error)
+	GetServiceAccounts(ctx context.Context) ([]model.User, error)
+	// GetServiceAccount returns ErrServiceAccountNotFound unless id is a
+	// service account
+	GetServiceAccount(ctx context.Context, id int64) (model.User, error)
 	AuthenticateUser(ctx context.Context, email, pwd string) (model.User, error)
-	GenerateRefreshToken(ctx context.Context, userID int64) (string, time.Time, error)
-	ValidateRefreshToken(ctx context.Context, token string) (int64, error)
+	VerifyPassword(ctx context.Context, id int64, pwd string) (model.User, error)
+	GenerateRefreshToken(ctx context.Context, userID int64, client model.ClientInfo) (string, time.Time, error)
+	// GrantRefreshToken opens a session for the third-party app
+	// client.OAuthClientID that is limited to scopes, and returns its first
+	// refresh token
+	GrantRefreshToken(ctx context.Context, userID int64, scopes []string, client model.ClientInfo) (model.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, token string, client model.ClientInfo) (model.RefreshToken, error)
 	RevokeRefreshToken(ctx context.Context, token string) error
+	// RevokeGrantRefreshToken is RevokeRefreshToken for the third-party app
+	// clientID; tokens of other clients are left alone
+	RevokeGrantRefreshToken(ctx context.Context, clientID, token string) error
 	RevokeAllUserRefreshTokens(ctx context.Context, userID int64) error
+	PurgeExpiredRefreshTokens(ctx context.Context) (int64, error)
+	ListSessions(ctx context.Context, userID int64) ([]model.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID int64) error
+	// CheckAccessToken returns ErrAccessTokenRevoked unless the user still
+	// exists, is active, has not raised their token version past version and
+	// has not revoked jti
+	CheckAccessToken(ctx context.Context, userID, version int64, jti string) error
+	// RevokeAccessToken denies the access token jti until it expires
+	RevokeAccessToken(ctx context.Context, userID int64, jti string, expiresAt time.Time) error
+	PurgeExpiredRevokedAccessTokens(ctx context.Context) (int64, error)
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo      repository.UserRepository
+	passwords PasswordPolicy
 }
 
-func NewUserService(r repository.UserRepository) UserService {
-	return &userService{repo: r}
+func NewUserService(r repository.UserRepository, passwords PasswordPolicy) UserService {
+	return &userService{repo: r, passwords: passwords}
 }
 
 func (s *userService) GetAllUsers(ctx context.Context) ([]model.User, error) {
@@ -64,19 +116,22 @@ func (s *userService) GetUserByID(ctx context.Context, id int64) (model.User, er
 }
 
 func (s *userService) CreateUser(ctx context.Context, u *model.User) error {
+	// PasswordHash still holds the plain password here
+	if err := s.passwords.check(ctx, s.repo, *u, u.PasswordHash); err != nil {
+		return err
+	}
 	hashed, err := bcrypt.GenerateFromPassword([]byte(u.PasswordHash), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("service:hash: %w", err)
 	}
 	u.PasswordHash = string(hashed)
 	if u.Role == "" {
-		u.Role = "user"
+		u.Role = model.RoleUser
 	}
 	u.IsActive = true
 
 	if err := s.repo.AddUser(ctx, u); err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		if isPgError(err, pgUniqueViolation) {
 			return ErrEmailAlreadyRegistered
 		}
 		return fmt.Errorf("service:AddUser: %w", err)
@@ -84,26 +139,24 @@ func (s *userService) CreateUser(ctx context.Context, u *model.User) error {
 	return nil
 }
 
-func (s *userService) UpdateUserEmail(ctx context.Context, id int64, email string) error {
-	if err := s.repo.UpdateUserEmail(ctx, id, email); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrUserNotFound
-		}
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			return ErrEmailAlreadyRegistered
-		}
-		return fmt.Errorf("service:UpdateEmail: %w", err)
-	}
-	return nil
-}
-
 func (s *userService) UpdateUserPassword(ctx context.Context, id int64, pwd string) error {
+	u, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u.ServiceAccount {
+		return ErrServiceAccountPassword
+	}
+	if err := s.passwords.check(ctx, s.repo, u, pwd); err != nil {
+		return err
+	}
 	hashed, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("service:hashPwd: %w", err)
 	}
-	if err := s.repo.UpdateUserPassword(ctx, id, string(hashed)); err != nil {
+	// Sessions opened with the old password must not outlive it, so they
+	// end in the same transaction as the change
+	if err := s.repo.ChangePassword(ctx, id, string(hashed), s.passwords.historyKeep()); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrUserNotFound
 		}
@@ -112,6 +165,58 @@ func (s *userService) UpdateUserPassword(ctx context.Context, id int64, pwd stri
 	return nil
 }
 
+func (s *userService) CreateServiceAccount(ctx context.Context, name, role string) (model.User, error) {
+	handle, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return model.User{}, err
+	}
+	now := time.Now()
+	u := model.User{
+		FullName:        strings.TrimSpace(name),
+		Email:           "svc-" + handle + "@" + serviceAccountEmailDomain,
+		PasswordHash:    noPasswordHash,
+		Role:            role,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+		ServiceAccount:  true,
+	}
+	if u.Role == "" {
+		u.Role = model.RoleUser
+	}
+	if err := s.repo.AddUser(ctx, &u); err != nil {
+		return model.User{}, fmt.Errorf("service:CreateServiceAccount: %w", err)
+	}
+	return u, nil
+}
+
+func (s *userService) GetServiceAccounts(ctx context.Context) ([]model.User, error) {
+	users, err := s.repo.GetAllUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service:GetServiceAccounts: %w", err)
+	}
+	accounts := make([]model.User, 0, len(users))
+	for _, u := range users {
+		if u.ServiceAccount {
+			accounts = append(accounts, u)
+		}
+	}
+	return accounts, nil
+}
+
+func (s *userService) GetServiceAccount(ctx context.Context, id int64) (model.User, error) {
+	u, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return model.User{}, ErrServiceAccountNotFound
+		}
+		return model.User{}, err
+	}
+	if !u.ServiceAccount {
+		return model.User{}, ErrServiceAccountNotFound
+	}
+	return u, nil
+}
+
 func (s *userService) UpdateUserActiveStatus(ctx context.Context, id int64, active bool) error {
 	if err := s.repo.UpdateUserActiveStatus(ctx, id, active); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -119,6 +224,12 @@ func (s *userService) UpdateUserActiveStatus(ctx context.Context, id int64, acti
 		}
 		return fmt.Errorf("service:UpdateStatus: %w", err)
 	}
+	if !active {
+		// A deactivated user must not be able to refresh their way back in
+		if err := s.repo.DeleteUserRefreshTokens(ctx, id); err != nil {
+			return fmt.Errorf("service:UpdateStatus:revokeSessions: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -127,6 +238,10 @@ func (s *userService) DeleteUserByID(ctx context.Context, id int64) error {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrUserNotFound
 		}
+		if isPgError(err, pgForeignKeyViolation) {
+			// Postings keep their accounts from being cascaded away
+			return ErrUserHasLedgerHistory
+		}
 		return fmt.Errorf("service:DeleteUser: %w", err)
 	}
 	return nil
@@ -140,6 +255,9 @@ func (s *userService) AuthenticateUser(ctx context.Context, email, pwd string) (
 		}
 		return model.User{}, fmt.Errorf("service:AuthenticateUser: %w", err)
 	}
+	if u.ServiceAccount {
+		return model.User{}, ErrInvalidCredentials
+	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(pwd)); err != nil {
 		return model.User{}, ErrInvalidCredentials
@@ -152,42 +270,278 @@ func (s *userService) AuthenticateUser(ctx context.Context, email, pwd string) (
 	return u, nil
 }
 
-func (s *userService) GenerateRefreshToken(ctx context.Context, userID int64) (string, time.Time, error) {
-	b := make([]byte, refreshTokenLength)
-	_, err := rand.Read(b)
+// VerifyPassword re-checks the password of an already authenticated user
+func (s *userService) VerifyPassword(ctx context.Context, id int64, pwd string) (model.User, error) {
+	u, err := s.GetUserByID(ctx, id)
 	if err != nil {
-		return "", time.Time{}, err
+		return model.User{}, err
 	}
-	token := base64.URLEncoding.EncodeToString(b)
-	expiresAt := time.Now().Add(refreshTokenTTL)
-	rt := &model.RefreshToken{
-		UserID:    userID,
-		Token:     token,
-		ExpiresAt: expiresAt,
-		CreatedAt: time.Now(),
+	if u.ServiceAccount {
+		return model.User{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(pwd)); err != nil {
+		return model.User{}, ErrInvalidCredentials
+	}
+	if !u.IsActive {
+		return model.User{}, ErrInactiveAccount
 	}
-	if err := s.repo.InsertRefreshToken(ctx, rt); err != nil {
+	return u, nil
+}
+
+// GenerateRefreshToken opens a new session for the client and returns the
+// first refresh token of its family
+func (s *userService) GenerateRefreshToken(ctx context.Context, userID int64, client model.ClientInfo) (string, time.Time, error) {
+	client.OAuthClientID = ""
+	rt, err := s.openSession(ctx, userID, nil, client)
+	if err != nil {
 		return "", time.Time{}, err
 	}
-	return token, expiresAt, nil
+	return rt.Token, rt.ExpiresAt, nil
+}
+
+func (s *userService) GrantRefreshToken(ctx context.Context, userID int64, scopes []string, client model.ClientInfo) (model.RefreshToken, error) {
+	if client.OAuthClientID == "" || len(scopes) == 0 {
+		return model.RefreshToken{}, errors.New("service:GrantRefreshToken: client and scopes required")
+	}
+	return s.openSession(ctx, userID, scopes, client)
+}
+
+func (s *userService) openSession(ctx context.Context, userID int64, scopes []string, client model.ClientInfo) (model.RefreshToken, error) {
+	family, err := randomString(refreshTokenFamilyIDLength, hex.EncodeToString)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+	now := time.Now()
+	session := model.Session{
+		UserID:      userID,
+		FamilyID:    family,
+		ClientID:    client.OAuthClientID,
+		Scopes:      scopes,
+		DeviceLabel: deviceLabel(client),
+		UserAgent:   truncate(client.UserAgent, maxUserAgentLength),
+		IPAddress:   client.IPAddress,
+		CreatedAt:   now,
+		LastUsedAt:  now,
+	}
+	if err := s.repo.InsertSession(ctx, &session); err != nil {
+		return model.RefreshToken{}, err
+	}
+	rt, err := s.issueRefreshToken(ctx, userID, family)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+	rt.ClientID = session.ClientID
+	rt.Scopes = session.Scopes
+	return rt, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for its successor in the same
+// family. Each token can be exchanged exactly once; presenting a token that
+// was already rotated means it leaked, so the whole family is revoked. Tokens
+// of OAuth grants are only accepted from the app they were granted to, and
+// those of our own sessions only without an app.
+func (s *userService) RotateRefreshToken(ctx context.Context, token string, client model.ClientInfo) (model.RefreshToken, error) {
+	now := time.Now()
+	hash := HashRefreshToken(token)
+	old, err := s.repo.ClaimRefreshToken(ctx, hash, client.OAuthClientID, now)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return model.RefreshToken{}, fmt.Errorf("service:RotateRefreshToken: %w", err)
+		}
+		return model.RefreshToken{}, s.rejectRefreshToken(ctx, hash, now)
+	}
+	if !hashMatches(old, hash) {
+		return model.RefreshToken{}, ErrInvalidCredentials
+	}
+
+	next, err := s.issueRefreshToken(ctx, old.UserID, old.FamilyID)
+	if err != nil {
+		return model.RefreshToken{}, fmt.Errorf("service:RotateRefreshToken: %w", err)
+	}
+	next.ClientID = old.ClientID
+	next.Scopes = old.Scopes
+	if err := s.repo.TouchSession(ctx, old.FamilyID, now, client.IPAddress); err != nil {
+		return model.RefreshToken{}, fmt.Errorf("service:RotateRefreshToken:touchSession: %w", err)
+	}
+	return next, nil
+}
+
+// rejectRefreshToken explains why a token could not be claimed
+func (s *userService) rejectRefreshToken(ctx context.Context, hash string, now time.Time) error {
+	rt, err := s.repo.GetRefreshToken(ctx, hash)
+	if err != nil || !hashMatches(rt, hash) {
+		return ErrInvalidCredentials
+	}
+	if rt.Used() {
+		log.Printf("security: refresh token reuse detected user_id=%d family=%s used_at=%s; revoking family",
+			rt.UserID, rt.FamilyID, rt.UsedAt.Format(time.RFC3339))
+		if err := s.repo.DeleteRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+			return fmt.Errorf("service:RotateRefreshToken:revokeFamily: %w", err)
+		}
+		return ErrRefreshTokenReused
+	}
+	if now.After(rt.ExpiresAt) {
+		_ = s.repo.DeleteRefreshTokenFamily(ctx, rt.FamilyID)
+	}
+	return ErrInvalidCredentials
 }
 
-func (s *userService) ValidateRefreshToken(ctx context.Context, token string) (int64, error) {
-	rt, err := s.repo.GetRefreshToken(ctx, token)
+func (s *userService) issueRefreshToken(ctx context.Context, userID int64, family string) (model.RefreshToken, error) {
+	token, err := randomString(refreshTokenLength, base64.URLEncoding.EncodeToString)
 	if err != nil {
-		return 0, ErrInvalidCredentials
+		return model.RefreshToken{}, err
 	}
-	if time.Now().After(rt.ExpiresAt) {
-		_ = s.repo.DeleteRefreshToken(ctx, token)
-		return 0, ErrInvalidCredentials
+	now := time.Now()
+	rt := model.RefreshToken{
+		UserID:    userID,
+		Token:     token,
+		TokenHash: HashRefreshToken(token),
+		FamilyID:  family,
+		ExpiresAt: now.Add(refreshTokenTTL),
+		CreatedAt: now,
 	}
-	return rt.UserID, nil
+	if err := s.repo.InsertRefreshToken(ctx, &rt); err != nil {
+		return model.RefreshToken{}, err
+	}
+	return rt, nil
 }
 
+// RevokeRefreshToken ends the session the token belongs to, including any
+// earlier tokens of the same family
 func (s *userService) RevokeRefreshToken(ctx context.Context, token string) error {
-	return s.repo.DeleteRefreshToken(ctx, token)
+	return s.revokeRefreshToken(ctx, token, func(model.RefreshToken) bool { return true })
+}
+
+func (s *userService) RevokeGrantRefreshToken(ctx context.Context, clientID, token string) error {
+	return s.revokeRefreshToken(ctx, token, func(rt model.RefreshToken) bool {
+		return clientID != "" && rt.ClientID == clientID
+	})
+}
+
+func (s *userService) revokeRefreshToken(ctx context.Context, token string, match func(model.RefreshToken) bool) error {
+	hash := HashRefreshToken(token)
+	rt, err := s.repo.GetRefreshToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("service:RevokeRefreshToken: %w", err)
+	}
+	if !hashMatches(rt, hash) || !match(rt) {
+		return nil
+	}
+	return s.repo.DeleteRefreshTokenFamily(ctx, rt.FamilyID)
 }
 
 func (s *userService) RevokeAllUserRefreshTokens(ctx context.Context, userID int64) error {
 	return s.repo.DeleteUserRefreshTokens(ctx, userID)
 }
+
+// PurgeExpiredRefreshTokens deletes every expired refresh token and returns
+// how many were removed
+func (s *userService) PurgeExpiredRefreshTokens(ctx context.Context) (int64, error) {
+	n, err := s.repo.DeleteExpiredRefreshTokens(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("service:PurgeExpiredRefreshTokens: %w", err)
+	}
+	return n, nil
+}
+
+// ListSessions returns the user's active sessions, most recently used first
+func (s *userService) ListSessions(ctx context.Context, userID int64) ([]model.Session, error) {
+	sessions, err := s.repo.GetUserSessions(ctx, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("service:ListSessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions. Sessions of other users are
+// reported as not found.
+func (s *userService) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	if err := s.repo.DeleteUserSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("service:RevokeSession: %w", err)
+	}
+	return nil
+}
+
+func (s *userService) CheckAccessToken(ctx context.Context, userID, version int64, jti string) error {
+	st, err := s.repo.GetAccessTokenState(ctx, userID, jti)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAccessTokenRevoked
+		}
+		return fmt.Errorf("service:CheckAccessToken: %w", err)
+	}
+	if !st.IsActive || st.Revoked || version < st.TokenVersion {
+		return ErrAccessTokenRevoked
+	}
+	return nil
+}
+
+func (s *userService) RevokeAccessToken(ctx context.Context, userID int64, jti string, expiresAt time.Time) error {
+	if jti == "" || !expiresAt.After(time.Now()) {
+		return nil
+	}
+	t := model.RevokedAccessToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}
+	if err := s.repo.InsertRevokedAccessToken(ctx, &t); err != nil {
+		return fmt.Errorf("service:RevokeAccessToken: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredRevokedAccessTokens drops denylist entries of tokens that have
+// expired, since those are rejected anyway
+func (s *userService) PurgeExpiredRevokedAccessTokens(ctx context.Context) (int64, error) {
+	n, err := s.repo.DeleteExpiredRevokedAccessTokens(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("service:PurgeExpiredRevokedAccessTokens: %w", err)
+	}
+	return n, nil
+}
+
+// deviceLabel prefers the label the client chose and falls back to one
+// derived from its user agent
+func deviceLabel(client model.ClientInfo) string {
+	if label := strings.TrimSpace(client.DeviceLabel); label != "" {
+		return truncate(label, maxDeviceLabelLength)
+	}
+	return useragent.Label(client.UserAgent)
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
+
+// HashRefreshToken returns the hex SHA-256 digest under which a refresh token
+// is stored. Tokens carry 512 bits of entropy, so an unsalted digest is enough
+// to make a leaked table useless.
+func HashRefreshToken(token string) string {
+	return digest(token)
+}
+
+// digest returns the hex SHA-256 of a high-entropy secret
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashMatches compares the stored digest in constant time
+func hashMatches(rt model.RefreshToken, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(rt.TokenHash), []byte(hash)) == 1
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
@@ -0,0 +1,38 @@
+-- Brings a refresh_tokens table created before token rotation and hashing up
+-- to the current schema. Plaintext tokens are replaced by their SHA-256 digest,
+-- so sessions that are already logged in keep working. Safe to run repeatedly.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/001_hash_refresh_tokens.sql
+
+BEGIN;
+
+-- Token families: every pre-existing token becomes its own session
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id TEXT;
+UPDATE refresh_tokens SET family_id = 'legacy-' || id WHERE family_id IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMP;
+
+-- Hash plaintext tokens, then drop them
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS token_hash CHAR(64);
+DO $$
+BEGIN
+  IF EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_name = 'refresh_tokens' AND column_name = 'token'
+  ) THEN
+    UPDATE refresh_tokens
+       SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex')
+     WHERE token_hash IS NULL;
+    ALTER TABLE refresh_tokens DROP COLUMN token;
+  END IF;
+END $$;
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+-- Tokens that already expired are not worth migrating
+DELETE FROM refresh_tokens WHERE expires_at <= NOW();
+
+COMMIT;
@@ -0,0 +1,35 @@
+-- Adds the sessions table and gives every existing refresh token family a
+-- session row, then ties tokens to their session so deleting a session
+-- revokes its tokens. Run after 001_hash_refresh_tokens.sql. Safe to run
+-- repeatedly.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/002_sessions.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS sessions (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  family_id TEXT NOT NULL UNIQUE,
+  device_label TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip_address TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  last_used_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- Where older sessions came from was never recorded
+INSERT INTO sessions (user_id, family_id, device_label, created_at, last_used_at)
+SELECT user_id, family_id, 'Unknown device', MIN(created_at), MAX(created_at)
+FROM refresh_tokens
+GROUP BY user_id, family_id
+ON CONFLICT (family_id) DO NOTHING;
+
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_session;
+ALTER TABLE refresh_tokens
+  ADD CONSTRAINT fk_refresh_tokens_session
+  FOREIGN KEY (family_id) REFERENCES sessions(family_id) ON DELETE CASCADE;
+
+COMMIT;
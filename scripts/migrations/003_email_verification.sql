@@ -0,0 +1,33 @@
+-- Adds email verification. Users that exist when the column is added are
+-- treated as verified, since they signed up before verification existed;
+-- everyone registering afterwards has to confirm their address. Safe to run
+-- repeatedly: the backfill only happens together with adding the column.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/003_email_verification.sql
+
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_name = 'users' AND column_name = 'email_verified_at'
+  ) THEN
+    ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE;
+    UPDATE users SET email_verified_at = created_at;
+  END IF;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email VARCHAR(255) NOT NULL,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications(user_id);
+
+COMMIT;
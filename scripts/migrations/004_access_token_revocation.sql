@@ -0,0 +1,20 @@
+-- Adds access token revocation. Access tokens issued before this migration
+-- carry no version and count as version 0, so they stay valid until the user
+-- next logs out everywhere, changes their password or is deactivated. Safe to
+-- run repeatedly.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/004_access_token_revocation.sql
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+  jti VARCHAR(64) PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires_at ON revoked_access_tokens(expires_at);
+
+COMMIT;
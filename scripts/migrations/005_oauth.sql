@@ -0,0 +1,37 @@
+-- Adds the OAuth client registry and authorization codes, and lets sessions
+-- belong to a third-party app with a set of scopes. Existing sessions keep no
+-- client and no scopes. Safe to run repeatedly.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/005_oauth.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+  id BIGSERIAL PRIMARY KEY,
+  client_id VARCHAR(64) NOT NULL UNIQUE,
+  secret_hash CHAR(64),
+  name TEXT NOT NULL,
+  redirect_uris TEXT[] NOT NULL,
+  scopes TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_id VARCHAR(64) REFERENCES oauth_clients(client_id) ON DELETE CASCADE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+  id BIGSERIAL PRIMARY KEY,
+  code_hash CHAR(64) NOT NULL UNIQUE,
+  client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  redirect_uri TEXT NOT NULL,
+  scopes TEXT[] NOT NULL,
+  code_challenge VARCHAR(128) NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_authorization_codes_expires_at ON oauth_authorization_codes(expires_at);
+
+COMMIT;
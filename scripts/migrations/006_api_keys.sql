@@ -0,0 +1,22 @@
+-- Adds personal API keys. Only the SHA-256 digest of each key is stored.
+-- Safe to run repeatedly.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/006_api_keys.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  key_hash CHAR(64) NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Counts wrong two-factor codes per user so they can be locked out after a
+-- few in a row. Safe to run repeatedly.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/007_totp_failures.sql
+
+BEGIN;
+
+ALTER TABLE user_totp ADD COLUMN IF NOT EXISTS failed_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE user_totp ADD COLUMN IF NOT EXISTS last_failure_at TIMESTAMP;
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Tells links for the current address apart from email change links, so
+-- sending one no longer cancels the other. Outstanding links are treated as
+-- confirmations of the current address unless they name another one. Safe to
+-- run repeatedly: the backfill only happens together with adding the column.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/008_email_verification_purpose.sql
+
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_name = 'email_verifications' AND column_name = 'purpose'
+  ) THEN
+    ALTER TABLE email_verifications ADD COLUMN purpose VARCHAR(16) NOT NULL DEFAULT 'verify';
+    UPDATE email_verifications v SET purpose = 'change'
+    FROM users u
+    WHERE u.id = v.user_id AND u.email <> v.email;
+  END IF;
+END
+$$;
+
+COMMIT;
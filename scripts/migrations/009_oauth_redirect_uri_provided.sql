@@ -0,0 +1,22 @@
+-- Remembers whether an authorization request named its redirect URI, so the
+-- token request can be held to repeating it. Codes live for minutes; the
+-- ones outstanding while this runs are treated as having named it. Safe to
+-- run repeatedly.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/009_oauth_redirect_uri_provided.sql
+
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_name = 'oauth_authorization_codes' AND column_name = 'redirect_uri_provided'
+  ) THEN
+    ALTER TABLE oauth_authorization_codes ADD COLUMN redirect_uri_provided BOOLEAN NOT NULL DEFAULT FALSE;
+    UPDATE oauth_authorization_codes SET redirect_uri_provided = TRUE;
+  END IF;
+END
+$$;
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- Ties API keys to their user's token version, so logging out everywhere,
+-- changing or resetting the password and deactivation revoke them like
+-- access tokens. Existing keys take the user's current version and keep
+-- working. Safe to run repeatedly: the backfill only happens together with
+-- adding the column.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/010_api_key_token_version.sql
+
+BEGIN;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_name = 'api_keys' AND column_name = 'token_version'
+  ) THEN
+    ALTER TABLE api_keys ADD COLUMN token_version BIGINT NOT NULL DEFAULT 0;
+    UPDATE api_keys k SET token_version = u.token_version
+    FROM users u
+    WHERE u.id = k.user_id;
+  END IF;
+END
+$$;
+
+COMMIT;
@@ -0,0 +1,30 @@
+-- Prepares the cards table for encrypted card numbers and CVVs and for card
+-- replacement. The columns become TEXT to hold sealed values and
+-- card_number_hash is added for lookups; existing rows keep their plain
+-- values until `go run ./cmd/seal-cards` seals them, fills in the hash and
+-- makes it required. Safe to run repeatedly.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/011_card_encryption.sql
+
+BEGIN;
+
+ALTER TABLE cards ALTER COLUMN card_number TYPE TEXT;
+ALTER TABLE cards ALTER COLUMN cvv TYPE TEXT;
+
+-- Sealed values differ on every write, so uniqueness moves to the hash
+ALTER TABLE cards DROP CONSTRAINT IF EXISTS cards_card_number_key;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns
+    WHERE table_name = 'cards' AND column_name = 'card_number_hash'
+  ) THEN
+    ALTER TABLE cards ADD COLUMN card_number_hash CHAR(64) UNIQUE;
+  END IF;
+END
+$$;
+
+ALTER TABLE cards ADD COLUMN IF NOT EXISTS replaced_by_id BIGINT REFERENCES cards(id);
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Marks service accounts: users that scripts act as through API keys and
+-- that cannot log in. Existing users stay regular users. Safe to run
+-- repeatedly.
+--
+--   psql -U postgres -d bankapp -f scripts/migrations/012_service_accounts.sql
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_service_account BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
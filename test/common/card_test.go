@@ -0,0 +1,67 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/card"
+)
+
+// TestCard kart numarası üretimi, Luhn doğrulaması ve maskeleme testleri
+func TestCard(t *testing.T) {
+	t.Run("GeneratePAN_ValidLuhn", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			pan, err := card.GeneratePAN("979200")
+			require.NoError(t, err)
+			assert.Len(t, pan, card.PANLength)
+			assert.Equal(t, "979200", pan[:6])
+			assert.True(t, card.ValidLuhn(pan), pan)
+		}
+	})
+
+	t.Run("GeneratePAN_EightDigitBIN", func(t *testing.T) {
+		pan, err := card.GeneratePAN("45876312")
+		require.NoError(t, err)
+		assert.Equal(t, "45876312", pan[:8])
+		assert.True(t, card.ValidLuhn(pan))
+	})
+
+	t.Run("GeneratePAN_InvalidBIN", func(t *testing.T) {
+		for _, bin := range []string{"", "12345", "123456789", "12a456"} {
+			_, err := card.GeneratePAN(bin)
+			assert.ErrorIs(t, err, card.ErrInvalidBIN, bin)
+		}
+	})
+
+	t.Run("ValidLuhn_KnownNumbers", func(t *testing.T) {
+		// Bilinen test kart numaraları
+		assert.True(t, card.ValidLuhn("4111111111111111"))
+		assert.True(t, card.ValidLuhn("5555555555554444"))
+		assert.True(t, card.ValidLuhn("79927398713"))
+		assert.False(t, card.ValidLuhn("4111111111111112"))
+		assert.False(t, card.ValidLuhn("41111111111111a1"))
+		assert.False(t, card.ValidLuhn(""))
+	})
+
+	t.Run("ValidLuhn_DetectsTypos", func(t *testing.T) {
+		pan, err := card.GeneratePAN("979200")
+		require.NoError(t, err)
+
+		digits := []byte(pan)
+		digits[10] = '0' + (digits[10]-'0'+1)%10
+		assert.False(t, card.ValidLuhn(string(digits)))
+	})
+
+	t.Run("GenerateCVV", func(t *testing.T) {
+		cvv, err := card.GenerateCVV()
+		require.NoError(t, err)
+		assert.Len(t, cvv, card.CVVLength)
+		assert.Regexp(t, `^\d{3}$`, cvv)
+	})
+
+	t.Run("MaskPAN", func(t *testing.T) {
+		assert.Equal(t, "************1111", card.MaskPAN("4111111111111111"))
+		assert.Equal(t, "***", card.MaskPAN("123"))
+	})
+}
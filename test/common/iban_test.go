@@ -0,0 +1,57 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/iban"
+)
+
+// TestIBAN hesap numarası üretimi ve doğrulaması için testler
+func TestIBAN(t *testing.T) {
+	t.Run("Generate_ValidLayout", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			number, err := iban.Generate("TR")
+			require.NoError(t, err)
+			assert.Len(t, number, iban.Length)
+			assert.Equal(t, "TR", number[:2])
+			assert.True(t, iban.Validate(number), number)
+		}
+	})
+
+	t.Run("Generate_InvalidCountryCode", func(t *testing.T) {
+		for _, cc := range []string{"", "T", "tr", "T1", "TUR"} {
+			_, err := iban.Generate(cc)
+			assert.ErrorIs(t, err, iban.ErrInvalidCountryCode, cc)
+		}
+	})
+
+	t.Run("Validate_KnownNumber", func(t *testing.T) {
+		// TR + check digits + 16 haneli BBAN, mod 97 kontrolü elle hesaplandı
+		assert.True(t, iban.Validate("TR200000000000000001"))
+	})
+
+	t.Run("Validate_DetectsTypos", func(t *testing.T) {
+		number, err := iban.Generate("DE")
+		require.NoError(t, err)
+
+		// Tek hane değişikliği
+		digits := []byte(number)
+		digits[10] = '0' + (digits[10]-'0'+1)%10
+		assert.False(t, iban.Validate(string(digits)))
+
+		// Yan yana iki hanenin yer değiştirmesi
+		swapped := []byte(number)
+		swapped[8], swapped[9] = swapped[9], swapped[8]
+		if swapped[8] != swapped[9] {
+			assert.False(t, iban.Validate(string(swapped)))
+		}
+	})
+
+	t.Run("Validate_Malformed", func(t *testing.T) {
+		for _, s := range []string{"", "TR52", "tr200000000000000001", "TR52000000000000000X", "TR2000000000000000011"} {
+			assert.False(t, iban.Validate(s), s)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package common
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/jwtkeys"
+)
+
+func newEd25519Key(t *testing.T, id string, activeFrom time.Time) jwtkeys.Key {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	k, err := jwtkeys.NewKey(id, priv, activeFrom)
+	require.NoError(t, err)
+	return k
+}
+
+func newRSAKey(t *testing.T, id string) (jwtkeys.Key, *rsa.PrivateKey) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	k, err := jwtkeys.NewKey(id, priv, time.Time{})
+	require.NoError(t, err)
+	return k, priv
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": 1, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestJWTKeysSignAndVerify(t *testing.T) {
+	rsaKey, _ := newRSAKey(t, "rsa-1")
+
+	for _, k := range []jwtkeys.Key{newEd25519Key(t, "ed-1", time.Time{}), rsaKey} {
+		t.Run(k.Algorithm(), func(t *testing.T) {
+			keys, err := jwtkeys.New(k)
+			require.NoError(t, err)
+
+			signed, err := keys.Sign(testClaims())
+			require.NoError(t, err)
+			token, err := jwt.Parse(signed, keys.Keyfunc)
+			require.NoError(t, err)
+			assert.Equal(t, k.ID, token.Header["kid"])
+			assert.Equal(t, k.Algorithm(), token.Method.Alg())
+		})
+	}
+}
+
+func TestJWTKeysRotation(t *testing.T) {
+	old := newEd25519Key(t, "2026-01", time.Time{})
+	current := newEd25519Key(t, "2026-04", time.Now().Add(-time.Hour))
+	next := newEd25519Key(t, "2026-07", time.Now().Add(24*time.Hour))
+
+	before, err := jwtkeys.New(old)
+	require.NoError(t, err)
+	issuedBefore, err := before.Sign(testClaims())
+	require.NoError(t, err)
+
+	keys, err := jwtkeys.New(next, old, current)
+	require.NoError(t, err)
+
+	// En son devreye giren anahtar imzalar, planlanmış anahtar henüz imzalamaz
+	k, err := keys.Current()
+	require.NoError(t, err)
+	assert.Equal(t, "2026-04", k.ID)
+	upcoming := keys.Upcoming()
+	require.Len(t, upcoming, 1)
+	assert.Equal(t, "2026-07", upcoming[0].ID)
+
+	// Eski anahtarla imzalanmış token'lar hâlâ doğrulanır
+	_, err = jwt.Parse(issuedBefore, keys.Keyfunc)
+	assert.NoError(t, err)
+
+	// Planlanmış anahtar da yayınlanır
+	var kids []string
+	for _, jwk := range keys.JWKS().Keys {
+		kids = append(kids, jwk.Kid)
+	}
+	assert.ElementsMatch(t, []string{"2026-01", "2026-04", "2026-07"}, kids)
+
+	_, err = jwtkeys.New(next)
+	assert.ErrorIs(t, err, jwtkeys.ErrNoActiveKey)
+	_, err = jwtkeys.New(old, old)
+	assert.ErrorIs(t, err, jwtkeys.ErrDuplicateKeyID)
+}
+
+func TestJWTKeysRejects(t *testing.T) {
+	rsaKey, rsaPriv := newRSAKey(t, "rsa-1")
+	keys, err := jwtkeys.New(rsaKey)
+	require.NoError(t, err)
+
+	t.Run("WeakRSAKey", func(t *testing.T) {
+		weak, err := rsa.GenerateKey(rand.Reader, 1024)
+		require.NoError(t, err)
+		_, err = jwtkeys.NewKey("weak", weak, time.Time{})
+		assert.ErrorIs(t, err, jwtkeys.ErrUnsupportedKey)
+	})
+
+	t.Run("AlgorithmConfusion", func(t *testing.T) {
+		// Açık anahtarı HMAC sırrı olarak kullanan sahte token
+		pub, err := x509.MarshalPKIXPublicKey(&rsaPriv.PublicKey)
+		require.NoError(t, err)
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+		forged.Header["kid"] = "rsa-1"
+		signed, err := forged.SignedString(pub)
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, keys.Keyfunc)
+		assert.ErrorIs(t, err, jwtkeys.ErrAlgorithmMismatch)
+	})
+
+	t.Run("UnknownKid", func(t *testing.T) {
+		other, err := jwtkeys.New(newEd25519Key(t, "other", time.Time{}))
+		require.NoError(t, err)
+		signed, err := other.Sign(testClaims())
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, keys.Keyfunc)
+		assert.ErrorIs(t, err, jwtkeys.ErrUnknownKey)
+	})
+
+	t.Run("HS256Rejected", func(t *testing.T) {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString([]byte("legacy-secret"))
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(signed, keys.Keyfunc)
+		assert.ErrorIs(t, err, jwtkeys.ErrUnknownKey)
+	})
+}
+
+func TestJWTKeysJWKS(t *testing.T) {
+	rsaKey, rsaPriv := newRSAKey(t, "rsa-1")
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edKey, err := jwtkeys.NewKey("ed-1", edPriv, time.Time{})
+	require.NoError(t, err)
+	keys, err := jwtkeys.New(rsaKey, edKey)
+	require.NoError(t, err)
+
+	set := keys.JWKS()
+	require.Len(t, set.Keys, 2)
+	for _, jwk := range set.Keys {
+		assert.Equal(t, "sig", jwk.Use)
+		switch jwk.Kid {
+		case "rsa-1":
+			assert.Equal(t, "RSA", jwk.Kty)
+			assert.Equal(t, "RS256", jwk.Alg)
+			assert.Equal(t, "AQAB", jwk.E)
+			assert.Equal(t, base64.RawURLEncoding.EncodeToString(rsaPriv.N.Bytes()), jwk.N)
+		case "ed-1":
+			assert.Equal(t, "OKP", jwk.Kty)
+			assert.Equal(t, "EdDSA", jwk.Alg)
+			assert.Equal(t, "Ed25519", jwk.Crv)
+			assert.Equal(t, base64.RawURLEncoding.EncodeToString(edPriv.Public().(ed25519.PublicKey)), jwk.X)
+		default:
+			t.Fatalf("beklenmeyen kid %q", jwk.Kid)
+		}
+	}
+}
+
+func TestJWTKeysLoad(t *testing.T) {
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+		return path
+	}
+
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edPriv)
+	require.NoError(t, err)
+	edPath := writePEM("ed.pem", "PRIVATE KEY", edDER)
+
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaPath := writePEM("rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaPriv))
+
+	keys, err := jwtkeys.LoadKeys("2026-01:" + edPath + ", 2026-04:" + rsaPath + "@2026-04-01T00:00:00Z")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "EdDSA", keys[0].Algorithm())
+	assert.True(t, keys[0].ActiveFrom.IsZero())
+	assert.Equal(t, "RS256", keys[1].Algorithm())
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), keys[1].ActiveFrom)
+
+	_, err = jwtkeys.LoadKeys("bad id:" + edPath)
+	assert.ErrorIs(t, err, jwtkeys.ErrInvalidKeyID)
+	_, err = jwtkeys.LoadKeys("k:" + edPath + "@tomorrow")
+	assert.Error(t, err)
+	_, err = jwtkeys.LoadKeys("k:" + filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
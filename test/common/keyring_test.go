@@ -0,0 +1,138 @@
+package common
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/keyring"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keyring.KeySize)
+}
+
+// TestKeyring zarf şifreleme, anahtar rotasyonu ve anahtarlı hash testleri
+func TestKeyring(t *testing.T) {
+	t.Run("SealOpen_RoundTrip", func(t *testing.T) {
+		kr, err := keyring.New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+		require.NoError(t, err)
+
+		sealed, err := kr.Seal("4111111111111111", "cards.card_number:1")
+		require.NoError(t, err)
+		assert.NotContains(t, sealed, "4111111111111111")
+		assert.True(t, strings.HasPrefix(sealed, "v1:k1:"))
+
+		plain, err := kr.Open(sealed, "cards.card_number:1")
+		require.NoError(t, err)
+		assert.Equal(t, "4111111111111111", plain)
+	})
+
+	t.Run("Seal_IsRandomized", func(t *testing.T) {
+		kr, err := keyring.New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+		require.NoError(t, err)
+
+		a, err := kr.Seal("123", "aad")
+		require.NoError(t, err)
+		b, err := kr.Seal("123", "aad")
+		require.NoError(t, err)
+		assert.NotEqual(t, a, b)
+	})
+
+	t.Run("Open_WrongAADFails", func(t *testing.T) {
+		kr, err := keyring.New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+		require.NoError(t, err)
+
+		sealed, err := kr.Seal("123", "cards.cvv:1")
+		require.NoError(t, err)
+		_, err = kr.Open(sealed, "cards.cvv:2")
+		assert.Error(t, err)
+	})
+
+	t.Run("Open_TamperedFails", func(t *testing.T) {
+		kr, err := keyring.New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+		require.NoError(t, err)
+
+		sealed, err := kr.Seal("123", "aad")
+		require.NoError(t, err)
+		// Şifreli metnin ortasındaki bir karakter değiştirilir
+		tampered := []byte(sealed)
+		i := len(tampered) - 10
+		if tampered[i] == 'A' {
+			tampered[i] = 'B'
+		} else {
+			tampered[i] = 'A'
+		}
+		_, err = kr.Open(string(tampered), "aad")
+		assert.Error(t, err)
+
+		_, err = kr.Open("not-sealed", "aad")
+		assert.ErrorIs(t, err, keyring.ErrMalformed)
+	})
+
+	t.Run("Rotation_OldValuesStillOpen", func(t *testing.T) {
+		old, err := keyring.New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+		require.NoError(t, err)
+		sealed, err := old.Seal("secret", "aad")
+		require.NoError(t, err)
+
+		// Yeni anahtar eklenip güncel yapılır, eski anahtar sadece çözmek için kalır
+		rotated, err := keyring.New("k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, testKey(9))
+		require.NoError(t, err)
+		plain, err := rotated.Open(sealed, "aad")
+		require.NoError(t, err)
+		assert.Equal(t, "secret", plain)
+
+		fresh, err := rotated.Seal("secret", "aad")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(fresh, "v1:k2:"))
+
+		// Eski anahtar kaldırılırsa değer çözülemez
+		withoutOld, err := keyring.New("k2", map[string][]byte{"k2": testKey(2)}, testKey(9))
+		require.NoError(t, err)
+		_, err = withoutOld.Open(sealed, "aad")
+		assert.ErrorIs(t, err, keyring.ErrUnknownKey)
+	})
+
+	t.Run("Hash_KeyedAndStable", func(t *testing.T) {
+		kr1, err := keyring.New("k1", map[string][]byte{"k1": testKey(1)}, testKey(9))
+		require.NoError(t, err)
+		kr2, err := keyring.New("k2", map[string][]byte{"k2": testKey(2)}, testKey(9))
+		require.NoError(t, err)
+		other, err := keyring.New("k1", map[string][]byte{"k1": testKey(1)}, testKey(8))
+		require.NoError(t, err)
+
+		// Hash şifreleme anahtarından bağımsızdır, rotasyondan etkilenmez
+		assert.Equal(t, kr1.Hash("4111111111111111"), kr2.Hash("4111111111111111"))
+		assert.Len(t, kr1.Hash("4111111111111111"), 64)
+		assert.NotEqual(t, kr1.Hash("4111111111111111"), other.Hash("4111111111111111"))
+		assert.NotEqual(t, kr1.Hash("4111111111111111"), kr1.Hash("4111111111111112"))
+	})
+
+	t.Run("New_InvalidConfiguration", func(t *testing.T) {
+		_, err := keyring.New("k1", map[string][]byte{"k1": testKey(1)[:16]}, testKey(9))
+		assert.ErrorIs(t, err, keyring.ErrInvalidKey)
+		_, err = keyring.New("k1", map[string][]byte{"k1": testKey(1)}, nil)
+		assert.ErrorIs(t, err, keyring.ErrInvalidKey)
+		_, err = keyring.New("k3", map[string][]byte{"k1": testKey(1)}, testKey(9))
+		assert.ErrorIs(t, err, keyring.ErrUnknownKey)
+	})
+
+	t.Run("ParseKeys", func(t *testing.T) {
+		enc := base64.StdEncoding.EncodeToString
+		keys, err := keyring.ParseKeys("2025-01:" + enc(testKey(1)) + ", 2024-06:" + enc(testKey(2)))
+		require.NoError(t, err)
+		assert.Equal(t, testKey(1), keys["2025-01"])
+		assert.Equal(t, testKey(2), keys["2024-06"])
+
+		_, err = keyring.ParseKeys("nokey")
+		assert.ErrorIs(t, err, keyring.ErrInvalidKeyID)
+		_, err = keyring.ParseKeys("k1:" + enc([]byte("short")))
+		assert.ErrorIs(t, err, keyring.ErrInvalidKey)
+		_, err = keyring.ParseKeys("k1:not base64!")
+		assert.Error(t, err)
+	})
+}
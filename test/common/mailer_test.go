@@ -0,0 +1,51 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/mailer"
+)
+
+func TestLogMailer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("WritesMessage", func(t *testing.T) {
+		var buf bytes.Buffer
+		m := mailer.NewLogMailer(&buf, "Bank App <no-reply@example.com>")
+
+		err := m.Send(ctx, mailer.Message{To: "user@example.com", Subject: "Şifre sıfırlama", Body: "line one\nline two"})
+		require.NoError(t, err)
+
+		out := buf.String()
+		assert.Contains(t, out, "From: Bank App <no-reply@example.com>\r\n")
+		assert.Contains(t, out, "To: user@example.com\r\n")
+		// ASCII dışı konu RFC 2047 ile kodlanır
+		assert.Contains(t, out, "Subject: =?utf-8?q?")
+		assert.Contains(t, out, "Content-Type: text/plain; charset=utf-8\r\n")
+		assert.Contains(t, out, "\r\n\r\nline one\r\nline two\r\n")
+	})
+
+	t.Run("RejectsHeaderInjection", func(t *testing.T) {
+		var buf bytes.Buffer
+		m := mailer.NewLogMailer(&buf, "no-reply@example.com")
+
+		err := m.Send(ctx, mailer.Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi", Body: "x"})
+		assert.ErrorIs(t, err, mailer.ErrInvalidHeader)
+
+		err = m.Send(ctx, mailer.Message{To: "user@example.com", Subject: "Hi\nBcc: victim@example.com", Body: "x"})
+		assert.ErrorIs(t, err, mailer.ErrInvalidHeader)
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestSMTPMailerRejectsBadAddress(t *testing.T) {
+	m := mailer.NewSMTPMailer(mailer.SMTPConfig{Host: "localhost", Port: "25", From: "no-reply@example.com"})
+
+	// Adres hatası bağlantı kurulmadan döner
+	err := m.Send(context.Background(), mailer.Message{To: "not an address", Subject: "Hi", Body: "x"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+package common
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/money"
+)
+
+// TestMoney tam sayı tabanlı para tipi için testler
+func TestMoney(t *testing.T) {
+	t.Run("Parse_Valid", func(t *testing.T) {
+		cases := map[string]int64{
+			"0":        0,
+			"12":       1200,
+			"12.3":     1230,
+			"12.34":    1234,
+			"-0.05":    -5,
+			"0.10":     10,
+			"99999.99": 9999999,
+		}
+		for s, minor := range cases {
+			m, err := money.Parse(s, "TRY")
+			require.NoError(t, err, s)
+			assert.Equal(t, minor, m.MinorUnits(), s)
+			assert.Equal(t, "TRY", m.Currency(), s)
+		}
+	})
+
+	t.Run("Parse_Invalid", func(t *testing.T) {
+		for _, s := range []string{"", "-", "abc", "1.", ".5", "1,50", "+1", "1e3", "1.2.3", " 1"} {
+			_, err := money.Parse(s, "TRY")
+			assert.ErrorIs(t, err, money.ErrInvalidAmount, s)
+		}
+	})
+
+	t.Run("Parse_RejectsSubCentPrecision", func(t *testing.T) {
+		_, err := money.Parse("0.001", "TRY")
+		assert.ErrorIs(t, err, money.ErrPrecision)
+	})
+
+	t.Run("Parse_Overflow", func(t *testing.T) {
+		_, err := money.Parse("999999999999999999999", "TRY")
+		assert.ErrorIs(t, err, money.ErrOverflow)
+	})
+
+	t.Run("ParseRounded", func(t *testing.T) {
+		cases := []struct {
+			in   string
+			mode money.RoundingMode
+			want string
+		}{
+			{"1.005", money.RoundHalfEven, "1.00"},
+			{"1.015", money.RoundHalfEven, "1.02"},
+			{"1.0051", money.RoundHalfEven, "1.01"},
+			{"-1.005", money.RoundHalfEven, "-1.00"},
+			{"1.005", money.RoundHalfUp, "1.01"},
+			{"-1.005", money.RoundHalfUp, "-1.01"},
+			{"1.004", money.RoundHalfUp, "1.00"},
+			{"1.999", money.RoundDown, "1.99"},
+			{"-1.999", money.RoundDown, "-1.99"},
+			{"0.995", money.RoundHalfUp, "1.00"},
+			{"2.5", money.RoundHalfEven, "2.50"},
+		}
+		for _, c := range cases {
+			m, err := money.ParseRounded(c.in, "TRY", c.mode)
+			require.NoError(t, err, c.in)
+			assert.Equal(t, c.want, m.String(), c.in)
+		}
+	})
+
+	t.Run("Arithmetic_IsExact", func(t *testing.T) {
+		// float64 ile 0.1 + 0.2 != 0.3
+		a := money.MustParse("0.10", "TRY")
+		b := money.MustParse("0.20", "TRY")
+		sum, err := a.Add(b)
+		require.NoError(t, err)
+		assert.Equal(t, money.MustParse("0.30", "TRY"), sum)
+
+		diff, err := sum.Sub(money.MustParse("0.30", "TRY"))
+		require.NoError(t, err)
+		assert.True(t, diff.IsZero())
+
+		cmp, err := a.Cmp(b)
+		require.NoError(t, err)
+		assert.Equal(t, -1, cmp)
+	})
+
+	t.Run("Arithmetic_CurrencyMismatch", func(t *testing.T) {
+		_, err := money.MustParse("1", "TRY").Add(money.MustParse("1", "EUR"))
+		assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+		_, err = money.MustParse("1", "TRY").Cmp(money.MustParse("1", "EUR"))
+		assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+	})
+
+	t.Run("Arithmetic_Overflow", func(t *testing.T) {
+		_, err := money.New(math.MaxInt64, "TRY").Add(money.New(1, "TRY"))
+		assert.ErrorIs(t, err, money.ErrOverflow)
+		_, err = money.New(0, "TRY").Sub(money.New(math.MinInt64, "TRY"))
+		assert.ErrorIs(t, err, money.ErrOverflow)
+	})
+
+	t.Run("String", func(t *testing.T) {
+		assert.Equal(t, "0.00", money.New(0, "TRY").String())
+		assert.Equal(t, "-0.05", money.New(-5, "TRY").String())
+		assert.Equal(t, "1234.50", money.New(123450, "TRY").String())
+		assert.Equal(t, "-92233720368547758.08", money.New(math.MinInt64, "TRY").String())
+	})
+
+	t.Run("JSON_RoundTrip", func(t *testing.T) {
+		b, err := json.Marshal(struct {
+			Amount money.Money `json:"amount"`
+		}{money.MustParse("10.5", "TRY")})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"amount":"10.50"}`, string(b))
+
+		var req struct {
+			Amount money.Money `json:"amount"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(`{"amount":"10.50"}`), &req))
+		assert.Equal(t, int64(1050), req.Amount.MinorUnits())
+
+		// Sayı olarak gönderilen tutar da metinden, float'a çevrilmeden okunur
+		require.NoError(t, json.Unmarshal([]byte(`{"amount":0.29}`), &req))
+		assert.Equal(t, int64(29), req.Amount.MinorUnits())
+
+		assert.Error(t, json.Unmarshal([]byte(`{"amount":"0.001"}`), &req))
+		assert.Error(t, json.Unmarshal([]byte(`{"amount":true}`), &req))
+	})
+
+	t.Run("ScanNumeric", func(t *testing.T) {
+		cases := []struct {
+			num  pgtype.Numeric
+			want int64
+		}{
+			{pgtype.Numeric{Int: big.NewInt(1010), Exp: -2, Valid: true}, 1010},
+			{pgtype.Numeric{Int: big.NewInt(5), Exp: 0, Valid: true}, 500},
+			{pgtype.Numeric{Int: big.NewInt(12), Exp: 3, Valid: true}, 1200000},
+			{pgtype.Numeric{Int: big.NewInt(-12340), Exp: -3, Valid: true}, -1234},
+		}
+		for _, c := range cases {
+			var m money.Money
+			require.NoError(t, m.ScanNumeric(c.num))
+			assert.Equal(t, c.want, m.MinorUnits())
+		}
+
+		var m money.Money
+		assert.ErrorIs(t, m.ScanNumeric(pgtype.Numeric{Int: big.NewInt(1234), Exp: -3, Valid: true}), money.ErrPrecision)
+		assert.Error(t, m.ScanNumeric(pgtype.Numeric{}))
+		assert.Error(t, m.ScanNumeric(pgtype.Numeric{NaN: true, Valid: true}))
+	})
+
+	t.Run("NumericValue", func(t *testing.T) {
+		n, err := money.MustParse("-12.34", "TRY").NumericValue()
+		require.NoError(t, err)
+		assert.True(t, n.Valid)
+		assert.Equal(t, int32(-2), n.Exp)
+		assert.Equal(t, int64(-1234), n.Int.Int64())
+	})
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/password"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := password.Policy{
+		MinLength:          10,
+		MaxLength:          72,
+		MinClasses:         3,
+		Required:           []password.Class{password.ClassDigit},
+		RejectPersonalInfo: true,
+	}
+
+	tests := []struct {
+		name     string
+		pwd      string
+		personal []string
+		want     []string
+	}{
+		{"Valid", "Correct-Horse7", nil, nil},
+		{"TooShort", "Ab1!", nil, []string{"at least 10 characters"}},
+		{"TooLong", "Aa1!" + strings.Repeat("x", 72), nil, []string{"at most 72 bytes"}},
+		{"TooFewClasses", "lowercase123", nil, []string{"at least 3 of"}},
+		{"MissingRequired", "No-Digits-Here", nil, []string{"one digit character"}},
+		{"ContainsName", "Ayse-Yilmaz-99", []string{"Ayşe Yılmaz", "ayse.yilmaz"}, []string{"name or email"}},
+		{"ShortNamePartIgnored", "Correct-Horse7", []string{"Al Or"}, nil},
+		// Karakterler byte değil rune olarak sayılır
+		{"UnicodeLength", "Şifreğüçlü1", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Check(tt.pwd, tt.personal...)
+			require.Len(t, got, len(tt.want), "violations: %v", got)
+			for i, want := range tt.want {
+				assert.Contains(t, got[i], want)
+			}
+		})
+	}
+}
+
+func TestPasswordParseClasses(t *testing.T) {
+	classes, err := password.ParseClasses(" Upper, digit ,")
+	require.NoError(t, err)
+	assert.Equal(t, []password.Class{password.ClassUpper, password.ClassDigit}, classes)
+
+	classes, err = password.ParseClasses("")
+	require.NoError(t, err)
+	assert.Empty(t, classes)
+
+	_, err = password.ParseClasses("upper,emoji")
+	assert.Error(t, err)
+}
+
+func TestBreachedList(t *testing.T) {
+	// Have I Been Pwned biçimi: SHA-1 özeti ve isteğe bağlı sayı
+	list, err := password.LoadBreachedList(strings.NewReader(`# test listesi
+CBFDAC6008F9CAB4083784CBD1874F76618D2A97:251682
+7e8b0a3433f1210a9699d85420e363a1b162ecac
+
+CBFDAC6008F9CAB4083784CBD1874F76618D2A97:3
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, list.Len())
+	assert.True(t, list.Contains("password123"))
+	assert.True(t, list.Contains("Summer2024!"))
+	assert.False(t, list.Contains("P@ssw0rd"))
+	// Önek büyük/küçük harf duyarsızdır ve yalnızca sonekler döner
+	assert.Equal(t, []string{"C6008F9CAB4083784CBD1874F76618D2A97"}, list.Range("cbfda"))
+	assert.Empty(t, list.Range("00000"))
+
+	// Liste yüklenmemişse hiçbir şifre sızdırılmış sayılmaz
+	var none *password.BreachedList
+	assert.False(t, none.Contains("password123"))
+
+	_, err = password.LoadBreachedList(strings.NewReader("not-a-hash\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/totp"
+)
+
+// RFC 6238 Appendix B SHA1 test vectors, truncated to 6 digits
+func TestTOTPRFCVectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := totp.Code(secret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "t=%d", unix)
+	}
+}
+
+func TestTOTPValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+
+	t.Run("CurrentStep", func(t *testing.T) {
+		code, err := totp.Code(secret, now)
+		require.NoError(t, err)
+		step, ok := totp.Validate(secret, code, now, 1)
+		assert.True(t, ok)
+		assert.Equal(t, totp.Step(now), step)
+	})
+
+	t.Run("PreviousStepWithinSkew", func(t *testing.T) {
+		code, err := totp.Code(secret, now.Add(-totp.Period*time.Second))
+		require.NoError(t, err)
+		step, ok := totp.Validate(secret, code, now, 1)
+		assert.True(t, ok)
+		assert.Equal(t, totp.Step(now)-1, step)
+	})
+
+	t.Run("OutsideSkew", func(t *testing.T) {
+		code, err := totp.Code(secret, now.Add(-3*totp.Period*time.Second))
+		require.NoError(t, err)
+		_, ok := totp.Validate(secret, code, now, 1)
+		assert.False(t, ok)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+			_, ok := totp.Validate(secret, code, now, 1)
+			assert.False(t, ok, code)
+		}
+		_, ok := totp.Validate("not base32!", "123456", now, 1)
+		assert.False(t, ok)
+	})
+}
+
+func TestTOTPGenerateSecret(t *testing.T) {
+	a, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	b, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	assert.Len(t, a, 32) // 20 byte = 32 base32 karakter
+	assert.NotContains(t, a, "=")
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totp.URI("Bank App", "user@example.com", "JBSWY3DPEHPK3PXP")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/"))
+
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Bank App:user@example.com", u.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal(t, "Bank App", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+	assert.Equal(t, "30", u.Query().Get("period"))
+}
@@ -10,8 +10,14 @@ import (
 
 // ClearTestDatabase test veritabanını temizler
 func ClearTestDatabase(ctx context.Context, pool *pgxpool.Pool) error {
+	// Ledger kayıtları hesapların silinmesini engeller, önce onları temizle
+	_, err := pool.Exec(ctx, "TRUNCATE cards, transactions, transfers, postings, journal_entries, accounts RESTART IDENTITY")
+	if err != nil {
+		return fmt.Errorf("failed to clear ledger tables: %w", err)
+	}
+
 	// Users tablosunu temizle
-	_, err := pool.Exec(ctx, "DELETE FROM users")
+	_, err = pool.Exec(ctx, "DELETE FROM users")
 	if err != nil {
 		return fmt.Errorf("failed to clear users table: %w", err)
 	}
@@ -40,6 +40,71 @@ func InitializeTestDatabase(ctx context.Context, pool *pgxpool.Pool) error {
 		return fmt.Errorf("failed to create users table: %w", err)
 	}
 
+	// Hesap ve ledger tablolarını oluştur
+	createLedgerTables := `
+	CREATE TABLE IF NOT EXISTS accounts (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+		account_number VARCHAR(20) UNIQUE NOT NULL,
+		balance NUMERIC(12,2) DEFAULT 0,
+		currency CHAR(3) NOT NULL DEFAULT 'TRY',
+		is_active BOOLEAN DEFAULT true,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS journal_entries (
+		id BIGSERIAL PRIMARY KEY,
+		description TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS postings (
+		id BIGSERIAL PRIMARY KEY,
+		journal_entry_id BIGINT NOT NULL REFERENCES journal_entries(id),
+		account_id BIGINT NOT NULL REFERENCES accounts(id),
+		direction VARCHAR(6) CHECK (direction IN ('debit', 'credit')) NOT NULL,
+		amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+		currency CHAR(3) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS transfers (
+		id BIGSERIAL PRIMARY KEY,
+		from_account_id BIGINT NOT NULL REFERENCES accounts(id),
+		to_account_id BIGINT NOT NULL REFERENCES accounts(id),
+		amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+		currency CHAR(3) NOT NULL,
+		description TEXT,
+		journal_entry_id BIGINT NOT NULL REFERENCES journal_entries(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		CHECK (from_account_id <> to_account_id)
+	);
+	CREATE TABLE IF NOT EXISTS transactions (
+		id BIGSERIAL PRIMARY KEY,
+		account_id BIGINT REFERENCES accounts(id) ON DELETE CASCADE,
+		amount NUMERIC(12,2) NOT NULL,
+		type VARCHAR(10) CHECK (type IN ('deposit', 'withdraw', 'transfer')) NOT NULL,
+		description TEXT,
+		journal_entry_id BIGINT REFERENCES journal_entries(id),
+		transfer_id BIGINT REFERENCES transfers(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS cards (
+		id BIGSERIAL PRIMARY KEY,
+		account_id BIGINT REFERENCES accounts(id) ON DELETE CASCADE,
+		card_number TEXT NOT NULL,
+		card_number_hash CHAR(64) UNIQUE NOT NULL,
+		expiry_date DATE NOT NULL,
+		cvv TEXT NOT NULL,
+		is_active BOOLEAN DEFAULT true,
+		replaced_by_id BIGINT REFERENCES cards(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	_, err = pool.Exec(ctx, createLedgerTables)
+	if err != nil {
+		return fmt.Errorf("failed to create ledger tables: %w", err)
+	}
+
 	// Test verilerini ekle
 	return insertTestData(ctx, pool)
 }
@@ -176,6 +176,27 @@ func TestUserRepository(t *testing.T) {
 		assert.ErrorIs(t, err, pgx.ErrNoRows)
 	})
 
+	t.Run("ChangePassword_RevokesSessions", func(t *testing.T) {
+		testUser, err := GetTestUserByEmail(ctx, pool, "test1@example.com")
+		require.NoError(t, err)
+		before, err := repo.GetUserByID(ctx, testUser.ID)
+		require.NoError(t, err)
+
+		err = repo.ChangePassword(ctx, testUser.ID, "changedhashedpassword", 2)
+		require.NoError(t, err)
+
+		// Şifre değişir ve token sürümü aynı işlemde artar
+		updatedUser, err := repo.GetUserByID(ctx, testUser.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "changedhashedpassword", updatedUser.PasswordHash)
+		assert.Equal(t, before.TokenVersion+1, updatedUser.TokenVersion)
+	})
+
+	t.Run("ChangePassword_UserNotFound", func(t *testing.T) {
+		err := repo.ChangePassword(ctx, 99999, "newpassword", 2)
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
 	t.Run("UpdateUserActiveStatus_Success", func(t *testing.T) {
 		// Önce test kullanıcısını al
 		testUser, err := GetTestUserByEmail(ctx, pool, "test1@example.com")
@@ -35,6 +35,9 @@ CREATE TABLE IF NOT EXISTS users (
     password_hash VARCHAR(255) NOT NULL,
     role VARCHAR(50) NOT NULL DEFAULT 'user',
     is_active BOOLEAN DEFAULT TRUE,
+    email_verified_at TIMESTAMP WITH TIME ZONE,
+    token_version BIGINT NOT NULL DEFAULT 0,
+    is_service_account BOOLEAN NOT NULL DEFAULT FALSE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
@@ -44,38 +47,244 @@ CREATE TABLE IF NOT EXISTS accounts (
     user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
     account_number VARCHAR(20) UNIQUE NOT NULL,
     balance NUMERIC(12,2) DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT 'TRY',
+    is_active BOOLEAN DEFAULT TRUE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id BIGSERIAL PRIMARY KEY,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Bakiyeler postings üzerinden türetilir; accounts.balance aynı transaction içinde güncellenen özet değerdir
+CREATE TABLE IF NOT EXISTS postings (
+    id BIGSERIAL PRIMARY KEY,
+    journal_entry_id BIGINT NOT NULL REFERENCES journal_entries(id),
+    account_id BIGINT NOT NULL REFERENCES accounts(id),
+    direction VARCHAR(6) CHECK (direction IN ('debit', 'credit')) NOT NULL,
+    amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_postings_account_id ON postings(account_id);
+CREATE INDEX IF NOT EXISTS idx_postings_journal_entry_id ON postings(journal_entry_id);
+
+CREATE TABLE IF NOT EXISTS transfers (
+    id BIGSERIAL PRIMARY KEY,
+    from_account_id BIGINT NOT NULL REFERENCES accounts(id),
+    to_account_id BIGINT NOT NULL REFERENCES accounts(id),
+    amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    description TEXT,
+    journal_entry_id BIGINT NOT NULL REFERENCES journal_entries(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_account_id <> to_account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_from_account_id ON transfers(from_account_id);
+CREATE INDEX IF NOT EXISTS idx_transfers_to_account_id ON transfers(to_account_id);
+
 CREATE TABLE IF NOT EXISTS transactions (
     id BIGSERIAL PRIMARY KEY,
     account_id BIGINT REFERENCES accounts(id) ON DELETE CASCADE,
     amount NUMERIC(12,2) NOT NULL,
     type VARCHAR(10) CHECK (type IN ('deposit', 'withdraw', 'transfer')) NOT NULL,
     description TEXT,
+    journal_entry_id BIGINT REFERENCES journal_entries(id),
+    transfer_id BIGINT REFERENCES transfers(id),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions(account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_transfer_id ON transactions(transfer_id);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    response_status INT,
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
 CREATE TABLE IF NOT EXISTS cards (
     id BIGSERIAL PRIMARY KEY,
     account_id BIGINT REFERENCES accounts(id) ON DELETE CASCADE,
-    card_number VARCHAR(16) UNIQUE NOT NULL,
+    -- card_number ve cvv keyring ile şifrelenmiş olarak saklanır, aramalar card_number_hash üzerinden yapılır
+    card_number TEXT NOT NULL,
+    card_number_hash CHAR(64) UNIQUE NOT NULL,
     expiry_date DATE NOT NULL,
-    cvv VARCHAR(4) NOT NULL,
+    cvv TEXT NOT NULL,
     is_active BOOLEAN DEFAULT TRUE,
+    replaced_by_id BIGINT REFERENCES cards(id),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE INDEX IF NOT EXISTS idx_cards_account_id ON cards(account_id);
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+  id BIGSERIAL PRIMARY KEY,
+  client_id VARCHAR(64) NOT NULL UNIQUE,
+  secret_hash CHAR(64),
+  name TEXT NOT NULL,
+  redirect_uris TEXT[] NOT NULL,
+  scopes TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  family_id TEXT NOT NULL UNIQUE,
+  client_id VARCHAR(64) REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  device_label TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  ip_address TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  last_used_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
 CREATE TABLE IF NOT EXISTS refresh_tokens (
   id SERIAL PRIMARY KEY,
   user_id BIGINT NOT NULL,
-  token TEXT NOT NULL UNIQUE,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  family_id TEXT NOT NULL REFERENCES sessions(family_id) ON DELETE CASCADE,
   expires_at TIMESTAMP NOT NULL,
   created_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
   CONSTRAINT fk_user FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 );
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+  id BIGSERIAL PRIMARY KEY,
+  code_hash CHAR(64) NOT NULL UNIQUE,
+  client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  redirect_uri TEXT NOT NULL,
+  redirect_uri_provided BOOLEAN NOT NULL DEFAULT FALSE,
+  scopes TEXT[] NOT NULL,
+  code_challenge VARCHAR(128) NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_authorization_codes_expires_at ON oauth_authorization_codes(expires_at);
+
+CREATE TABLE IF NOT EXISTS user_totp (
+  user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret TEXT NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  failed_attempts INT NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMP,
+  confirmed_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash CHAR(64) NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  attempts INT NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_user_id ON mfa_challenges(user_id);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email VARCHAR(255) NOT NULL,
+  purpose VARCHAR(16) NOT NULL DEFAULT 'verify',
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications(user_id);
+
+CREATE TABLE IF NOT EXISTS password_history (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  password_hash VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history(user_id);
+
+CREATE TABLE IF NOT EXISTS login_failures (
+  scope VARCHAR(16) NOT NULL,
+  key VARCHAR(255) NOT NULL,
+  failures INT NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMP NOT NULL,
+  locked_until TIMESTAMP,
+  PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_last_failure_at ON login_failures(last_failure_at);
+
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+  jti VARCHAR(64) PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires_at ON revoked_access_tokens(expires_at);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  key_hash CHAR(64) NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL,
+  token_version BIGINT NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
 EOF
 
 echo "✔ Tüm tablolar başarıyla oluşturuldu ✅"
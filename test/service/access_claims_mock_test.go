@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// TestAccessTokenRegisteredClaims verilen token'ların standart claim'leri taşıdığını test eder
+func TestAccessTokenRegisteredClaims(t *testing.T) {
+	r := newTestRouter(t)
+	auth := registerForStepUp(t, r, "registered@example.com")
+
+	token, err := testAccessTokens.Parse(auth.Token)
+	require.NoError(t, err)
+	claims := token.Claims.(*controller.AccessClaims)
+	assert.Equal(t, testAccessTokens.Issuer, claims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{testAccessTokens.Audience}, claims.Audience)
+	assert.Equal(t, fmt.Sprint(auth.User.ID), claims.Subject)
+	assert.Equal(t, model.RoleUser, claims.Role)
+	assert.NotEmpty(t, claims.ID)
+	require.NotNil(t, claims.IssuedAt)
+	require.NotNil(t, claims.NotBefore)
+	assert.WithinDuration(t, time.Now(), claims.IssuedAt.Time, time.Minute)
+	assert.Equal(t, claims.IssuedAt.Time.Add(testAccessTokens.TTL), claims.ExpiresAt.Time)
+	assert.Nil(t, claims.AuthTime)
+}
+
+// TestAccessTokenValidation issuer, audience ve zaman claim'lerinin doğrulanmasını test eder
+func TestAccessTokenValidation(t *testing.T) {
+	r := newTestRouter(t)
+	user := &model.User{FullName: "User", Email: "validation@example.com", Role: model.RoleUser, IsActive: true}
+	r.users.AddTestUser(user)
+	path := fmt.Sprintf("/api/v1/users/%d", user.ID)
+
+	tests := []struct {
+		name   string
+		modify func(c *controller.AccessClaims)
+		status int
+	}{
+		{"Valid", func(c *controller.AccessClaims) {}, http.StatusOK},
+		{"WrongIssuer", func(c *controller.AccessClaims) { c.Issuer = "someone-else" }, http.StatusUnauthorized},
+		{"MissingIssuer", func(c *controller.AccessClaims) { c.Issuer = "" }, http.StatusUnauthorized},
+		{"WrongAudience", func(c *controller.AccessClaims) { c.Audience = jwt.ClaimStrings{"other-api"} }, http.StatusUnauthorized},
+		{"AudienceAmongOthers", func(c *controller.AccessClaims) {
+			c.Audience = jwt.ClaimStrings{"other-api", testAccessTokens.Audience}
+		}, http.StatusOK},
+		{"MissingExpiry", func(c *controller.AccessClaims) { c.ExpiresAt = nil }, http.StatusUnauthorized},
+		{"ExpiredWithinLeeway", func(c *controller.AccessClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Second))
+		}, http.StatusOK},
+		{"Expired", func(c *controller.AccessClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		}, http.StatusUnauthorized},
+		{"NotYetValid", func(c *controller.AccessClaims) {
+			c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute))
+		}, http.StatusUnauthorized},
+		{"IssuedInFuture", func(c *controller.AccessClaims) {
+			c.IssuedAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+		}, http.StatusUnauthorized},
+		{"NonNumericSubject", func(c *controller.AccessClaims) { c.Subject = "alice" }, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := testClaims(user.ID, model.RoleUser)
+			tt.modify(&claims)
+			rec := r.do(http.MethodGet, path, signClaims(t, claims), "")
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+		})
+	}
+
+	t.Run("NumericSubject", func(t *testing.T) {
+		// Eski biçimdeki sayısal sub claim'i kabul edilmez
+		claims := jwt.MapClaims{
+			"iss": testAccessTokens.Issuer, "aud": testAccessTokens.Audience,
+			"sub": user.ID, "role": model.RoleUser, "exp": time.Now().Add(time.Hour).Unix(),
+		}
+		rec := r.do(http.MethodGet, path, signClaims(t, claims), "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+// TestUserControllerUsesPrincipal route middleware'i olmadan da handler'ın :id yerine token sahibine göre yetki verdiğini test eder
+func TestUserControllerUsesPrincipal(t *testing.T) {
+	users := NewMockUserRepository()
+	self := &model.User{FullName: "Self", Email: "self@example.com", Role: model.RoleUser, IsActive: true}
+	other := &model.User{FullName: "Other", Email: "other@example.com", Role: model.RoleUser, IsActive: true}
+	users.AddTestUser(self)
+	users.AddTestUser(other)
+
+	authenticate := func(role string) echo.MiddlewareFunc {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				claims := testClaims(self.ID, role)
+				c.Set("user", &jwt.Token{Claims: &claims})
+				return next(c)
+			}
+		}
+	}
+	ctrl := controller.NewUserController(service.NewUserService(users, testPasswordPolicy), nil, nil)
+	e := echo.New()
+	e.GET("/users/:id", ctrl.GetByID, authenticate(model.RoleUser))
+	e.GET("/admin/users/:id", ctrl.GetByID, authenticate(model.RoleAdmin))
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, get(fmt.Sprintf("/users/%d", self.ID)))
+	assert.Equal(t, http.StatusForbidden, get(fmt.Sprintf("/users/%d", other.ID)))
+	assert.Equal(t, http.StatusOK, get(fmt.Sprintf("/admin/users/%d", other.ID)))
+}
+
+// TestPrincipalScopes scope taşımayan token'ların kısıtlanmadığını test eder
+func TestPrincipalScopes(t *testing.T) {
+	unrestricted := controller.Principal{UserID: 1, Role: model.RoleUser}
+	assert.True(t, unrestricted.HasScope("accounts:read"))
+
+	limited := controller.Principal{UserID: 1, Role: model.RoleUser, Scopes: []string{"accounts:read"}}
+	assert.True(t, limited.HasScope("accounts:read"))
+	assert.False(t, limited.HasScope("transfers:write"))
+
+	assert.True(t, limited.CanManageUser(1))
+	assert.False(t, limited.CanManageUser(2))
+	assert.True(t, controller.Principal{UserID: 1, Role: model.RoleAdmin}.CanManageUser(2))
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// loginFor kullanıcıyı giriş yaptırır ve token'ları döner
+func loginFor(t *testing.T, r *testRouter, email string) dto.AuthResponse {
+	rec := r.do(http.MethodPost, "/api/v1/login", "", fmt.Sprintf(`{"email":%q,"password":"password123"}`, email))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var auth dto.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+	return auth
+}
+
+// addAdmin test router'ına admin kullanıcı ekler
+func addAdmin(r *testRouter) *model.User {
+	admin := &model.User{FullName: "Admin", Email: "admin@example.com", Role: model.RoleAdmin, IsActive: true}
+	r.users.AddTestUser(admin)
+	return admin
+}
+
+// assertRevoked token'ın korumalı endpoint'lerde reddedildiğini doğrular
+func assertRevoked(t *testing.T, r *testRouter, token string, userID int64) {
+	t.Helper()
+	rec := r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", userID), token, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "TOKEN_REVOKED")
+}
+
+// TestAccessTokenClaims access token'ların jti ve token sürümü taşıdığını test eder
+func TestAccessTokenClaims(t *testing.T) {
+	r := newTestRouter(t)
+	auth := registerForStepUp(t, r, "claims@example.com")
+	second := loginFor(t, r, "claims@example.com")
+
+	parse := func(token string) jwt.MapClaims {
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(token, claims, testJWTKeys.Keyfunc)
+		require.NoError(t, err)
+		return claims
+	}
+	first, other := parse(auth.Token), parse(second.Token)
+	assert.NotEmpty(t, first["jti"])
+	assert.NotEqual(t, first["jti"], other["jti"])
+	assert.Equal(t, float64(0), first["ver"])
+}
+
+// TestAccessTokenRevocation iptal edilen access token'ların süresi dolmadan reddedilmesini test eder
+func TestAccessTokenRevocation(t *testing.T) {
+	t.Run("Deactivate", func(t *testing.T) {
+		r := newTestRouter(t)
+		auth := registerForStepUp(t, r, "deactivate@example.com")
+		adminToken := tokenFor(t, addAdmin(r).ID, model.RoleAdmin)
+
+		rec := r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/status", auth.User.ID), adminToken, `{"is_active":false}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assertRevoked(t, r, auth.Token, auth.User.ID)
+
+		// Yeniden aktifleştirmek eski token'ları geri getirmez
+		rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/status", auth.User.ID), adminToken, `{"is_active":true}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assertRevoked(t, r, auth.Token, auth.User.ID)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := newTestRouter(t)
+		auth := registerForStepUp(t, r, "delete@example.com")
+		adminToken := tokenFor(t, addAdmin(r).ID, model.RoleAdmin)
+
+		rec := r.do(http.MethodDelete, fmt.Sprintf("/api/v1/users/%d", auth.User.ID), adminToken, "")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		rec = r.do(http.MethodGet, "/api/v1/accounts", auth.Token, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("LogoutAll", func(t *testing.T) {
+		r := newTestRouter(t)
+		auth := registerForStepUp(t, r, "logout-all@example.com")
+		other := loginFor(t, r, "logout-all@example.com")
+
+		rec := r.do(http.MethodPost, "/api/v1/logout-all", auth.Token, "")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assertRevoked(t, r, auth.Token, auth.User.ID)
+		assertRevoked(t, r, other.Token, auth.User.ID)
+
+		// Sonraki girişler yeni sürümle imzalanır
+		fresh := loginFor(t, r, "logout-all@example.com")
+		rec = r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", auth.User.ID), fresh.Token, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		r := newTestRouter(t)
+		auth := registerForStepUp(t, r, "logout@example.com")
+		other := loginFor(t, r, "logout@example.com")
+
+		rec := r.do(http.MethodPost, "/api/v1/logout", auth.Token, `{"refresh_token":"`+auth.RefreshToken+`"}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assertRevoked(t, r, auth.Token, auth.User.ID)
+
+		// Diğer oturumun token'ı etkilenmez
+		rec = r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", auth.User.ID), other.Token, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Geçersiz Authorization başlığı çıkışı engellemez
+		rec = r.do(http.MethodPost, "/api/v1/logout", "bogus", `{"refresh_token":"`+other.RefreshToken+`"}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("PasswordChange", func(t *testing.T) {
+		r := newTestRouter(t)
+		auth := registerForStepUp(t, r, "password@example.com")
+		elevated := reauth(t, r, auth.Token, `{"password":"password123"}`)
+
+		rec := r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/password", auth.User.ID), elevated.Token, `{"new_password":"newpassword123"}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		assertRevoked(t, r, auth.Token, auth.User.ID)
+		assertRevoked(t, r, elevated.Token, auth.User.ID)
+	})
+
+	t.Run("TokensWithoutClaims", func(t *testing.T) {
+		r := newTestRouter(t)
+		user := &model.User{FullName: "Legacy", Email: "legacy@example.com", Role: model.RoleUser, IsActive: true}
+		r.users.AddTestUser(user)
+
+		// jti ve sürüm taşımayan eski token'lar ilk sürüm değişikliğine kadar geçerlidir
+		legacy := tokenFor(t, user.ID, model.RoleUser)
+		rec := r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", user.ID), legacy, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = r.do(http.MethodPost, "/api/v1/logout-all", legacy, "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		assertRevoked(t, r, legacy, user.ID)
+	})
+}
+
+// TestRevokedAccessTokenPurge süresi dolmuş iptal kayıtlarının temizlenmesini test eder
+func TestRevokedAccessTokenPurge(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockUserRepository()
+	svc := service.NewUserService(repo, testPasswordPolicy)
+	user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+	repo.AddTestUser(user)
+
+	require.NoError(t, svc.RevokeAccessToken(ctx, user.ID, "live", time.Now().Add(time.Hour)))
+	require.NoError(t, svc.RevokeAccessToken(ctx, user.ID, "expiring", time.Now().Add(20*time.Millisecond)))
+	// Süresi geçmiş token'ların listeye eklenmesine gerek yoktur
+	require.NoError(t, svc.RevokeAccessToken(ctx, user.ID, "expired", time.Now().Add(-time.Minute)))
+	require.Equal(t, 2, repo.RevokedAccessTokenCount())
+
+	assert.ErrorIs(t, svc.CheckAccessToken(ctx, user.ID, 0, "live"), service.ErrAccessTokenRevoked)
+	assert.NoError(t, svc.CheckAccessToken(ctx, user.ID, 0, "other"))
+
+	time.Sleep(50 * time.Millisecond)
+	n, err := svc.PurgeExpiredRevokedAccessTokens(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, 1, repo.RevokedAccessTokenCount())
+}
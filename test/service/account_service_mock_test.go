@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/iban"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// TestAccountServiceWithMock AccountService için mock repository ile testler
+func TestAccountServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("OpenAccount_Success", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		account, err := svc.OpenAccount(ctx, 1)
+		require.NoError(t, err)
+		assert.NotZero(t, account.ID)
+		assert.Equal(t, int64(1), account.UserID)
+		assert.True(t, iban.Validate(account.AccountNumber))
+		assert.Equal(t, "TR", account.AccountNumber[:2])
+		assert.True(t, account.Balance.IsZero())
+		assert.Equal(t, "TRY", account.Balance.Currency())
+		assert.Equal(t, "TRY", account.Currency)
+		assert.True(t, account.IsActive)
+	})
+
+	t.Run("OpenAccount_RetriesOnDuplicateNumber", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		// İlk iki deneme unique violation ile sonuçlanır
+		mockRepo.UniqueViolations = 2
+
+		account, err := svc.OpenAccount(ctx, 1)
+		require.NoError(t, err)
+		assert.NotZero(t, account.ID)
+		assert.Zero(t, mockRepo.UniqueViolations)
+	})
+
+	t.Run("OpenAccount_GivesUpAfterMaxAttempts", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		mockRepo.UniqueViolations = 100
+
+		_, err := svc.OpenAccount(ctx, 1)
+		assert.Error(t, err)
+	})
+
+	t.Run("GetUserAccounts_OnlyOwnAccounts", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		mockRepo.AddTestAccount(&model.Account{UserID: 1, AccountNumber: "1111", IsActive: true})
+		mockRepo.AddTestAccount(&model.Account{UserID: 1, AccountNumber: "2222", IsActive: true})
+		mockRepo.AddTestAccount(&model.Account{UserID: 2, AccountNumber: "3333", IsActive: true})
+
+		accounts, err := svc.GetUserAccounts(ctx, 1)
+		require.NoError(t, err)
+		assert.Len(t, accounts, 2)
+		for _, a := range accounts {
+			assert.Equal(t, int64(1), a.UserID)
+		}
+	})
+
+	t.Run("GetAccountByID_Success", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		testAccount := &model.Account{UserID: 1, AccountNumber: "1111", IsActive: true}
+		mockRepo.AddTestAccount(testAccount)
+
+		account, err := svc.GetAccountByID(ctx, 1, testAccount.ID)
+		require.NoError(t, err)
+		assert.Equal(t, testAccount.AccountNumber, account.AccountNumber)
+	})
+
+	t.Run("GetAccountByID_NotFound", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		_, err := svc.GetAccountByID(ctx, 1, 999)
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	})
+
+	t.Run("GetAccountByID_OtherUsersAccount", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		// Başka kullanıcının hesabı bulunamadı olarak dönmeli
+		testAccount := &model.Account{UserID: 2, AccountNumber: "1111", IsActive: true}
+		mockRepo.AddTestAccount(testAccount)
+
+		account, err := svc.GetAccountByID(ctx, 1, testAccount.ID)
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+		assert.Empty(t, account)
+	})
+
+	t.Run("CloseAccount_Success", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		testAccount := &model.Account{UserID: 1, AccountNumber: "1111", IsActive: true}
+		mockRepo.AddTestAccount(testAccount)
+
+		err := svc.CloseAccount(ctx, 1, testAccount.ID)
+		require.NoError(t, err)
+
+		account, err := svc.GetAccountByID(ctx, 1, testAccount.ID)
+		require.NoError(t, err)
+		assert.False(t, account.IsActive)
+	})
+
+	t.Run("CloseAccount_AlreadyClosed", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		testAccount := &model.Account{UserID: 1, AccountNumber: "1111", IsActive: false}
+		mockRepo.AddTestAccount(testAccount)
+
+		err := svc.CloseAccount(ctx, 1, testAccount.ID)
+		assert.ErrorIs(t, err, service.ErrAccountClosed)
+	})
+
+	t.Run("CloseAccount_NonZeroBalance", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		testAccount := &model.Account{UserID: 1, AccountNumber: "1111", Balance: money.MustParse("10", "TRY"), Currency: "TRY", IsActive: true}
+		mockRepo.AddTestAccount(testAccount)
+
+		err := svc.CloseAccount(ctx, 1, testAccount.ID)
+		assert.ErrorIs(t, err, service.ErrAccountHasBalance)
+	})
+
+	t.Run("CloseAccount_OtherUsersAccount", func(t *testing.T) {
+		mockRepo := NewMockAccountRepository()
+		svc := service.NewAccountService(mockRepo, "TR", "TRY")
+
+		testAccount := &model.Account{UserID: 2, AccountNumber: "1111", IsActive: true}
+		mockRepo.AddTestAccount(testAccount)
+
+		err := svc.CloseAccount(ctx, 1, testAccount.ID)
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	})
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// createAPIKey yeniden doğrulanmış token ile anahtar oluşturur
+func createAPIKey(t *testing.T, r *testRouter, token, body string) dto.APIKeyResponse {
+	rec := r.do(http.MethodPost, "/api/v1/api-keys", token, body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var key dto.APIKeyResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &key))
+	return key
+}
+
+// TestAPIKeyServiceWithMock anahtar oluşturma, doğrulama ve iptal kurallarını test eder
+func TestAPIKeyServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*MockAPIKeyRepository, *MockUserRepository, service.APIKeyService, *model.User) {
+		users := NewMockUserRepository()
+		repo := NewMockAPIKeyRepository(users)
+		svc := service.NewAPIKeyService(repo, service.NewUserService(users, testPasswordPolicy))
+		user := &model.User{FullName: "Script", Email: "script@example.com", Role: model.RoleUser, IsActive: true}
+		users.AddTestUser(user)
+		return repo, users, svc, user
+	}
+
+	t.Run("CreateAndAuthenticate", func(t *testing.T) {
+		repo, _, svc, user := setup(t)
+
+		key, raw, err := svc.CreateKey(ctx, user.ID, " nightly export ", []string{model.ScopeAccountsRead}, 24*time.Hour)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(raw, model.APIKeyPrefix))
+		assert.True(t, strings.HasPrefix(raw, key.Prefix))
+		assert.Equal(t, "nightly export", key.Name)
+
+		// Yalnızca özet saklanır
+		keys, err := repo.GetUserAPIKeys(ctx, user.ID, time.Now())
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.NotContains(t, keys[0].KeyHash, raw[len(model.APIKeyPrefix):])
+		assert.Nil(t, keys[0].LastUsedAt)
+
+		got, u, err := svc.Authenticate(ctx, raw)
+		require.NoError(t, err)
+		assert.Equal(t, key.ID, got.ID)
+		assert.Equal(t, user.ID, u.ID)
+		assert.NotNil(t, got.LastUsedAt)
+
+		_, _, err = svc.Authenticate(ctx, raw+"x")
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+		_, _, err = svc.Authenticate(ctx, "not-a-key")
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		_, _, svc, user := setup(t)
+
+		_, _, err := svc.CreateKey(ctx, user.ID, "k", []string{"admin"}, time.Hour)
+		assert.ErrorIs(t, err, service.ErrInvalidScope)
+		_, _, err = svc.CreateKey(ctx, user.ID, "k", nil, time.Hour)
+		assert.ErrorIs(t, err, service.ErrInvalidScope)
+		_, _, err = svc.CreateKey(ctx, user.ID, "k", []string{model.ScopeAccountsRead}, 0)
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKeyExpiry)
+		_, _, err = svc.CreateKey(ctx, user.ID, "k", []string{model.ScopeAccountsRead}, 366*24*time.Hour)
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKeyExpiry)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		repo, _, svc, user := setup(t)
+
+		for i := 0; i < 10; i++ {
+			_, _, err := svc.CreateKey(ctx, user.ID, fmt.Sprintf("k%d", i), []string{model.ScopeAccountsRead}, time.Hour)
+			require.NoError(t, err)
+		}
+		_, _, err := svc.CreateKey(ctx, user.ID, "one too many", []string{model.ScopeAccountsRead}, time.Hour)
+		assert.ErrorIs(t, err, service.ErrAPIKeyLimitReached)
+
+		// Süresi dolan anahtarlar limite sayılmaz
+		repo.ExpireAPIKeys()
+		_, _, err = svc.CreateKey(ctx, user.ID, "fresh", []string{model.ScopeAccountsRead}, time.Hour)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ExpiredKey", func(t *testing.T) {
+		repo, _, svc, user := setup(t)
+
+		_, raw, err := svc.CreateKey(ctx, user.ID, "k", []string{model.ScopeAccountsRead}, time.Hour)
+		require.NoError(t, err)
+		repo.ExpireAPIKeys()
+
+		_, _, err = svc.Authenticate(ctx, raw)
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+		keys, err := svc.GetUserKeys(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+
+	t.Run("InactiveUser", func(t *testing.T) {
+		_, users, svc, user := setup(t)
+
+		_, raw, err := svc.CreateKey(ctx, user.ID, "k", []string{model.ScopeAccountsRead}, time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, users.UpdateUserActiveStatus(ctx, user.ID, false))
+
+		_, _, err = svc.Authenticate(ctx, raw)
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+	})
+
+	t.Run("RevokedWithUserTokens", func(t *testing.T) {
+		_, users, svc, user := setup(t)
+
+		_, raw, err := svc.CreateKey(ctx, user.ID, "k", []string{model.ScopeAccountsRead}, time.Hour)
+		require.NoError(t, err)
+		// Tüm oturumları kapatmak token sürümünü artırır
+		require.NoError(t, users.DeleteUserRefreshTokens(ctx, user.ID))
+
+		_, _, err = svc.Authenticate(ctx, raw)
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+		keys, err := svc.GetUserKeys(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+
+		// Yeni anahtar güncel sürümle oluşturulur
+		_, raw, err = svc.CreateKey(ctx, user.ID, "k", []string{model.ScopeAccountsRead}, time.Hour)
+		require.NoError(t, err)
+		_, _, err = svc.Authenticate(ctx, raw)
+		assert.NoError(t, err)
+	})
+
+	t.Run("RevokeOnlyOwnKeys", func(t *testing.T) {
+		_, users, svc, user := setup(t)
+		other := &model.User{FullName: "Other", Email: "other@example.com", Role: model.RoleUser, IsActive: true}
+		users.AddTestUser(other)
+
+		key, raw, err := svc.CreateKey(ctx, user.ID, "k", []string{model.ScopeAccountsRead}, time.Hour)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, svc.RevokeKey(ctx, other.ID, key.ID), service.ErrAPIKeyNotFound)
+		require.NoError(t, svc.RevokeKey(ctx, user.ID, key.ID))
+		assert.ErrorIs(t, svc.RevokeKey(ctx, user.ID, key.ID), service.ErrAPIKeyNotFound)
+
+		_, _, err = svc.Authenticate(ctx, raw)
+		assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+	})
+}
+
+// TestAPIKeyAuthentication anahtarların korumalı endpoint'lerde Bearer token yerine kabul edildiğini test eder
+func TestAPIKeyAuthentication(t *testing.T) {
+	r := newTestRouter(t)
+	auth := registerForStepUp(t, r, "ops@example.com")
+
+	rec := r.do(http.MethodPost, "/api/v1/accounts", auth.Token, `{}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var account dto.AccountResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &account))
+
+	body := `{"name":"reporting","scopes":["accounts:read"],"expires_in_days":30}`
+
+	t.Run("CreationNeedsStepUp", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/api-keys", auth.Token, body)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "STEP_UP_REQUIRED")
+	})
+
+	elevated := reauth(t, r, auth.Token, `{"password":"password123"}`).Token
+	key := createAPIKey(t, r, elevated, body)
+	assert.True(t, strings.HasPrefix(key.Key, model.APIKeyPrefix))
+	assert.Equal(t, []string{model.ScopeAccountsRead}, key.Scopes)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), key.ExpiresAt, time.Minute)
+
+	t.Run("Validation", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/api-keys", elevated, `{"name":"x","scopes":["accounts:read"],"expires_in_days":400}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = r.do(http.MethodPost, "/api/v1/api-keys", elevated, `{"name":"x","scopes":["everything"],"expires_in_days":1}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "INVALID_SCOPE")
+	})
+
+	t.Run("ListHidesKey", func(t *testing.T) {
+		rec := r.do(http.MethodGet, "/api/v1/api-keys", auth.Token, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), key.Key)
+		var keys dto.APIKeysResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &keys))
+		require.Equal(t, 1, keys.Count)
+		assert.Equal(t, key.Prefix, keys.Keys[0].Prefix)
+	})
+
+	t.Run("ScopedAccess", func(t *testing.T) {
+		rec := r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		rec = r.do(http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d", account.ID), key.Key, "")
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		// Anahtar kapsamı dışındaki işlemler ve anahtar yönetimi reddedilir
+		for _, req := range []struct{ method, path, body string }{
+			{http.MethodPost, "/api/v1/transfers", `{}`},
+			{http.MethodPost, "/api/v1/accounts", `{}`},
+			{http.MethodGet, "/api/v1/api-keys", ""},
+			{http.MethodPost, "/api/v1/api-keys", body},
+			{http.MethodPost, "/api/v1/reauth", `{"password":"password123"}`},
+			{http.MethodGet, fmt.Sprintf("/api/v1/users/%d", auth.User.ID), ""},
+		} {
+			rec := r.do(req.method, req.path, key.Key, req.body)
+			assert.Equal(t, http.StatusForbidden, rec.Code, req.path)
+			assert.Contains(t, rec.Body.String(), "INSUFFICIENT_SCOPE", req.path)
+		}
+
+		rec = r.do(http.MethodGet, "/api/v1/api-keys", auth.Token, "")
+		var keys dto.APIKeysResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &keys))
+		require.Equal(t, 1, keys.Count)
+		assert.NotNil(t, keys.Keys[0].LastUsedAt)
+	})
+
+	t.Run("UnknownKey", func(t *testing.T) {
+		rec := r.do(http.MethodGet, "/api/v1/accounts", model.APIKeyPrefix+"unknown", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "INVALID_API_KEY")
+	})
+
+	// freshKey yeni bir oturumla anahtar oluşturur; önceki testler anahtarları iptal etmiş olabilir
+	freshKey := func(t *testing.T) (dto.APIKeyResponse, dto.AuthResponse) {
+		session := loginFor(t, r, "ops@example.com")
+		elevated := reauth(t, r, session.Token, `{"password":"password123"}`).Token
+		return createAPIKey(t, r, elevated, body), session
+	}
+
+	t.Run("Revoke", func(t *testing.T) {
+		// Başka kullanıcının anahtarı görünmez
+		other := registerForStepUp(t, r, "other@example.com")
+		rec := r.do(http.MethodDelete, fmt.Sprintf("/api/v1/api-keys/%d", key.ID), other.Token, "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = r.do(http.MethodDelete, fmt.Sprintf("/api/v1/api-keys/%d", key.ID), auth.Token, "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		rec = r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		rec = r.do(http.MethodDelete, fmt.Sprintf("/api/v1/api-keys/%d", key.ID), auth.Token, "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("RevokedByLogoutAll", func(t *testing.T) {
+		key, session := freshKey(t)
+		rec := r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rec = r.do(http.MethodPost, "/api/v1/logout-all", session.Token, "")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		rec = r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "INVALID_API_KEY")
+
+		// İptal edilen anahtar listede de görünmez
+		rec = r.do(http.MethodGet, "/api/v1/api-keys", loginFor(t, r, "ops@example.com").Token, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var keys dto.APIKeysResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &keys))
+		assert.Zero(t, keys.Count)
+	})
+
+	t.Run("RevokedByDeactivation", func(t *testing.T) {
+		key, _ := freshKey(t)
+		admin := addAdmin(r)
+		adminToken := tokenFor(t, admin.ID, model.RoleAdmin)
+		rec := r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/status", auth.User.ID), adminToken, `{"is_active":false}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		rec = r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		// Kullanıcı yeniden etkinleştirilse de anahtar geçersiz kalır
+		rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/status", auth.User.ID), adminToken, `{"is_active":true}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		rec = r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
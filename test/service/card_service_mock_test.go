@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/card"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+func newCardTestEnv() (*MockAccountRepository, *MockCardRepository, service.CardService) {
+	accounts := NewMockAccountRepository()
+	cards := NewMockCardRepository()
+	return accounts, cards, service.NewCardService(cards, accounts, "979200")
+}
+
+func addCardTestAccount(accounts *MockAccountRepository, userID int64, active bool) *model.Account {
+	a := &model.Account{UserID: userID, Balance: money.New(0, "TRY"), Currency: "TRY", IsActive: active}
+	accounts.AddTestAccount(a)
+	return a
+}
+
+// TestCardServiceWithMock CardService için mock repository ile testler
+func TestCardServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("IssueCard_Success", func(t *testing.T) {
+		accounts, _, svc := newCardTestEnv()
+		a := addCardTestAccount(accounts, 1, true)
+
+		c, err := svc.IssueCard(ctx, 1, a.ID)
+		require.NoError(t, err)
+		assert.NotZero(t, c.ID)
+		assert.Equal(t, a.ID, c.AccountID)
+		assert.True(t, c.IsActive)
+		assert.Equal(t, "979200", c.CardNumber[:6])
+		assert.True(t, card.ValidLuhn(c.CardNumber))
+		assert.Len(t, c.CVV, card.CVVLength)
+
+		// Son kullanma tarihi üç yıl sonrasının ay sonu olmalı
+		expected := time.Now().AddDate(3, 0, 0)
+		assert.Equal(t, expected.Year(), c.ExpiryDate.Year())
+		assert.Equal(t, expected.Month(), c.ExpiryDate.Month())
+		assert.Equal(t, 1, c.ExpiryDate.AddDate(0, 0, 1).Day())
+	})
+
+	t.Run("IssueCard_RetriesOnCollision", func(t *testing.T) {
+		accounts, cards, svc := newCardTestEnv()
+		a := addCardTestAccount(accounts, 1, true)
+		cards.UniqueViolations = 2
+
+		c, err := svc.IssueCard(ctx, 1, a.ID)
+		require.NoError(t, err)
+		assert.NotZero(t, c.ID)
+	})
+
+	t.Run("IssueCard_ClosedAccount", func(t *testing.T) {
+		accounts, _, svc := newCardTestEnv()
+		a := addCardTestAccount(accounts, 1, false)
+
+		_, err := svc.IssueCard(ctx, 1, a.ID)
+		assert.ErrorIs(t, err, service.ErrAccountClosed)
+	})
+
+	t.Run("IssueCard_OtherUsersAccount", func(t *testing.T) {
+		accounts, _, svc := newCardTestEnv()
+		a := addCardTestAccount(accounts, 2, true)
+
+		_, err := svc.IssueCard(ctx, 1, a.ID)
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	})
+
+	t.Run("GetAccountCards", func(t *testing.T) {
+		accounts, _, svc := newCardTestEnv()
+		a := addCardTestAccount(accounts, 1, true)
+		other := addCardTestAccount(accounts, 1, true)
+
+		_, err := svc.IssueCard(ctx, 1, a.ID)
+		require.NoError(t, err)
+		_, err = svc.IssueCard(ctx, 1, a.ID)
+		require.NoError(t, err)
+		_, err = svc.IssueCard(ctx, 1, other.ID)
+		require.NoError(t, err)
+
+		cards, err := svc.GetAccountCards(ctx, 1, a.ID)
+		require.NoError(t, err)
+		assert.Len(t, cards, 2)
+
+		_, err = svc.GetAccountCards(ctx, 2, a.ID)
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	})
+
+	t.Run("GetCard_OtherUsersCard", func(t *testing.T) {
+		accounts, _, svc := newCardTestEnv()
+		a := addCardTestAccount(accounts, 1, true)
+		c, err := svc.IssueCard(ctx, 1, a.ID)
+		require.NoError(t, err)
+
+		_, err = svc.GetCard(ctx, 2, c.ID)
+		assert.ErrorIs(t, err, service.ErrCardNotFound)
+		_, err = svc.GetCard(ctx, 1, 999)
+		assert.ErrorIs(t, err, service.ErrCardNotFound)
+	})
+
+	t.Run("FreezeAndUnfreeze", func(t *testing.T) {
+		accounts, cards, svc := newCardTestEnv()
+		a := addCardTestAccount(accounts, 1, true)
+		c, err := svc.IssueCard(ctx, 1, a.ID)
+		require.NoError(t, err)
+
+		frozen, err := svc.FreezeCard(ctx, 1, c.ID)
+		require.NoError(t, err)
+		assert.False(t, frozen.IsActive)
+		stored, err := cards.GetCardByID(ctx, c.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.IsActive)
+
+		unfrozen, err := svc.UnfreezeCard(ctx, 1, c.ID)
+		require.NoError(t, err)
+		assert.True(t, unfrozen.IsActive)
+
+		_, err = svc.FreezeCard(ctx, 2, c.ID)
+		assert.ErrorIs(t, err, service.ErrCardNotFound)
+	})
+
+	t.Run("ReplaceCard_Success", func(t *testing.T) {
+		accounts, cards, svc := newCardTestEnv()
+		a := addCardTestAccount(accounts, 1, true)
+		old, err := svc.IssueCard(ctx, 1, a.ID)
+		require.NoError(t, err)
+
+		replacement, err := svc.ReplaceCard(ctx, 1, old.ID)
+		require.NoError(t, err)
+		assert.NotEqual(t, old.ID, replacement.ID)
+		assert.NotEqual(t, old.CardNumber, replacement.CardNumber)
+		assert.Equal(t, a.ID, replacement.AccountID)
+		assert.True(t, replacement.IsActive)
+
+		// Eski kart kalıcı olarak kapatılmalı
+		retired, err := cards.GetCardByID(ctx, old.ID)
+		require.NoError(t, err)
+		assert.False(t, retired.IsActive)
+		require.NotNil(t, retired.ReplacedByID)
+		assert.Equal(t, replacement.ID, *retired.ReplacedByID)
+
+		_, err = svc.UnfreezeCard(ctx, 1, old.ID)
+		assert.ErrorIs(t, err, service.ErrCardReplaced)
+		_, err = svc.ReplaceCard(ctx, 1, old.ID)
+		assert.ErrorIs(t, err, service.ErrCardReplaced)
+	})
+
+	t.Run("ReplaceCard_FrozenCard", func(t *testing.T) {
+		accounts, _, svc := newCardTestEnv()
+		a := addCardTestAccount(accounts, 1, true)
+		old, err := svc.IssueCard(ctx, 1, a.ID)
+		require.NoError(t, err)
+		_, err = svc.FreezeCard(ctx, 1, old.ID)
+		require.NoError(t, err)
+
+		replacement, err := svc.ReplaceCard(ctx, 1, old.ID)
+		require.NoError(t, err)
+		assert.True(t, replacement.IsActive)
+	})
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/mailer"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+var verificationTokenPattern = regexp.MustCompile(`verify-email\?token=([A-Za-z0-9_-]+)`)
+
+// lastVerificationToken verilen adrese yazılan son doğrulama e-postasındaki token'ı döner
+func lastVerificationToken(t *testing.T, mail *bytes.Buffer, to string) string {
+	var token string
+	for _, msg := range strings.Split(mail.String(), "----- end mail -----") {
+		if !strings.Contains(msg, "To: "+to+"\r\n") {
+			continue
+		}
+		if m := verificationTokenPattern.FindStringSubmatch(msg); m != nil {
+			token = m[1]
+		}
+	}
+	require.NotEmpty(t, token, "%s için doğrulama e-postası bulunamadı", to)
+	return token
+}
+
+// TestEmailVerificationServiceWithMock doğrulama token'larının ve bekleyen email değişikliğinin davranışını test eder
+func TestEmailVerificationServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*MockUserRepository, *MockEmailVerificationRepository, service.EmailVerificationService, *bytes.Buffer, *model.User) {
+		users := NewMockUserRepository()
+		repo := NewMockEmailVerificationRepository(users)
+		mail := &bytes.Buffer{}
+		svc := service.NewEmailVerificationService(repo, users, mailer.NewLogMailer(mail, "no-reply@example.com"), "https://bank.example.com/verify-email")
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		users.AddTestUser(user)
+		return users, repo, svc, mail, user
+	}
+
+	t.Run("ConfirmMarksVerified", func(t *testing.T) {
+		_, _, svc, mail, user := setup(t)
+
+		require.NoError(t, svc.SendVerification(ctx, user.ID))
+		token := lastVerificationToken(t, mail, "user@example.com")
+		require.NoError(t, svc.ConfirmEmail(ctx, token))
+		assert.True(t, user.EmailVerified())
+
+		// Token tek kullanımlıktır
+		assert.ErrorIs(t, svc.ConfirmEmail(ctx, token), service.ErrInvalidVerificationToken)
+		assert.ErrorIs(t, svc.SendVerification(ctx, user.ID), service.ErrEmailAlreadyVerified)
+	})
+
+	t.Run("ExpiredToken", func(t *testing.T) {
+		_, repo, svc, mail, user := setup(t)
+
+		require.NoError(t, svc.SendVerification(ctx, user.ID))
+		repo.ExpireVerifications()
+
+		err := svc.ConfirmEmail(ctx, lastVerificationToken(t, mail, "user@example.com"))
+		assert.ErrorIs(t, err, service.ErrInvalidVerificationToken)
+		assert.False(t, user.EmailVerified())
+	})
+
+	t.Run("NewLinkReplacesOld", func(t *testing.T) {
+		_, _, svc, mail, user := setup(t)
+
+		require.NoError(t, svc.SendVerification(ctx, user.ID))
+		first := lastVerificationToken(t, mail, "user@example.com")
+		require.NoError(t, svc.SendVerification(ctx, user.ID))
+
+		assert.ErrorIs(t, svc.ConfirmEmail(ctx, first), service.ErrInvalidVerificationToken)
+	})
+
+	t.Run("EmailChangeIsPendingUntilConfirmed", func(t *testing.T) {
+		users, _, svc, mail, user := setup(t)
+		users.MarkEmailVerified(user.ID)
+
+		require.NoError(t, svc.RequestEmailChange(ctx, user.ID, "new@example.com"))
+		assert.Equal(t, "user@example.com", user.Email)
+		assert.True(t, user.EmailVerified())
+		// Eski adres değişiklikten haberdar edilir
+		assert.Contains(t, mail.String(), "Subject: Your email address is being changed")
+
+		require.NoError(t, svc.ConfirmEmail(ctx, lastVerificationToken(t, mail, "new@example.com")))
+		assert.Equal(t, "new@example.com", user.Email)
+		assert.True(t, user.EmailVerified())
+	})
+
+	t.Run("ResendKeepsPendingChange", func(t *testing.T) {
+		_, _, svc, mail, user := setup(t)
+
+		require.NoError(t, svc.RequestEmailChange(ctx, user.ID, "new@example.com"))
+		require.NoError(t, svc.SendVerification(ctx, user.ID))
+
+		require.NoError(t, svc.ConfirmEmail(ctx, lastVerificationToken(t, mail, "new@example.com")))
+		assert.Equal(t, "new@example.com", user.Email)
+		assert.True(t, user.EmailVerified())
+
+		// Eski adresin bağlantısı değişiklikten sonra adresi geri alamaz
+		err := svc.ConfirmEmail(ctx, lastVerificationToken(t, mail, "user@example.com"))
+		assert.ErrorIs(t, err, service.ErrInvalidVerificationToken)
+		assert.Equal(t, "new@example.com", user.Email)
+	})
+
+	t.Run("EmailChangeToTakenAddress", func(t *testing.T) {
+		users, _, svc, _, user := setup(t)
+		users.AddTestUser(&model.User{FullName: "Other", Email: "other@example.com", Role: model.RoleUser, IsActive: true})
+
+		err := svc.RequestEmailChange(ctx, user.ID, "other@example.com")
+		assert.ErrorIs(t, err, service.ErrEmailAlreadyRegistered)
+	})
+
+	t.Run("AddressTakenBeforeConfirmation", func(t *testing.T) {
+		users, _, svc, mail, user := setup(t)
+
+		require.NoError(t, svc.RequestEmailChange(ctx, user.ID, "new@example.com"))
+		users.AddTestUser(&model.User{FullName: "Other", Email: "new@example.com", Role: model.RoleUser, IsActive: true})
+
+		err := svc.ConfirmEmail(ctx, lastVerificationToken(t, mail, "new@example.com"))
+		assert.ErrorIs(t, err, service.ErrEmailAlreadyRegistered)
+		assert.Equal(t, "user@example.com", user.Email)
+	})
+
+	t.Run("SameAddress", func(t *testing.T) {
+		users, _, svc, _, user := setup(t)
+		users.MarkEmailVerified(user.ID)
+
+		err := svc.RequestEmailChange(ctx, user.ID, "USER@example.com")
+		assert.ErrorIs(t, err, service.ErrEmailAlreadyVerified)
+	})
+}
+
+// TestEmailVerificationEndpoints kayıt sonrası doğrulama ve para hareketi kısıtını HTTP üzerinden test eder
+func TestEmailVerificationEndpoints(t *testing.T) {
+	r := newTestRouter(t)
+	rec := r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"verify@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var auth dto.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+	assert.False(t, auth.User.EmailVerified)
+
+	rec = r.do(http.MethodPost, "/api/v1/accounts", auth.Token, `{}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var account dto.AccountResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &account))
+	depositPath := fmt.Sprintf("/api/v1/accounts/%d/deposits", account.ID)
+
+	t.Run("MoneyMovementBlockedUntilVerified", func(t *testing.T) {
+		rec := r.do(http.MethodPost, depositPath, auth.Token, `{"amount":"10.00"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "EMAIL_NOT_VERIFIED")
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/email/verify", "", `{"token":"bogus"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "INVALID_VERIFICATION_TOKEN")
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/email/verify/resend", auth.Token, "")
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+		token := lastVerificationToken(t, r.mail, "verify@example.com")
+		rec = r.do(http.MethodPost, "/api/v1/email/verify", "", `{"token":"`+token+`"}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+		rec = r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", auth.User.ID), auth.Token, "")
+		assert.Contains(t, rec.Body.String(), `"email_verified":true`)
+
+		// Aynı access token ile para hareketi artık serbest
+		rec = r.do(http.MethodPost, depositPath, auth.Token, `{"amount":"10.00"}`)
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		rec = r.do(http.MethodPost, "/api/v1/email/verify/resend", auth.Token, "")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("EmailChange", func(t *testing.T) {
+		elevated := stepUpTokenFor(t, auth.User.ID, auth.User.Role, time.Now())
+		rec := r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/email", auth.User.ID), elevated, `{"new_email":"changed@example.com"}`)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+		// Onaydan önce eski adresle giriş yapılır
+		rec = r.do(http.MethodPost, "/api/v1/login", "", `{"email":"verify@example.com","password":"password123"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		token := lastVerificationToken(t, r.mail, "changed@example.com")
+		rec = r.do(http.MethodPost, "/api/v1/email/verify", "", `{"token":"`+token+`"}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+		rec = r.do(http.MethodPost, "/api/v1/login", "", `{"email":"changed@example.com","password":"password123"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"email_verified":true`)
+	})
+}
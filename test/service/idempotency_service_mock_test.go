@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// TestIdempotencyServiceWithMock IdempotencyService için mock repository ile testler
+func TestIdempotencyServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Begin_NewKey", func(t *testing.T) {
+		svc := service.NewIdempotencyService(NewMockIdempotencyRepository())
+
+		rec, replay, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		assert.False(t, replay)
+		assert.NotZero(t, rec.ID)
+		assert.True(t, rec.ExpiresAt.After(rec.CreatedAt))
+	})
+
+	t.Run("Begin_ReplaysCompletedResponse", func(t *testing.T) {
+		svc := service.NewIdempotencyService(NewMockIdempotencyRepository())
+
+		_, _, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		require.NoError(t, svc.Complete(ctx, 1, "key-1", http.StatusCreated, []byte(`{"ok":true}`)))
+
+		rec, replay, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		assert.True(t, replay)
+		assert.Equal(t, http.StatusCreated, rec.ResponseStatus)
+		assert.JSONEq(t, `{"ok":true}`, string(rec.ResponseBody))
+	})
+
+	t.Run("Begin_InProgress", func(t *testing.T) {
+		svc := service.NewIdempotencyService(NewMockIdempotencyRepository())
+
+		_, _, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+
+		_, _, err = svc.Begin(ctx, 1, "key-1", "hash-a")
+		assert.ErrorIs(t, err, service.ErrIdempotencyInProgress)
+	})
+
+	t.Run("Begin_KeyReusedWithDifferentRequest", func(t *testing.T) {
+		svc := service.NewIdempotencyService(NewMockIdempotencyRepository())
+
+		_, _, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		require.NoError(t, svc.Complete(ctx, 1, "key-1", http.StatusCreated, []byte(`{}`)))
+
+		_, _, err = svc.Begin(ctx, 1, "key-1", "hash-b")
+		assert.ErrorIs(t, err, service.ErrIdempotencyKeyReused)
+	})
+
+	t.Run("Begin_KeysAreScopedPerUser", func(t *testing.T) {
+		svc := service.NewIdempotencyService(NewMockIdempotencyRepository())
+
+		_, _, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+
+		_, replay, err := svc.Begin(ctx, 2, "key-1", "hash-b")
+		require.NoError(t, err)
+		assert.False(t, replay)
+	})
+
+	t.Run("Begin_AfterAbandon", func(t *testing.T) {
+		svc := service.NewIdempotencyService(NewMockIdempotencyRepository())
+
+		_, _, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		require.NoError(t, svc.Abandon(ctx, 1, "key-1"))
+
+		_, replay, err := svc.Begin(ctx, 1, "key-1", "hash-b")
+		require.NoError(t, err)
+		assert.False(t, replay)
+	})
+
+	t.Run("Begin_ExpiredKeyIsReserved", func(t *testing.T) {
+		repo := NewMockIdempotencyRepository()
+		svc := service.NewIdempotencyService(repo)
+
+		_, _, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		require.NoError(t, svc.Complete(ctx, 1, "key-1", http.StatusCreated, []byte(`{}`)))
+		repo.Expire(1, "key-1")
+
+		_, replay, err := svc.Begin(ctx, 1, "key-1", "hash-b")
+		require.NoError(t, err)
+		assert.False(t, replay)
+	})
+
+	t.Run("Begin_StaleReservationIsTakenOver", func(t *testing.T) {
+		repo := NewMockIdempotencyRepository()
+		svc := service.NewIdempotencyService(repo)
+
+		_, _, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		repo.Age(1, "key-1", 2*time.Minute)
+
+		// Cevabı yazılmadan kalan rezervasyon 24 saat boyunca anahtarı kilitlememeli
+		_, replay, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		assert.False(t, replay)
+	})
+
+	t.Run("Begin_OldCompletedKeyStillReplays", func(t *testing.T) {
+		repo := NewMockIdempotencyRepository()
+		svc := service.NewIdempotencyService(repo)
+
+		_, _, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		require.NoError(t, svc.Complete(ctx, 1, "key-1", http.StatusCreated, []byte(`{}`)))
+		repo.Age(1, "key-1", time.Hour)
+
+		_, replay, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		assert.True(t, replay)
+	})
+
+	t.Run("PurgeExpired", func(t *testing.T) {
+		repo := NewMockIdempotencyRepository()
+		svc := service.NewIdempotencyService(repo)
+
+		_, _, err := svc.Begin(ctx, 1, "key-1", "hash-a")
+		require.NoError(t, err)
+		_, _, err = svc.Begin(ctx, 1, "key-2", "hash-a")
+		require.NoError(t, err)
+		repo.Expire(1, "key-1")
+
+		n, err := svc.PurgeExpired(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		assert.Equal(t, 1, repo.Count())
+	})
+}
+
+// TestIdempotencyMiddleware Idempotency middleware'inin tekrar eden istekleri engellediğini test eder
+func TestIdempotencyMiddleware(t *testing.T) {
+	newServer := func(status int) (*echo.Echo, *int) {
+		calls := 0
+		e := echo.New()
+		e.Use(middleware.Recover())
+		authenticate := func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				c.Set("user", &jwt.Token{Claims: &controller.AccessClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}}})
+				return next(c)
+			}
+		}
+		handler := func(c echo.Context) error {
+			calls++
+			if status == 0 {
+				panic("handler failed")
+			}
+			return c.JSON(status, map[string]int{"call": calls})
+		}
+		svc := service.NewIdempotencyService(NewMockIdempotencyRepository())
+		e.POST("/transfers", handler, authenticate, controller.Idempotency(svc))
+		e.POST("/deposits", handler, authenticate, controller.Idempotency(svc))
+		return e, &calls
+	}
+	send := func(e *echo.Echo, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if key != "" {
+			req.Header.Set(controller.HeaderIdempotencyKey, key)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("RetryReturnsOriginalResponse", func(t *testing.T) {
+		e, calls := newServer(http.StatusCreated)
+
+		first := send(e, "/transfers", "key-1", `{"amount":"10"}`)
+		second := send(e, "/transfers", "key-1", `{"amount":"10"}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusCreated, second.Code)
+		assert.JSONEq(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, "true", second.Header().Get(controller.HeaderIdempotentReplayed))
+	})
+
+	t.Run("DifferentBodyConflicts", func(t *testing.T) {
+		e, calls := newServer(http.StatusCreated)
+
+		send(e, "/transfers", "key-1", `{"amount":"10"}`)
+		rec := send(e, "/transfers", "key-1", `{"amount":"20"}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Contains(t, rec.Body.String(), "IDEMPOTENCY_KEY_REUSED")
+	})
+
+	t.Run("DifferentEndpointConflicts", func(t *testing.T) {
+		e, calls := newServer(http.StatusCreated)
+
+		send(e, "/transfers", "key-1", `{"amount":"10"}`)
+		rec := send(e, "/deposits", "key-1", `{"amount":"10"}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("WithoutKeyNotDeduplicated", func(t *testing.T) {
+		e, calls := newServer(http.StatusCreated)
+
+		send(e, "/transfers", "", `{"amount":"10"}`)
+		send(e, "/transfers", "", `{"amount":"10"}`)
+
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("ServerErrorReleasesKey", func(t *testing.T) {
+		e, calls := newServer(http.StatusInternalServerError)
+
+		send(e, "/transfers", "key-1", `{"amount":"10"}`)
+		send(e, "/transfers", "key-1", `{"amount":"10"}`)
+
+		// 5xx cevaplar saklanmamalı, tekrar deneme handler'a ulaşmalı
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("AuthErrorReleasesKey", func(t *testing.T) {
+		e, calls := newServer(http.StatusForbidden)
+
+		send(e, "/transfers", "key-1", `{"amount":"10"}`)
+		send(e, "/transfers", "key-1", `{"amount":"10"}`)
+
+		// Yeniden doğrulama sonrası aynı anahtarla tekrar denenebilmeli
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("PanicReleasesKey", func(t *testing.T) {
+		e, calls := newServer(0)
+
+		rec := send(e, "/transfers", "key-1", `{"amount":"10"}`)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		rec = send(e, "/transfers", "key-1", `{"amount":"10"}`)
+
+		// Panik sonrası anahtar serbest kalmalı, tekrar deneme 409 almamalı
+		assert.Equal(t, 2, *calls)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("KeyTooLong", func(t *testing.T) {
+		e, calls := newServer(http.StatusCreated)
+
+		rec := send(e, "/transfers", strings.Repeat("k", 256), `{"amount":"10"}`)
+
+		assert.Equal(t, 0, *calls)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
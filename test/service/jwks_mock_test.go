@@ -0,0 +1,59 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/jwtkeys"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+)
+
+// TestJWKSEndpoint başka bir servisin token'ları yayınlanan anahtarlarla doğrulayabildiğini test eder
+func TestJWKSEndpoint(t *testing.T) {
+	r := newTestRouter(t)
+
+	rec := r.do(http.MethodGet, "/.well-known/jwks.json", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Cache-Control"), "max-age")
+	var set jwtkeys.JWKS
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &set))
+	require.Len(t, set.Keys, 1)
+	jwk := set.Keys[0]
+	assert.Equal(t, "test", jwk.Kid)
+	assert.Equal(t, "OKP", jwk.Kty)
+	assert.NotContains(t, rec.Body.String(), `"d"`)
+
+	rec = r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"jwks@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var auth dto.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+
+	// Yalnızca JWKS'deki açık anahtarı bilen bir doğrulayıcı
+	pub, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	require.NoError(t, err)
+	token, err := jwt.Parse(auth.Token, func(tok *jwt.Token) (interface{}, error) {
+		assert.Equal(t, jwk.Kid, tok.Header["kid"])
+		return ed25519.PublicKey(pub), nil
+	}, jwt.WithValidMethods([]string{jwk.Alg}))
+	require.NoError(t, err)
+	assert.True(t, token.Valid)
+}
+
+// TestSymmetricTokensRejected paylaşılan sırla imzalanmış token'ların kabul edilmediğini test eder
+func TestSymmetricTokensRejected(t *testing.T) {
+	r := newTestRouter(t)
+
+	claims := jwt.MapClaims{"sub": 1, "role": "admin", "exp": time.Now().Add(time.Hour).Unix()}
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("change-me"))
+	require.NoError(t, err)
+
+	rec := r.do(http.MethodGet, "/api/v1/users", forged, "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+func try(s string) money.Money {
+	return money.MustParse(s, "TRY")
+}
+
+func posting(accountID int64, direction, amount string) model.Posting {
+	return model.Posting{AccountID: accountID, Direction: direction, Amount: try(amount), Currency: "TRY"}
+}
+
+func transferEntry(from, to int64, amount string) *model.JournalEntry {
+	return &model.JournalEntry{
+		Description: "test transfer",
+		Postings: []model.Posting{
+			posting(from, model.PostingDebit, amount),
+			posting(to, model.PostingCredit, amount),
+		},
+	}
+}
+
+// TestLedgerServiceWithMock LedgerService için mock repository ile testler
+func TestLedgerServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("RecordEntry_Success", func(t *testing.T) {
+		mockRepo := NewMockLedgerRepository()
+		svc := service.NewLedgerService(mockRepo)
+		mockRepo.AddTestAccount(1, "TRY")
+		mockRepo.AddTestAccount(2, "TRY")
+
+		entry := transferEntry(1, 2, "125.50")
+		err := svc.RecordEntry(ctx, entry)
+		require.NoError(t, err)
+		assert.NotZero(t, entry.ID)
+		for _, p := range entry.Postings {
+			assert.Equal(t, entry.ID, p.JournalEntryID)
+		}
+
+		assert.Equal(t, try("-125.50"), mockRepo.Balance(1))
+		assert.Equal(t, try("125.50"), mockRepo.Balance(2))
+
+		postings, err := svc.GetAccountPostings(ctx, 2)
+		require.NoError(t, err)
+		require.Len(t, postings, 1)
+		assert.Equal(t, model.PostingCredit, postings[0].Direction)
+	})
+
+	t.Run("RecordEntry_MultiplePostings", func(t *testing.T) {
+		mockRepo := NewMockLedgerRepository()
+		svc := service.NewLedgerService(mockRepo)
+		mockRepo.AddTestAccount(1, "TRY")
+		mockRepo.AddTestAccount(2, "TRY")
+		mockRepo.AddTestAccount(3, "TRY")
+
+		// Bir borç iki alacağa bölünüyor
+		entry := &model.JournalEntry{Postings: []model.Posting{
+			posting(1, model.PostingDebit, "0.30"),
+			posting(2, model.PostingCredit, "0.10"),
+			posting(3, model.PostingCredit, "0.20"),
+		}}
+		require.NoError(t, svc.RecordEntry(ctx, entry))
+
+		report, err := svc.Reconcile(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, report.UnbalancedEntries)
+	})
+
+	t.Run("RecordEntry_Unbalanced", func(t *testing.T) {
+		mockRepo := NewMockLedgerRepository()
+		svc := service.NewLedgerService(mockRepo)
+		mockRepo.AddTestAccount(1, "TRY")
+		mockRepo.AddTestAccount(2, "TRY")
+
+		entry := &model.JournalEntry{Postings: []model.Posting{
+			posting(1, model.PostingDebit, "100"),
+			posting(2, model.PostingCredit, "99.99"),
+		}}
+		err := svc.RecordEntry(ctx, entry)
+		assert.ErrorIs(t, err, service.ErrUnbalancedEntry)
+		assert.True(t, mockRepo.Balance(1).IsZero())
+		assert.True(t, mockRepo.Balance(2).IsZero())
+	})
+
+	t.Run("RecordEntry_InvalidPostings", func(t *testing.T) {
+		mockRepo := NewMockLedgerRepository()
+		svc := service.NewLedgerService(mockRepo)
+		mockRepo.AddTestAccount(1, "TRY")
+		mockRepo.AddTestAccount(2, "TRY")
+
+		entries := map[string]*model.JournalEntry{
+			"single posting": {Postings: []model.Posting{
+				posting(1, model.PostingDebit, "10"),
+			}},
+			"zero amount": transferEntry(1, 2, "0"),
+			"negative amount": {Postings: []model.Posting{
+				posting(1, model.PostingDebit, "-10"),
+				posting(2, model.PostingCredit, "-10"),
+			}},
+			"unknown direction": {Postings: []model.Posting{
+				posting(1, "sideways", "10"),
+				posting(2, model.PostingCredit, "10"),
+			}},
+			"missing account": transferEntry(0, 2, "10"),
+			"mixed currency": {Postings: []model.Posting{
+				posting(1, model.PostingDebit, "10"),
+				{AccountID: 2, Direction: model.PostingCredit, Amount: money.MustParse("10", "EUR"), Currency: "EUR"},
+			}},
+		}
+		for name, entry := range entries {
+			err := svc.RecordEntry(ctx, entry)
+			assert.ErrorIs(t, err, service.ErrInvalidPosting, name)
+		}
+	})
+
+	t.Run("RecordEntry_UnknownAccount", func(t *testing.T) {
+		mockRepo := NewMockLedgerRepository()
+		svc := service.NewLedgerService(mockRepo)
+		mockRepo.AddTestAccount(1, "TRY")
+
+		err := svc.RecordEntry(ctx, transferEntry(1, 999, "10"))
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	})
+
+	t.Run("Reconcile_Balanced", func(t *testing.T) {
+		mockRepo := NewMockLedgerRepository()
+		svc := service.NewLedgerService(mockRepo)
+		mockRepo.AddTestAccount(1, "TRY")
+		mockRepo.AddTestAccount(2, "TRY")
+
+		require.NoError(t, svc.RecordEntry(ctx, transferEntry(1, 2, "50")))
+		require.NoError(t, svc.RecordEntry(ctx, transferEntry(2, 1, "20")))
+
+		report, err := svc.Reconcile(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.Balanced())
+		assert.NotZero(t, report.CheckedAt)
+	})
+
+	t.Run("Reconcile_DetectsBalanceDrift", func(t *testing.T) {
+		mockRepo := NewMockLedgerRepository()
+		svc := service.NewLedgerService(mockRepo)
+		mockRepo.AddTestAccount(1, "TRY")
+		mockRepo.AddTestAccount(2, "TRY")
+
+		require.NoError(t, svc.RecordEntry(ctx, transferEntry(1, 2, "50")))
+
+		// Bakiye ledger dışından değiştiriliyor
+		mockRepo.SetBalance(2, try("1000"))
+
+		report, err := svc.Reconcile(ctx)
+		require.NoError(t, err)
+		assert.False(t, report.Balanced())
+		require.Len(t, report.BalanceMismatches, 1)
+		assert.Equal(t, int64(2), report.BalanceMismatches[0].AccountID)
+		assert.Equal(t, try("50"), report.BalanceMismatches[0].DerivedBalance)
+	})
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// TestLoginAttemptServiceWithMock hatalı giriş sayaçlarını, gecikmeleri ve kilitlemeyi test eder
+func TestLoginAttemptServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*MockLoginAttemptRepository, service.LoginAttemptService, *model.User) {
+		users := NewMockUserRepository()
+		repo := NewMockLoginAttemptRepository()
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		users.AddTestUser(user)
+		return repo, service.NewLoginAttemptService(repo, users, testLoginPolicy), user
+	}
+
+	// fail bir hatalı giriş kaydeder ve gecikmenin geçmesini bekletmeden atlar
+	fail := func(t *testing.T, repo *MockLoginAttemptRepository, svc service.LoginAttemptService, email, ip string) {
+		require.NoError(t, svc.RecordFailure(ctx, email, ip))
+		repo.Rewind(time.Minute)
+	}
+
+	t.Run("FirstFailuresNotDelayed", func(t *testing.T) {
+		_, svc, _ := setup(t)
+
+		require.NoError(t, svc.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+		require.NoError(t, svc.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+		assert.NoError(t, svc.Check(ctx, "user@example.com", "10.0.0.1"))
+	})
+
+	t.Run("ProgressiveDelay", func(t *testing.T) {
+		repo, svc, _ := setup(t)
+		fail(t, repo, svc, "user@example.com", "10.0.0.1")
+		fail(t, repo, svc, "user@example.com", "10.0.0.1")
+
+		require.NoError(t, svc.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+		err := svc.Check(ctx, "USER@example.com", "10.0.0.2")
+		require.ErrorIs(t, err, service.ErrLoginThrottled)
+		var blocked *service.LoginBlockedError
+		require.True(t, errors.As(err, &blocked))
+		assert.True(t, blocked.RetryAfter > 0 && blocked.RetryAfter <= time.Second)
+
+		require.NoError(t, svc.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+		require.True(t, errors.As(svc.Check(ctx, "user@example.com", "10.0.0.2"), &blocked))
+		assert.True(t, blocked.RetryAfter > time.Second && blocked.RetryAfter <= 2*time.Second)
+
+		repo.Rewind(time.Minute)
+		assert.NoError(t, svc.Check(ctx, "user@example.com", "10.0.0.2"))
+	})
+
+	t.Run("LockoutAfterLimit", func(t *testing.T) {
+		repo, svc, _ := setup(t)
+		for i := 0; i < testLoginPolicy.MaxAccountFailures; i++ {
+			fail(t, repo, svc, "user@example.com", fmt.Sprintf("10.0.0.%d", i))
+		}
+
+		err := svc.Check(ctx, "user@example.com", "10.0.1.1")
+		require.ErrorIs(t, err, service.ErrAccountLocked)
+		var blocked *service.LoginBlockedError
+		require.True(t, errors.As(err, &blocked))
+		assert.True(t, blocked.RetryAfter > 10*time.Minute)
+
+		// Kilit süresi dolunca giriş yeniden denenebilir
+		repo.Rewind(testLoginPolicy.LockoutDuration)
+		assert.NoError(t, svc.Check(ctx, "user@example.com", "10.0.1.1"))
+	})
+
+	t.Run("FailuresOutsideWindowForgotten", func(t *testing.T) {
+		repo, svc, _ := setup(t)
+		for i := 0; i < testLoginPolicy.MaxAccountFailures-1; i++ {
+			fail(t, repo, svc, "user@example.com", "10.0.0.1")
+		}
+		repo.Rewind(testLoginPolicy.Window)
+
+		fail(t, repo, svc, "user@example.com", "10.0.0.1")
+		assert.NoError(t, svc.Check(ctx, "user@example.com", "10.0.0.1"))
+	})
+
+	t.Run("IPLockout", func(t *testing.T) {
+		repo, svc, _ := setup(t)
+		// Her denemede farklı email kullanılsa da IP sayacı artar
+		for i := 0; i < testLoginPolicy.MaxIPFailures; i++ {
+			fail(t, repo, svc, fmt.Sprintf("guess%d@example.com", i), "10.0.0.9")
+		}
+
+		assert.ErrorIs(t, svc.Check(ctx, "user@example.com", "10.0.0.9"), service.ErrLoginThrottled)
+		assert.NoError(t, svc.Check(ctx, "user@example.com", "10.0.0.10"))
+	})
+
+	t.Run("InvalidIPNotCounted", func(t *testing.T) {
+		repo, svc, _ := setup(t)
+		require.NoError(t, svc.RecordFailure(ctx, "user@example.com", "not-an-ip"))
+		assert.Equal(t, 1, repo.Count())
+
+		// Aynı adresin farklı yazımları tek sayaçta toplanır
+		require.NoError(t, svc.RecordFailure(ctx, "user@example.com", "::ffff:10.0.0.1"))
+		require.NoError(t, svc.RecordFailure(ctx, "user@example.com", "10.0.0.1"))
+		assert.Equal(t, 2, repo.Failures(model.LoginScopeIP, "10.0.0.1"))
+	})
+
+	t.Run("SuccessResetsAccountOnly", func(t *testing.T) {
+		repo, svc, _ := setup(t)
+		for i := 0; i < testLoginPolicy.MaxAccountFailures-1; i++ {
+			fail(t, repo, svc, "user@example.com", "10.0.0.1")
+		}
+		require.NoError(t, svc.RecordSuccess(ctx, "user@example.com"))
+
+		fail(t, repo, svc, "user@example.com", "10.0.0.1")
+		assert.NoError(t, svc.Check(ctx, "user@example.com", "10.0.0.1"))
+		// IP sayacı silinmez
+		assert.Equal(t, 2, repo.Count())
+	})
+
+	t.Run("Unlock", func(t *testing.T) {
+		repo, svc, user := setup(t)
+		for i := 0; i < testLoginPolicy.MaxAccountFailures; i++ {
+			fail(t, repo, svc, "user@example.com", "10.0.0.1")
+		}
+		require.ErrorIs(t, svc.Check(ctx, "user@example.com", "10.0.0.2"), service.ErrAccountLocked)
+
+		require.NoError(t, svc.Unlock(ctx, user.ID))
+		assert.NoError(t, svc.Check(ctx, "user@example.com", "10.0.0.2"))
+		assert.ErrorIs(t, svc.Unlock(ctx, 999), service.ErrUserNotFound)
+	})
+
+	t.Run("PurgeKeepsActiveLocks", func(t *testing.T) {
+		repo := NewMockLoginAttemptRepository()
+		policy := testLoginPolicy
+		policy.LockoutDuration = time.Hour
+		svc := service.NewLoginAttemptService(repo, NewMockUserRepository(), policy)
+		for i := 0; i < testLoginPolicy.MaxAccountFailures; i++ {
+			fail(t, repo, svc, "user@example.com", "10.0.0.1")
+		}
+		repo.Rewind(testLoginPolicy.Window)
+
+		// Hesap hâlâ kilitli, IP sayacının penceresi geçti
+		n, err := svc.PurgeStaleFailures(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		assert.ErrorIs(t, svc.Check(ctx, "user@example.com", "10.0.0.1"), service.ErrAccountLocked)
+	})
+}
+
+// TestLoginLockoutEndpoints kilitlemeyi ve admin kilit açmayı HTTP üzerinden test eder
+func TestLoginLockoutEndpoints(t *testing.T) {
+	r := newTestRouter(t)
+	admin := &model.User{FullName: "Admin", Email: "admin@example.com", Role: model.RoleAdmin, IsActive: true}
+	r.users.AddTestUser(admin)
+	rec := r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"locked@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var auth dto.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+
+	login := func(password string) int {
+		body := fmt.Sprintf(`{"email":"locked@example.com","password":%q}`, password)
+		return r.do(http.MethodPost, "/api/v1/login", "", body).Code
+	}
+
+	t.Run("ThrottledThenLocked", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, login("wrongpassword"))
+		assert.Equal(t, http.StatusUnauthorized, login("wrongpassword"))
+		assert.Equal(t, http.StatusUnauthorized, login("wrongpassword"))
+
+		rec := r.do(http.MethodPost, "/api/v1/login", "", `{"email":"locked@example.com","password":"password123"}`)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Contains(t, rec.Body.String(), "TOO_MANY_LOGIN_ATTEMPTS")
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+		for i := 0; i < 2; i++ {
+			r.logins.Rewind(time.Minute)
+			assert.Equal(t, http.StatusUnauthorized, login("wrongpassword"))
+		}
+
+		// Doğru şifre de kilit süresince reddedilir
+		rec = r.do(http.MethodPost, "/api/v1/login", "", `{"email":"locked@example.com","password":"password123"}`)
+		assert.Equal(t, http.StatusLocked, rec.Code)
+		assert.Contains(t, rec.Body.String(), "ACCOUNT_LOCKED")
+		assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("UnlockRequiresAdmin", func(t *testing.T) {
+		rec := r.do(http.MethodPost, fmt.Sprintf("/api/v1/users/%d/unlock", auth.User.ID), auth.Token, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("AdminUnlock", func(t *testing.T) {
+		rec := r.do(http.MethodPost, fmt.Sprintf("/api/v1/users/%d/unlock", auth.User.ID), tokenFor(t, admin.ID, model.RoleAdmin), "")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+		assert.Equal(t, http.StatusOK, login("password123"))
+	})
+
+	t.Run("UnlockUnknownUser", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/users/999/unlock", tokenFor(t, admin.ID, model.RoleAdmin), "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+// TestLoginIPCounterIgnoresForgedHeaders sahte X-Forwarded-For ve X-Real-IP başlıklarının IP sayacını sıfırlayamadığını test eder
+func TestLoginIPCounterIgnoresForgedHeaders(t *testing.T) {
+	r := newTestRouter(t)
+	rec := r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"victim@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	// httptest istekleri 192.0.2.1 adresinden gelir; bu adres güvenilir proxy değildir
+	for i := 0; i < testLoginPolicy.MaxIPFailures; i++ {
+		rec := r.doWithHeaders(http.MethodPost, "/api/v1/login", "", fmt.Sprintf(`{"email":"guess%d@example.com","password":"wrongpassword"}`, i), map[string]string{
+			"X-Forwarded-For": fmt.Sprintf("203.0.113.%d", i),
+			"X-Real-IP":       fmt.Sprintf("198.51.100.%d", i),
+		})
+		require.Equal(t, http.StatusUnauthorized, rec.Code, rec.Body.String())
+	}
+	assert.Equal(t, 0, r.logins.Failures(model.LoginScopeIP, "203.0.113.0"))
+
+	rec = r.doWithHeaders(http.MethodPost, "/api/v1/login", "", `{"email":"victim@example.com","password":"password123"}`, map[string]string{
+		"X-Forwarded-For": "203.0.113.250",
+	})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "TOO_MANY_LOGIN_ATTEMPTS")
+
+	// Güvenilir proxy'den gelen başlık istemciyi belirler
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"email":"victim@example.com","password":"password123"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-Forwarded-For", "203.0.113.250")
+	req.RemoteAddr = "127.0.0.1:4000"
+	rec = httptest.NewRecorder()
+	r.e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+// TestLoginLockoutCountsMFAFailures yanlış iki adımlı doğrulama kodlarının da sayıldığını test eder
+func TestLoginLockoutCountsMFAFailures(t *testing.T) {
+	r := newTestRouter(t)
+	auth := registerForStepUp(t, r, "mfa-lock@example.com")
+	rec := r.do(http.MethodPost, "/api/v1/mfa/totp/enroll", auth.Token, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var enrollment dto.TOTPEnrollmentResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &enrollment))
+	rec = r.do(http.MethodPost, "/api/v1/mfa/totp/confirm", auth.Token, fmt.Sprintf(`{"code":%q}`, nextTOTPCode(t, enrollment.Secret, 0)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Şifre bilinse bile her yeni challenge ile kod tahmini sınırsız olmamalı
+	for i := 0; i < testLoginPolicy.MaxAccountFailures; i++ {
+		rec := r.do(http.MethodPost, "/api/v1/login", "", `{"email":"mfa-lock@example.com","password":"password123"}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var challenge dto.MFAChallengeResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &challenge))
+
+		rec = r.do(http.MethodPost, "/api/v1/login/mfa", "", fmt.Sprintf(`{"mfa_token":%q,"code":"000000"}`, challenge.MFAToken))
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		r.logins.Rewind(time.Minute)
+	}
+
+	rec = r.do(http.MethodPost, "/api/v1/login", "", `{"email":"mfa-lock@example.com","password":"password123"}`)
+	assert.Equal(t, http.StatusLocked, rec.Code)
+}
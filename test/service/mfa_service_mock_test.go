@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/totp"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// nextTOTPCode onay sırasında kullanılan adımdan sonraki adımın kodunu üretir
+func nextTOTPCode(t *testing.T, secret string, steps int) string {
+	code, err := totp.Code(secret, time.Now().Add(time.Duration(steps)*totp.Period*time.Second))
+	require.NoError(t, err)
+	return code
+}
+
+// TestMFAServiceWithMock TOTP kaydı ve iki adımlı giriş akışını test eder
+func TestMFAServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*MockMFARepository, service.MFAService, *model.User) {
+		users := NewMockUserRepository()
+		repo := NewMockMFARepository()
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		users.AddTestUser(user)
+		return repo, service.NewMFAService(repo, users, "Bank App"), user
+	}
+	enroll := func(t *testing.T, svc service.MFAService, userID int64) (string, []string) {
+		secret, _, err := svc.BeginTOTPEnrollment(ctx, userID)
+		require.NoError(t, err)
+		codes, err := svc.ConfirmTOTPEnrollment(ctx, userID, nextTOTPCode(t, secret, 0))
+		require.NoError(t, err)
+		return secret, codes
+	}
+
+	t.Run("Enrollment", func(t *testing.T) {
+		_, svc, user := setup(t)
+
+		secret, uri, err := svc.BeginTOTPEnrollment(ctx, user.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, secret)
+		assert.Contains(t, uri, "otpauth://totp/")
+		assert.Contains(t, uri, "secret="+secret)
+		assert.Contains(t, uri, "user@example.com")
+
+		// Onaylanmadan önce MFA etkin olmamalı
+		enabled, err := svc.IsEnabled(ctx, user.ID)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+
+		_, err = svc.ConfirmTOTPEnrollment(ctx, user.ID, "000000")
+		assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+		codes, err := svc.ConfirmTOTPEnrollment(ctx, user.ID, nextTOTPCode(t, secret, 0))
+		require.NoError(t, err)
+		assert.Len(t, codes, 10)
+		for _, c := range codes {
+			assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, c)
+		}
+
+		enabled, err = svc.IsEnabled(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, enabled)
+
+		_, _, err = svc.BeginTOTPEnrollment(ctx, user.ID)
+		assert.ErrorIs(t, err, service.ErrMFAAlreadyEnabled)
+	})
+
+	t.Run("ConfirmWithoutEnrollment", func(t *testing.T) {
+		_, svc, user := setup(t)
+
+		_, err := svc.ConfirmTOTPEnrollment(ctx, user.ID, "123456")
+		assert.ErrorIs(t, err, service.ErrMFANotEnrolled)
+	})
+
+	t.Run("RestartEnrollment_ReplacesSecret", func(t *testing.T) {
+		_, svc, user := setup(t)
+		first, _, err := svc.BeginTOTPEnrollment(ctx, user.ID)
+		require.NoError(t, err)
+		second, _, err := svc.BeginTOTPEnrollment(ctx, user.ID)
+		require.NoError(t, err)
+		require.NotEqual(t, first, second)
+
+		_, err = svc.ConfirmTOTPEnrollment(ctx, user.ID, nextTOTPCode(t, first, 0))
+		assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+		_, err = svc.ConfirmTOTPEnrollment(ctx, user.ID, nextTOTPCode(t, second, 0))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Challenge_TOTP", func(t *testing.T) {
+		_, svc, user := setup(t)
+		secret, _ := enroll(t, svc, user.ID)
+
+		token, exp, err := svc.CreateChallenge(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, exp.After(time.Now()))
+
+		userID, err := svc.VerifyChallenge(ctx, token, nextTOTPCode(t, secret, 1))
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, userID)
+
+		// Challenge tek kullanımlık olmalı
+		_, err = svc.VerifyChallenge(ctx, token, nextTOTPCode(t, secret, 1))
+		assert.ErrorIs(t, err, service.ErrInvalidMFAChallenge)
+	})
+
+	t.Run("Challenge_CodeReplayRejected", func(t *testing.T) {
+		_, svc, user := setup(t)
+		secret, _ := enroll(t, svc, user.ID)
+		code := nextTOTPCode(t, secret, 1)
+
+		first, _, err := svc.CreateChallenge(ctx, user.ID)
+		require.NoError(t, err)
+		_, err = svc.VerifyChallenge(ctx, first, code)
+		require.NoError(t, err)
+
+		second, _, err := svc.CreateChallenge(ctx, user.ID)
+		require.NoError(t, err)
+		_, err = svc.VerifyChallenge(ctx, second, code)
+		assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+	})
+
+	t.Run("Challenge_RecoveryCodeSingleUse", func(t *testing.T) {
+		_, svc, user := setup(t)
+		_, codes := enroll(t, svc, user.ID)
+
+		token, _, err := svc.CreateChallenge(ctx, user.ID)
+		require.NoError(t, err)
+		// Büyük/küçük harf ve ayraç önemsiz olmalı
+		_, err = svc.VerifyChallenge(ctx, token, strings.ToUpper(strings.ReplaceAll(codes[0], "-", "")))
+		require.NoError(t, err)
+
+		token, _, err = svc.CreateChallenge(ctx, user.ID)
+		require.NoError(t, err)
+		_, err = svc.VerifyChallenge(ctx, token, codes[0])
+		assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+		_, err = svc.VerifyChallenge(ctx, token, codes[1])
+		assert.NoError(t, err)
+	})
+
+	t.Run("Challenge_AttemptLimit", func(t *testing.T) {
+		_, svc, user := setup(t)
+		secret, _ := enroll(t, svc, user.ID)
+
+		token, _, err := svc.CreateChallenge(ctx, user.ID)
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			_, err = svc.VerifyChallenge(ctx, token, "000000")
+			require.ErrorIs(t, err, service.ErrInvalidMFACode)
+		}
+		_, err = svc.VerifyChallenge(ctx, token, nextTOTPCode(t, secret, 1))
+		assert.ErrorIs(t, err, service.ErrInvalidMFAChallenge)
+	})
+
+	t.Run("Challenge_Expired", func(t *testing.T) {
+		repo, svc, user := setup(t)
+		secret, _ := enroll(t, svc, user.ID)
+
+		token, _, err := svc.CreateChallenge(ctx, user.ID)
+		require.NoError(t, err)
+		repo.ExpireChallenges()
+
+		_, err = svc.VerifyChallenge(ctx, token, nextTOTPCode(t, secret, 1))
+		assert.ErrorIs(t, err, service.ErrInvalidMFAChallenge)
+	})
+
+	t.Run("Challenge_UnknownToken", func(t *testing.T) {
+		_, svc, _ := setup(t)
+
+		_, err := svc.VerifyChallenge(ctx, "does-not-exist", "123456")
+		assert.ErrorIs(t, err, service.ErrInvalidMFAChallenge)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		repo, svc, user := setup(t)
+		secret, _ := enroll(t, svc, user.ID)
+
+		err := svc.DisableTOTP(ctx, user.ID, "000000")
+		assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+		repo.RewindTOTPStep(user.ID)
+		require.NoError(t, svc.DisableTOTP(ctx, user.ID, nextTOTPCode(t, secret, 0)))
+		enabled, err := svc.IsEnabled(ctx, user.ID)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+
+		err = svc.DisableTOTP(ctx, user.ID, nextTOTPCode(t, secret, 0))
+		assert.ErrorIs(t, err, service.ErrMFANotEnabled)
+	})
+
+	t.Run("LockoutAfterWrongCodes", func(t *testing.T) {
+		_, svc, user := setup(t)
+		secret, codes := enroll(t, svc, user.ID)
+
+		for i := 0; i < 5; i++ {
+			assert.ErrorIs(t, svc.VerifyCode(ctx, user.ID, "000000"), service.ErrInvalidMFACode)
+		}
+		// Kilit süresince doğru kod ve kurtarma kodu da reddedilir
+		assert.ErrorIs(t, svc.VerifyCode(ctx, user.ID, nextTOTPCode(t, secret, 1)), service.ErrMFALocked)
+		assert.ErrorIs(t, svc.DisableTOTP(ctx, user.ID, codes[0]), service.ErrMFALocked)
+		enabled, err := svc.IsEnabled(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, enabled)
+	})
+
+	t.Run("ParallelGuessesRespectLimit", func(t *testing.T) {
+		_, svc, user := setup(t)
+		secret, _ := enroll(t, svc, user.ID)
+
+		// Aynı anda gönderilen tahminler de en fazla 5 kez denenebilir
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- svc.VerifyCode(ctx, user.ID, "000000")
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		invalid, locked := 0, 0
+		for err := range errs {
+			switch {
+			case errors.Is(err, service.ErrInvalidMFACode):
+				invalid++
+			case errors.Is(err, service.ErrMFALocked):
+				locked++
+			default:
+				t.Errorf("beklenmeyen hata: %v", err)
+			}
+		}
+		assert.Equal(t, 5, invalid)
+		assert.Equal(t, 15, locked)
+		assert.ErrorIs(t, svc.VerifyCode(ctx, user.ID, nextTOTPCode(t, secret, 1)), service.ErrMFALocked)
+	})
+
+	t.Run("SuccessResetsFailures", func(t *testing.T) {
+		_, svc, user := setup(t)
+		secret, _ := enroll(t, svc, user.ID)
+
+		for i := 0; i < 4; i++ {
+			assert.ErrorIs(t, svc.VerifyCode(ctx, user.ID, "000000"), service.ErrInvalidMFACode)
+		}
+		require.NoError(t, svc.VerifyCode(ctx, user.ID, nextTOTPCode(t, secret, 1)))
+		for i := 0; i < 4; i++ {
+			assert.ErrorIs(t, svc.VerifyCode(ctx, user.ID, "000000"), service.ErrInvalidMFACode)
+		}
+	})
+}
+
+// TestMFALoginEndpoints iki adımlı giriş akışını HTTP üzerinden test eder
+func TestMFALoginEndpoints(t *testing.T) {
+	r := newTestRouter(t)
+	rec := r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"mfa@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var auth dto.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+
+	rec = r.do(http.MethodPost, "/api/v1/mfa/totp/enroll", auth.Token, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var enrollment dto.TOTPEnrollmentResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &enrollment))
+
+	rec = r.do(http.MethodPost, "/api/v1/mfa/totp/confirm", auth.Token, `{"code":"000000"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "INVALID_MFA_CODE")
+
+	rec = r.do(http.MethodPost, "/api/v1/mfa/totp/confirm", auth.Token, fmt.Sprintf(`{"code":%q}`, nextTOTPCode(t, enrollment.Secret, 0)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var recovery dto.RecoveryCodesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recovery))
+	require.Len(t, recovery.RecoveryCodes, 10)
+
+	login := func(t *testing.T) dto.MFAChallengeResponse {
+		rec := r.do(http.MethodPost, "/api/v1/login", "", `{"email":"mfa@example.com","password":"password123"}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.NotContains(t, rec.Body.String(), `"refresh_token"`)
+		var challenge dto.MFAChallengeResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &challenge))
+		require.True(t, challenge.MFARequired)
+		require.NotEmpty(t, challenge.MFAToken)
+		return challenge
+	}
+
+	t.Run("ChallengeTokenIsNotAnAccessToken", func(t *testing.T) {
+		challenge := login(t)
+		rec := r.do(http.MethodGet, "/api/v1/sessions", challenge.MFAToken, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("WrongCode", func(t *testing.T) {
+		challenge := login(t)
+		rec := r.do(http.MethodPost, "/api/v1/login/mfa", "", fmt.Sprintf(`{"mfa_token":%q,"code":"000000"}`, challenge.MFAToken))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "INVALID_MFA_CODE")
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/login/mfa", "", `{"mfa_token":"bogus","code":"123456"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "INVALID_MFA_TOKEN")
+	})
+
+	t.Run("RecoveryCode", func(t *testing.T) {
+		challenge := login(t)
+		rec := r.do(http.MethodPost, "/api/v1/login/mfa", "",
+			fmt.Sprintf(`{"mfa_token":%q,"code":%q,"device_label":"Backup phone"}`, challenge.MFAToken, recovery.RecoveryCodes[0]))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.AuthResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.NotEmpty(t, resp.Token)
+		assert.NotEmpty(t, resp.RefreshToken)
+		assert.Equal(t, "mfa@example.com", resp.User.Email)
+	})
+
+	t.Run("TOTPCode", func(t *testing.T) {
+		challenge := login(t)
+		rec := r.do(http.MethodPost, "/api/v1/login/mfa", "",
+			fmt.Sprintf(`{"mfa_token":%q,"code":%q}`, challenge.MFAToken, nextTOTPCode(t, enrollment.Secret, 1)))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	})
+
+	t.Run("EnrollAgainConflicts", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/mfa/totp/enroll", auth.Token, "")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
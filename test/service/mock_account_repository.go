@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockAccountRepository AccountRepository için mock implementasyonu
+type MockAccountRepository struct {
+	accounts map[int64]*model.Account
+	mu       sync.RWMutex
+	nextID   int64
+
+	// UniqueViolations sonraki kaç AddAccount çağrısının 23505 ile başarısız olacağını belirler
+	UniqueViolations int
+}
+
+// NewMockAccountRepository yeni mock account repository oluşturur
+func NewMockAccountRepository() *MockAccountRepository {
+	return &MockAccountRepository{
+		accounts: make(map[int64]*model.Account),
+		nextID:   1,
+	}
+}
+
+// AddTestAccount test için hesap ekler
+func (m *MockAccountRepository) AddTestAccount(a *model.Account) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a.ID == 0 {
+		a.ID = m.nextID
+		m.nextID++
+	}
+
+	a.CreatedAt = time.Now()
+	a.UpdatedAt = time.Now()
+
+	m.accounts[a.ID] = a
+}
+
+// GetAccountsByUserID kullanıcının hesaplarını getirir
+func (m *MockAccountRepository) GetAccountsByUserID(ctx context.Context, userID int64) ([]model.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accounts := make([]model.Account, 0)
+	for id := int64(1); id < m.nextID; id++ {
+		if a, exists := m.accounts[id]; exists && a.UserID == userID {
+			accounts = append(accounts, *a)
+		}
+	}
+	return accounts, nil
+}
+
+// GetAccountByID ID ile hesap getirir
+func (m *MockAccountRepository) GetAccountByID(ctx context.Context, id int64) (model.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a, exists := m.accounts[id]
+	if !exists {
+		return model.Account{}, pgx.ErrNoRows
+	}
+	return *a, nil
+}
+
+// GetAccountByNumber hesap numarası ile hesap getirir
+func (m *MockAccountRepository) GetAccountByNumber(ctx context.Context, number string) (model.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.accounts {
+		if a.AccountNumber == number {
+			return *a, nil
+		}
+	}
+	return model.Account{}, pgx.ErrNoRows
+}
+
+// AddAccount hesap ekler
+func (m *MockAccountRepository) AddAccount(ctx context.Context, a *model.Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.UniqueViolations > 0 {
+		m.UniqueViolations--
+		return &pgconn.PgError{Code: "23505"}
+	}
+	for _, existing := range m.accounts {
+		if existing.AccountNumber == a.AccountNumber {
+			return &pgconn.PgError{Code: "23505"}
+		}
+	}
+
+	a.ID = m.nextID
+	m.nextID++
+	a.CreatedAt = time.Now()
+	a.UpdatedAt = time.Now()
+
+	stored := *a
+	m.accounts[a.ID] = &stored
+	return nil
+}
+
+// CloseAccount bakiyesi sıfır olan aktif hesabı kapatır
+func (m *MockAccountRepository) CloseAccount(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, exists := m.accounts[id]
+	if !exists || !a.IsActive || !a.Balance.IsZero() {
+		return pgx.ErrNoRows
+	}
+
+	a.IsActive = false
+	a.UpdatedAt = time.Now()
+	return nil
+}
+
+// WithTransaction mock transaction desteği
+func (m *MockAccountRepository) WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error {
+	return fn(nil)
+}
+
+// GetAccountForUpdate hesabı getirir; mock'ta satır kilidi yoktur
+func (m *MockAccountRepository) GetAccountForUpdate(ctx context.Context, tx pgx.Tx, id int64) (model.Account, error) {
+	return m.GetAccountByID(ctx, id)
+}
+
+// EnsureSystemAccount sistem hesabını yoksa oluşturur
+func (m *MockAccountRepository) EnsureSystemAccount(ctx context.Context, tx pgx.Tx, number, currency string) (model.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, a := range m.accounts {
+		if a.AccountNumber == number {
+			return *a, nil
+		}
+	}
+
+	a := &model.Account{
+		ID:            m.nextID,
+		AccountNumber: number,
+		Balance:       money.New(0, currency),
+		Currency:      currency,
+		IsActive:      true,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	m.nextID++
+	m.accounts[a.ID] = a
+	return *a, nil
+}
+
+// applyPosting hesap bakiyesine işaretli tutarı ekler
+func (m *MockAccountRepository) applyPosting(accountID int64, delta money.Money) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, exists := m.accounts[accountID]
+	if !exists || a.Currency != delta.Currency() {
+		return pgx.ErrNoRows
+	}
+	balance, err := a.Balance.Add(delta)
+	if err != nil {
+		return err
+	}
+	a.Balance = balance
+	a.UpdatedAt = time.Now()
+	return nil
+}
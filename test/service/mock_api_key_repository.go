@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockAPIKeyRepository APIKeyRepository için mock implementasyonu
+type MockAPIKeyRepository struct {
+	users  *MockUserRepository
+	keys   map[int64]*model.APIKey
+	mu     sync.Mutex
+	nextID int64
+}
+
+// NewMockAPIKeyRepository token sürümlerini verilen kullanıcı mock'undan okur
+func NewMockAPIKeyRepository(users *MockUserRepository) *MockAPIKeyRepository {
+	return &MockAPIKeyRepository{
+		users:  users,
+		keys:   make(map[int64]*model.APIKey),
+		nextID: 1,
+	}
+}
+
+// tokenVersion kullanıcının güncel token sürümünü döner
+func (m *MockAPIKeyRepository) tokenVersion(userID int64) (int64, bool) {
+	m.users.mu.RLock()
+	defer m.users.mu.RUnlock()
+
+	u, exists := m.users.users[userID]
+	if !exists {
+		return 0, false
+	}
+	return u.TokenVersion, true
+}
+
+// InsertAPIKey kullanıcının süresi dolmuş ve eski sürümlü anahtarlarını silip limit aşılmıyorsa anahtarı ekler
+func (m *MockAPIKeyRepository) InsertAPIKey(ctx context.Context, k *model.APIKey, limit int) (bool, error) {
+	version, exists := m.tokenVersion(k.UserID)
+	if !exists {
+		return false, pgx.ErrNoRows
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k.TokenVersion = version
+	count := 0
+	for id, key := range m.keys {
+		if key.UserID != k.UserID {
+			continue
+		}
+		if !key.ExpiresAt.After(k.CreatedAt) || key.TokenVersion != version {
+			delete(m.keys, id)
+			continue
+		}
+		count++
+	}
+	if count >= limit {
+		return false, nil
+	}
+	k.ID = m.nextID
+	m.nextID++
+	stored := *k
+	m.keys[k.ID] = &stored
+	return true, nil
+}
+
+// GetAPIKeyByHash süresi dolmamış anahtarı özetiyle getirir
+func (m *MockAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string, now time.Time) (model.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.keys {
+		if k.KeyHash == keyHash && k.ExpiresAt.After(now) {
+			return *k, nil
+		}
+	}
+	return model.APIKey{}, pgx.ErrNoRows
+}
+
+// GetUserAPIKeys kullanıcının süresi dolmamış ve güncel sürümlü anahtarlarını oluşturulma sırasıyla döner
+func (m *MockAPIKeyRepository) GetUserAPIKeys(ctx context.Context, userID int64, now time.Time) ([]model.APIKey, error) {
+	version, _ := m.tokenVersion(userID)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []model.APIKey{}
+	for _, k := range m.keys {
+		if k.UserID == userID && k.ExpiresAt.After(now) && k.TokenVersion == version {
+			keys = append(keys, *k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// TouchAPIKey anahtarın son kullanım zamanını günceller
+func (m *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, id int64, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if k, exists := m.keys[id]; exists {
+		usedAt := at
+		k.LastUsedAt = &usedAt
+	}
+	return nil
+}
+
+// DeleteUserAPIKey kullanıcının anahtarını siler
+func (m *MockAPIKeyRepository) DeleteUserAPIKey(ctx context.Context, userID, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, exists := m.keys[id]
+	if !exists || k.UserID != userID {
+		return pgx.ErrNoRows
+	}
+	delete(m.keys, id)
+	return nil
+}
+
+// ExpireAPIKeys test için tüm anahtarların süresini doldurur
+func (m *MockAPIKeyRepository) ExpireAPIKeys() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range m.keys {
+		k.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
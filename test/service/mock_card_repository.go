@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockCardRepository CardRepository için mock implementasyonu
+type MockCardRepository struct {
+	cards  map[int64]*model.Card
+	mu     sync.RWMutex
+	nextID int64
+
+	// UniqueViolations sonraki kaç kart eklemenin 23505 ile başarısız olacağını belirler
+	UniqueViolations int
+}
+
+// NewMockCardRepository yeni mock card repository oluşturur
+func NewMockCardRepository() *MockCardRepository {
+	return &MockCardRepository{
+		cards:  make(map[int64]*model.Card),
+		nextID: 1,
+	}
+}
+
+// addCard kartı ekler; çağıran kilidi tutmalıdır
+func (m *MockCardRepository) addCard(c *model.Card) error {
+	if m.UniqueViolations > 0 {
+		m.UniqueViolations--
+		return &pgconn.PgError{Code: "23505"}
+	}
+	for _, existing := range m.cards {
+		if existing.CardNumber == c.CardNumber {
+			return &pgconn.PgError{Code: "23505"}
+		}
+	}
+
+	c.ID = m.nextID
+	m.nextID++
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = time.Now()
+
+	stored := *c
+	m.cards[c.ID] = &stored
+	return nil
+}
+
+// AddCard kart ekler
+func (m *MockCardRepository) AddCard(ctx context.Context, c *model.Card) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.addCard(c)
+}
+
+// GetCardByID ID ile kart getirir
+func (m *MockCardRepository) GetCardByID(ctx context.Context, id int64) (model.Card, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, exists := m.cards[id]
+	if !exists {
+		return model.Card{}, pgx.ErrNoRows
+	}
+	return *c, nil
+}
+
+// GetCardsByAccountID hesabın kartlarını getirir
+func (m *MockCardRepository) GetCardsByAccountID(ctx context.Context, accountID int64) ([]model.Card, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cards := make([]model.Card, 0)
+	for id := int64(1); id < m.nextID; id++ {
+		if c, exists := m.cards[id]; exists && c.AccountID == accountID {
+			cards = append(cards, *c)
+		}
+	}
+	return cards, nil
+}
+
+// GetCardByNumber kart numarası ile kart getirir
+func (m *MockCardRepository) GetCardByNumber(ctx context.Context, cardNumber string) (model.Card, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.cards {
+		if c.CardNumber == cardNumber {
+			return *c, nil
+		}
+	}
+	return model.Card{}, pgx.ErrNoRows
+}
+
+// SetCardActive kartı dondurur ya da aktif eder
+func (m *MockCardRepository) SetCardActive(ctx context.Context, id int64, active bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.cards[id]
+	if !exists || c.Replaced() {
+		return pgx.ErrNoRows
+	}
+	c.IsActive = active
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+// ReplaceCard yeni kartı ekler ve eski kartı kalıcı olarak kapatır
+func (m *MockCardRepository) ReplaceCard(ctx context.Context, oldID int64, replacement *model.Card) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, exists := m.cards[oldID]
+	if !exists || old.Replaced() {
+		return pgx.ErrNoRows
+	}
+	if err := m.addCard(replacement); err != nil {
+		return err
+	}
+	newID := replacement.ID
+	old.IsActive = false
+	old.ReplacedByID = &newID
+	old.UpdatedAt = time.Now()
+	return nil
+}
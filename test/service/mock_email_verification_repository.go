@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockEmailVerificationRepository EmailVerificationRepository için mock implementasyonu
+type MockEmailVerificationRepository struct {
+	users         *MockUserRepository
+	verifications map[string]*model.EmailVerification // token_hash -> doğrulama
+	mu            sync.Mutex
+	nextID        int64
+}
+
+// NewMockEmailVerificationRepository onaylanan adresleri verilen kullanıcı mock'una uygular
+func NewMockEmailVerificationRepository(users *MockUserRepository) *MockEmailVerificationRepository {
+	return &MockEmailVerificationRepository{
+		users:         users,
+		verifications: make(map[string]*model.EmailVerification),
+		nextID:        1,
+	}
+}
+
+// InsertEmailVerification kullanıcının aynı amaçlı önceki doğrulamalarını silip yenisini ekler
+func (m *MockEmailVerificationRepository) InsertEmailVerification(ctx context.Context, v *model.EmailVerification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for h, existing := range m.verifications {
+		if existing.UserID == v.UserID && existing.Purpose == v.Purpose {
+			delete(m.verifications, h)
+		}
+	}
+	v.ID = m.nextID
+	m.nextID++
+	stored := *v
+	m.verifications[v.TokenHash] = &stored
+	return nil
+}
+
+// ConfirmEmail token'ı tüketir ve adresi kullanıcının doğrulanmış email'i yapar
+func (m *MockEmailVerificationRepository) ConfirmEmail(ctx context.Context, tokenHash string, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, exists := m.verifications[tokenHash]
+	if !exists || !v.ExpiresAt.After(now) {
+		return 0, pgx.ErrNoRows
+	}
+	delete(m.verifications, tokenHash)
+
+	m.users.mu.Lock()
+	defer m.users.mu.Unlock()
+	user, exists := m.users.users[v.UserID]
+	if !exists {
+		return 0, pgx.ErrNoRows
+	}
+	if v.Purpose == model.EmailVerificationVerify && user.Email != v.Email {
+		// Adres o arada değiştiyse eski adresin bağlantısı geçersizdir
+		return 0, pgx.ErrNoRows
+	}
+	if other, taken := m.users.emails[v.Email]; taken && other.ID != user.ID {
+		// Gerçek veritabanındaki unique kısıtı gibi davranır
+		return 0, &pgconn.PgError{Code: "23505"}
+	}
+	delete(m.users.emails, user.Email)
+	user.Email = v.Email
+	verifiedAt := now
+	user.EmailVerifiedAt = &verifiedAt
+	user.UpdatedAt = now
+	m.users.emails[v.Email] = user
+	return user.ID, nil
+}
+
+// ExpireVerifications test için tüm doğrulamaların süresini doldurur
+func (m *MockEmailVerificationRepository) ExpireVerifications() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, v := range m.verifications {
+		v.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
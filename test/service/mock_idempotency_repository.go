@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockIdempotencyRepository IdempotencyRepository için mock implementasyonu
+type MockIdempotencyRepository struct {
+	records map[string]*model.IdempotencyRecord
+	mu      sync.RWMutex
+	nextID  int64
+}
+
+// NewMockIdempotencyRepository yeni mock idempotency repository oluşturur
+func NewMockIdempotencyRepository() *MockIdempotencyRepository {
+	return &MockIdempotencyRepository{
+		records: make(map[string]*model.IdempotencyRecord),
+		nextID:  1,
+	}
+}
+
+func idempotencyMapKey(userID int64, key string) string {
+	return fmt.Sprintf("%d:%s", userID, key)
+}
+
+// ReserveKey süresi dolmamış kayıt yoksa anahtarı ayırır; staleBefore'dan eski, cevapsız kayıt terk edilmiş sayılır
+func (m *MockIdempotencyRepository) ReserveKey(ctx context.Context, r *model.IdempotencyRecord, staleBefore time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := idempotencyMapKey(r.UserID, r.Key)
+	if existing, exists := m.records[k]; exists && existing.ExpiresAt.After(r.CreatedAt) &&
+		(existing.Completed() || existing.CreatedAt.After(staleBefore)) {
+		return false, nil
+	}
+
+	r.ID = m.nextID
+	m.nextID++
+	stored := *r
+	m.records[k] = &stored
+	return true, nil
+}
+
+// GetRecord kullanıcı ve anahtar ile kaydı getirir
+func (m *MockIdempotencyRepository) GetRecord(ctx context.Context, userID int64, key string) (model.IdempotencyRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	r, exists := m.records[idempotencyMapKey(userID, key)]
+	if !exists {
+		return model.IdempotencyRecord{}, pgx.ErrNoRows
+	}
+	return *r, nil
+}
+
+// SaveResponse cevabı kayda yazar
+func (m *MockIdempotencyRepository) SaveResponse(ctx context.Context, userID int64, key string, status int, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, exists := m.records[idempotencyMapKey(userID, key)]
+	if !exists {
+		return pgx.ErrNoRows
+	}
+	r.ResponseStatus = status
+	r.ResponseBody = append([]byte(nil), body...)
+	return nil
+}
+
+// DeleteRecord kaydı siler
+func (m *MockIdempotencyRepository) DeleteRecord(ctx context.Context, userID int64, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, idempotencyMapKey(userID, key))
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys süresi dolmuş kayıtları siler
+func (m *MockIdempotencyRepository) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for k, r := range m.records {
+		if !r.ExpiresAt.After(now) {
+			delete(m.records, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Age test için kaydın oluşturulma zamanını d kadar geçmişe kaydırır
+func (m *MockIdempotencyRepository) Age(userID int64, key string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, exists := m.records[idempotencyMapKey(userID, key)]; exists {
+		r.CreatedAt = r.CreatedAt.Add(-d)
+	}
+}
+
+// Count test için kayıt sayısını döner
+func (m *MockIdempotencyRepository) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.records)
+}
+
+// Expire test için kaydın süresini doldurur
+func (m *MockIdempotencyRepository) Expire(userID int64, key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, exists := m.records[idempotencyMapKey(userID, key)]; exists {
+		r.ExpiresAt = r.CreatedAt
+	}
+}
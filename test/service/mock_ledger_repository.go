@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockLedgerRepository LedgerRepository için mock implementasyonu
+type MockLedgerRepository struct {
+	entries  map[int64]*model.JournalEntry
+	balances map[int64]money.Money
+	mu       sync.RWMutex
+	nextID   int64
+
+	// accounts set edilirse postingler bu mock'taki hesap bakiyelerine de uygulanır
+	accounts *MockAccountRepository
+}
+
+// NewMockLedgerRepository yeni mock ledger repository oluşturur
+func NewMockLedgerRepository() *MockLedgerRepository {
+	return &MockLedgerRepository{
+		entries:  make(map[int64]*model.JournalEntry),
+		balances: make(map[int64]money.Money),
+		nextID:   1,
+	}
+}
+
+// NewLinkedMockLedgerRepository postingleri verilen account mock'una da uygulayan repository oluşturur
+func NewLinkedMockLedgerRepository(accounts *MockAccountRepository) *MockLedgerRepository {
+	m := NewMockLedgerRepository()
+	m.accounts = accounts
+	return m
+}
+
+// AddTestAccount test için bakiyesi sıfır olan hesap ekler
+func (m *MockLedgerRepository) AddTestAccount(accountID int64, currency string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.balances[accountID] = money.New(0, currency)
+}
+
+// SetBalance materialize edilmiş bakiyeyi postinglerden bağımsız olarak değiştirir
+func (m *MockLedgerRepository) SetBalance(accountID int64, balance money.Money) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.balances[accountID] = balance
+}
+
+// Balance hesabın materialize edilmiş bakiyesini döner
+func (m *MockLedgerRepository) Balance(accountID int64) money.Money {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.balances[accountID]
+}
+
+// WithTransaction mock transaction desteği
+func (m *MockLedgerRepository) WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error {
+	return fn(nil)
+}
+
+// InsertJournalEntry kaydı saklar ve postingleri bakiyelere uygular
+func (m *MockLedgerRepository) InsertJournalEntry(ctx context.Context, tx pgx.Tx, e *model.JournalEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range e.Postings {
+		if m.accounts != nil {
+			if _, exists := m.balances[p.AccountID]; !exists {
+				m.balances[p.AccountID] = money.New(0, p.Currency)
+			}
+		}
+		if balance, exists := m.balances[p.AccountID]; !exists || balance.Currency() != p.Currency {
+			return pgx.ErrNoRows
+		}
+	}
+	if m.accounts != nil {
+		for _, p := range e.Postings {
+			if err := m.accounts.applyPosting(p.AccountID, signed(p)); err != nil {
+				return err
+			}
+		}
+	}
+
+	e.ID = m.nextID
+	m.nextID++
+	e.CreatedAt = time.Now()
+	for i := range e.Postings {
+		p := &e.Postings[i]
+		p.ID = m.nextID
+		m.nextID++
+		p.JournalEntryID = e.ID
+		p.CreatedAt = e.CreatedAt
+		m.balances[p.AccountID], _ = m.balances[p.AccountID].Add(signed(*p))
+	}
+
+	stored := *e
+	stored.Postings = append([]model.Posting(nil), e.Postings...)
+	m.entries[e.ID] = &stored
+	return nil
+}
+
+// GetPostingsByAccountID hesabın postinglerini getirir
+func (m *MockLedgerRepository) GetPostingsByAccountID(ctx context.Context, accountID int64) ([]model.Posting, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	postings := make([]model.Posting, 0)
+	for id := int64(1); id < m.nextID; id++ {
+		e, exists := m.entries[id]
+		if !exists {
+			continue
+		}
+		for _, p := range e.Postings {
+			if p.AccountID == accountID {
+				postings = append(postings, p)
+			}
+		}
+	}
+	return postings, nil
+}
+
+// GetUnbalancedEntries borç ve alacak toplamı eşit olmayan kayıtları getirir
+func (m *MockLedgerRepository) GetUnbalancedEntries(ctx context.Context) ([]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []int64
+	for id, e := range m.entries {
+		var sum int64
+		for _, p := range e.Postings {
+			sum += signed(p).MinorUnits()
+		}
+		if sum != 0 || len(e.Postings) < 2 {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// GetBalanceMismatches bakiyesi postinglerle uyuşmayan hesapları getirir
+func (m *MockLedgerRepository) GetBalanceMismatches(ctx context.Context) ([]model.BalanceMismatch, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	derived := make(map[int64]money.Money)
+	for accountID, balance := range m.balances {
+		derived[accountID] = money.New(0, balance.Currency())
+	}
+	for _, e := range m.entries {
+		for _, p := range e.Postings {
+			derived[p.AccountID], _ = derived[p.AccountID].Add(signed(p))
+		}
+	}
+
+	var mismatches []model.BalanceMismatch
+	for accountID, balance := range m.balances {
+		if balance != derived[accountID] {
+			mismatches = append(mismatches, model.BalanceMismatch{
+				AccountID:      accountID,
+				Currency:       balance.Currency(),
+				Balance:        balance,
+				DerivedBalance: derived[accountID],
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+// signed alacakları pozitif, borçları negatif tutar olarak döner
+func signed(p model.Posting) money.Money {
+	if p.Direction == model.PostingDebit {
+		return p.Amount.Neg()
+	}
+	return p.Amount
+}
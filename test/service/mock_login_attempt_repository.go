@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+type loginFailureKey struct {
+	scope string
+	key   string
+}
+
+// MockLoginAttemptRepository LoginAttemptRepository için mock implementasyonu
+type MockLoginAttemptRepository struct {
+	failures map[loginFailureKey]*model.LoginFailure
+	mu       sync.Mutex
+}
+
+func NewMockLoginAttemptRepository() *MockLoginAttemptRepository {
+	return &MockLoginAttemptRepository{failures: make(map[loginFailureKey]*model.LoginFailure)}
+}
+
+// GetLoginFailures hesap ve IP sayaçlarını döner
+func (m *MockLoginAttemptRepository) GetLoginFailures(ctx context.Context, accountKey, ip string) ([]model.LoginFailure, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []model.LoginFailure
+	for _, k := range []loginFailureKey{{model.LoginScopeAccount, accountKey}, {model.LoginScopeIP, ip}} {
+		if f, exists := m.failures[k]; exists {
+			out = append(out, *f)
+		}
+	}
+	return out, nil
+}
+
+// RecordLoginFailure sayacı artırır; pencere dışındaki sayaç 1'den başlar
+func (m *MockLoginAttemptRepository) RecordLoginFailure(ctx context.Context, scope, key string, now, windowStart time.Time) (model.LoginFailure, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := loginFailureKey{scope, key}
+	f, exists := m.failures[k]
+	if !exists {
+		f = &model.LoginFailure{Scope: scope, Key: key}
+		m.failures[k] = f
+	}
+	if !f.LastFailureAt.After(windowStart) {
+		f.Failures = 0
+	}
+	f.Failures++
+	f.LastFailureAt = now
+	return *f, nil
+}
+
+// LockLogin anahtarı verilen zamana kadar kilitler ve sayacı sıfırlar
+func (m *MockLoginAttemptRepository) LockLogin(ctx context.Context, scope, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, exists := m.failures[loginFailureKey{scope, key}]
+	if !exists {
+		return pgx.ErrNoRows
+	}
+	f.LockedUntil = &until
+	f.Failures = 0
+	return nil
+}
+
+// DeleteLoginFailures sayacı siler
+func (m *MockLoginAttemptRepository) DeleteLoginFailures(ctx context.Context, scope, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := loginFailureKey{scope, key}
+	if _, exists := m.failures[k]; !exists {
+		return 0, nil
+	}
+	delete(m.failures, k)
+	return 1, nil
+}
+
+// DeleteStaleLoginFailures son hatası before'dan eski ve kilidi bitmiş sayaçları siler
+func (m *MockLoginAttemptRepository) DeleteStaleLoginFailures(ctx context.Context, before, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for k, f := range m.failures {
+		if f.LastFailureAt.After(before) || (f.LockedUntil != nil && f.LockedUntil.After(now)) {
+			continue
+		}
+		delete(m.failures, k)
+		n++
+	}
+	return n, nil
+}
+
+// Rewind test için tüm sayaçları ve kilitleri d kadar geçmişe kaydırır
+func (m *MockLoginAttemptRepository) Rewind(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.failures {
+		f.LastFailureAt = f.LastFailureAt.Add(-d)
+		if f.LockedUntil != nil {
+			until := f.LockedUntil.Add(-d)
+			f.LockedUntil = &until
+		}
+	}
+}
+
+// Count test için kayıtlı sayaç sayısını döner
+func (m *MockLoginAttemptRepository) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.failures)
+}
+
+// Failures test için verilen sayacın hata sayısını döner
+func (m *MockLoginAttemptRepository) Failures(scope, key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if f, exists := m.failures[loginFailureKey{scope, key}]; exists {
+		return f.Failures
+	}
+	return 0
+}
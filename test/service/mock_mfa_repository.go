@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockMFARepository MFARepository için mock implementasyonu
+type MockMFARepository struct {
+	totp       map[int64]*model.UserTOTP
+	codes      map[int64]map[string]bool // user_id -> code_hash -> kullanıldı mı
+	challenges map[string]*model.MFAChallenge
+	mu         sync.RWMutex
+	nextID     int64
+}
+
+// NewMockMFARepository yeni mock MFA repository oluşturur
+func NewMockMFARepository() *MockMFARepository {
+	return &MockMFARepository{
+		totp:       make(map[int64]*model.UserTOTP),
+		codes:      make(map[int64]map[string]bool),
+		challenges: make(map[string]*model.MFAChallenge),
+		nextID:     1,
+	}
+}
+
+// UpsertPendingTOTP onaylanmamış TOTP kaydı ekler veya günceller
+func (m *MockMFARepository) UpsertPendingTOTP(ctx context.Context, userID int64, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if t, exists := m.totp[userID]; exists {
+		if t.Enabled {
+			return pgx.ErrNoRows
+		}
+		t.Secret = secret
+		t.LastUsedStep = 0
+		t.UpdatedAt = now
+		return nil
+	}
+	m.totp[userID] = &model.UserTOTP{UserID: userID, Secret: secret, CreatedAt: now, UpdatedAt: now}
+	return nil
+}
+
+// GetTOTP kullanıcının TOTP kaydını getirir
+func (m *MockMFARepository) GetTOTP(ctx context.Context, userID int64) (model.UserTOTP, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, exists := m.totp[userID]
+	if !exists {
+		return model.UserTOTP{}, pgx.ErrNoRows
+	}
+	return *t, nil
+}
+
+// EnableTOTP kaydı etkinleştirir ve kurtarma kodlarını değiştirir
+func (m *MockMFARepository) EnableTOTP(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, exists := m.totp[userID]
+	if !exists || t.Enabled {
+		return pgx.ErrNoRows
+	}
+	now := time.Now()
+	t.Enabled = true
+	t.LastUsedStep = step
+	t.ConfirmedAt = &now
+	t.UpdatedAt = now
+
+	m.codes[userID] = make(map[string]bool)
+	for _, h := range recoveryCodeHashes {
+		m.codes[userID][h] = false
+	}
+	return nil
+}
+
+// AdvanceTOTPStep yalnızca daha yeni adımları kabul eder
+func (m *MockMFARepository) AdvanceTOTPStep(ctx context.Context, userID, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, exists := m.totp[userID]
+	if !exists || t.LastUsedStep >= step {
+		return pgx.ErrNoRows
+	}
+	t.LastUsedStep = step
+	return nil
+}
+
+// ReserveTOTPAttempt denemeyi hata olarak sayar; son hata pencereden eskiyse sayaç baştan başlar,
+// pencerede max hata varsa pgx.ErrNoRows döner
+func (m *MockMFARepository) ReserveTOTPAttempt(ctx context.Context, userID int64, at, windowStart time.Time, max int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, exists := m.totp[userID]
+	if !exists {
+		return pgx.ErrNoRows
+	}
+	if t.LastFailureAt == nil || !t.LastFailureAt.After(windowStart) {
+		t.FailedAttempts = 1
+	} else if t.FailedAttempts < max {
+		t.FailedAttempts++
+	} else {
+		return pgx.ErrNoRows
+	}
+	failedAt := at
+	t.LastFailureAt = &failedAt
+	return nil
+}
+
+// ResetTOTPFailures hatalı kod sayacını sıfırlar
+func (m *MockMFARepository) ResetTOTPFailures(ctx context.Context, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if t, exists := m.totp[userID]; exists {
+		t.FailedAttempts = 0
+		t.LastFailureAt = nil
+	}
+	return nil
+}
+
+// DeleteTOTP TOTP kaydını ve kurtarma kodlarını siler
+func (m *MockMFARepository) DeleteTOTP(ctx context.Context, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.totp, userID)
+	delete(m.codes, userID)
+	return nil
+}
+
+// UseRecoveryCode kullanılmamış kurtarma kodunu kullanıldı olarak işaretler
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	used, exists := m.codes[userID][codeHash]
+	if !exists || used {
+		return pgx.ErrNoRows
+	}
+	m.codes[userID][codeHash] = true
+	return nil
+}
+
+// InsertMFAChallenge yeni giriş doğrulama isteği ekler
+func (m *MockMFARepository) InsertMFAChallenge(ctx context.Context, ch *model.MFAChallenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, existing := range m.challenges {
+		if existing.UserID == ch.UserID && !existing.ExpiresAt.After(ch.CreatedAt) {
+			delete(m.challenges, hash)
+		}
+	}
+	ch.ID = m.nextID
+	m.nextID++
+	stored := *ch
+	m.challenges[ch.TokenHash] = &stored
+	return nil
+}
+
+// GetMFAChallenge doğrulama isteğini getirir
+func (m *MockMFARepository) GetMFAChallenge(ctx context.Context, tokenHash string) (model.MFAChallenge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ch, exists := m.challenges[tokenHash]
+	if !exists {
+		return model.MFAChallenge{}, pgx.ErrNoRows
+	}
+	return *ch, nil
+}
+
+// IncrementMFAChallengeAttempts deneme sayısını artırır
+func (m *MockMFARepository) IncrementMFAChallengeAttempts(ctx context.Context, tokenHash string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch, exists := m.challenges[tokenHash]
+	if !exists {
+		return 0, pgx.ErrNoRows
+	}
+	ch.Attempts++
+	return ch.Attempts, nil
+}
+
+// DeleteMFAChallenge doğrulama isteğini siler
+func (m *MockMFARepository) DeleteMFAChallenge(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.challenges, tokenHash)
+	return nil
+}
+
+// ExpireChallenges test için tüm doğrulama isteklerinin süresini doldurur
+func (m *MockMFARepository) ExpireChallenges() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ch := range m.challenges {
+		ch.ExpiresAt = time.Now().Add(-time.Second)
+	}
+}
+
+// RewindTOTPStep test için son kullanılan adımı sıfırlar; aynı kodun tekrar denenmesini sağlar
+func (m *MockMFARepository) RewindTOTPStep(userID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if t, exists := m.totp[userID]; exists {
+		t.LastUsedStep = 0
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockOAuthRepository OAuthRepository için mock implementasyonu
+type MockOAuthRepository struct {
+	users   *MockUserRepository
+	clients map[string]*model.OAuthClient            // client_id -> istemci
+	codes   map[string]*model.OAuthAuthorizationCode // code_hash -> kod
+	mu      sync.Mutex
+	nextID  int64
+}
+
+// NewMockOAuthRepository istemci silinince oturumlarını verilen kullanıcı mock'undan siler
+func NewMockOAuthRepository(users *MockUserRepository) *MockOAuthRepository {
+	return &MockOAuthRepository{
+		users:   users,
+		clients: make(map[string]*model.OAuthClient),
+		codes:   make(map[string]*model.OAuthAuthorizationCode),
+		nextID:  1,
+	}
+}
+
+// InsertClient istemci ekler
+func (m *MockOAuthRepository) InsertClient(ctx context.Context, c *model.OAuthClient) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c.ID = m.nextID
+	m.nextID++
+	stored := *c
+	m.clients[c.ClientID] = &stored
+	return nil
+}
+
+// GetClient istemciyi client_id ile getirir
+func (m *MockOAuthRepository) GetClient(ctx context.Context, clientID string) (model.OAuthClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.clients[clientID]
+	if !exists {
+		return model.OAuthClient{}, pgx.ErrNoRows
+	}
+	return *c, nil
+}
+
+// GetAllClients tüm istemcileri kayıt sırasıyla döner
+func (m *MockOAuthRepository) GetAllClients(ctx context.Context) ([]model.OAuthClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients := []model.OAuthClient{}
+	for _, c := range m.clients {
+		clients = append(clients, *c)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].ID < clients[j].ID })
+	return clients, nil
+}
+
+// DeleteClient istemciyi, kodlarını ve oturumlarını siler
+func (m *MockOAuthRepository) DeleteClient(ctx context.Context, clientID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.clients[clientID]; !exists {
+		return pgx.ErrNoRows
+	}
+	delete(m.clients, clientID)
+	for hash, code := range m.codes {
+		if code.ClientID == clientID {
+			delete(m.codes, hash)
+		}
+	}
+	m.users.DeleteClientSessions(clientID)
+	return nil
+}
+
+// InsertAuthorizationCode kodu ekler ve süresi dolmuş kodları siler
+func (m *MockOAuthRepository) InsertAuthorizationCode(ctx context.Context, code *model.OAuthAuthorizationCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for hash, c := range m.codes {
+		if !c.ExpiresAt.After(code.CreatedAt) {
+			delete(m.codes, hash)
+		}
+	}
+	code.ID = m.nextID
+	m.nextID++
+	stored := *code
+	m.codes[code.CodeHash] = &stored
+	return nil
+}
+
+// ClaimAuthorizationCode kullanılmamış ve süresi dolmamış kodu kullanıldı olarak işaretler
+func (m *MockOAuthRepository) ClaimAuthorizationCode(ctx context.Context, codeHash string, now time.Time) (model.OAuthAuthorizationCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code, exists := m.codes[codeHash]
+	if !exists || code.UsedAt != nil || !code.ExpiresAt.After(now) {
+		return model.OAuthAuthorizationCode{}, pgx.ErrNoRows
+	}
+	usedAt := now
+	code.UsedAt = &usedAt
+	return *code, nil
+}
+
+// ExpireAuthorizationCodes test için tüm kodların süresini doldurur
+func (m *MockOAuthRepository) ExpireAuthorizationCodes() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, code := range m.codes {
+		code.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockPasswordResetRepository PasswordResetRepository için mock implementasyonu
+type MockPasswordResetRepository struct {
+	users  *MockUserRepository
+	tokens map[string]*model.PasswordResetToken // token_hash -> token
+	mu     sync.Mutex
+	nextID int64
+}
+
+// NewMockPasswordResetRepository şifre ve oturum değişikliklerini verilen kullanıcı mock'una uygular
+func NewMockPasswordResetRepository(users *MockUserRepository) *MockPasswordResetRepository {
+	return &MockPasswordResetRepository{
+		users:  users,
+		tokens: make(map[string]*model.PasswordResetToken),
+		nextID: 1,
+	}
+}
+
+// InsertPasswordResetToken kullanıcının önceki token'larını silip yenisini ekler
+func (m *MockPasswordResetRepository) InsertPasswordResetToken(ctx context.Context, t *model.PasswordResetToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteUserTokens(t.UserID)
+	t.ID = m.nextID
+	m.nextID++
+	stored := *t
+	m.tokens[t.TokenHash] = &stored
+	return nil
+}
+
+// GetPasswordResetUser kullanılmamış ve süresi dolmamış token'ın kullanıcısını döner
+func (m *MockPasswordResetRepository) GetPasswordResetUser(ctx context.Context, tokenHash string, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, exists := m.tokens[tokenHash]
+	if !exists || t.UsedAt != nil || !t.ExpiresAt.After(now) {
+		return 0, pgx.ErrNoRows
+	}
+	return t.UserID, nil
+}
+
+// ResetPassword token'ı tüketir, şifreyi günceller ve oturumları kapatır
+func (m *MockPasswordResetRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, exists := m.tokens[tokenHash]
+	if !exists || t.UsedAt != nil || !t.ExpiresAt.After(now) {
+		return 0, pgx.ErrNoRows
+	}
+	if err := m.users.UpdateUserPassword(ctx, t.UserID, passwordHash); err != nil {
+		return 0, err
+	}
+	if err := m.users.DeleteUserRefreshTokens(ctx, t.UserID); err != nil {
+		return 0, err
+	}
+	usedAt := now
+	t.UsedAt = &usedAt
+	m.deleteUserTokens(t.UserID)
+	return t.UserID, nil
+}
+
+// ExpireResetTokens test için tüm token'ların süresini doldurur
+func (m *MockPasswordResetRepository) ExpireResetTokens() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, t := range m.tokens {
+		t.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
+
+// StoredTokenHashes saklanan token özetlerini döner
+func (m *MockPasswordResetRepository) StoredTokenHashes() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hashes := make([]string, 0, len(m.tokens))
+	for h := range m.tokens {
+		hashes = append(hashes, h)
+	}
+	return hashes
+}
+
+func (m *MockPasswordResetRepository) deleteUserTokens(userID int64) {
+	for h, t := range m.tokens {
+		if t.UserID == userID {
+			delete(m.tokens, h)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,16 +15,27 @@ import (
 type MockUserRepository struct {
 	users  map[int64]*model.User
 	emails map[string]*model.User
-	mu     sync.RWMutex
-	nextID int64
+	tokens map[string]model.RefreshToken
+	// sessions family_id ile tutulur; silinen oturumun token'ları da silinir
+	sessions map[string]*model.Session
+	// history kullanıcının önceki şifre özetlerini en yeni sonda olacak şekilde tutar
+	history map[int64][]string
+	// revoked iptal edilen access token'ları jti ile tutar
+	revoked map[string]model.RevokedAccessToken
+	mu      sync.RWMutex
+	nextID  int64
 }
 
 // NewMockUserRepository yeni mock repository oluşturur
 func NewMockUserRepository() *MockUserRepository {
 	return &MockUserRepository{
-		users:  make(map[int64]*model.User),
-		emails: make(map[string]*model.User),
-		nextID: 1,
+		users:    make(map[int64]*model.User),
+		emails:   make(map[string]*model.User),
+		tokens:   make(map[string]model.RefreshToken),
+		sessions: make(map[string]*model.Session),
+		history:  make(map[int64][]string),
+		revoked:  make(map[string]model.RevokedAccessToken),
+		nextID:   1,
 	}
 }
 
@@ -75,11 +87,23 @@ func (m *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (
 
 	user, exists := m.emails[email]
 	if !exists {
-		return model.User{}, service.ErrUserNotFound
+		// Gerçek repository gibi pgx.ErrNoRows döner
+		return model.User{}, pgx.ErrNoRows
 	}
 	return *user, nil
 }
 
+// MarkEmailVerified test için kullanıcının email adresini doğrulanmış yapar
+func (m *MockUserRepository) MarkEmailVerified(id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user, exists := m.users[id]; exists {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+}
+
 // AddUser kullanıcı ekler
 func (m *MockUserRepository) AddUser(ctx context.Context, u *model.User) error {
 	m.mu.Lock()
@@ -118,8 +142,9 @@ func (m *MockUserRepository) UpdateUserEmail(ctx context.Context, id int64, emai
 	// Eski email'i sil
 	delete(m.emails, user.Email)
 
-	// Yeni email'i ekle
+	// Yeni email'i ekle; yeni adres henüz doğrulanmamıştır
 	user.Email = email
+	user.EmailVerifiedAt = nil
 	user.UpdatedAt = time.Now()
 	m.emails[email] = user
 
@@ -136,12 +161,77 @@ func (m *MockUserRepository) UpdateUserPassword(ctx context.Context, id int64, h
 		return service.ErrUserNotFound
 	}
 
+	m.history[id] = append(m.history[id], user.PasswordHash)
+	user.PasswordHash = hash
+	user.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// ChangePassword şifreyi günceller, geçmişi kırpar ve tüm oturumları tek kilit altında sonlandırır
+func (m *MockUserRepository) ChangePassword(ctx context.Context, id int64, hash string, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, exists := m.users[id]
+	if !exists {
+		return pgx.ErrNoRows
+	}
+
+	past := append(m.history[id], user.PasswordHash)
+	if len(past) > keep {
+		past = append([]string(nil), past[len(past)-keep:]...)
+	}
+	m.history[id] = past
 	user.PasswordHash = hash
 	user.UpdatedAt = time.Now()
 
+	user.TokenVersion++
+	for familyID, s := range m.sessions {
+		if s.UserID == id {
+			m.deleteSessionLocked(familyID)
+		}
+	}
+	for h, rt := range m.tokens {
+		if rt.UserID == id {
+			delete(m.tokens, h)
+		}
+	}
 	return nil
 }
 
+// GetPasswordHistory önceki şifre özetlerini en yeniden başlayarak döner
+func (m *MockUserRepository) GetPasswordHistory(ctx context.Context, id int64, limit int) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var hashes []string
+	past := m.history[id]
+	for i := len(past) - 1; i >= 0 && len(hashes) < limit; i-- {
+		hashes = append(hashes, past[i])
+	}
+	return hashes, nil
+}
+
+// TrimPasswordHistory en yeni keep özet dışındakileri siler
+func (m *MockUserRepository) TrimPasswordHistory(ctx context.Context, id int64, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if past := m.history[id]; len(past) > keep {
+		m.history[id] = append([]string(nil), past[len(past)-keep:]...)
+	}
+	return nil
+}
+
+// PasswordHistoryLen test için saklanan önceki şifre sayısını döner
+func (m *MockUserRepository) PasswordHistoryLen(id int64) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.history[id])
+}
+
 // UpdateUserActiveStatus kullanıcı aktiflik durumunu günceller
 func (m *MockUserRepository) UpdateUserActiveStatus(ctx context.Context, id int64, isActive bool) error {
 	m.mu.Lock()
@@ -187,6 +277,8 @@ func (m *MockUserRepository) Clear() {
 
 	m.users = make(map[int64]*model.User)
 	m.emails = make(map[string]*model.User)
+	m.tokens = make(map[string]model.RefreshToken)
+	m.sessions = make(map[string]*model.Session)
 	m.nextID = 1
 }
 
@@ -198,39 +290,286 @@ func (m *MockUserRepository) InsertRefreshToken(ctx context.Context, rt *model.R
 	rt.ID = m.nextID
 	m.nextID++
 	rt.CreatedAt = time.Now()
+	// Veritabanı gibi yalnızca özeti sakla
+	stored := *rt
+	stored.Token = ""
+	m.tokens[rt.TokenHash] = stored
 
 	return nil
 }
 
 // GetRefreshToken refresh token getirir
-func (m *MockUserRepository) GetRefreshToken(ctx context.Context, token string) (model.RefreshToken, error) {
+func (m *MockUserRepository) GetRefreshToken(ctx context.Context, tokenHash string) (model.RefreshToken, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Mock için basit implementasyon - her zaman geçerli token döner
-	return model.RefreshToken{
-		ID:        1,
-		UserID:    1,
-		Token:     token,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-		CreatedAt: time.Now(),
-	}, nil
+	rt, exists := m.tokens[tokenHash]
+	if !exists {
+		return model.RefreshToken{}, pgx.ErrNoRows
+	}
+	return m.withSessionLocked(rt), nil
+}
+
+// withSessionLocked token'a oturumunun istemci ve yetki bilgisini ekler
+func (m *MockUserRepository) withSessionLocked(rt model.RefreshToken) model.RefreshToken {
+	if s, exists := m.sessions[rt.FamilyID]; exists {
+		rt.ClientID = s.ClientID
+		rt.Scopes = s.Scopes
+	}
+	return rt
+}
+
+// ClaimRefreshToken istemcinin oturumuna ait, kullanılmamış ve süresi dolmamış token'ı kullanıldı olarak işaretler
+func (m *MockUserRepository) ClaimRefreshToken(ctx context.Context, tokenHash, clientID string, now time.Time) (model.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rt, exists := m.tokens[tokenHash]
+	if !exists || rt.Used() || !now.Before(rt.ExpiresAt) {
+		return model.RefreshToken{}, pgx.ErrNoRows
+	}
+	if s, exists := m.sessions[rt.FamilyID]; !exists || s.ClientID != clientID {
+		return model.RefreshToken{}, pgx.ErrNoRows
+	}
+	usedAt := now
+	rt.UsedAt = &usedAt
+	m.tokens[tokenHash] = rt
+	return m.withSessionLocked(rt), nil
+}
+
+// DeleteRefreshTokenFamily oturumu ve aileye ait tüm refresh token'ları siler
+func (m *MockUserRepository) DeleteRefreshTokenFamily(ctx context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteSessionLocked(familyID)
+	return nil
+}
+
+// deleteSessionLocked ON DELETE CASCADE davranışını taklit eder
+func (m *MockUserRepository) deleteSessionLocked(familyID string) {
+	delete(m.sessions, familyID)
+	for hash, rt := range m.tokens {
+		if rt.FamilyID == familyID {
+			delete(m.tokens, hash)
+		}
+	}
+}
+
+// ExpireRefreshToken test için token'ın süresini geçmişe çeker
+func (m *MockUserRepository) ExpireRefreshToken(tokenHash string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rt, exists := m.tokens[tokenHash]; exists {
+		rt.ExpiresAt = time.Now().Add(-time.Minute)
+		m.tokens[tokenHash] = rt
+	}
 }
 
 // DeleteRefreshToken refresh token siler
-func (m *MockUserRepository) DeleteRefreshToken(ctx context.Context, token string) error {
+func (m *MockUserRepository) DeleteRefreshToken(ctx context.Context, tokenHash string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Mock için basit implementasyon
+	delete(m.tokens, tokenHash)
 	return nil
 }
 
-// DeleteUserRefreshTokens kullanıcının tüm refresh token'larını siler
+// DeleteExpiredRefreshTokens süresi dolmuş refresh token'ları siler
+func (m *MockUserRepository) DeleteExpiredRefreshTokens(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for hash, rt := range m.tokens {
+		if !rt.ExpiresAt.After(now) {
+			delete(m.tokens, hash)
+			n++
+		}
+	}
+	// Token'ı kalmayan oturumları da sil
+	for familyID := range m.sessions {
+		if !m.familyHasTokenLocked(familyID, func(model.RefreshToken) bool { return true }) {
+			delete(m.sessions, familyID)
+		}
+	}
+	return n, nil
+}
+
+func (m *MockUserRepository) familyHasTokenLocked(familyID string, match func(model.RefreshToken) bool) bool {
+	for _, rt := range m.tokens {
+		if rt.FamilyID == familyID && match(rt) {
+			return true
+		}
+	}
+	return false
+}
+
+// InsertSession yeni oturum ekler
+func (m *MockUserRepository) InsertSession(ctx context.Context, s *model.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s.ID = m.nextID
+	m.nextID++
+	stored := *s
+	m.sessions[s.FamilyID] = &stored
+	return nil
+}
+
+// TouchSession oturumun son kullanım zamanını ve IP adresini günceller
+func (m *MockUserRepository) TouchSession(ctx context.Context, familyID string, at time.Time, ip string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, exists := m.sessions[familyID]; exists {
+		s.LastUsedAt = at
+		if ip != "" {
+			s.IPAddress = ip
+		}
+	}
+	return nil
+}
+
+// GetUserSessions kullanıcının aktif oturumlarını son kullanıma göre sıralı döner
+func (m *MockUserRepository) GetUserSessions(ctx context.Context, userID int64, now time.Time) ([]model.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	live := func(rt model.RefreshToken) bool { return !rt.Used() && rt.ExpiresAt.After(now) }
+	sessions := []model.Session{}
+	for familyID, s := range m.sessions {
+		if s.UserID == userID && m.familyHasTokenLocked(familyID, live) {
+			sessions = append(sessions, *s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// DeleteClientSessions istemci silindiğinde ON DELETE CASCADE ile silinen oturumları siler
+func (m *MockUserRepository) DeleteClientSessions(clientID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for familyID, s := range m.sessions {
+		if s.ClientID == clientID {
+			m.deleteSessionLocked(familyID)
+		}
+	}
+}
+
+// DeleteUserSession kullanıcının oturumunu ve token'larını siler
+func (m *MockUserRepository) DeleteUserSession(ctx context.Context, userID, sessionID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for familyID, s := range m.sessions {
+		if s.ID == sessionID && s.UserID == userID {
+			m.deleteSessionLocked(familyID)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+// StoredRefreshTokens saklanan tüm refresh token kayıtlarını döner
+func (m *MockUserRepository) StoredRefreshTokens() []model.RefreshToken {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tokens := make([]model.RefreshToken, 0, len(m.tokens))
+	for _, rt := range m.tokens {
+		tokens = append(tokens, rt)
+	}
+	return tokens
+}
+
+// DeleteUserRefreshTokens kullanıcının tüm refresh token'larını siler ve token sürümünü artırır
 func (m *MockUserRepository) DeleteUserRefreshTokens(ctx context.Context, userID int64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Mock için basit implementasyon
+	if user, exists := m.users[userID]; exists {
+		user.TokenVersion++
+	}
+
+	for familyID, s := range m.sessions {
+		if s.UserID == userID {
+			m.deleteSessionLocked(familyID)
+		}
+	}
+	for hash, rt := range m.tokens {
+		if rt.UserID == userID {
+			delete(m.tokens, hash)
+		}
+	}
 	return nil
 }
+
+// RefreshTokenCount kullanıcının henüz kullanılmamış refresh token sayısını döner
+func (m *MockUserRepository) RefreshTokenCount(userID int64) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, rt := range m.tokens {
+		if rt.UserID == userID && !rt.Used() {
+			count++
+		}
+	}
+	return count
+}
+
+// GetAccessTokenState kullanıcının token sürümünü, durumunu ve jti'nin iptal edilip edilmediğini döner
+func (m *MockUserRepository) GetAccessTokenState(ctx context.Context, userID int64, jti string) (model.AccessTokenState, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, exists := m.users[userID]
+	if !exists {
+		return model.AccessTokenState{}, pgx.ErrNoRows
+	}
+	_, revoked := m.revoked[jti]
+	return model.AccessTokenState{TokenVersion: user.TokenVersion, IsActive: user.IsActive, Revoked: revoked}, nil
+}
+
+// InsertRevokedAccessToken access token'ı iptal listesine ekler
+func (m *MockUserRepository) InsertRevokedAccessToken(ctx context.Context, t *model.RevokedAccessToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.revoked[t.JTI]; !exists {
+		m.revoked[t.JTI] = *t
+	}
+	return nil
+}
+
+// DeleteExpiredRevokedAccessTokens süresi dolmuş iptal kayıtlarını siler
+func (m *MockUserRepository) DeleteExpiredRevokedAccessTokens(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for jti, t := range m.revoked {
+		if !t.ExpiresAt.After(now) {
+			delete(m.revoked, jti)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RevokedAccessTokenCount iptal listesindeki token sayısını döner
+func (m *MockUserRepository) RevokedAccessTokenCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.revoked)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// MockTransactionRepository TransactionRepository için mock implementasyonu
+type MockTransactionRepository struct {
+	transactions []model.Transaction
+	transfers    []model.Transfer
+	mu           sync.RWMutex
+	nextID       int64
+}
+
+// NewMockTransactionRepository yeni mock transaction repository oluşturur
+func NewMockTransactionRepository() *MockTransactionRepository {
+	return &MockTransactionRepository{nextID: 1}
+}
+
+// InsertTransaction işlem geçmişine satır ekler
+func (m *MockTransactionRepository) InsertTransaction(ctx context.Context, tx pgx.Tx, t *model.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t.ID = m.nextID
+	m.nextID++
+	t.CreatedAt = time.Now()
+	m.transactions = append(m.transactions, *t)
+	return nil
+}
+
+// Transactions kaydedilen işlemleri döner
+func (m *MockTransactionRepository) Transactions() []model.Transaction {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]model.Transaction(nil), m.transactions...)
+}
+
+// InsertTransfer transfer kaydı ekler
+func (m *MockTransactionRepository) InsertTransfer(ctx context.Context, tx pgx.Tx, t *model.Transfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t.ID = int64(len(m.transfers) + 1)
+	t.CreatedAt = time.Now()
+	m.transfers = append(m.transfers, *t)
+	return nil
+}
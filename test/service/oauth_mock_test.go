@@ -0,0 +1,600 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+const testRedirectURI = "https://partner.example.com/callback"
+
+// testVerifier RFC 7636 örneğindeki code_verifier değeridir
+const testVerifier = "dBjftJeZ4CVP-mJ92K9XzOaLu5X8Ew2sm3dB8zZPMk4"
+
+// pkceChallengeFor verifier'ın S256 challenge değerini döner
+func pkceChallengeFor(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorizationRequestFor verilen scope için PKCE'li yetkilendirme isteği döner
+func authorizationRequestFor(clientID, scope string) service.AuthorizationRequest {
+	return service.AuthorizationRequest{
+		ClientID:            clientID,
+		RedirectURI:         testRedirectURI,
+		Scope:               scope,
+		CodeChallenge:       pkceChallengeFor(testVerifier),
+		CodeChallengeMethod: "S256",
+	}
+}
+
+// postForm form gövdeli istek gönderir; clientID boş değilse HTTP Basic ile istemci doğrulaması ekler
+func (r *testRouter) postForm(path string, form url.Values, clientID, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	if clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+	}
+	rec := httptest.NewRecorder()
+	r.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestOAuthServiceWithMock istemci kaydını, yetkilendirme kodlarını ve PKCE kontrolünü test eder
+func TestOAuthServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*MockOAuthRepository, service.UserService, service.OAuthService, model.OAuthClient, *model.User) {
+		users := NewMockUserRepository()
+		repo := NewMockOAuthRepository(users)
+		userSvc := service.NewUserService(users, testPasswordPolicy)
+		svc := service.NewOAuthService(repo, userSvc)
+		client, _, err := svc.RegisterClient(ctx, "Partner", []string{testRedirectURI}, []string{model.ScopeAccountsRead}, false)
+		require.NoError(t, err)
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		users.AddTestUser(user)
+		return repo, userSvc, svc, client, user
+	}
+
+	t.Run("RegisterClientValidation", func(t *testing.T) {
+		_, _, svc, _, _ := setup(t)
+
+		for _, uri := range []string{"http://partner.example.com/cb", "https://partner.example.com/cb#frag", "/callback", "partner://cb"} {
+			_, _, err := svc.RegisterClient(ctx, "Bad", []string{uri}, []string{model.ScopeAccountsRead}, false)
+			assert.ErrorIs(t, err, service.ErrInvalidRedirectURI, uri)
+		}
+		_, _, err := svc.RegisterClient(ctx, "Bad", []string{testRedirectURI}, []string{"admin"}, false)
+		assert.ErrorIs(t, err, service.ErrInvalidScope)
+
+		// Yerel makinedeki uygulamalar loopback üzerinde http kullanabilir
+		_, secret, err := svc.RegisterClient(ctx, "Desktop", []string{"http://127.0.0.1:8080/cb"}, []string{model.ScopeAccountsRead}, false)
+		require.NoError(t, err)
+		assert.Empty(t, secret)
+	})
+
+	t.Run("ConfidentialClientSecret", func(t *testing.T) {
+		repo, _, svc, _, _ := setup(t)
+
+		client, secret, err := svc.RegisterClient(ctx, "Server App", []string{testRedirectURI}, []string{model.ScopePaymentsWrite}, true)
+		require.NoError(t, err)
+		require.NotEmpty(t, secret)
+
+		// Veritabanında yalnızca özet saklanır
+		stored, err := repo.GetClient(ctx, client.ClientID)
+		require.NoError(t, err)
+		assert.NotEqual(t, secret, stored.SecretHash)
+		assert.True(t, stored.Confidential())
+
+		_, err = svc.AuthenticateClient(ctx, client.ClientID, secret)
+		assert.NoError(t, err)
+		_, err = svc.AuthenticateClient(ctx, client.ClientID, "wrong")
+		assert.ErrorIs(t, err, service.ErrInvalidClient)
+		_, err = svc.AuthenticateClient(ctx, client.ClientID, "")
+		assert.ErrorIs(t, err, service.ErrInvalidClient)
+		_, err = svc.AuthenticateClient(ctx, "unknown", "")
+		assert.ErrorIs(t, err, service.ErrInvalidClient)
+	})
+
+	t.Run("AuthorizationValidation", func(t *testing.T) {
+		_, _, svc, client, _ := setup(t)
+
+		req := authorizationRequestFor(client.ClientID, model.ScopeAccountsRead)
+		grant, err := svc.ValidateAuthorization(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, []string{model.ScopeAccountsRead}, grant.Scopes)
+
+		// Tek kayıtlı adres varsa redirect_uri gönderilmeyebilir
+		noRedirect := req
+		noRedirect.RedirectURI = ""
+		grant, err = svc.ValidateAuthorization(ctx, noRedirect)
+		require.NoError(t, err)
+		assert.Equal(t, testRedirectURI, grant.RedirectURI)
+
+		wrongRedirect := req
+		wrongRedirect.RedirectURI = "https://evil.example.com/callback"
+		_, err = svc.ValidateAuthorization(ctx, wrongRedirect)
+		assert.ErrorIs(t, err, service.ErrInvalidRedirectURI)
+
+		notAllowed := authorizationRequestFor(client.ClientID, model.ScopeAccountsRead+" "+model.ScopePaymentsWrite)
+		_, err = svc.ValidateAuthorization(ctx, notAllowed)
+		assert.ErrorIs(t, err, service.ErrInvalidScope)
+
+		plain := req
+		plain.CodeChallengeMethod = "plain"
+		_, err = svc.ValidateAuthorization(ctx, plain)
+		assert.ErrorIs(t, err, service.ErrPKCERequired)
+
+		_, err = svc.ValidateAuthorization(ctx, authorizationRequestFor("unknown", model.ScopeAccountsRead))
+		assert.ErrorIs(t, err, service.ErrOAuthClientNotFound)
+	})
+
+	t.Run("CodeIsSingleUse", func(t *testing.T) {
+		_, _, svc, client, user := setup(t)
+
+		code, _, err := svc.Approve(ctx, user.ID, authorizationRequestFor(client.ClientID, model.ScopeAccountsRead))
+		require.NoError(t, err)
+
+		rt, err := svc.ExchangeCode(ctx, client, code, testRedirectURI, testVerifier, model.ClientInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, rt.UserID)
+		assert.Equal(t, client.ClientID, rt.ClientID)
+		assert.Equal(t, []string{model.ScopeAccountsRead}, rt.Scopes)
+
+		_, err = svc.ExchangeCode(ctx, client, code, testRedirectURI, testVerifier, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+	})
+
+	t.Run("WrongVerifierBurnsCode", func(t *testing.T) {
+		_, _, svc, client, user := setup(t)
+
+		code, _, err := svc.Approve(ctx, user.ID, authorizationRequestFor(client.ClientID, model.ScopeAccountsRead))
+		require.NoError(t, err)
+
+		_, err = svc.ExchangeCode(ctx, client, code, testRedirectURI, strings.Repeat("a", 43), model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+		_, err = svc.ExchangeCode(ctx, client, code, testRedirectURI, testVerifier, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+	})
+
+	t.Run("RedirectURIMustBeRepeated", func(t *testing.T) {
+		_, _, svc, client, user := setup(t)
+
+		// Yetkilendirme isteğinde gönderilen redirect_uri token isteğinde de gönderilmelidir
+		code, _, err := svc.Approve(ctx, user.ID, authorizationRequestFor(client.ClientID, model.ScopeAccountsRead))
+		require.NoError(t, err)
+		_, err = svc.ExchangeCode(ctx, client, code, "", testVerifier, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+
+		code, _, err = svc.Approve(ctx, user.ID, authorizationRequestFor(client.ClientID, model.ScopeAccountsRead))
+		require.NoError(t, err)
+		_, err = svc.ExchangeCode(ctx, client, code, "https://evil.example.com/callback", testVerifier, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+
+		// Gönderilmediyse token isteğinde de gerekmez
+		noRedirect := authorizationRequestFor(client.ClientID, model.ScopeAccountsRead)
+		noRedirect.RedirectURI = ""
+		code, _, err = svc.Approve(ctx, user.ID, noRedirect)
+		require.NoError(t, err)
+		_, err = svc.ExchangeCode(ctx, client, code, "", testVerifier, model.ClientInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("CodeOfAnotherClient", func(t *testing.T) {
+		_, _, svc, client, user := setup(t)
+		other, _, err := svc.RegisterClient(ctx, "Other", []string{testRedirectURI}, []string{model.ScopeAccountsRead}, false)
+		require.NoError(t, err)
+
+		code, _, err := svc.Approve(ctx, user.ID, authorizationRequestFor(client.ClientID, model.ScopeAccountsRead))
+		require.NoError(t, err)
+		_, err = svc.ExchangeCode(ctx, other, code, testRedirectURI, testVerifier, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+	})
+
+	t.Run("ExpiredCode", func(t *testing.T) {
+		repo, _, svc, client, user := setup(t)
+
+		code, _, err := svc.Approve(ctx, user.ID, authorizationRequestFor(client.ClientID, model.ScopeAccountsRead))
+		require.NoError(t, err)
+		repo.ExpireAuthorizationCodes()
+
+		_, err = svc.ExchangeCode(ctx, client, code, testRedirectURI, testVerifier, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+	})
+
+	t.Run("GrantTokensStayWithTheirClient", func(t *testing.T) {
+		_, userSvc, svc, client, user := setup(t)
+		other, _, err := svc.RegisterClient(ctx, "Other", []string{testRedirectURI}, []string{model.ScopeAccountsRead}, false)
+		require.NoError(t, err)
+
+		code, _, err := svc.Approve(ctx, user.ID, authorizationRequestFor(client.ClientID, model.ScopeAccountsRead))
+		require.NoError(t, err)
+		rt, err := svc.ExchangeCode(ctx, client, code, testRedirectURI, testVerifier, model.ClientInfo{})
+		require.NoError(t, err)
+
+		// Uygulamanın refresh token'ı ne kendi istemcimizde ne başka uygulamada çalışır
+		_, err = userSvc.RotateRefreshToken(ctx, rt.Token, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+		_, err = svc.RefreshGrant(ctx, other, rt.Token, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+
+		// Reddedilen denemeler token'ı tüketmez
+		next, err := svc.RefreshGrant(ctx, client, rt.Token, model.ClientInfo{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{model.ScopeAccountsRead}, next.Scopes)
+
+		// Kendi oturumlarımızın token'ları uygulamaya verilemez
+		own, _, err := userSvc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+		_, err = svc.RefreshGrant(ctx, client, own, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidGrant)
+	})
+}
+
+// oauthFlow uygulama kaydından token alımına kadar yetkilendirme akışını yürütür
+type oauthFlow struct {
+	r      *testRouter
+	admin  string
+	user   dto.AuthResponse
+	client dto.OAuthClientResponse
+}
+
+func newOAuthFlow(t *testing.T, scopes []string, confidential bool) *oauthFlow {
+	r := newTestRouter(t)
+	admin := addAdmin(r)
+	f := &oauthFlow{r: r, admin: tokenFor(t, admin.ID, model.RoleAdmin)}
+	f.user = registerForStepUp(t, r, "customer@example.com")
+
+	body, err := json.Marshal(dto.OAuthClientRequest{
+		Name:         "Budget App",
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       scopes,
+		Confidential: confidential,
+	})
+	require.NoError(t, err)
+	rec := r.do(http.MethodPost, "/api/v1/oauth/clients", f.admin, string(body))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &f.client))
+	return f
+}
+
+// authorizeQuery yetkilendirme isteğinin sorgu parametrelerini döner
+func (f *oauthFlow) authorizeQuery(scope string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {f.client.ClientID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {scope},
+		"state":                 {"xyz"},
+		"code_challenge":        {pkceChallengeFor(testVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+// consent kullanıcının kararını gönderir ve yönlendirilecek adresi döner
+func (f *oauthFlow) consent(t *testing.T, scope string, approve bool) *url.URL {
+	q := f.authorizeQuery(scope)
+	body, err := json.Marshal(map[string]interface{}{
+		"response_type":         "code",
+		"client_id":             f.client.ClientID,
+		"redirect_uri":          testRedirectURI,
+		"scope":                 scope,
+		"state":                 q.Get("state"),
+		"code_challenge":        q.Get("code_challenge"),
+		"code_challenge_method": "S256",
+		"approve":               approve,
+	})
+	require.NoError(t, err)
+	rec := f.r.do(http.MethodPost, "/api/v1/oauth/authorize", f.user.Token, string(body))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var result dto.OAuthConsentResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	u, err := url.Parse(result.RedirectTo)
+	require.NoError(t, err)
+	return u
+}
+
+// token token endpoint'ini çağırır
+func (f *oauthFlow) token(form url.Values) *httptest.ResponseRecorder {
+	if f.client.Confidential {
+		return f.r.postForm("/api/v1/oauth/token", form, f.client.ClientID, f.client.ClientSecret)
+	}
+	form.Set("client_id", f.client.ClientID)
+	return f.r.postForm("/api/v1/oauth/token", form, "", "")
+}
+
+// exchange onaylanan isteğin kodunu token'lara çevirir
+func (f *oauthFlow) exchange(t *testing.T, scope string) dto.OAuthTokenResponse {
+	code := f.consent(t, scope, true).Query().Get("code")
+	require.NotEmpty(t, code)
+	rec := f.token(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {testVerifier},
+	})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var tokens dto.OAuthTokenResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	return tokens
+}
+
+// TestOAuthAuthorizationCodeFlow onay ekranından kapsamlı token kullanımına kadar akışı HTTP üzerinden test eder
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	f := newOAuthFlow(t, []string{model.ScopeAccountsRead, model.ScopePaymentsWrite}, true)
+	r := f.r
+	require.NotEmpty(t, f.client.ClientSecret)
+
+	rec := r.do(http.MethodPost, "/api/v1/accounts", f.user.Token, `{}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var account dto.AccountResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &account))
+
+	t.Run("ClientRegistryIsAdminOnly", func(t *testing.T) {
+		rec := r.do(http.MethodGet, "/api/v1/oauth/clients", f.user.Token, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = r.do(http.MethodGet, "/api/v1/oauth/clients", f.admin, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), f.client.ClientID)
+		assert.NotContains(t, rec.Body.String(), f.client.ClientSecret)
+	})
+
+	t.Run("ConsentScreen", func(t *testing.T) {
+		rec := r.do(http.MethodGet, "/api/v1/oauth/authorize?"+f.authorizeQuery(model.ScopeAccountsRead).Encode(), f.user.Token, "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var consent dto.OAuthConsentResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &consent))
+		assert.Equal(t, "Budget App", consent.ClientName)
+		assert.Equal(t, []string{model.ScopeAccountsRead}, consent.Scopes)
+		assert.Equal(t, "xyz", consent.State)
+
+		// Kayıtlı olmayan adrese yönlendirilmez
+		q := f.authorizeQuery(model.ScopeAccountsRead)
+		q.Set("redirect_uri", "https://evil.example.com/callback")
+		rec = r.do(http.MethodGet, "/api/v1/oauth/authorize?"+q.Encode(), f.user.Token, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "INVALID_REDIRECT_URI")
+
+		q = f.authorizeQuery(model.ScopeAccountsRead)
+		q.Del("code_challenge")
+		rec = r.do(http.MethodGet, "/api/v1/oauth/authorize?"+q.Encode(), f.user.Token, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = r.do(http.MethodGet, "/api/v1/oauth/authorize?"+f.authorizeQuery(model.ScopeAccountsRead).Encode(), "", "")
+		assert.NotEqual(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("DeniedConsent", func(t *testing.T) {
+		u := f.consent(t, model.ScopeAccountsRead, false)
+		assert.Equal(t, testRedirectURI, u.Scheme+"://"+u.Host+u.Path)
+		assert.Equal(t, "access_denied", u.Query().Get("error"))
+		assert.Equal(t, "xyz", u.Query().Get("state"))
+		assert.Empty(t, u.Query().Get("code"))
+	})
+
+	t.Run("ReadOnlyToken", func(t *testing.T) {
+		tokens := f.exchange(t, model.ScopeAccountsRead)
+		assert.Equal(t, "Bearer", tokens.TokenType)
+		assert.Equal(t, model.ScopeAccountsRead, tokens.Scope)
+		assert.Equal(t, int64(testAccessTokens.TTL.Seconds()), tokens.ExpiresIn)
+
+		claims := &controller.AccessClaims{}
+		_, err := jwt.ParseWithClaims(tokens.AccessToken, claims, testJWTKeys.Keyfunc)
+		require.NoError(t, err)
+		assert.Equal(t, []string{model.ScopeAccountsRead}, claims.Scopes)
+		assert.Equal(t, f.client.ClientID, claims.ClientID)
+
+		rec := r.do(http.MethodGet, "/api/v1/accounts", tokens.AccessToken, "")
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		rec = r.do(http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d", account.ID), tokens.AccessToken, "")
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		// Kapsam dışındaki ve uygulamalara açılmamış endpoint'ler reddedilir
+		for _, req := range []struct{ method, path, body string }{
+			{http.MethodPost, "/api/v1/transfers", `{}`},
+			{http.MethodPost, "/api/v1/accounts", `{}`},
+			{http.MethodGet, "/api/v1/sessions", ""},
+			{http.MethodGet, fmt.Sprintf("/api/v1/users/%d", f.user.User.ID), ""},
+			{http.MethodPost, "/api/v1/logout-all", ""},
+			{http.MethodGet, "/api/v1/oauth/authorize?" + f.authorizeQuery(model.ScopeAccountsRead).Encode(), ""},
+		} {
+			rec := r.do(req.method, req.path, tokens.AccessToken, req.body)
+			assert.Equal(t, http.StatusForbidden, rec.Code, req.path)
+			assert.Contains(t, rec.Body.String(), "INSUFFICIENT_SCOPE", req.path)
+			assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "insufficient_scope")
+		}
+	})
+
+	t.Run("PaymentsToken", func(t *testing.T) {
+		tokens := f.exchange(t, model.ScopePaymentsWrite)
+
+		// payments:write hesapları okumaya yetmez
+		rec := r.do(http.MethodGet, "/api/v1/accounts", tokens.AccessToken, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		// Havale endpoint'ine ulaşır; e-posta doğrulaması gibi diğer kurallar geçerli kalır
+		body := fmt.Sprintf(`{"from_account_id":%d,"to_account_number":%q,"amount":"1.00"}`, account.ID, account.AccountNumber)
+		rec = r.do(http.MethodPost, "/api/v1/transfers", tokens.AccessToken, body)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "EMAIL_NOT_VERIFIED")
+	})
+
+	t.Run("GrantShownAsSession", func(t *testing.T) {
+		rec := r.do(http.MethodGet, "/api/v1/sessions", f.user.Token, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var sessions dto.SessionsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sessions))
+		var grants int
+		for _, s := range sessions.Sessions {
+			if s.ClientID == f.client.ClientID {
+				grants++
+				assert.Equal(t, "Budget App", s.DeviceLabel)
+				assert.NotEmpty(t, s.Scopes)
+			}
+		}
+		assert.Equal(t, 2, grants)
+	})
+
+	t.Run("RefreshGrant", func(t *testing.T) {
+		tokens := f.exchange(t, model.ScopeAccountsRead)
+
+		// Birinci taraf refresh endpoint'i uygulama token'ını kabul etmez
+		rec := r.do(http.MethodPost, "/api/v1/refresh", "", `{"refresh_token":"`+tokens.RefreshToken+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = f.token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var next dto.OAuthTokenResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &next))
+		assert.Equal(t, model.ScopeAccountsRead, next.Scope)
+		assert.NotEqual(t, tokens.RefreshToken, next.RefreshToken)
+
+		// Eski token tekrar kullanılırsa grant iptal edilir
+		rec = f.token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"error":"invalid_grant"`)
+		rec = f.token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {next.RefreshToken}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("TokenEndpointErrors", func(t *testing.T) {
+		rec := r.postForm("/api/v1/oauth/token", url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"x"}}, f.client.ClientID, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"error":"invalid_client"`)
+		assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+		rec = f.token(url.Values{"grant_type": {"password"}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"error":"unsupported_grant_type"`)
+
+		rec = f.token(url.Values{"grant_type": {"authorization_code"}, "code": {"bogus"}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"error":"invalid_request"`)
+
+		rec = f.token(url.Values{"grant_type": {"authorization_code"}, "code": {"bogus"}, "code_verifier": {testVerifier}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"error":"invalid_grant"`)
+	})
+
+	t.Run("IntrospectAndRevoke", func(t *testing.T) {
+		tokens := f.exchange(t, model.ScopeAccountsRead)
+		introspect := func(token string) dto.OAuthIntrospectionResponse {
+			rec := r.postForm("/api/v1/oauth/introspect", url.Values{"token": {token}}, f.client.ClientID, f.client.ClientSecret)
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			var resp dto.OAuthIntrospectionResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			return resp
+		}
+
+		info := introspect(tokens.AccessToken)
+		assert.True(t, info.Active)
+		assert.Equal(t, model.ScopeAccountsRead, info.Scope)
+		assert.Equal(t, f.client.ClientID, info.ClientID)
+		assert.Equal(t, fmt.Sprint(f.user.User.ID), info.Subject)
+
+		// Kullanıcının kendi token'ı ve refresh token'lar aktif görünmez
+		assert.False(t, introspect(f.user.Token).Active)
+		assert.False(t, introspect(tokens.RefreshToken).Active)
+		assert.False(t, introspect("garbage").Active)
+
+		rec := r.postForm("/api/v1/oauth/revoke", url.Values{"token": {tokens.AccessToken}}, f.client.ClientID, f.client.ClientSecret)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.False(t, introspect(tokens.AccessToken).Active)
+		rec = r.do(http.MethodGet, "/api/v1/accounts", tokens.AccessToken, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = r.postForm("/api/v1/oauth/revoke", url.Values{"token": {tokens.RefreshToken}}, f.client.ClientID, f.client.ClientSecret)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = f.token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		// Bilinmeyen token'lar da 200 döner
+		rec = r.postForm("/api/v1/oauth/revoke", url.Values{"token": {"unknown"}}, f.client.ClientID, f.client.ClientSecret)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("UserRevokesGrant", func(t *testing.T) {
+		tokens := f.exchange(t, model.ScopeAccountsRead)
+		rec := r.do(http.MethodGet, "/api/v1/sessions", f.user.Token, "")
+		var sessions dto.SessionsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sessions))
+		for _, s := range sessions.Sessions {
+			if s.ClientID == f.client.ClientID {
+				rec = r.do(http.MethodDelete, fmt.Sprintf("/api/v1/sessions/%d", s.ID), f.user.Token, "")
+				require.Equal(t, http.StatusNoContent, rec.Code)
+			}
+		}
+
+		rec = f.token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("DeleteClient", func(t *testing.T) {
+		tokens := f.exchange(t, model.ScopeAccountsRead)
+
+		rec := r.do(http.MethodDelete, "/api/v1/oauth/clients/"+f.client.ClientID, f.admin, "")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		rec = r.do(http.MethodDelete, "/api/v1/oauth/clients/"+f.client.ClientID, f.admin, "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = f.token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+// TestOAuthPublicClient gizli anahtarı olmayan istemcilerin yalnızca PKCE ile çalıştığını test eder
+func TestOAuthPublicClient(t *testing.T) {
+	f := newOAuthFlow(t, []string{model.ScopeAccountsRead}, false)
+	r := f.r
+	assert.Empty(t, f.client.ClientSecret)
+	assert.False(t, f.client.Confidential)
+
+	tokens := f.exchange(t, model.ScopeAccountsRead)
+	rec := r.do(http.MethodGet, "/api/v1/accounts", tokens.AccessToken, "")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	t.Run("IntrospectionNeedsConfidentialClient", func(t *testing.T) {
+		rec := r.postForm("/api/v1/oauth/introspect", url.Values{"token": {tokens.AccessToken}, "client_id": {f.client.ClientID}}, "", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"error":"invalid_client"`)
+	})
+
+	t.Run("RevokeWithClientID", func(t *testing.T) {
+		rec := r.postForm("/api/v1/oauth/revoke", url.Values{"token": {tokens.RefreshToken}, "client_id": {f.client.ClientID}}, "", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = f.token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokens.RefreshToken}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("OtherClientCannotRevoke", func(t *testing.T) {
+		other := f.exchange(t, model.ScopeAccountsRead)
+		body, err := json.Marshal(dto.OAuthClientRequest{Name: "Other", RedirectURIs: []string{testRedirectURI}, Scopes: []string{model.ScopeAccountsRead}})
+		require.NoError(t, err)
+		rec := r.do(http.MethodPost, "/api/v1/oauth/clients", f.admin, string(body))
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var otherClient dto.OAuthClientResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &otherClient))
+
+		rec = r.postForm("/api/v1/oauth/revoke", url.Values{"token": {other.RefreshToken}, "client_id": {otherClient.ClientID}}, "", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = f.token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {other.RefreshToken}})
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	})
+}
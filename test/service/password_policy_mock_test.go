@@ -0,0 +1,117 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/mailer"
+	"github.com/yusufziyrek/bank-app/common/password"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// strictPasswordPolicy canlı ortam varsayılanlarına benzer bir politika kurar; "Summer2024!" sızdırılmış sayılır
+func strictPasswordPolicy(t *testing.T) service.PasswordPolicy {
+	breached, err := password.LoadBreachedList(strings.NewReader("7E8B0A3433F1210A9699D85420E363A1B162ECAC:42\n"))
+	require.NoError(t, err)
+	return service.PasswordPolicy{
+		Rules: password.Policy{
+			MinLength:          10,
+			MaxLength:          72,
+			MinClasses:         3,
+			RejectPersonalInfo: true,
+		},
+		History:  3,
+		Breached: breached,
+	}
+}
+
+// TestPasswordPolicyWithMock şifre politikasının kayıt, değişiklik ve sıfırlamada uygulandığını test eder
+func TestPasswordPolicyWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	newUser := func() *model.User {
+		return &model.User{FullName: "Deniz Kaya", Email: "deniz.kaya@example.com", PasswordHash: "Initial-Pass1"}
+	}
+
+	t.Run("CreateUserWeak", func(t *testing.T) {
+		svc := service.NewUserService(NewMockUserRepository(), strictPasswordPolicy(t))
+
+		u := newUser()
+		u.PasswordHash = "short1A"
+		err := svc.CreateUser(ctx, u)
+		assert.ErrorIs(t, err, service.ErrWeakPassword)
+		assert.Contains(t, err.Error(), "at least 10 characters")
+	})
+
+	t.Run("CreateUserPersonalInfo", func(t *testing.T) {
+		svc := service.NewUserService(NewMockUserRepository(), strictPasswordPolicy(t))
+
+		u := newUser()
+		u.PasswordHash = "Kaya-Family-2024"
+		assert.ErrorIs(t, svc.CreateUser(ctx, u), service.ErrWeakPassword)
+	})
+
+	t.Run("CreateUserBreached", func(t *testing.T) {
+		svc := service.NewUserService(NewMockUserRepository(), strictPasswordPolicy(t))
+
+		u := newUser()
+		u.PasswordHash = "Summer2024!"
+		assert.ErrorIs(t, svc.CreateUser(ctx, u), service.ErrBreachedPassword)
+	})
+
+	t.Run("HistoryRejectsRecentPasswords", func(t *testing.T) {
+		users := NewMockUserRepository()
+		svc := service.NewUserService(users, strictPasswordPolicy(t))
+		u := newUser()
+		require.NoError(t, svc.CreateUser(ctx, u))
+
+		assert.ErrorIs(t, svc.UpdateUserPassword(ctx, u.ID, "Initial-Pass1"), service.ErrPasswordReused)
+		require.NoError(t, svc.UpdateUserPassword(ctx, u.ID, "Second-Pass2"))
+		require.NoError(t, svc.UpdateUserPassword(ctx, u.ID, "Third-Pass3"))
+		assert.ErrorIs(t, svc.UpdateUserPassword(ctx, u.ID, "Initial-Pass1"), service.ErrPasswordReused)
+
+		// Son üç şifre hatırlanır; dördüncü değişiklikten sonra ilk şifre yeniden kullanılabilir
+		require.NoError(t, svc.UpdateUserPassword(ctx, u.ID, "Fourth-Pass4"))
+		assert.NoError(t, svc.UpdateUserPassword(ctx, u.ID, "Initial-Pass1"))
+		assert.Equal(t, 2, users.PasswordHistoryLen(u.ID))
+	})
+
+	t.Run("ResetRejectsWeakWithoutUsingToken", func(t *testing.T) {
+		users := NewMockUserRepository()
+		policy := strictPasswordPolicy(t)
+		require.NoError(t, service.NewUserService(users, policy).CreateUser(ctx, newUser()))
+		var mail bytes.Buffer
+		svc := service.NewPasswordResetService(NewMockPasswordResetRepository(users), users, policy,
+			mailer.NewLogMailer(&mail, "no-reply@example.com"), "https://bank.example.com/reset")
+
+		require.NoError(t, svc.RequestReset(ctx, "deniz.kaya@example.com"))
+		token := lastResetToken(t, &mail)
+
+		assert.ErrorIs(t, svc.ResetPassword(ctx, token, "Summer2024!"), service.ErrBreachedPassword)
+		assert.ErrorIs(t, svc.ResetPassword(ctx, token, "Initial-Pass1"), service.ErrPasswordReused)
+		assert.NoError(t, svc.ResetPassword(ctx, token, "Brand-New-Pass5"))
+	})
+}
+
+// TestPasswordPolicyEndpoints politika hatalarının HTTP yanıtlarını test eder
+func TestPasswordPolicyEndpoints(t *testing.T) {
+	r := newTestRouter(t)
+
+	rec := r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"policy@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	user, err := r.users.GetUserByEmail(context.Background(), "policy@example.com")
+	require.NoError(t, err)
+
+	elevated := stepUpTokenFor(t, user.ID, model.RoleUser, time.Now())
+	rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/password", user.ID), elevated, `{"new_password":"password123"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "PASSWORD_REUSED")
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/mailer"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+var resetTokenPattern = regexp.MustCompile(`reset(?:-password)?\?token=([A-Za-z0-9_-]+)`)
+
+// lastResetToken yazılan son e-postadaki sıfırlama token'ını döner
+func lastResetToken(t *testing.T, mail *bytes.Buffer) string {
+	matches := resetTokenPattern.FindAllStringSubmatch(mail.String(), -1)
+	require.NotEmpty(t, matches, "sıfırlama e-postası bulunamadı")
+	return matches[len(matches)-1][1]
+}
+
+// failingMailer her gönderimde hata döner
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	return errors.New("smtp unavailable")
+}
+
+// TestPasswordResetServiceWithMock sıfırlama token'larının saklanmasını ve tek kullanımlık olmasını test eder
+func TestPasswordResetServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, m mailer.Mailer) (*MockPasswordResetRepository, service.PasswordResetService, *model.User) {
+		users := NewMockUserRepository()
+		repo := NewMockPasswordResetRepository(users)
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		users.AddTestUser(user)
+		return repo, service.NewPasswordResetService(repo, users, testPasswordPolicy, m, "https://bank.example.com/reset"), user
+	}
+
+	t.Run("TokenStoredAsDigest", func(t *testing.T) {
+		var mail bytes.Buffer
+		repo, svc, _ := setup(t, mailer.NewLogMailer(&mail, "no-reply@example.com"))
+
+		require.NoError(t, svc.RequestReset(ctx, "user@example.com"))
+		token := lastResetToken(t, &mail)
+		assert.Contains(t, mail.String(), "https://bank.example.com/reset?token=")
+		assert.Equal(t, []string{service.HashRefreshToken(token)}, repo.StoredTokenHashes())
+	})
+
+	t.Run("UnknownEmail_NoMail", func(t *testing.T) {
+		var mail bytes.Buffer
+		repo, svc, _ := setup(t, mailer.NewLogMailer(&mail, "no-reply@example.com"))
+
+		require.NoError(t, svc.RequestReset(ctx, "nobody@example.com"))
+		assert.Empty(t, mail.String())
+		assert.Empty(t, repo.StoredTokenHashes())
+	})
+
+	t.Run("InactiveUser_NoMail", func(t *testing.T) {
+		var mail bytes.Buffer
+		_, svc, user := setup(t, mailer.NewLogMailer(&mail, "no-reply@example.com"))
+		user.IsActive = false
+
+		require.NoError(t, svc.RequestReset(ctx, "user@example.com"))
+		assert.Empty(t, mail.String())
+	})
+
+	t.Run("MailFailureNotReported", func(t *testing.T) {
+		_, svc, _ := setup(t, failingMailer{})
+
+		// Hata dönülürse adresin kayıtlı olduğu anlaşılır
+		assert.NoError(t, svc.RequestReset(ctx, "user@example.com"))
+	})
+
+	t.Run("NewRequestReplacesOldToken", func(t *testing.T) {
+		var mail bytes.Buffer
+		_, svc, _ := setup(t, mailer.NewLogMailer(&mail, "no-reply@example.com"))
+
+		require.NoError(t, svc.RequestReset(ctx, "user@example.com"))
+		first := lastResetToken(t, &mail)
+		require.NoError(t, svc.RequestReset(ctx, "user@example.com"))
+		second := lastResetToken(t, &mail)
+
+		assert.ErrorIs(t, svc.ResetPassword(ctx, first, "newpassword123"), service.ErrInvalidResetToken)
+		assert.NoError(t, svc.ResetPassword(ctx, second, "newpassword123"))
+	})
+
+	t.Run("ExpiredToken", func(t *testing.T) {
+		var mail bytes.Buffer
+		repo, svc, _ := setup(t, mailer.NewLogMailer(&mail, "no-reply@example.com"))
+
+		require.NoError(t, svc.RequestReset(ctx, "user@example.com"))
+		repo.ExpireResetTokens()
+
+		err := svc.ResetPassword(ctx, lastResetToken(t, &mail), "newpassword123")
+		assert.ErrorIs(t, err, service.ErrInvalidResetToken)
+	})
+}
+
+// TestPasswordResetEndpoints şifre sıfırlama akışını HTTP üzerinden test eder
+func TestPasswordResetEndpoints(t *testing.T) {
+	r := newTestRouter(t)
+	rec := r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"reset@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var auth dto.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+
+	t.Run("UnknownEmailLooksTheSame", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/password/forgot", "", `{"email":"nobody@example.com"}`)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.NotContains(t, r.mail.String(), "Reset your password")
+	})
+
+	t.Run("InvalidToken", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/password/reset", "", `{"token":"bogus","new_password":"newpassword123"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "INVALID_RESET_TOKEN")
+	})
+
+	t.Run("ResetRevokesSessions", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/password/forgot", "", `{"email":"reset@example.com"}`)
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		token := lastResetToken(t, r.mail)
+
+		rec = r.do(http.MethodPost, "/api/v1/password/reset", "", `{"token":"`+token+`","new_password":"short"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = r.do(http.MethodPost, "/api/v1/password/reset", "", `{"token":"`+token+`","new_password":"newpassword123"}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+		// Eski refresh token artık geçersiz
+		rec = r.do(http.MethodPost, "/api/v1/refresh", "", `{"refresh_token":"`+auth.RefreshToken+`"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		// Token ikinci kez kullanılamaz
+		rec = r.do(http.MethodPost, "/api/v1/password/reset", "", `{"token":"`+token+`","new_password":"anotherpassword123"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = r.do(http.MethodPost, "/api/v1/login", "", `{"email":"reset@example.com","password":"password123"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		rec = r.do(http.MethodPost, "/api/v1/login", "", `{"email":"reset@example.com","password":"newpassword123"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
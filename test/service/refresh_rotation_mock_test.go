@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// TestRefreshTokenRotation refresh token rotasyonu ve tekrar kullanım tespitini test eder
+func TestRefreshTokenRotation(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*MockUserRepository, service.UserService, *model.User, string) {
+		repo := NewMockUserRepository()
+		svc := service.NewUserService(repo, testPasswordPolicy)
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		repo.AddTestUser(user)
+		token, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+		return repo, svc, user, token
+	}
+
+	t.Run("Rotate_IssuesSuccessorInSameFamily", func(t *testing.T) {
+		repo, svc, user, token := setup(t)
+		first, err := repo.GetRefreshToken(ctx, service.HashRefreshToken(token))
+		require.NoError(t, err)
+
+		next, err := svc.RotateRefreshToken(ctx, token, model.ClientInfo{})
+		require.NoError(t, err)
+		assert.NotEqual(t, token, next.Token)
+		assert.Equal(t, user.ID, next.UserID)
+		assert.Equal(t, first.FamilyID, next.FamilyID)
+		assert.Equal(t, 1, repo.RefreshTokenCount(user.ID))
+
+		// Eski token kullanıldı olarak işaretlenmeli
+		old, err := repo.GetRefreshToken(ctx, service.HashRefreshToken(token))
+		require.NoError(t, err)
+		assert.True(t, old.Used())
+	})
+
+	t.Run("Reuse_RevokesWholeFamily", func(t *testing.T) {
+		_, svc, user, token := setup(t)
+		other, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+
+		next, err := svc.RotateRefreshToken(ctx, token, model.ClientInfo{})
+		require.NoError(t, err)
+
+		_, err = svc.RotateRefreshToken(ctx, token, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrRefreshTokenReused)
+
+		// Ailedeki en güncel token da artık geçersiz olmalı
+		_, err = svc.RotateRefreshToken(ctx, next.Token, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+		// Başka bir oturumun ailesi etkilenmemeli
+		_, err = svc.RotateRefreshToken(ctx, other, model.ClientInfo{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("UnknownToken", func(t *testing.T) {
+		_, svc, _, _ := setup(t)
+
+		_, err := svc.RotateRefreshToken(ctx, "does-not-exist", model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	})
+
+	t.Run("ExpiredToken", func(t *testing.T) {
+		repo, svc, user, token := setup(t)
+		repo.ExpireRefreshToken(service.HashRefreshToken(token))
+
+		_, err := svc.RotateRefreshToken(ctx, token, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+		assert.Equal(t, 0, repo.RefreshTokenCount(user.ID))
+	})
+
+	t.Run("ConcurrentRotation_SingleWinner", func(t *testing.T) {
+		_, svc, _, token := setup(t)
+
+		const workers = 8
+		var wg sync.WaitGroup
+		results := make(chan error, workers)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := svc.RotateRefreshToken(ctx, token, model.ClientInfo{})
+				results <- err
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		succeeded := 0
+		for err := range results {
+			if err == nil {
+				succeeded++
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+
+	t.Run("Revoke_RemovesEarlierTokensOfFamily", func(t *testing.T) {
+		repo, svc, user, token := setup(t)
+		next, err := svc.RotateRefreshToken(ctx, token, model.ClientInfo{})
+		require.NoError(t, err)
+
+		require.NoError(t, svc.RevokeRefreshToken(ctx, next.Token))
+		_, err = repo.GetRefreshToken(ctx, service.HashRefreshToken(token))
+		assert.Error(t, err)
+		assert.Equal(t, 0, repo.RefreshTokenCount(user.ID))
+	})
+}
+
+// TestRefreshEndpointRotation refresh endpoint'inin yeni token döndürmesini test eder
+func TestRefreshEndpointRotation(t *testing.T) {
+	r := newTestRouter(t)
+	rec := r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"rotate@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var auth dto.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+
+	refresh := func(token string) *http.Response {
+		return r.do(http.MethodPost, "/api/v1/refresh", "", fmt.Sprintf(`{"refresh_token":%q}`, token)).Result()
+	}
+
+	resp := refresh(auth.RefreshToken)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var rotated dto.RefreshResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rotated))
+	assert.NotEmpty(t, rotated.Token)
+	assert.NotEmpty(t, rotated.RefreshToken)
+	assert.NotEqual(t, auth.RefreshToken, rotated.RefreshToken)
+
+	// Eski token tekrar kullanılırsa oturum kapatılmalı
+	resp = refresh(auth.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	var errResp map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Contains(t, fmt.Sprint(errResp), "REFRESH_TOKEN_REUSED")
+
+	resp = refresh(rotated.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
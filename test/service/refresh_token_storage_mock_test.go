@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// TestRefreshTokenStorage refresh token'ların yalnızca özet olarak saklanmasını test eder
+func TestRefreshTokenStorage(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*MockUserRepository, service.UserService, *model.User) {
+		repo := NewMockUserRepository()
+		svc := service.NewUserService(repo, testPasswordPolicy)
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		repo.AddTestUser(user)
+		return repo, svc, user
+	}
+
+	t.Run("OnlyDigestPersisted", func(t *testing.T) {
+		repo, svc, user := setup(t)
+		token, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+
+		stored := repo.StoredRefreshTokens()
+		require.Len(t, stored, 1)
+		assert.Empty(t, stored[0].Token)
+		assert.Equal(t, service.HashRefreshToken(token), stored[0].TokenHash)
+		assert.NotEqual(t, token, stored[0].TokenHash)
+		assert.Len(t, stored[0].TokenHash, 64)
+
+		// Düz metin ile arama sonuç vermemeli
+		_, err = repo.GetRefreshToken(ctx, token)
+		assert.Error(t, err)
+	})
+
+	t.Run("HashRefreshToken_Deterministic", func(t *testing.T) {
+		assert.Equal(t, service.HashRefreshToken("abc"), service.HashRefreshToken("abc"))
+		assert.NotEqual(t, service.HashRefreshToken("abc"), service.HashRefreshToken("abd"))
+		// sha256("abc") bilinen değeri; SQL migrasyonu ile aynı özet üretilmeli
+		assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", service.HashRefreshToken("abc"))
+	})
+
+	t.Run("PurgeExpired_RemovesOnlyExpired", func(t *testing.T) {
+		repo, svc, user := setup(t)
+		expired, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+		live, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+		repo.ExpireRefreshToken(service.HashRefreshToken(expired))
+
+		n, err := svc.PurgeExpiredRefreshTokens(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		_, err = repo.GetRefreshToken(ctx, service.HashRefreshToken(expired))
+		assert.Error(t, err)
+		_, err = repo.GetRefreshToken(ctx, service.HashRefreshToken(live))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Sweeper_PurgesUntilCancelled", func(t *testing.T) {
+		repo, svc, user := setup(t)
+		token, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+		repo.ExpireRefreshToken(service.HashRefreshToken(token))
+
+		sweepCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			service.RunRefreshTokenSweeper(sweepCtx, svc, 10*time.Millisecond)
+			close(done)
+		}()
+
+		assert.Eventually(t, func() bool {
+			return len(repo.StoredRefreshTokens()) == 0
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("sweeper iptal edildikten sonra durmadı")
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/jwtkeys"
+	"github.com/yusufziyrek/bank-app/common/mailer"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/common/password"
+	"github.com/yusufziyrek/bank-app/internal/controller"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/routes"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// testJWTKeys sabit bir Ed25519 anahtarıyla imzalar; router ve tokenFor aynı anahtar setini kullanır
+var testJWTKeys = func() *jwtkeys.KeySet {
+	key, err := jwtkeys.NewKey("test", ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)), time.Time{})
+	if err != nil {
+		panic(err)
+	}
+	keys, err := jwtkeys.New(key)
+	if err != nil {
+		panic(err)
+	}
+	return keys
+}()
+
+// testAccessTokens router'ın token imzalama ve doğrulama ayarlarıdır
+var testAccessTokens = controller.AccessTokenConfig{
+	Keys:     testJWTKeys,
+	Issuer:   "bank-app-test",
+	Audience: "bank-app-test-api",
+	TTL:      time.Hour,
+	Leeway:   5 * time.Second,
+}
+
+// testPasswordPolicy eski testlerdeki basit şifreleri kabul eder; yalnızca uzunluk ve son üç şifre kontrol edilir
+var testPasswordPolicy = service.PasswordPolicy{
+	Rules:   password.Policy{MinLength: 8},
+	History: 3,
+}
+
+// testTrustedProxies yalnızca yerel proxy'nin X-Forwarded-For başlığına güvenir
+var testTrustedProxies = []*net.IPNet{{IP: net.IPv4(127, 0, 0, 1).To4(), Mask: net.CIDRMask(32, 32)}}
+
+// testLoginPolicy hesabı 5, IP'yi 20 hatalı girişte 15 dakika kilitler
+var testLoginPolicy = service.LoginThrottlePolicy{
+	MaxAccountFailures: 5,
+	MaxIPFailures:      20,
+	Window:             15 * time.Minute,
+	LockoutDuration:    15 * time.Minute,
+}
+
+// testStepUpPolicy 1000.00 ve üzeri havaleler için yeniden doğrulama ister
+var testStepUpPolicy = controller.StepUpPolicy{
+	TokenTTL:               10 * time.Minute,
+	EmailChange:            5 * time.Minute,
+	PasswordChange:         5 * time.Minute,
+	LargeTransfer:          5 * time.Minute,
+	APIKeyCreation:         5 * time.Minute,
+	MFADisable:             5 * time.Minute,
+	LargeTransferThreshold: money.New(100000, "TRY"),
+}
+
+type testValidator struct {
+	v *validator.Validate
+}
+
+func (tv *testValidator) Validate(i interface{}) error {
+	return tv.v.Struct(i)
+}
+
+// testRouter tüm route'ları mock repository'ler ile kurar
+type testRouter struct {
+	e     *echo.Echo
+	users *MockUserRepository
+	mfa   *MockMFARepository
+	// logins hatalı giriş sayaçlarını tutar
+	logins *MockLoginAttemptRepository
+	oauth  *MockOAuthRepository
+	keys   *MockAPIKeyRepository
+	// mail LogMailer'ın yazdığı e-postaları tutar
+	mail *bytes.Buffer
+}
+
+func newTestRouter(t *testing.T) *testRouter {
+	v := validator.New()
+	require.NoError(t, dto.RegisterValidations(v))
+
+	e := echo.New()
+	e.Validator = &testValidator{v: v}
+	e.IPExtractor = controller.ClientIPExtractor(testTrustedProxies)
+
+	users := NewMockUserRepository()
+	accounts := NewMockAccountRepository()
+	ledger := NewLinkedMockLedgerRepository(accounts)
+	mfa := NewMockMFARepository()
+	logins := NewMockLoginAttemptRepository()
+	oauth := NewMockOAuthRepository(users)
+	keys := NewMockAPIKeyRepository(users)
+	mail := &bytes.Buffer{}
+	logMailer := mailer.NewLogMailer(mail, "Bank App <no-reply@example.com>")
+	userService := service.NewUserService(users, testPasswordPolicy)
+	routes.SetupRoutes(e,
+		userService,
+		service.NewAccountService(accounts, "TR", "TRY"),
+		service.NewLedgerService(ledger),
+		service.NewTransactionService(accounts, ledger, NewMockTransactionRepository()),
+		service.NewIdempotencyService(NewMockIdempotencyRepository()),
+		service.NewCardService(NewMockCardRepository(), accounts, "979200"),
+		service.NewMFAService(mfa, users, "Bank App"),
+		service.NewPasswordResetService(NewMockPasswordResetRepository(users), users, testPasswordPolicy, logMailer, "https://bank.example.com/reset-password"),
+		service.NewEmailVerificationService(NewMockEmailVerificationRepository(users), users, logMailer, "https://bank.example.com/verify-email"),
+		service.NewLoginAttemptService(logins, users, testLoginPolicy),
+		service.NewOAuthService(oauth, userService),
+		service.NewAPIKeyService(keys, userService),
+		testAccessTokens, testStepUpPolicy)
+	return &testRouter{e: e, users: users, mfa: mfa, logins: logins, oauth: oauth, keys: keys, mail: mail}
+}
+
+// testClaims testAccessTokens'ın kabul ettiği, jti ve sürüm taşımayan claim'leri döner
+func testClaims(userID int64, role string) controller.AccessClaims {
+	now := time.Now()
+	return controller.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testAccessTokens.Issuer,
+			Subject:   strconv.FormatInt(userID, 10),
+			Audience:  jwt.ClaimStrings{testAccessTokens.Audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Role: role,
+	}
+}
+
+// signClaims claim'leri test anahtarıyla imzalar
+func signClaims(t *testing.T, claims jwt.Claims) string {
+	s, err := testJWTKeys.Sign(claims)
+	require.NoError(t, err)
+	return s
+}
+
+// tokenFor verilen kullanıcı ve rol için imzalı access token üretir
+func tokenFor(t *testing.T, userID int64, role string) string {
+	return signClaims(t, testClaims(userID, role))
+}
+
+// stepUpTokenFor authTime anında yeniden doğrulama yapılmış gibi auth_time claim'i taşıyan token üretir
+func stepUpTokenFor(t *testing.T, userID int64, role string, authTime time.Time) string {
+	claims := testClaims(userID, role)
+	claims.AuthTime = jwt.NewNumericDate(authTime)
+	claims.AMR = []string{"pwd"}
+	return signClaims(t, claims)
+}
+
+// do isteği gönderir; token boşsa Authorization başlığı eklenmez
+func (r *testRouter) do(method, path, token, body string) *httptest.ResponseRecorder {
+	return r.doWithHeaders(method, path, token, body, nil)
+}
+
+// doWithHeaders do ile aynıdır, ek başlıkları da isteğe ekler
+func (r *testRouter) doWithHeaders(method, path, token, body string, headers map[string]string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	r.e.ServeHTTP(rec, req)
+	return rec
+}
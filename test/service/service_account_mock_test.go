@@ -0,0 +1,147 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// TestServiceAccounts servis hesaplarının kişilerin kimlik bilgilerinden bağımsız anahtar sahibi olduğunu test eder
+func TestServiceAccounts(t *testing.T) {
+	r := newTestRouter(t)
+	admin := addAdmin(r)
+	adminToken := tokenFor(t, admin.ID, model.RoleAdmin)
+	elevated := stepUpTokenFor(t, admin.ID, model.RoleAdmin, time.Now())
+	person := registerForStepUp(t, r, "ops@example.com")
+
+	keyBody := `{"name":"nightly export","scopes":["accounts:read"],"expires_in_days":30}`
+
+	t.Run("CreateRequiresAdmin", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/service-accounts", person.Token, `{"name":"Export job"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	rec := r.do(http.MethodPost, "/api/v1/service-accounts", adminToken, `{"name":"Export job"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var account dto.UserResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &account))
+	assert.True(t, account.ServiceAccount)
+	assert.Equal(t, model.RoleUser, account.Role)
+	assert.True(t, strings.HasSuffix(account.Email, ".invalid"))
+	keysPath := fmt.Sprintf("/api/v1/service-accounts/%d/api-keys", account.ID)
+
+	t.Run("List", func(t *testing.T) {
+		rec := r.do(http.MethodGet, "/api/v1/service-accounts", adminToken, "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var accounts dto.UsersResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &accounts))
+		require.Equal(t, 1, accounts.Count)
+		assert.Equal(t, account.ID, accounts.Users[0].ID)
+	})
+
+	t.Run("HasNoPassword", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/login", "", fmt.Sprintf(`{"email":%q,"password":"!"}`, account.Email))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/password", account.ID), elevated, `{"new_password":"Some-Password1"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "SERVICE_ACCOUNT_PASSWORD")
+
+		// Geçersiz adrese sıfırlama maili gönderilmez
+		rec = r.do(http.MethodPost, "/api/v1/password/forgot", "", fmt.Sprintf(`{"email":%q}`, account.Email))
+		assert.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		assert.NotContains(t, r.mail.String(), account.Email)
+	})
+
+	t.Run("KeyCreationNeedsStepUp", func(t *testing.T) {
+		rec := r.do(http.MethodPost, keysPath, adminToken, keyBody)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "STEP_UP_REQUIRED")
+	})
+
+	t.Run("OnlyForServiceAccounts", func(t *testing.T) {
+		// Adminler kişiler adına anahtar oluşturamaz
+		rec := r.do(http.MethodPost, fmt.Sprintf("/api/v1/service-accounts/%d/api-keys", person.User.ID), elevated, keyBody)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "SERVICE_ACCOUNT_NOT_FOUND")
+	})
+
+	createKey := func(t *testing.T) dto.APIKeyResponse {
+		rec := r.do(http.MethodPost, keysPath, elevated, keyBody)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+		var key dto.APIKeyResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &key))
+		return key
+	}
+
+	t.Run("KeyActsAsServiceAccount", func(t *testing.T) {
+		key := createKey(t)
+		assert.True(t, strings.HasPrefix(key.Key, model.APIKeyPrefix))
+
+		rec := r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rec = r.do(http.MethodGet, keysPath, adminToken, "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.NotContains(t, rec.Body.String(), key.Key)
+		var keys dto.APIKeysResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &keys))
+		assert.Equal(t, 1, keys.Count)
+
+		// Anahtar servis hesaplarını yönetemez
+		rec = r.do(http.MethodGet, "/api/v1/service-accounts", key.Key, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		key := createKey(t)
+
+		rec := r.do(http.MethodDelete, fmt.Sprintf("/api/v1/service-accounts/%d/api-keys/%d", person.User.ID, key.ID), adminToken, "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = r.do(http.MethodDelete, fmt.Sprintf("%s/%d", keysPath, key.ID), adminToken, "")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		rec = r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("RevokedByDeactivation", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/service-accounts", adminToken, `{"name":"Retired job","role":"admin"}`)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var retired dto.UserResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &retired))
+		assert.Equal(t, model.RoleAdmin, retired.Role)
+
+		rec = r.do(http.MethodPost, fmt.Sprintf("/api/v1/service-accounts/%d/api-keys", retired.ID), elevated, keyBody)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var key dto.APIKeyResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &key))
+
+		rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/status", retired.ID), adminToken, `{"is_active":false}`)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		rec = r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("SurvivesPeoplesCredentialChanges", func(t *testing.T) {
+		key := createKey(t)
+
+		// Anahtarı oluşturan admin ve diğer kullanıcılar tüm oturumlarını kapatsa da anahtar çalışır
+		rec := r.do(http.MethodPost, "/api/v1/logout-all", person.Token, "")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		rec = r.do(http.MethodPost, "/api/v1/logout-all", adminToken, "")
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+		rec = r.do(http.MethodGet, "/api/v1/accounts", key.Key, "")
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	})
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+const testChromeUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+
+// TestSessionService oturum kaydı, listeleme ve sonlandırmayı test eder
+func TestSessionService(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*MockUserRepository, service.UserService, *model.User) {
+		repo := NewMockUserRepository()
+		svc := service.NewUserService(repo, testPasswordPolicy)
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		repo.AddTestUser(user)
+		return repo, svc, user
+	}
+
+	t.Run("Login_RecordsClient", func(t *testing.T) {
+		_, svc, user := setup(t)
+		_, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{UserAgent: testChromeUA, IPAddress: "10.0.0.1"})
+		require.NoError(t, err)
+
+		sessions, err := svc.ListSessions(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "Chrome on Windows", sessions[0].DeviceLabel)
+		assert.Equal(t, testChromeUA, sessions[0].UserAgent)
+		assert.Equal(t, "10.0.0.1", sessions[0].IPAddress)
+		assert.False(t, sessions[0].CreatedAt.IsZero())
+		assert.Equal(t, sessions[0].CreatedAt, sessions[0].LastUsedAt)
+	})
+
+	t.Run("Login_ExplicitLabelWins", func(t *testing.T) {
+		_, svc, user := setup(t)
+		_, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{DeviceLabel: "  Work laptop ", UserAgent: testChromeUA})
+		require.NoError(t, err)
+
+		sessions, err := svc.ListSessions(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "Work laptop", sessions[0].DeviceLabel)
+	})
+
+	t.Run("Rotate_UpdatesLastUsedAndIP", func(t *testing.T) {
+		_, svc, user := setup(t)
+		token, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{UserAgent: testChromeUA, IPAddress: "10.0.0.1"})
+		require.NoError(t, err)
+		before, err := svc.ListSessions(ctx, user.ID)
+		require.NoError(t, err)
+
+		_, err = svc.RotateRefreshToken(ctx, token, model.ClientInfo{IPAddress: "10.0.0.2"})
+		require.NoError(t, err)
+
+		after, err := svc.ListSessions(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, after, 1)
+		assert.Equal(t, before[0].ID, after[0].ID)
+		assert.Equal(t, "10.0.0.2", after[0].IPAddress)
+		assert.True(t, after[0].LastUsedAt.After(before[0].LastUsedAt))
+		// Cihaz bilgisi giriş anındaki gibi kalmalı
+		assert.Equal(t, testChromeUA, after[0].UserAgent)
+	})
+
+	t.Run("RevokeSession_KillsItsTokens", func(t *testing.T) {
+		_, svc, user := setup(t)
+		token, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+		other, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+		sessions, err := svc.ListSessions(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+
+		// Listeler en son kullanılan önce gelir; ilk oturum sonda olmalı
+		target := sessions[len(sessions)-1]
+		require.NoError(t, svc.RevokeSession(ctx, user.ID, target.ID))
+
+		_, err = svc.RotateRefreshToken(ctx, token, model.ClientInfo{})
+		assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+		_, err = svc.RotateRefreshToken(ctx, other, model.ClientInfo{})
+		assert.NoError(t, err)
+
+		sessions, err = svc.ListSessions(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+
+	t.Run("RevokeSession_OtherUser", func(t *testing.T) {
+		repo, svc, user := setup(t)
+		_, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+		sessions, err := svc.ListSessions(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+
+		intruder := &model.User{FullName: "Other", Email: "other@example.com", Role: model.RoleUser, IsActive: true}
+		repo.AddTestUser(intruder)
+
+		err = svc.RevokeSession(ctx, intruder.ID, sessions[0].ID)
+		assert.ErrorIs(t, err, service.ErrSessionNotFound)
+		assert.Equal(t, 1, repo.RefreshTokenCount(user.ID))
+	})
+
+	t.Run("LogoutAndReuse_RemoveSession", func(t *testing.T) {
+		_, svc, user := setup(t)
+		loggedOut, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+		stolen, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+		require.NoError(t, err)
+
+		require.NoError(t, svc.RevokeRefreshToken(ctx, loggedOut))
+		_, err = svc.RotateRefreshToken(ctx, stolen, model.ClientInfo{})
+		require.NoError(t, err)
+		_, err = svc.RotateRefreshToken(ctx, stolen, model.ClientInfo{})
+		require.ErrorIs(t, err, service.ErrRefreshTokenReused)
+
+		sessions, err := svc.ListSessions(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+}
+
+// TestSessionEndpoints oturum listeleme ve silme endpoint'lerini test eder
+func TestSessionEndpoints(t *testing.T) {
+	r := newTestRouter(t)
+	rec := r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"sessions@example.com","password":"password123"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = r.doWithHeaders(http.MethodPost, "/api/v1/login", "",
+		`{"email":"sessions@example.com","password":"password123","device_label":"Phone"}`,
+		map[string]string{"User-Agent": testChromeUA, "X-Real-IP": "192.0.2.10"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var auth dto.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+
+	list := func(token string) dto.SessionsResponse {
+		rec := r.do(http.MethodGet, "/api/v1/sessions", token, "")
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.SessionsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	sessions := list(auth.Token)
+	require.Equal(t, 2, sessions.Count)
+	var phone dto.SessionResponse
+	for _, s := range sessions.Sessions {
+		if s.DeviceLabel == "Phone" {
+			phone = s
+		}
+	}
+	require.NotZero(t, phone.ID)
+	// Güvenilmeyen istemcinin X-Real-IP başlığı yok sayılır, bağlantı adresi kaydedilir
+	assert.Equal(t, "192.0.2.1", phone.IPAddress)
+	assert.Equal(t, testChromeUA, phone.UserAgent)
+
+	t.Run("OtherUserCannotRevoke", func(t *testing.T) {
+		other := &model.User{FullName: "Other", Email: "other-sessions@example.com", Role: model.RoleUser, IsActive: true}
+		r.users.AddTestUser(other)
+		rec := r.do(http.MethodDelete, fmt.Sprintf("/api/v1/sessions/%d", phone.ID), tokenFor(t, other.ID, model.RoleUser), "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "SESSION_NOT_FOUND")
+	})
+
+	t.Run("InvalidID", func(t *testing.T) {
+		rec := r.do(http.MethodDelete, "/api/v1/sessions/abc", auth.Token, "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("RevokeOwnSession", func(t *testing.T) {
+		rec := r.do(http.MethodDelete, fmt.Sprintf("/api/v1/sessions/%d", phone.ID), auth.Token, "")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = r.do(http.MethodPost, "/api/v1/refresh", "", fmt.Sprintf(`{"refresh_token":%q}`, auth.RefreshToken))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, 1, list(auth.Token).Count)
+
+		rec = r.do(http.MethodDelete, fmt.Sprintf("/api/v1/sessions/%d", phone.ID), auth.Token, "")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("RequiresAuth", func(t *testing.T) {
+		rec := r.do(http.MethodGet, "/api/v1/sessions", "", "")
+		assert.NotEqual(t, http.StatusOK, rec.Code)
+	})
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+// TestRefreshTokenRevocation şifre değişikliği ve pasifleştirmede oturumların kapatılmasını test eder
+func TestRefreshTokenRevocation(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*MockUserRepository, service.UserService, *model.User) {
+		repo := NewMockUserRepository()
+		svc := service.NewUserService(repo, testPasswordPolicy)
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		repo.AddTestUser(user)
+		for i := 0; i < 2; i++ {
+			_, _, err := svc.GenerateRefreshToken(ctx, user.ID, model.ClientInfo{})
+			require.NoError(t, err)
+		}
+		require.Equal(t, 2, repo.RefreshTokenCount(user.ID))
+		return repo, svc, user
+	}
+
+	t.Run("UpdateUserPassword_RevokesAllTokens", func(t *testing.T) {
+		repo, svc, user := setup(t)
+
+		require.NoError(t, svc.UpdateUserPassword(ctx, user.ID, "newpassword123"))
+		assert.Equal(t, 0, repo.RefreshTokenCount(user.ID))
+	})
+
+	t.Run("Deactivate_RevokesAllTokens", func(t *testing.T) {
+		repo, svc, user := setup(t)
+
+		require.NoError(t, svc.UpdateUserActiveStatus(ctx, user.ID, false))
+		assert.Equal(t, 0, repo.RefreshTokenCount(user.ID))
+	})
+
+	t.Run("Activate_KeepsTokens", func(t *testing.T) {
+		repo, svc, user := setup(t)
+
+		require.NoError(t, svc.UpdateUserActiveStatus(ctx, user.ID, true))
+		assert.Equal(t, 2, repo.RefreshTokenCount(user.ID))
+	})
+
+	t.Run("OtherUsersTokensUntouched", func(t *testing.T) {
+		repo, svc, user := setup(t)
+		other := &model.User{FullName: "Other", Email: "other@example.com", Role: model.RoleUser, IsActive: true}
+		repo.AddTestUser(other)
+		_, _, err := svc.GenerateRefreshToken(ctx, other.ID, model.ClientInfo{})
+		require.NoError(t, err)
+
+		require.NoError(t, svc.UpdateUserPassword(ctx, user.ID, "newpassword123"))
+		assert.Equal(t, 1, repo.RefreshTokenCount(other.ID))
+	})
+}
+
+// TestLogoutEndpoints logout ve logout-all endpoint'lerini test eder
+func TestLogoutEndpoints(t *testing.T) {
+	login := func(t *testing.T, r *testRouter) dto.AuthResponse {
+		rec := r.do(http.MethodPost, "/api/v1/register", "", `{"full_name":"Test User","email":"logout@example.com","password":"password123"}`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		rec = r.do(http.MethodPost, "/api/v1/login", "", `{"email":"logout@example.com","password":"password123"}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var resp dto.AuthResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+	refresh := func(r *testRouter, token string) int {
+		return r.do(http.MethodPost, "/api/v1/refresh", "", fmt.Sprintf(`{"refresh_token":%q}`, token)).Code
+	}
+
+	t.Run("Logout_RevokesPresentedToken", func(t *testing.T) {
+		r := newTestRouter(t)
+		auth := login(t, r)
+		require.Equal(t, 2, r.users.RefreshTokenCount(auth.User.ID))
+
+		rec := r.do(http.MethodPost, "/api/v1/logout", "", fmt.Sprintf(`{"refresh_token":%q}`, auth.RefreshToken))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, http.StatusUnauthorized, refresh(r, auth.RefreshToken))
+
+		// Kayıt sırasında açılan diğer oturum etkilenmemeli
+		assert.Equal(t, 1, r.users.RefreshTokenCount(auth.User.ID))
+
+		// Aynı token ile tekrar çıkış hata vermemeli
+		rec = r.do(http.MethodPost, "/api/v1/logout", "", fmt.Sprintf(`{"refresh_token":%q}`, auth.RefreshToken))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("Logout_MissingToken", func(t *testing.T) {
+		r := newTestRouter(t)
+
+		rec := r.do(http.MethodPost, "/api/v1/logout", "", `{}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("LogoutAll_RevokesEverySession", func(t *testing.T) {
+		r := newTestRouter(t)
+		auth := login(t, r)
+
+		rec := r.do(http.MethodPost, "/api/v1/logout-all", auth.Token, "")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, 0, r.users.RefreshTokenCount(auth.User.ID))
+		assert.Equal(t, http.StatusUnauthorized, refresh(r, auth.RefreshToken))
+	})
+
+	t.Run("LogoutAll_RequiresAccessToken", func(t *testing.T) {
+		r := newTestRouter(t)
+		auth := login(t, r)
+
+		rec := r.do(http.MethodPost, "/api/v1/logout-all", "", "")
+		assert.NotEqual(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, 2, r.users.RefreshTokenCount(auth.User.ID))
+	})
+}
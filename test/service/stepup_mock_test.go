@@ -0,0 +1,179 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+)
+
+// registerForStepUp kullanıcı kaydeder ve access token ile kullanıcıyı döner
+func registerForStepUp(t *testing.T, r *testRouter, email string) dto.AuthResponse {
+	rec := r.do(http.MethodPost, "/api/v1/register", "",
+		fmt.Sprintf(`{"full_name":"Test User","email":%q,"password":"password123"}`, email))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var auth dto.AuthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &auth))
+	return auth
+}
+
+// reauth POST /reauth çağırır ve yükseltilmiş token'ı döner
+func reauth(t *testing.T, r *testRouter, token, body string) dto.ReauthResponse {
+	rec := r.do(http.MethodPost, "/api/v1/reauth", token, body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var resp dto.ReauthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+// TestStepUpCredentialChanges email ve şifre değişikliklerinin yakın zamanda yeniden doğrulama istediğini test eder
+func TestStepUpCredentialChanges(t *testing.T) {
+	r := newTestRouter(t)
+	auth := registerForStepUp(t, r, "stepup@example.com")
+	emailPath := fmt.Sprintf("/api/v1/users/%d/email", auth.User.ID)
+	passwordPath := fmt.Sprintf("/api/v1/users/%d/password", auth.User.ID)
+
+	t.Run("LoginTokenRejected", func(t *testing.T) {
+		rec := r.do(http.MethodPut, emailPath, auth.Token, `{"new_email":"new@example.com"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "STEP_UP_REQUIRED")
+
+		rec = r.do(http.MethodPut, passwordPath, auth.Token, `{"new_password":"newpassword123"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "STEP_UP_REQUIRED")
+	})
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/reauth", auth.Token, `{"password":"wrongpassword"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("MissingCredentials", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/reauth", auth.Token, `{}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("ElevatedTokenAccepted", func(t *testing.T) {
+		elevated := reauth(t, r, auth.Token, `{"password":"password123"}`)
+		assert.True(t, elevated.ExpiresAt.Before(time.Now().Add(testStepUpPolicy.TokenTTL+time.Minute)))
+
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(elevated.Token, claims, testJWTKeys.Keyfunc)
+		require.NoError(t, err)
+		assert.Contains(t, claims, "auth_time")
+		assert.Equal(t, []interface{}{"pwd"}, claims["amr"])
+
+		rec := r.do(http.MethodPut, emailPath, elevated.Token, `{"new_email":"new@example.com"}`)
+		assert.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	})
+
+	t.Run("StaleAuthTimeRejected", func(t *testing.T) {
+		stale := stepUpTokenFor(t, auth.User.ID, auth.User.Role, time.Now().Add(-testStepUpPolicy.PasswordChange-time.Minute))
+		rec := r.do(http.MethodPut, passwordPath, stale, `{"new_password":"newpassword123"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "STEP_UP_REQUIRED")
+	})
+}
+
+// TestStepUpWithMFA iki adımlı doğrulaması açık kullanıcının kod ile yeniden doğrulanmasını test eder
+func TestStepUpWithMFA(t *testing.T) {
+	r := newTestRouter(t)
+	auth := registerForStepUp(t, r, "stepup-mfa@example.com")
+
+	rec := r.do(http.MethodPost, "/api/v1/mfa/totp/enroll", auth.Token, "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var enrollment dto.TOTPEnrollmentResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &enrollment))
+	rec = r.do(http.MethodPost, "/api/v1/mfa/totp/confirm", auth.Token, fmt.Sprintf(`{"code":%q}`, nextTOTPCode(t, enrollment.Secret, 0)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	t.Run("PasswordNotEnough", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/reauth", auth.Token, `{"password":"password123"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "MFA_CODE_REQUIRED")
+	})
+
+	t.Run("WrongCode", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/reauth", auth.Token, `{"code":"000000"}`)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "INVALID_MFA_CODE")
+	})
+
+	t.Run("DisableNeedsStepUp", func(t *testing.T) {
+		rec := r.do(http.MethodPost, "/api/v1/mfa/totp/disable", auth.Token, `{"code":"000000"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "STEP_UP_REQUIRED")
+	})
+
+	t.Run("TOTPCode", func(t *testing.T) {
+		elevated := reauth(t, r, auth.Token, fmt.Sprintf(`{"code":%q}`, nextTOTPCode(t, enrollment.Secret, 1)))
+
+		rec := r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/password", auth.User.ID), elevated.Token, `{"new_password":"newpassword123"}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	})
+}
+
+// TestStepUpLargeTransfer eşik üstü havalelerin yeniden doğrulama istediğini test eder
+func TestStepUpLargeTransfer(t *testing.T) {
+	r := newTestRouter(t)
+	openAccount := func(t *testing.T, token string) dto.AccountResponse {
+		rec := r.do(http.MethodPost, "/api/v1/accounts", token, `{}`)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var account dto.AccountResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &account))
+		return account
+	}
+
+	sender := registerForStepUp(t, r, "sender@example.com")
+	receiver := registerForStepUp(t, r, "receiver@example.com")
+	r.users.MarkEmailVerified(sender.User.ID)
+	from := openAccount(t, sender.Token)
+	to := openAccount(t, receiver.Token)
+	rec := r.do(http.MethodPost, fmt.Sprintf("/api/v1/accounts/%d/deposits", from.ID), sender.Token, `{"amount":"5000.00"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	transfer := func(token, amount string) int {
+		body := fmt.Sprintf(`{"from_account_id":%d,"to_account_number":%q,"amount":%q}`, from.ID, to.AccountNumber, amount)
+		rec := r.do(http.MethodPost, "/api/v1/transfers", token, body)
+		if rec.Code == http.StatusForbidden {
+			assert.Contains(t, rec.Body.String(), "STEP_UP_REQUIRED")
+		}
+		return rec.Code
+	}
+
+	// Eşiğin altındaki havale normal token ile yapılabilir
+	assert.Equal(t, http.StatusCreated, transfer(sender.Token, "999.99"))
+	// Eşik ve üzeri yeniden doğrulama ister
+	assert.Equal(t, http.StatusForbidden, transfer(sender.Token, "1000.00"))
+
+	elevated := reauth(t, r, sender.Token, `{"password":"password123"}`)
+	assert.Equal(t, http.StatusCreated, transfer(elevated.Token, "1000.00"))
+}
+
+// TestReauthLockout yeniden doğrulamadaki yanlış şifrelerin giriş kilidine sayıldığını test eder
+func TestReauthLockout(t *testing.T) {
+	r := newTestRouter(t)
+	auth := registerForStepUp(t, r, "reauth-lock@example.com")
+
+	// Çalınan access token ile şifre tahmini sınırsız olmamalı
+	for i := 0; i < testLoginPolicy.MaxAccountFailures; i++ {
+		rec := r.do(http.MethodPost, "/api/v1/reauth", auth.Token, `{"password":"wrongpassword"}`)
+		require.Equal(t, http.StatusUnauthorized, rec.Code, rec.Body.String())
+		r.logins.Rewind(time.Minute)
+	}
+
+	rec := r.do(http.MethodPost, "/api/v1/reauth", auth.Token, `{"password":"password123"}`)
+	assert.Equal(t, http.StatusLocked, rec.Code)
+	assert.Contains(t, rec.Body.String(), "ACCOUNT_LOCKED")
+
+	// Kilit girişi de kapsar
+	rec = r.do(http.MethodPost, "/api/v1/login", "", `{"email":"reauth-lock@example.com","password":"password123"}`)
+	assert.Equal(t, http.StatusLocked, rec.Code)
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/common/money"
+	"github.com/yusufziyrek/bank-app/internal/model"
+	"github.com/yusufziyrek/bank-app/internal/service"
+)
+
+type transactionTestEnv struct {
+	accounts     *MockAccountRepository
+	ledger       *MockLedgerRepository
+	transactions *MockTransactionRepository
+	svc          service.TransactionService
+	ledgerSvc    service.LedgerService
+}
+
+func newTransactionTestEnv() *transactionTestEnv {
+	accounts := NewMockAccountRepository()
+	ledger := NewLinkedMockLedgerRepository(accounts)
+	transactions := NewMockTransactionRepository()
+	return &transactionTestEnv{
+		accounts:     accounts,
+		ledger:       ledger,
+		transactions: transactions,
+		svc:          service.NewTransactionService(accounts, ledger, transactions),
+		ledgerSvc:    service.NewLedgerService(ledger),
+	}
+}
+
+func (e *transactionTestEnv) openAccount(userID int64) *model.Account {
+	a := &model.Account{UserID: userID, Balance: money.New(0, "TRY"), Currency: "TRY", IsActive: true}
+	e.accounts.AddTestAccount(a)
+	a.AccountNumber = fmt.Sprintf("TR%018d", a.ID)
+	return a
+}
+
+// fund hesaba test için para yatırır
+func (e *transactionTestEnv) fund(t *testing.T, a *model.Account, amount string) {
+	_, _, err := e.svc.Deposit(context.Background(), a.UserID, a.ID, try(amount), "")
+	require.NoError(t, err)
+}
+
+// TestTransactionServiceWithMock TransactionService için mock repository ile testler
+func TestTransactionServiceWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Deposit_Success", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		a := env.openAccount(1)
+
+		tx, account, err := env.svc.Deposit(ctx, 1, a.ID, money.MustParse("150.25", ""), "maaş")
+		require.NoError(t, err)
+		assert.NotZero(t, tx.ID)
+		assert.NotZero(t, tx.JournalEntryID)
+		assert.Equal(t, model.TransactionDeposit, tx.Type)
+		assert.Equal(t, try("150.25"), tx.Amount)
+		assert.Equal(t, try("150.25"), account.Balance)
+
+		stored, err := env.accounts.GetAccountByID(ctx, a.ID)
+		require.NoError(t, err)
+		assert.Equal(t, try("150.25"), stored.Balance)
+		assert.Len(t, env.transactions.Transactions(), 1)
+
+		report, err := env.ledgerSvc.Reconcile(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.Balanced())
+	})
+
+	t.Run("Withdraw_Success", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		a := env.openAccount(1)
+
+		_, _, err := env.svc.Deposit(ctx, 1, a.ID, try("100"), "")
+		require.NoError(t, err)
+
+		tx, account, err := env.svc.Withdraw(ctx, 1, a.ID, try("40.10"), "ATM")
+		require.NoError(t, err)
+		assert.Equal(t, model.TransactionWithdraw, tx.Type)
+		assert.Equal(t, try("-40.10"), tx.Amount)
+		assert.Equal(t, try("59.90"), account.Balance)
+
+		report, err := env.ledgerSvc.Reconcile(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.Balanced())
+	})
+
+	t.Run("Withdraw_EntireBalance", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		a := env.openAccount(1)
+
+		_, _, err := env.svc.Deposit(ctx, 1, a.ID, try("10"), "")
+		require.NoError(t, err)
+
+		_, account, err := env.svc.Withdraw(ctx, 1, a.ID, try("10"), "")
+		require.NoError(t, err)
+		assert.True(t, account.Balance.IsZero())
+	})
+
+	t.Run("Withdraw_InsufficientFunds", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		a := env.openAccount(1)
+
+		_, _, err := env.svc.Deposit(ctx, 1, a.ID, try("10"), "")
+		require.NoError(t, err)
+
+		_, _, err = env.svc.Withdraw(ctx, 1, a.ID, try("10.01"), "")
+		assert.ErrorIs(t, err, service.ErrInsufficientFunds)
+
+		// Bakiye ve işlem geçmişi değişmemeli
+		stored, err := env.accounts.GetAccountByID(ctx, a.ID)
+		require.NoError(t, err)
+		assert.Equal(t, try("10"), stored.Balance)
+		assert.Len(t, env.transactions.Transactions(), 1)
+	})
+
+	t.Run("InvalidAmount", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		a := env.openAccount(1)
+
+		for _, amount := range []money.Money{try("0"), try("-5")} {
+			_, _, err := env.svc.Deposit(ctx, 1, a.ID, amount, "")
+			assert.ErrorIs(t, err, service.ErrInvalidAmount)
+			_, _, err = env.svc.Withdraw(ctx, 1, a.ID, amount, "")
+			assert.ErrorIs(t, err, service.ErrInvalidAmount)
+		}
+	})
+
+	t.Run("CurrencyMismatch", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		a := env.openAccount(1)
+
+		_, _, err := env.svc.Deposit(ctx, 1, a.ID, money.MustParse("10", "EUR"), "")
+		assert.ErrorIs(t, err, service.ErrCurrencyMismatch)
+	})
+
+	t.Run("OtherUsersAccount", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		a := env.openAccount(2)
+
+		_, _, err := env.svc.Deposit(ctx, 1, a.ID, try("10"), "")
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+		_, _, err = env.svc.Withdraw(ctx, 1, a.ID, try("10"), "")
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	})
+
+	t.Run("ClosedAccount", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		a := &model.Account{UserID: 1, Balance: money.New(0, "TRY"), Currency: "TRY", IsActive: false}
+		env.accounts.AddTestAccount(a)
+
+		_, _, err := env.svc.Deposit(ctx, 1, a.ID, try("10"), "")
+		assert.ErrorIs(t, err, service.ErrAccountClosed)
+	})
+
+	t.Run("AccountNotFound", func(t *testing.T) {
+		env := newTransactionTestEnv()
+
+		_, _, err := env.svc.Deposit(ctx, 1, 999, try("10"), "")
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	})
+}
+
+// TestTransferWithMock Transfer akışı için mock repository ile testler
+func TestTransferWithMock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Transfer_Success", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		from := env.openAccount(1)
+		to := env.openAccount(2)
+		env.fund(t, from, "100")
+
+		transfer, account, err := env.svc.Transfer(ctx, 1, from.ID, to.AccountNumber, try("35.75"), "kira")
+		require.NoError(t, err)
+		assert.NotZero(t, transfer.ID)
+		assert.Equal(t, from.ID, transfer.FromAccountID)
+		assert.Equal(t, to.ID, transfer.ToAccountID)
+		assert.Equal(t, try("35.75"), transfer.Amount)
+		assert.Equal(t, try("64.25"), account.Balance)
+
+		// İki bağlı işlem aynı transfer ID'sini ve yevmiye kaydını paylaşmalı
+		require.Len(t, transfer.Transactions, 2)
+		debit, credit := transfer.Transactions[0], transfer.Transactions[1]
+		assert.Equal(t, from.ID, debit.AccountID)
+		assert.Equal(t, try("-35.75"), debit.Amount)
+		assert.Equal(t, to.ID, credit.AccountID)
+		assert.Equal(t, try("35.75"), credit.Amount)
+		for _, tx := range transfer.Transactions {
+			assert.Equal(t, model.TransactionTransfer, tx.Type)
+			require.NotNil(t, tx.TransferID)
+			assert.Equal(t, transfer.ID, *tx.TransferID)
+			assert.Equal(t, transfer.JournalEntryID, tx.JournalEntryID)
+		}
+		assert.NotEqual(t, debit.ID, credit.ID)
+
+		stored, err := env.accounts.GetAccountByID(ctx, to.ID)
+		require.NoError(t, err)
+		assert.Equal(t, try("35.75"), stored.Balance)
+
+		report, err := env.ledgerSvc.Reconcile(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.Balanced())
+	})
+
+	t.Run("Transfer_LowerIDRecipient", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		to := env.openAccount(2)
+		from := env.openAccount(1)
+		env.fund(t, from, "50")
+
+		transfer, _, err := env.svc.Transfer(ctx, 1, from.ID, to.AccountNumber, try("50"), "")
+		require.NoError(t, err)
+		assert.Equal(t, from.ID, transfer.FromAccountID)
+		assert.Equal(t, to.ID, transfer.ToAccountID)
+
+		stored, err := env.accounts.GetAccountByID(ctx, to.ID)
+		require.NoError(t, err)
+		assert.Equal(t, try("50"), stored.Balance)
+	})
+
+	t.Run("Transfer_InsufficientFunds", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		from := env.openAccount(1)
+		to := env.openAccount(2)
+		env.fund(t, from, "10")
+
+		_, _, err := env.svc.Transfer(ctx, 1, from.ID, to.AccountNumber, try("10.01"), "")
+		assert.ErrorIs(t, err, service.ErrInsufficientFunds)
+
+		// Hiçbir bakiye ya da işlem değişmemeli
+		stored, err := env.accounts.GetAccountByID(ctx, to.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.Balance.IsZero())
+		assert.Len(t, env.transactions.Transactions(), 1)
+	})
+
+	t.Run("Transfer_SameAccount", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		a := env.openAccount(1)
+		env.fund(t, a, "10")
+
+		_, _, err := env.svc.Transfer(ctx, 1, a.ID, a.AccountNumber, try("5"), "")
+		assert.ErrorIs(t, err, service.ErrSameAccount)
+	})
+
+	t.Run("Transfer_RecipientNotFound", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		from := env.openAccount(1)
+		env.fund(t, from, "10")
+
+		_, _, err := env.svc.Transfer(ctx, 1, from.ID, "TR000000000000000999", try("5"), "")
+		assert.ErrorIs(t, err, service.ErrRecipientNotFound)
+
+		// Sistem kasa hesabına transfer yapılamamalı
+		_, _, err = env.svc.Transfer(ctx, 1, from.ID, "SYS-CASH-TRY", try("5"), "")
+		assert.ErrorIs(t, err, service.ErrRecipientNotFound)
+	})
+
+	t.Run("Transfer_FromOtherUsersAccount", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		from := env.openAccount(2)
+		to := env.openAccount(1)
+		env.fund(t, from, "10")
+
+		_, _, err := env.svc.Transfer(ctx, 1, from.ID, to.AccountNumber, try("5"), "")
+		assert.ErrorIs(t, err, service.ErrAccountNotFound)
+	})
+
+	t.Run("Transfer_ClosedRecipient", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		from := env.openAccount(1)
+		to := env.openAccount(2)
+		env.fund(t, from, "10")
+		require.NoError(t, env.accounts.CloseAccount(ctx, to.ID))
+
+		_, _, err := env.svc.Transfer(ctx, 1, from.ID, to.AccountNumber, try("5"), "")
+		assert.ErrorIs(t, err, service.ErrAccountClosed)
+	})
+
+	t.Run("Transfer_CurrencyMismatch", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		from := env.openAccount(1)
+		env.fund(t, from, "10")
+		to := &model.Account{UserID: 2, AccountNumber: "TR000000000000000777", Balance: money.New(0, "EUR"), Currency: "EUR", IsActive: true}
+		env.accounts.AddTestAccount(to)
+
+		_, _, err := env.svc.Transfer(ctx, 1, from.ID, to.AccountNumber, try("5"), "")
+		assert.ErrorIs(t, err, service.ErrCurrencyMismatch)
+	})
+
+	t.Run("Transfer_InvalidAmount", func(t *testing.T) {
+		env := newTransactionTestEnv()
+		from := env.openAccount(1)
+		to := env.openAccount(2)
+
+		_, _, err := env.svc.Transfer(ctx, 1, from.ID, to.AccountNumber, try("0"), "")
+		assert.ErrorIs(t, err, service.ErrInvalidAmount)
+	})
+}
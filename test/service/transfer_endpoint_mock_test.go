@@ -0,0 +1,51 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yusufziyrek/bank-app/internal/controller/dto"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// TestTransferResponseHidesRecipient havale cevabının alıcının hesap ID'sini ve hareketini göstermediğini test eder
+func TestTransferResponseHidesRecipient(t *testing.T) {
+	r := newTestRouter(t)
+	openAccount := func(t *testing.T, token string) dto.AccountResponse {
+		rec := r.do(http.MethodPost, "/api/v1/accounts", token, `{}`)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var account dto.AccountResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &account))
+		return account
+	}
+
+	sender := registerForStepUp(t, r, "payer@example.com")
+	receiver := registerForStepUp(t, r, "payee@example.com")
+	r.users.MarkEmailVerified(sender.User.ID)
+	from := openAccount(t, sender.Token)
+	to := openAccount(t, receiver.Token)
+	rec := r.do(http.MethodPost, fmt.Sprintf("/api/v1/accounts/%d/deposits", from.ID), sender.Token, `{"amount":"100.00"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	body := fmt.Sprintf(`{"from_account_id":%d,"to_account_number":%q,"amount":"25.00"}`, from.ID, to.AccountNumber)
+	rec = r.do(http.MethodPost, "/api/v1/transfers", sender.Token, body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "to_account_id")
+
+	var result dto.TransferResultResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, from.ID, result.Transfer.FromAccountID)
+	assert.Equal(t, to.AccountNumber, result.Transfer.ToAccountNumber)
+
+	// Yalnızca gönderenin borç hareketi döner
+	debit := result.Transfer.Transaction
+	assert.Equal(t, from.ID, debit.AccountID)
+	assert.Equal(t, model.TransactionTransfer, debit.Type)
+	assert.Equal(t, int64(-2500), debit.Amount.MinorUnits())
+	require.NotNil(t, debit.TransferID)
+	assert.Equal(t, result.Transfer.ID, *debit.TransferID)
+}
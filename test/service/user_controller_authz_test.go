@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yusufziyrek/bank-app/internal/model"
+)
+
+// TestUserControllerAuthorization her UserController handler'ı için rol ve sahiplik kontrollerini test eder
+func TestUserControllerAuthorization(t *testing.T) {
+	setup := func(t *testing.T) (*testRouter, *model.User, *model.User, string, string) {
+		r := newTestRouter(t)
+		admin := &model.User{FullName: "Admin", Email: "admin@example.com", Role: model.RoleAdmin, IsActive: true}
+		user := &model.User{FullName: "User", Email: "user@example.com", Role: model.RoleUser, IsActive: true}
+		r.users.AddTestUser(admin)
+		r.users.AddTestUser(user)
+		return r, admin, user, tokenFor(t, admin.ID, model.RoleAdmin), tokenFor(t, user.ID, model.RoleUser)
+	}
+
+	t.Run("MissingToken", func(t *testing.T) {
+		r, _, user, _, _ := setup(t)
+
+		// echo-jwt eksik token için 400 döner
+		rec := r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", user.ID), "", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", user.ID), "invalid.token.value", "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("GetAll", func(t *testing.T) {
+		r, _, _, adminToken, userToken := setup(t)
+
+		rec := r.do(http.MethodGet, "/api/v1/users", userToken, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"code":"FORBIDDEN"`)
+
+		rec = r.do(http.MethodGet, "/api/v1/users", adminToken, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"count":2`)
+	})
+
+	t.Run("GetByID", func(t *testing.T) {
+		r, admin, user, adminToken, userToken := setup(t)
+
+		// Kullanıcı kendi kaydını görebilir
+		rec := r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", user.ID), userToken, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// Başka kullanıcının kaydını göremez
+		rec = r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", admin.ID), userToken, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		// Admin herkesi görebilir
+		rec = r.do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", user.ID), adminToken, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("UpdateEmail", func(t *testing.T) {
+		r, admin, user, _, _ := setup(t)
+		// Kimlik bilgisi değişiklikleri yakın zamanda yeniden doğrulama ister
+		adminToken := stepUpTokenFor(t, admin.ID, model.RoleAdmin, time.Now())
+		userToken := stepUpTokenFor(t, user.ID, model.RoleUser, time.Now())
+
+		rec := r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/email", user.ID), userToken, `{"new_email":"self@example.com"}`)
+		// Yeni adres onaylanana kadar email değişmez
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/email", admin.ID), userToken, `{"new_email":"hijack@example.com"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/email", user.ID), adminToken, `{"new_email":"byadmin@example.com"}`)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("UpdatePassword", func(t *testing.T) {
+		r, admin, user, _, _ := setup(t)
+		// Kimlik bilgisi değişiklikleri yakın zamanda yeniden doğrulama ister
+		adminToken := stepUpTokenFor(t, admin.ID, model.RoleAdmin, time.Now())
+		userToken := stepUpTokenFor(t, user.ID, model.RoleUser, time.Now())
+
+		rec := r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/password", admin.ID), userToken, `{"new_password":"newpassword123"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		// Şifre değişikliği kullanıcının mevcut token'larını da geçersiz kılar
+		rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/password", user.ID), userToken, `{"new_password":"newpassword123"}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		// Aynı şifre tekrar kullanılamayacağı için admin farklı bir şifre belirler
+		rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/password", user.ID), adminToken, `{"new_password":"adminpassword123"}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("UpdateStatus", func(t *testing.T) {
+		r, _, user, adminToken, userToken := setup(t)
+
+		// Kullanıcı kendi durumunu bile değiştiremez
+		rec := r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/status", user.ID), userToken, `{"is_active":true}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = r.do(http.MethodPut, fmt.Sprintf("/api/v1/users/%d/status", user.ID), adminToken, `{"is_active":true}`)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("DeleteByID", func(t *testing.T) {
+		r, admin, user, adminToken, userToken := setup(t)
+
+		rec := r.do(http.MethodDelete, fmt.Sprintf("/api/v1/users/%d", user.ID), userToken, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		rec = r.do(http.MethodDelete, fmt.Sprintf("/api/v1/users/%d", admin.ID), userToken, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = r.do(http.MethodDelete, fmt.Sprintf("/api/v1/users/%d", user.ID), adminToken, "")
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("TokenWithoutRole", func(t *testing.T) {
+		r, _, user, _, _ := setup(t)
+
+		rec := r.do(http.MethodGet, "/api/v1/users", tokenFor(t, user.ID, ""), "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("LedgerReconciliation_AdminOnly", func(t *testing.T) {
+		r, _, _, adminToken, userToken := setup(t)
+
+		rec := r.do(http.MethodGet, "/api/v1/ledger/reconciliation", userToken, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = r.do(http.MethodGet, "/api/v1/ledger/reconciliation", adminToken, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
@@ -17,7 +17,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("GetAllUsers_Empty", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		users, err := svc.GetAllUsers(ctx)
 		require.NoError(t, err)
@@ -26,7 +26,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("GetAllUsers_WithData", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		// Test kullanıcıları ekle
 		testUsers := []*model.User{
@@ -57,7 +57,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("GetUserByID_Success", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		testUser := &model.User{
 			FullName:     "Test User",
@@ -76,7 +76,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("GetUserByID_NotFound", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		user, err := svc.GetUserByID(ctx, 999)
 		assert.Error(t, err)
@@ -86,7 +86,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("CreateUser_Success", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		newUser := &model.User{
 			FullName:     "New User",
@@ -109,7 +109,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("CreateUser_DuplicateEmail", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		// İlk kullanıcıyı ekle
 		existingUser := &model.User{
@@ -131,63 +131,9 @@ func TestUserServiceWithMock(t *testing.T) {
 		assert.ErrorIs(t, err, service.ErrEmailAlreadyRegistered)
 	})
 
-	t.Run("UpdateUserEmail_Success", func(t *testing.T) {
-		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
-
-		testUser := &model.User{
-			FullName:     "Test User",
-			Email:        "oldemail@example.com",
-			PasswordHash: "hashedpassword",
-		}
-		mockRepo.AddTestUser(testUser)
-
-		newEmail := "newemail@example.com"
-		err := svc.UpdateUserEmail(ctx, testUser.ID, newEmail)
-		require.NoError(t, err)
-
-		// Güncellemenin başarılı olduğunu kontrol et
-		updatedUser, err := svc.GetUserByID(ctx, testUser.ID)
-		require.NoError(t, err)
-		assert.Equal(t, newEmail, updatedUser.Email)
-	})
-
-	t.Run("UpdateUserEmail_UserNotFound", func(t *testing.T) {
-		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
-
-		err := svc.UpdateUserEmail(ctx, 999, "newemail@example.com")
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, service.ErrUserNotFound)
-	})
-
-	t.Run("UpdateUserEmail_DuplicateEmail", func(t *testing.T) {
-		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
-
-		// İki kullanıcı ekle
-		user1 := &model.User{
-			FullName:     "User 1",
-			Email:        "user1@example.com",
-			PasswordHash: "hashedpassword1",
-		}
-		user2 := &model.User{
-			FullName:     "User 2",
-			Email:        "user2@example.com",
-			PasswordHash: "hashedpassword2",
-		}
-		mockRepo.AddTestUser(user1)
-		mockRepo.AddTestUser(user2)
-
-		// User1'in email'ini User2'nin email'ine güncellemeye çalış
-		err := svc.UpdateUserEmail(ctx, user1.ID, user2.Email)
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, service.ErrEmailAlreadyRegistered)
-	})
-
 	t.Run("UpdateUserPassword_Success", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		testUser := &model.User{
 			FullName:     "Test User",
@@ -210,7 +156,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("UpdateUserPassword_UserNotFound", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		err := svc.UpdateUserPassword(ctx, 999, "newpassword")
 		assert.Error(t, err)
@@ -219,7 +165,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("UpdateUserActiveStatus_Success", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		testUser := &model.User{
 			FullName:     "Test User",
@@ -240,7 +186,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("UpdateUserActiveStatus_UserNotFound", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		err := svc.UpdateUserActiveStatus(ctx, 999, false)
 		assert.Error(t, err)
@@ -249,7 +195,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("DeleteUserByID_Success", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		testUser := &model.User{
 			FullName:     "Test User",
@@ -269,7 +215,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("DeleteUserByID_UserNotFound", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		err := svc.DeleteUserByID(ctx, 999)
 		assert.Error(t, err)
@@ -278,7 +224,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("AuthenticateUser_Success", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		// Hash'lenmiş şifre ile kullanıcı oluştur
 		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
@@ -299,7 +245,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("AuthenticateUser_InvalidCredentials", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 		testUser := &model.User{
@@ -319,7 +265,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("AuthenticateUser_UserNotFound", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		user, err := svc.AuthenticateUser(ctx, "nonexistent@example.com", "password123")
 		assert.Error(t, err)
@@ -329,7 +275,7 @@ func TestUserServiceWithMock(t *testing.T) {
 
 	t.Run("AuthenticateUser_InactiveAccount", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 		testUser := &model.User{
@@ -353,7 +299,7 @@ func TestUserServiceMockIntegration(t *testing.T) {
 
 	t.Run("FullUserLifecycleWithMock", func(t *testing.T) {
 		mockRepo := NewMockUserRepository()
-		svc := service.NewUserService(mockRepo)
+		svc := service.NewUserService(mockRepo, testPasswordPolicy)
 
 		// 1. Kullanıcı oluştur
 		newUser := &model.User{
@@ -378,42 +324,38 @@ func TestUserServiceMockIntegration(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, newUser.ID, authenticatedUser.ID)
 
-		// 4. Email güncelle
-		err = svc.UpdateUserEmail(ctx, newUser.ID, "updated@example.com")
-		require.NoError(t, err)
-
-		// 5. Şifre güncelle
+		// 4. Şifre güncelle
 		err = svc.UpdateUserPassword(ctx, newUser.ID, "newpassword")
 		require.NoError(t, err)
 
-		// 6. Durum güncelle
+		// 5. Durum güncelle
 		err = svc.UpdateUserActiveStatus(ctx, newUser.ID, false)
 		require.NoError(t, err)
 
-		// 7. Güncellenmiş kullanıcıyı kontrol et
+		// 6. Güncellenmiş kullanıcıyı kontrol et
 		updatedUser, err := svc.GetUserByID(ctx, newUser.ID)
 		require.NoError(t, err)
-		assert.Equal(t, "updated@example.com", updatedUser.Email)
+		assert.Equal(t, "lifecycle@example.com", updatedUser.Email)
 		assert.False(t, updatedUser.IsActive)
 
-		// 8. Inactive kullanıcı ile giriş yapmaya çalış
+		// 7. Inactive kullanıcı ile giriş yapmaya çalış
 		_, err = svc.AuthenticateUser(ctx, updatedUser.Email, "newpassword")
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, service.ErrInactiveAccount)
 
-		// 9. Kullanıcıyı tekrar aktif et
+		// 8. Kullanıcıyı tekrar aktif et
 		err = svc.UpdateUserActiveStatus(ctx, newUser.ID, true)
 		require.NoError(t, err)
 
-		// 10. Tekrar giriş yap
+		// 9. Tekrar giriş yap
 		_, err = svc.AuthenticateUser(ctx, updatedUser.Email, "newpassword")
 		require.NoError(t, err)
 
-		// 11. Kullanıcıyı sil
+		// 10. Kullanıcıyı sil
 		err = svc.DeleteUserByID(ctx, newUser.ID)
 		require.NoError(t, err)
 
-		// 12. Kullanıcının silindiğini kontrol et
+		// 11. Kullanıcının silindiğini kontrol et
 		_, err = svc.GetUserByID(ctx, newUser.ID)
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, service.ErrUserNotFound)
@@ -35,7 +35,7 @@ func TestUserService(t *testing.T) {
 	})
 
 	repo := repository.NewUserRepository(pool)
-	svc := service.NewUserService(repo)
+	svc := service.NewUserService(repo, testPasswordPolicy)
 
 	t.Run("GetAllUsers", func(t *testing.T) {
 		users, err := svc.GetAllUsers(ctx)
@@ -127,39 +127,6 @@ func TestUserService(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
-	t.Run("UpdateUserEmail_Success", func(t *testing.T) {
-		// Önce test kullanıcısını al
-		testUser, err := infrastructure.GetTestUserByEmail(ctx, pool, "test2@example.com")
-		require.NoError(t, err)
-
-		newEmail := "serviceupdated@example.com"
-		err = svc.UpdateUserEmail(ctx, testUser.ID, newEmail)
-		require.NoError(t, err)
-
-		// Güncellemenin başarılı olduğunu kontrol et
-		updatedUser, err := svc.GetUserByID(ctx, testUser.ID)
-		require.NoError(t, err)
-		assert.Equal(t, newEmail, updatedUser.Email)
-		assert.True(t, updatedUser.UpdatedAt.After(testUser.UpdatedAt))
-	})
-
-	t.Run("UpdateUserEmail_UserNotFound", func(t *testing.T) {
-		err := svc.UpdateUserEmail(ctx, 99999, "newemail@example.com")
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, service.ErrUserNotFound)
-	})
-
-	t.Run("UpdateUserEmail_DuplicateEmail", func(t *testing.T) {
-		// Önce test kullanıcısını al
-		testUser, err := infrastructure.GetTestUserByEmail(ctx, pool, "test1@example.com")
-		require.NoError(t, err)
-
-		// Zaten var olan bir email'e güncelle
-		err = svc.UpdateUserEmail(ctx, testUser.ID, "test2@example.com")
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, service.ErrEmailAlreadyRegistered)
-	})
-
 	t.Run("UpdateUserPassword_Success", func(t *testing.T) {
 		// Önce test kullanıcısını al
 		testUser, err := infrastructure.GetTestUserByEmail(ctx, pool, "test1@example.com")
@@ -307,7 +274,7 @@ func TestUserServiceIntegration(t *testing.T) {
 	})
 
 	repo := repository.NewUserRepository(pool)
-	svc := service.NewUserService(repo)
+	svc := service.NewUserService(repo, testPasswordPolicy)
 
 	t.Run("FullUserServiceLifecycle", func(t *testing.T) {
 		// 1. Kullanıcı oluştur
@@ -333,42 +300,38 @@ func TestUserServiceIntegration(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, newUser.ID, authenticatedUser.ID)
 
-		// 4. Email güncelle
-		err = svc.UpdateUserEmail(ctx, newUser.ID, "serviceupdated@example.com")
-		require.NoError(t, err)
-
-		// 5. Şifre güncelle
+		// 4. Şifre güncelle
 		err = svc.UpdateUserPassword(ctx, newUser.ID, "newservicepassword")
 		require.NoError(t, err)
 
-		// 6. Durum güncelle
+		// 5. Durum güncelle
 		err = svc.UpdateUserActiveStatus(ctx, newUser.ID, false)
 		require.NoError(t, err)
 
-		// 7. Güncellenmiş kullanıcıyı kontrol et
+		// 6. Güncellenmiş kullanıcıyı kontrol et
 		updatedUser, err := svc.GetUserByID(ctx, newUser.ID)
 		require.NoError(t, err)
-		assert.Equal(t, "serviceupdated@example.com", updatedUser.Email)
+		assert.Equal(t, "servicelifecycle@example.com", updatedUser.Email)
 		assert.False(t, updatedUser.IsActive)
 
-		// 8. Inactive kullanıcı ile giriş yapmaya çalış
+		// 7. Inactive kullanıcı ile giriş yapmaya çalış
 		_, err = svc.AuthenticateUser(ctx, updatedUser.Email, "newservicepassword")
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, service.ErrInactiveAccount)
 
-		// 9. Kullanıcıyı tekrar aktif et
+		// 8. Kullanıcıyı tekrar aktif et
 		err = svc.UpdateUserActiveStatus(ctx, newUser.ID, true)
 		require.NoError(t, err)
 
-		// 10. Tekrar giriş yap
+		// 9. Tekrar giriş yap
 		_, err = svc.AuthenticateUser(ctx, updatedUser.Email, "newservicepassword")
 		require.NoError(t, err)
 
-		// 11. Kullanıcıyı sil
+		// 10. Kullanıcıyı sil
 		err = svc.DeleteUserByID(ctx, newUser.ID)
 		require.NoError(t, err)
 
-		// 12. Kullanıcının silindiğini kontrol et
+		// 11. Kullanıcının silindiğini kontrol et
 		_, err = svc.GetUserByID(ctx, newUser.ID)
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, service.ErrUserNotFound)
@@ -393,7 +356,7 @@ func TestUserServiceEdgeCases(t *testing.T) {
 	})
 
 	repo := repository.NewUserRepository(pool)
-	svc := service.NewUserService(repo)
+	svc := service.NewUserService(repo, testPasswordPolicy)
 
 	t.Run("CreateUserWithEmptyRole", func(t *testing.T) {
 		newUser := &model.User{